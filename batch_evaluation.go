@@ -0,0 +1,116 @@
+package statsig
+
+import "sync"
+
+// Controls CheckGateForUsers and GetExperimentForUsers
+type BatchEvaluationOptions struct {
+	// How many goroutines evaluate the users. Defaults to one.
+	Parallelism int
+	// Logs an exposure for each user, as CheckGate and GetExperiment do. Off by default, since batch jobs
+	// segmenting users offline do not expose them to anything.
+	LogExposures bool
+}
+
+// Checks the value of a Feature Gate for each user, in the order of the users. The gate is looked up once for
+// the whole batch. Users without a user ID or custom ID fail the gate, as do conditions only Statsig's servers
+// can evaluate, since the batch never calls the network.
+func (c *Client) CheckGateForUsers(users []User, gate string, options *BatchEvaluationOptions) []bool {
+	values := make([]bool, len(users))
+	c.errorBoundary.captureVoid(func() {
+		evaluate := c.evaluator.getBatchGateEvaluation(gate, len(users))
+		c.evaluateForUsers(users, options, func(i int, user User, context *logContext) {
+			res := evaluate(user)
+			if res.FetchFromServer {
+				return
+			}
+			values[i] = res.Pass
+			if context != nil {
+				c.logger.logGateExposure(user, gate, res.Pass, res.Id, res.SecondaryExposures, res.EvaluationDetails, context)
+			}
+		})
+	})
+	return values
+}
+
+// Gets the DynamicConfig value of an Experiment for each user, in the order of the users, without secondary
+// exposures. Users for whom the experiment cannot be evaluated get an empty config, as for CheckGateForUsers.
+func (c *Client) GetExperimentForUsers(users []User, experiment string, options *BatchEvaluationOptions) []DynamicConfig {
+	configs := make([]DynamicConfig, len(users))
+	c.errorBoundary.captureVoid(func() {
+		for i := range configs {
+			configs[i] = *NewConfig(experiment, nil, "")
+		}
+		evaluate := c.evaluator.getBatchConfigEvaluation(experiment, len(users))
+		c.evaluateForUsers(users, options, func(i int, user User, context *logContext) {
+			res := evaluate(user)
+			if res.FetchFromServer {
+				return
+			}
+			configs[i] = res.ConfigValue
+			if context != nil {
+				c.logger.logConfigExposure(user, experiment, res.Id, res.SecondaryExposures, res.EvaluationDetails, context)
+			}
+		})
+	})
+	return configs
+}
+
+// Splits the users between the goroutines, each queueing its exposures at once when it is done.
+// The context is nil when exposures are not logged.
+func (c *Client) evaluateForUsers(users []User, options *BatchEvaluationOptions, evaluate func(i int, user User, context *logContext)) {
+	if options == nil {
+		options = &BatchEvaluationOptions{}
+	}
+	workers := options.Parallelism
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(users) {
+		workers = len(users)
+	}
+	// Copied once for the batch rather than for each user
+	normalizeOptions := *c.options
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(first int) {
+			defer wg.Done()
+			var context *logContext
+			if options.LogExposures {
+				exposures := make([]interface{}, 0)
+				context = &logContext{batch: &exposures}
+				defer func() { c.logger.queueBatch(exposures) }()
+			}
+			c.errorBoundary.captureVoid(func() {
+				for i := first; i < len(users); i += workers {
+					if c.hasUnitID(users[i]) {
+						evaluate(i, c.stringInterner.internUser(normalizeUser(users[i], normalizeOptions)), context)
+					}
+				}
+			})
+		}(w)
+	}
+	wg.Wait()
+}
+
+// Evaluates the gate for a user, with the spec looked up once. Overridden and unknown gates go through evalGate,
+// which handles them without a spec.
+func (e *evaluator) getBatchGateEvaluation(name string, users int) func(User) *evalResult {
+	e.metrics.increment(metricEvaluations, "gate", float64(users))
+	rulesets := e.store.getRulesets()
+	gate, exists := rulesets.featureGates[name]
+	if _, overridden := e.getGateOverride(name); overridden || !exists {
+		return func(user User) *evalResult { return e.evalGate(user, rulesets, name, 0) }
+	}
+	return func(user User) *evalResult { return e.eval(user, rulesets, gate, 1) }
+}
+
+func (e *evaluator) getBatchConfigEvaluation(name string, users int) func(User) *evalResult {
+	e.metrics.increment(metricEvaluations, "config", float64(users))
+	rulesets := e.store.getRulesets()
+	config, exists := rulesets.dynamicConfigs[name]
+	if _, overridden := e.getConfigOverride(name); overridden || !exists {
+		return func(user User) *evalResult { return e.evalConfig(user, rulesets, name, 0) }
+	}
+	return func(user User) *evalResult { return e.eval(user, rulesets, config, 1) }
+}
@@ -0,0 +1,61 @@
+package statsig
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestBatchEvaluation(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	c := NewClientWithOptions("secret-key", &Options{
+		LocalMode:            true,
+		BootstrapValues:      string(bytes),
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+	users := make([]User, 0, 201)
+	for i := 0; i < 200; i++ {
+		users = append(users, User{UserID: fmt.Sprint(i), Email: fmt.Sprintf("user%d@statsig.com", i%2)})
+	}
+	users = append(users, User{})
+	queuedEvents := func() int {
+		c.logger.mu.Lock()
+		defer c.logger.mu.Unlock()
+		return len(c.logger.events)
+	}
+
+	for _, parallelism := range []int{0, 1, 4} {
+		t.Run(fmt.Sprintf("matches single evaluations with parallelism %d", parallelism), func(t *testing.T) {
+			options := &BatchEvaluationOptions{Parallelism: parallelism}
+			values := c.CheckGateForUsers(users, "fractional_gate", options)
+			configs := c.GetExperimentForUsers(users, "sample_experiment", options)
+			if len(values) != len(users) || len(configs) != len(users) {
+				t.Fatalf("Expected a result for each user")
+			}
+			for i, user := range users[:len(users)-1] {
+				if values[i] != c.CheckGateWithExposureLoggingDisabled(user, "fractional_gate") {
+					t.Errorf("Expected the gate value of user %d to match CheckGate", i)
+				}
+				if expected := c.GetExperimentWithExposureLoggingDisabled(user, "sample_experiment"); configs[i].RuleID != expected.RuleID || configs[i].GroupName != expected.GroupName {
+					t.Errorf("Expected the experiment of user %d to match GetExperiment, got %+v", i, configs[i])
+				}
+			}
+			last := len(users) - 1
+			if values[last] || configs[last].RuleID != "" || configs[last].Name != "sample_experiment" {
+				t.Errorf("Expected empty results for a user without IDs")
+			}
+			if queued := queuedEvents(); queued != 0 {
+				t.Errorf("Expected no exposures by default, got %d", queued)
+			}
+		})
+	}
+
+	t.Run("logs exposures when asked to", func(t *testing.T) {
+		c.CheckGateForUsers(users, "always_on_gate", &BatchEvaluationOptions{Parallelism: 3, LogExposures: true})
+		if queued := queuedEvents(); queued != len(users)-1 {
+			t.Errorf("Expected an exposure for each user with an ID, got %d", queued)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package statsig
+
+import (
+	"reflect"
+	"sync"
+)
+
+type ChangeType string
+
+const (
+	ChangeTypeAdded   ChangeType = "added"
+	ChangeTypeUpdated ChangeType = "updated"
+	ChangeTypeRemoved ChangeType = "removed"
+)
+
+// Describes how a subscribed gate, config or layer changed when new rulesets were applied
+type ChangeEvent struct {
+	Name string
+	// One of SpecTypeFeatureGate, SpecTypeDynamicConfig or SpecTypeLayer
+	Type       string
+	ChangeType ChangeType
+	// The lcut of the rulesets that introduced the change
+	LastConfigSyncTime int64
+}
+
+type changeListeners struct {
+	listeners map[string]map[int]func(ChangeEvent)
+	nextID    int
+	mu        sync.RWMutex
+}
+
+func newChangeListeners() *changeListeners {
+	return &changeListeners{listeners: make(map[string]map[int]func(ChangeEvent))}
+}
+
+// Registers the listener and returns a function that removes it
+func (c *changeListeners) subscribe(name string, listener func(ChangeEvent)) func() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id := c.nextID
+	c.nextID++
+	if c.listeners[name] == nil {
+		c.listeners[name] = make(map[int]func(ChangeEvent))
+	}
+	c.listeners[name][id] = listener
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.listeners[name], id)
+		if len(c.listeners[name]) == 0 {
+			delete(c.listeners, name)
+		}
+	}
+}
+
+func (c *changeListeners) subscribedNames() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	names := make([]string, 0, len(c.listeners))
+	for name := range c.listeners {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (c *changeListeners) notify(event ChangeEvent) {
+	c.mu.RLock()
+	listeners := make([]func(ChangeEvent), 0, len(c.listeners[event.Name]))
+	for _, listener := range c.listeners[event.Name] {
+		listeners = append(listeners, listener)
+	}
+	c.mu.RUnlock()
+	for _, listener := range listeners {
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					logRecoveredPanic("change listener for "+event.Name, err)
+				}
+			}()
+			listener(event)
+		}()
+	}
+}
+
+type specSnapshot map[string]configSpec
+
+// Copies the current definitions of the given names, keyed by spec type and name
+func takeSpecSnapshot(names []string, specsByType map[string]map[string]configSpec) specSnapshot {
+	snapshot := make(specSnapshot)
+	for specType, specs := range specsByType {
+		for _, name := range names {
+			if spec, exists := specs[name]; exists {
+				snapshot[specType+":"+name] = spec
+			}
+		}
+	}
+	return snapshot
+}
+
+// Compares the subscribed definitions before and after an update
+func diffSpecSnapshots(names []string, before specSnapshot, after specSnapshot, time int64) []ChangeEvent {
+	changes := make([]ChangeEvent, 0)
+	for _, specType := range []string{SpecTypeFeatureGate, SpecTypeDynamicConfig, SpecTypeLayer} {
+		for _, name := range names {
+			key := specType + ":" + name
+			old, existed := before[key]
+			updated, exists := after[key]
+			change := ChangeEvent{Name: name, Type: specType, LastConfigSyncTime: time}
+			switch {
+			case !existed && exists:
+				change.ChangeType = ChangeTypeAdded
+			case existed && !exists:
+				change.ChangeType = ChangeTypeRemoved
+			case existed && exists && !reflect.DeepEqual(old, updated):
+				change.ChangeType = ChangeTypeUpdated
+			default:
+				continue
+			}
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
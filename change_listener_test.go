@@ -0,0 +1,75 @@
+package statsig
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSubscribe(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	dataAdapter := dataAdapterWithPollingExample{store: make(map[string]string)}
+	dataAdapter.Set(CONFIG_SPECS_KEY, string(bytes))
+	options := &Options{
+		DataAdapter:          &dataAdapter,
+		LocalMode:            true,
+		ConfigSyncInterval:   20 * time.Millisecond,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	}
+	InitializeWithOptions("secret-key", options)
+	defer ShutdownAndDangerouslyClearInstance()
+
+	var mu sync.Mutex
+	changes := make([]ChangeEvent, 0)
+	unsubscribe := Subscribe("always_on_gate", func(change ChangeEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		changes = append(changes, change)
+	})
+	Subscribe("test_config", func(change ChangeEvent) {
+		panic("listener panics should not break syncing")
+	})
+	getChanges := func() []ChangeEvent {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]ChangeEvent{}, changes...)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if len(getChanges()) != 0 {
+		t.Errorf("Expected no changes while the rulesets are unchanged, got %v", getChanges())
+	}
+
+	var specs map[string]interface{}
+	_ = json.Unmarshal(bytes, &specs)
+	gate := specs["feature_gates"].([]interface{})[0].(map[string]interface{})
+	gate["enabled"] = false
+	specs["time"] = configSyncTime + 1
+	updated, _ := json.Marshal(specs)
+	dataAdapter.Set(CONFIG_SPECS_KEY, string(updated))
+	waitForCondition(t, func() bool { return len(getChanges()) == 1 })
+	change := getChanges()[0]
+	if change.Name != "always_on_gate" || change.Type != SpecTypeFeatureGate ||
+		change.ChangeType != ChangeTypeUpdated || change.LastConfigSyncTime != configSyncTime+1 {
+		t.Errorf("Unexpected change %+v", change)
+	}
+
+	dataAdapter.clearStore(CONFIG_SPECS_KEY)
+	waitForCondition(t, func() bool { return len(getChanges()) == 2 })
+	if getChanges()[1].ChangeType != ChangeTypeRemoved {
+		t.Errorf("Expected the gate to be removed, got %+v", getChanges()[1])
+	}
+
+	unsubscribe()
+	dataAdapter.Set(CONFIG_SPECS_KEY, string(bytes))
+	time.Sleep(100 * time.Millisecond)
+	if len(getChanges()) != 2 {
+		t.Errorf("Expected no changes after unsubscribing, got %v", getChanges())
+	}
+	if !CheckGate(User{UserID: "123"}, "always_on_gate") {
+		t.Errorf("Expected syncing to continue after a listener panicked")
+	}
+}
@@ -1,54 +1,143 @@
 package statsig
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
 // An instance of a StatsigClient for interfacing with Statsig Feature Gates, Dynamic Configs, Experiments, and Event Logging
 type Client struct {
-	sdkKey        string
-	evaluator     *evaluator
-	logger        *logger
-	transport     *transport
-	errorBoundary *errorBoundary
-	options       *Options
-	diagnostics   *diagnostics
+	sdkKey         string
+	evaluator      *evaluator
+	logger         *logger
+	transport      *transport
+	errorBoundary  *errorBoundary
+	options        *Options
+	diagnostics    *diagnostics
+	memoryMonitor  *memoryMonitor
+	stringInterner *stringInterner
+	// Set once Shutdown or ShutdownWithContext is called, after which the Client cannot be restarted
+	shutdown int32
 }
 
-// Initializes a Statsig Client with the given sdkKey
-func NewClient(sdkKey string) *Client {
-	return NewClientWithOptions(sdkKey, &Options{API: DefaultEndpoint})
+// Initializes a Statsig Client with the given sdkKey and functional options
+//
+//	client := statsig.NewClient(sdkKey, statsig.WithAPI(api), statsig.WithPolling(30*time.Second))
+func NewClient(sdkKey string, opts ...Option) *Client {
+	return NewClientWithOptions(sdkKey, NewOptions(opts...))
+}
+
+// Initializes a Statsig Client with the given sdkKey and functional options, like NewClient, but returns an
+// error instead of panicking when the sdkKey is invalid. Settings no Option sets keep their defaults.
+//
+//	client, err := statsig.New(sdkKey, statsig.WithEnvironmentTier("staging"), statsig.WithDataAdapter(adapter))
+func New(sdkKey string, opts ...Option) (*Client, error) {
+	options := NewOptions(opts...)
+	if err := validateSDKKey(sdkKey, options); err != nil {
+		return nil, err
+	}
+	return NewClientWithOptions(sdkKey, options), nil
+}
+
+// Server SDK keys start with "secret". Any key is accepted when no request is made with it.
+func validateSDKKey(sdkKey string, options *Options) error {
+	if !options.LocalMode && !options.DisableNetwork && !strings.HasPrefix(sdkKey, "secret") {
+		return errors.New(InvalidSDKKeyError)
+	}
+	return nil
 }
 
 // Initializes a Statsig Client with the given sdkKey and options
 func NewClientWithOptions(sdkKey string, options *Options) *Client {
+	return newClient(context.Background(), sdkKey, options, nil)
+}
+
+// Cancelling ctx aborts the initial requests. When onInitialized is set, the client is returned right away
+// and loads its rulesets in the background, calling onInitialized once they have loaded.
+func newClient(ctx context.Context, sdkKey string, options *Options, onInitialized func(InitResult)) *Client {
+	start := time.Now()
+	applyEnvironmentVariables(options)
 	diagnostics := newDiagnostics()
+	diagnostics.setTimeSource(getTimeSource(options))
+	if options.DisableDiagnostics {
+		diagnostics.disable()
+	}
 	diagnostics.initialize().overall().start().mark()
 	if len(options.API) == 0 {
 		options.API = "https://statsigapi.net/v1"
 	}
+	if options.PIIScrubbing == PIIScrubbingHash && len(options.PIIScrubbingKey) == 0 {
+		global.Logger().LogError("PIIScrubbingHash requires a PIIScrubbingKey, PII will be stripped instead")
+	}
 	errorBoundary := newErrorBoundary(sdkKey, options, diagnostics)
-	if !options.LocalMode && !strings.HasPrefix(sdkKey, "secret") {
-		err := errors.New(InvalidSDKKeyError)
+	if err := validateSDKKey(sdkKey, options); err != nil {
 		panic(err)
 	}
 	transport := newTransport(sdkKey, options)
+	_, span := transport.tracing.start(ctx, "statsig.initialize")
 	logger := newLogger(transport, options, diagnostics)
-	evaluator := newEvaluator(transport, errorBoundary, options, diagnostics)
-	diagnostics.initialize().overall().end().success(true).mark()
-	return &Client{
-		sdkKey:        sdkKey,
-		evaluator:     evaluator,
-		logger:        logger,
-		transport:     transport,
-		errorBoundary: errorBoundary,
-		options:       options,
-		diagnostics:   diagnostics,
+	evaluator := newEvaluator(transport, errorBoundary, options, diagnostics, initOptions{
+		ctx:        ctx,
+		background: onInitialized != nil,
+	})
+	stringInterner := newStringInterner(options.UserInterningOptions)
+	memoryMonitor := newMemoryMonitor(options.MemoryPressureOptions, logger, diagnostics, evaluator, stringInterner)
+	c := &Client{
+		sdkKey:         sdkKey,
+		evaluator:      evaluator,
+		logger:         logger,
+		transport:      transport,
+		errorBoundary:  errorBoundary,
+		options:        options,
+		diagnostics:    diagnostics,
+		memoryMonitor:  memoryMonitor,
+		stringInterner: stringInterner,
+	}
+	if onInitialized == nil {
+		c.finishInitialize(start, span)
+		return c
+	}
+	go func() {
+		<-evaluator.store.initialized
+		result := c.finishInitialize(start, span)
+		onInitialized(result)
+	}()
+	return c
+}
+
+func (c *Client) finishInitialize(start time.Time, span Span) InitResult {
+	defer span.End()
+	result := c.initResult(start)
+	span.SetAttribute("statsig.lcut", c.evaluator.store.getLastSyncTime())
+	c.transport.metrics.observeInitialization(start, evaluationReason(result.Source), result.Success)
+	c.diagnostics.initialize().overall().end().success(true).mark()
+	// Batches spooled before a restart are replayed once the DataAdapter they may be spooled to is initialized
+	c.logger.replayPendingSpool()
+	return result
+}
+
+func (c *Client) initResult(start time.Time) InitResult {
+	store := c.evaluator.store
+	rulesets := store.getRulesets()
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	result := InitResult{
+		Success:  rulesets.time != 0,
+		Source:   string(rulesets.initReason),
+		Duration: time.Since(start),
 	}
+	if store.sdkKeyRejectedError != nil {
+		result.Error = store.sdkKeyRejectedError
+	}
+	result.IDListsLoaded = !store.idListsSyncedAt.IsZero()
+	return result
 }
 
 // Checks the value of a Feature Gate for the given user
@@ -63,19 +152,77 @@ func (c *Client) CheckGateWithExposureLoggingDisabled(user User, gate string) bo
 	return c.checkGateImpl(user, gate, options)
 }
 
+// Checks the value of a Feature Gate for the given user, and returns the details of the evaluation
+func (c *Client) CheckGateWithDetails(user User, gate string) (bool, EvaluationDetails) {
+	var details EvaluationDetails
+	options := checkGateOptions{logExposure: true, details: &details}
+	return c.checkGateImpl(user, gate, options), details
+}
+
+// Checks the value of a Feature Gate for the given user. The evaluation span, if traced, is a child of the span in ctx.
+func (c *Client) CheckGateWithContext(ctx context.Context, user User, gate string) bool {
+	options := checkGateOptions{logExposure: true, ctx: ctx}
+	return c.checkGateImpl(user, gate, options)
+}
+
+// Checks the values of several Feature Gates for the given user, by gate name. The user is normalized once and
+// the exposure events are queued together, which is cheaper than calling CheckGate for each gate.
+func (c *Client) CheckGates(user User, gates []string) map[string]bool {
+	values := make(map[string]bool, len(gates))
+	for name, gate := range c.checkGatesImpl(user, gates, nil) {
+		values[name] = gate.Value
+	}
+	return values
+}
+
+// Checks the values of several Feature Gates for the given user, and returns the details of each evaluation
+func (c *Client) CheckGatesWithDetails(user User, gates []string) (map[string]bool, map[string]EvaluationDetails) {
+	details := make(map[string]EvaluationDetails, len(gates))
+	values := make(map[string]bool, len(gates))
+	for name, gate := range c.checkGatesImpl(user, gates, details) {
+		values[name] = gate.Value
+	}
+	return values, details
+}
+
+// Explains the value of a Feature Gate for the given user: the rules evaluated in order, the value each condition
+// compared and whether it passed, and the bucket the pass percentage applied to. No exposure event is logged.
+func (c *Client) ExplainGate(user User, gate string) GateExplanation {
+	explanation := GateExplanation{Name: gate}
+	c.errorBoundary.captureVoid(func() {
+		if !c.verifyUser(user) {
+			return
+		}
+		explanation = c.evaluator.explainGate(c.normalizeUser(user), c.evaluator.store.getRulesets(), gate, 0)
+	})
+	return explanation
+}
+
 // Logs an exposure event for the dynamic config
 func (c *Client) ManuallyLogGateExposure(user User, gate string) {
 	c.errorBoundary.captureVoid(func() {
 		if !c.verifyUser(user) {
 			return
 		}
-		user = normalizeUser(user, *c.options)
+		user = c.normalizeUser(user)
 		res := c.evaluator.checkGate(user, gate)
 		context := &logContext{isManualExposure: true}
 		c.logger.logGateExposure(user, gate, res.Pass, res.Id, res.SecondaryExposures, res.EvaluationDetails, context)
 	})
 }
 
+// Gets the Feature Gate for the given user, including the rule and gate dependencies that decided its value
+func (c *Client) GetGate(user User, gate string) FeatureGate {
+	options := checkGateOptions{logExposure: true}
+	return c.getGateImpl(user, gate, options)
+}
+
+// Gets the Feature Gate for the given user without logging an exposure event
+func (c *Client) GetGateWithExposureLoggingDisabled(user User, gate string) FeatureGate {
+	options := checkGateOptions{logExposure: false}
+	return c.getGateImpl(user, gate, options)
+}
+
 // Gets the DynamicConfig value for the given user
 func (c *Client) GetConfig(user User, config string) DynamicConfig {
 	options := getConfigOptions{logExposure: true}
@@ -88,13 +235,26 @@ func (c *Client) GetConfigWithExposureLoggingDisabled(user User, config string)
 	return c.getConfigImpl(user, config, options)
 }
 
+// Gets the DynamicConfig value for the given user, and the details of the evaluation
+func (c *Client) GetConfigWithDetails(user User, config string) (DynamicConfig, EvaluationDetails) {
+	var details EvaluationDetails
+	options := getConfigOptions{logExposure: true, details: &details}
+	return c.getConfigImpl(user, config, options), details
+}
+
+// Gets the DynamicConfig value for the given user. The evaluation span, if traced, is a child of the span in ctx.
+func (c *Client) GetConfigWithContext(ctx context.Context, user User, config string) DynamicConfig {
+	options := getConfigOptions{logExposure: true, ctx: ctx}
+	return c.getConfigImpl(user, config, options)
+}
+
 // Logs an exposure event for the config
 func (c *Client) ManuallyLogConfigExposure(user User, config string) {
 	c.errorBoundary.captureVoid(func() {
 		if !c.verifyUser(user) {
 			return
 		}
-		user = normalizeUser(user, *c.options)
+		user = c.normalizeUser(user)
 		res := c.evaluator.getConfig(user, config)
 		context := &logContext{isManualExposure: true}
 		c.logger.logConfigExposure(user, config, res.Id, res.SecondaryExposures, res.EvaluationDetails, context)
@@ -117,11 +277,127 @@ func (c *Client) GetExperimentWithExposureLoggingDisabled(user User, experiment
 	return c.GetConfigWithExposureLoggingDisabled(user, experiment)
 }
 
+// Gets the DynamicConfig value of an Experiment for the given user, and the details of the evaluation
+func (c *Client) GetExperimentWithDetails(user User, experiment string) (DynamicConfig, EvaluationDetails) {
+	if !c.verifyUser(user) {
+		return *NewConfig(experiment, nil, ""), EvaluationDetails{}
+	}
+	return c.GetConfigWithDetails(user, experiment)
+}
+
+// Gets the DynamicConfig value of an Experiment for the given user. The evaluation span, if traced, is a child
+// of the span in ctx.
+func (c *Client) GetExperimentWithContext(ctx context.Context, user User, experiment string) DynamicConfig {
+	if !c.verifyUser(user) {
+		return *NewConfig(experiment, nil, "")
+	}
+	return c.GetConfigWithContext(ctx, user, experiment)
+}
+
 // Logs an exposure event for the experiment
 func (c *Client) ManuallyLogExperimentExposure(user User, experiment string) {
 	c.ManuallyLogConfigExposure(user, experiment)
 }
 
+// Whether the user is in one of the groups of the experiment, e.g. to tag the events of a downstream pipeline.
+// No exposure event is logged. Returns false for unknown experiments and for dynamic configs.
+func (c *Client) IsUserInExperiment(user User, experiment string) bool {
+	inExperiment := false
+	c.errorBoundary.captureVoid(func() {
+		if !c.verifyUser(user) {
+			return
+		}
+		inExperiment = c.evaluator.isUserInExperiment(c.normalizeUser(user), experiment)
+	})
+	return inExperiment
+}
+
+// Whether the experiment is running, i.e. started and neither stopped nor launched.
+// Returns false for unknown experiments and for dynamic configs.
+func (c *Client) IsExperimentActive(experiment string) bool {
+	active := false
+	c.errorBoundary.captureVoid(func() {
+		active = c.evaluator.isExperimentActive(experiment)
+	})
+	return active
+}
+
+// Whether the ID of the user of the given type, "userID" or the name of one of the CustomIDs, is in the named
+// ID list, as synced for in_segment_list conditions. Returns false for unknown lists, and when ID lists are disabled.
+func (c *Client) IsUserInIDList(user User, listName string, idType string) bool {
+	inList := false
+	c.errorBoundary.captureVoid(func() {
+		if !c.verifyUser(user) {
+			return
+		}
+		inList = c.evaluator.isUserInIDList(c.normalizeUser(user), listName, idType)
+	})
+	return inList
+}
+
+// The names of the ID lists synced so far, sorted
+func (c *Client) GetIDListNames() []string {
+	names := []string{}
+	c.errorBoundary.captureVoid(func() {
+		names = c.evaluator.store.getIDListNames()
+	})
+	return names
+}
+
+// The server session ID sent with every request to Statsig, to find this Client's traffic in Statsig's
+// debugging tools. It changes as set by Options.SessionIDRotationOptions.
+func (c *Client) GetSessionID() string {
+	sessionID := ""
+	c.errorBoundary.captureVoid(func() {
+		sessionID = c.transport.session.getID()
+	})
+	return sessionID
+}
+
+// Replaces the server session ID right away, e.g. to mark a deployment, and returns the new one
+func (c *Client) RotateSessionID() string {
+	sessionID := ""
+	c.errorBoundary.captureVoid(func() {
+		sessionID = c.transport.session.rotate()
+	})
+	return sessionID
+}
+
+// Gets the parameters a contextual multi-armed bandit (CMAB) chose for the given user
+func (c *Client) GetCMAB(user User, cmab string) DynamicConfig {
+	options := getConfigOptions{logExposure: true}
+	return c.getCMABImpl(user, cmab, options)
+}
+
+// Gets the parameters a CMAB chose for the given user without logging an exposure event
+func (c *Client) GetCMABWithExposureLoggingDisabled(user User, cmab string) DynamicConfig {
+	options := getConfigOptions{logExposure: false}
+	return c.getCMABImpl(user, cmab, options)
+}
+
+// Logs an exposure event for the CMAB
+func (c *Client) ManuallyLogCMABExposure(user User, cmab string) {
+	c.errorBoundary.captureVoid(func() {
+		if !c.verifyUser(user) {
+			return
+		}
+		user = c.normalizeUser(user)
+		res := c.evaluator.getCMAB(user, cmab)
+		context := &logContext{isManualExposure: true}
+		c.logger.logConfigExposure(user, cmab, res.Id, res.SecondaryExposures, res.EvaluationDetails, context)
+	})
+}
+
+// Gets the Parameter Store for the given user. Parameters are evaluated when read.
+func (c *Client) GetParameterStore(user User, parameterStore string) ParameterStore {
+	return c.getParameterStoreImpl(user, parameterStore, true)
+}
+
+// Gets the Parameter Store for the given user. Reading its parameters does not log exposure events.
+func (c *Client) GetParameterStoreWithExposureLoggingDisabled(user User, parameterStore string) ParameterStore {
+	return c.getParameterStoreImpl(user, parameterStore, false)
+}
+
 // Gets the Layer object for the given user
 func (c *Client) GetLayer(user User, layer string) Layer {
 	options := getLayerOptions{logExposure: true}
@@ -134,15 +410,28 @@ func (c *Client) GetLayerWithExposureLoggingDisabled(user User, layer string) La
 	return c.getLayerImpl(user, layer, options)
 }
 
+// Gets the Layer object for the given user, and the details of the evaluation
+func (c *Client) GetLayerWithDetails(user User, layer string) (Layer, EvaluationDetails) {
+	var details EvaluationDetails
+	options := getLayerOptions{logExposure: true, details: &details}
+	return c.getLayerImpl(user, layer, options), details
+}
+
+// Gets the Layer object for the given user. The evaluation span, if traced, is a child of the span in ctx.
+func (c *Client) GetLayerWithContext(ctx context.Context, user User, layer string) Layer {
+	options := getLayerOptions{logExposure: true, ctx: ctx}
+	return c.getLayerImpl(user, layer, options)
+}
+
 // Logs an exposure event for the parameter in the given layer
 func (c *Client) ManuallyLogLayerParameterExposure(user User, layer string, parameter string) {
 	c.errorBoundary.captureVoid(func() {
 		if !c.verifyUser(user) {
 			return
 		}
-		user = normalizeUser(user, *c.options)
+		user = c.normalizeUser(user)
 		res := c.evaluator.getLayer(user, layer)
-		config := NewLayer(layer, res.ConfigValue.Value, res.ConfigValue.RuleID, nil).configBase
+		config := newLayerFromResult(layer, res, nil).configBase
 		context := &logContext{isManualExposure: true}
 		c.logger.logLayerExposure(user, config, parameter, *res, res.EvaluationDetails, context)
 	})
@@ -151,14 +440,39 @@ func (c *Client) ManuallyLogLayerParameterExposure(user User, layer string, para
 // Logs an event to Statsig for analysis in the Statsig Console
 func (c *Client) LogEvent(event Event) {
 	c.errorBoundary.captureVoid(func() {
-		event.User = normalizeUser(event.User, *c.options)
+		event.User = c.normalizeUser(event.User)
+		if event.EventName == "" {
+			return
+		}
+		c.logger.logCustom(event)
+	})
+}
+
+// Logs an event that happened at eventTime, e.g. when backfilling events or flushing a batch produced offline.
+// Times more than 7 days in the past or more than an hour in the future are clamped to that window.
+func (c *Client) LogEventWithTime(event Event, eventTime time.Time) {
+	c.errorBoundary.captureVoid(func() {
+		event.User = c.normalizeUser(event.User)
 		if event.EventName == "" {
 			return
 		}
+		event.Time = c.logger.clampEventTime(eventTime)
 		c.logger.logCustom(event)
 	})
 }
 
+// Logs a custom event with a numeric value, e.g. a latency or an order amount for a Pulse metric.
+// Returns an error, and logs nothing, when the name is empty or the value is NaN or infinite.
+func (c *Client) LogEventValue(user User, eventName string, value float64, metadata map[string]string) error {
+	if err := validateEventValue(eventName, value); err != nil {
+		return err
+	}
+	c.errorBoundary.captureVoid(func() {
+		c.logger.logCustomValue(Event{EventName: eventName, User: c.normalizeUser(user), Metadata: metadata}, value)
+	})
+	return nil
+}
+
 // Override the value of a Feature Gate for the given user
 func (c *Client) OverrideGate(gate string, val bool) {
 	c.errorBoundary.captureVoid(func() { c.evaluator.OverrideGate(gate, val) })
@@ -174,14 +488,66 @@ func (c *Client) OverrideLayer(layer string, val map[string]interface{}) {
 	c.errorBoundary.captureVoid(func() { c.evaluator.OverrideLayer(layer, val) })
 }
 
+// In LocalMode, puts the user in the named group of the experiment, so tests get the parameter values of that
+// group, including through the experiment's layer. An empty group removes the override. The experiment must be
+// in the rulesets, e.g. from BootstrapValues, and the user must have the unit ID the experiment is assigned by.
+func (c *Client) OverrideExperimentGroup(user User, experiment string, group string) {
+	c.errorBoundary.captureVoid(func() {
+		if !c.options.LocalMode {
+			global.Logger().LogError(fmt.Errorf("OverrideExperimentGroup is only available in LocalMode"))
+			return
+		}
+		if err := c.evaluator.OverrideExperimentGroup(c.normalizeUser(user), experiment, group); err != nil {
+			global.Logger().LogError(fmt.Errorf("Failed to override the group of %s: %w", experiment, err))
+		}
+	})
+}
+
+// Logs a slice of events to Statsig server immediately. Slices of more than 500 events are sent in
+// batches of 500, and each batch is retried on its own. Returns the response of the first batch that
+// failed, or else of the last batch, and an *EventBatchError holding the events that were not logged.
 func (c *Client) LogImmediate(events []Event) (*http.Response, error) {
-	if len(events) > 500 {
-		err := errors.New(EventBatchSizeError)
-		return nil, fmt.Errorf(err.Error())
+	var result *http.Response
+	var batchErr *EventBatchError
+	// An empty slice is still sent, as a single empty batch
+	for start := 0; start == 0 || start < len(events); start += maxEventBatchSize {
+		end := start + maxEventBatchSize
+		if end > len(events) {
+			end = len(events)
+		}
+		batch := events[start:end]
+		response, err := c.logEventBatch(batch)
+		// Keep the response of the first failed batch, or else of the last batch, open for the caller
+		if batchErr == nil {
+			closeResponse(result)
+			result = response
+		} else {
+			closeResponse(response)
+		}
+		if err != nil {
+			if batchErr == nil {
+				batchErr = &EventBatchError{Err: err}
+			}
+			batchErr.Batches++
+			batchErr.FailedEvents = append(batchErr.FailedEvents, batch...)
+		}
+	}
+	if batchErr != nil {
+		return result, batchErr
+	}
+	return result, nil
+}
+
+func (c *Client) logEventBatch(events []Event) (*http.Response, error) {
+	if c.options.DisableNetwork {
+		return nil, errNetworkDisabled
 	}
-	events_processed := make([]interface{}, 0)
+	events_processed := make([]interface{}, 0, len(events))
 	for _, event := range events {
-		event.User = normalizeUser(event.User, *c.options)
+		event.User = c.normalizeUser(event.User)
+		event.User.PrivateAttributes = nil
+		event.User = c.logger.piiScrubber.scrubUser(event.User)
+		event.Metadata = c.logger.piiScrubber.scrubMetadata(event.Metadata, false)
 		events_processed = append(events_processed, event)
 	}
 	input := &logEventInput{
@@ -193,7 +559,19 @@ func (c *Client) LogImmediate(events []Event) (*http.Response, error) {
 	if err != nil {
 		return nil, err
 	}
-	return c.transport.doRequest("/log_event", body)
+	var previous *http.Response
+	return retry(context.Background(), maxEventBatchRetries, eventBatchRetryBackoff, func() (*http.Response, bool, error) {
+		closeResponse(previous)
+		response, err := c.transport.doRequest("/log_event", body)
+		previous = response
+		if err != nil {
+			return response, true, err
+		}
+		if response.StatusCode >= 200 && response.StatusCode < 300 {
+			return response, false, nil
+		}
+		return response, shouldRetry(response.StatusCode), fmt.Errorf("http response error code: %d", response.StatusCode)
+	})
 }
 
 func (c *Client) GetClientInitializeResponse(user User, clientKey string) ClientInitializeResponse {
@@ -201,13 +579,25 @@ func (c *Client) GetClientInitializeResponse(user User, clientKey string) Client
 		if !c.verifyUser(user) {
 			return *new(ClientInitializeResponse)
 		}
-		user = normalizeUser(user, *c.options)
+		user = c.normalizeUser(user)
 		return c.evaluator.getClientInitializeResponse(user, clientKey)
 	})
 }
 
+// Gets the ClientInitializeResponse for the given user, shaped by the options. Pass the Time and Hash of
+// the response a client was last bootstrapped with to only get what changed since.
+func (c *Client) GetClientInitializeResponseWithOptions(user User, clientKey string, options *ClientInitializeResponseOptions) ClientInitializeResponse {
+	return c.errorBoundary.captureGetClientInitializeResponse(func() ClientInitializeResponse {
+		if !c.verifyUser(user) {
+			return *new(ClientInitializeResponse)
+		}
+		user = c.normalizeUser(user)
+		return c.evaluator.getClientInitializeResponseWithOptions(user, clientKey, options)
+	})
+}
+
 func (c *Client) verifyUser(user User) bool {
-	if user.UserID == "" && len(user.CustomIDs) == 0 {
+	if !c.hasUnitID(user) {
 		err := errors.New(EmptyUserError)
 		global.Logger().LogError(err)
 		return false
@@ -215,25 +605,244 @@ func (c *Client) verifyUser(user User) bool {
 	return true
 }
 
+func (c *Client) hasUnitID(user User) bool {
+	// The UserTransform may derive the IDs, so it gets a chance to before the user is rejected
+	return !isEmptyUser(user) || (c.options.UserTransform != nil && !isEmptyUser(transformUser(user, c.options.UserTransform)))
+}
+
+// Applies the given settings to the running Client without reinitializing it.
+// Sync and flush intervals take effect once the interval currently in progress elapses.
+func (c *Client) UpdateOptions(options RuntimeOptions) {
+	c.errorBoundary.captureVoid(func() {
+		c.evaluator.store.setSyncIntervals(options.ConfigSyncInterval, options.IDListSyncInterval)
+		c.logger.setLoggingInterval(options.LoggingInterval)
+		c.logger.setMaxEvents(options.LoggingMaxBufferSize)
+		if options.StatsigLoggerOptions != nil {
+			c.logger.setStatsigLoggerOptions(*options.StatsigLoggerOptions)
+		}
+		c.logger.setSamplingRates(options.ExposureSamplingRate, options.EventSamplingRates, options.DiagnosticsSamplingRate)
+		if options.EnableDebug != nil || options.LogLevel != nil {
+			global.updateLoggerLevel(options.LogLevel, options.EnableDebug)
+		}
+	})
+}
+
+// Tells the SDK whether the process is under memory pressure, e.g. from a GOMEMLIMIT or cgroup watcher.
+// While under pressure the SDK flushes and shrinks its event queue, drops buffered diagnostics and empties
+// its ClientInitializeResponse cache, user string table and exposure dedupe table.
+func (c *Client) ReportMemoryPressure(underPressure bool) {
+	c.errorBoundary.captureVoid(func() {
+		c.memoryMonitor.setUnderPressure(underPressure)
+	})
+}
+
 // Cleans up Statsig, persisting any Event Logs and cleanup processes
 // Using any method is undefined after Shutdown() has been called
 func (c *Client) Shutdown() {
+	if !atomic.CompareAndSwapInt32(&c.shutdown, 0, 1) {
+		return
+	}
 	c.errorBoundary.captureVoid(func() {
+		c.memoryMonitor.stop()
 		c.logger.flush(true)
 		c.evaluator.shutdown()
+		c.transport.metrics.shutdown()
+		c.closeIdleConnections()
+	})
+}
+
+func (c *Client) isShutdown() bool {
+	return atomic.LoadInt32(&c.shutdown) == 1
+}
+
+// Closes the keep-alive connections, whose goroutines would otherwise outlive the Client
+func (c *Client) closeIdleConnections() {
+	c.transport.client.CloseIdleConnections()
+	c.errorBoundary.client.CloseIdleConnections()
+}
+
+// Calls listener whenever the definition of the named gate, config or layer is added, changed or removed
+// by a ruleset update. Listeners run on the goroutine that applies the update, so slow work should be
+// handed off. Returns a function that unsubscribes the listener.
+func (c *Client) Subscribe(entityName string, listener func(change ChangeEvent)) func() {
+	unsubscribe := func() {}
+	c.errorBoundary.captureVoid(func() {
+		unsubscribe = c.evaluator.store.changeListeners.subscribe(entityName, listener)
+	})
+	return unsubscribe
+}
+
+// Gets a read-only copy of the gate, config and layer definitions currently in use, for inventory reports
+func (c *Client) GetSpecInventory() SpecInventory {
+	var inventory SpecInventory
+	c.errorBoundary.captureVoid(func() {
+		inventory = c.evaluator.store.getSpecInventory()
+	})
+	return inventory
+}
+
+// Evaluates every gate, config, experiment and layer for the given user without logging exposures,
+// for debug tooling such as "what does this user see" pages. Specs that must be evaluated by the
+// server are reported with their local fallback value.
+func (c *Client) EvaluateAll(user User) UserEvaluation {
+	evaluation := UserEvaluation{
+		User:           user,
+		FeatureGates:   make(map[string]FeatureGate),
+		DynamicConfigs: make(map[string]DynamicConfig),
+		Layers:         make(map[string]Layer),
+	}
+	c.errorBoundary.captureVoid(func() {
+		if !c.verifyUser(user) {
+			return
+		}
+		evaluation = c.evaluateAll(user)
+	})
+	return evaluation
+}
+
+// Serves the SDK's internal metrics in the Prometheus text format. Requires MetricsOptions.Enabled,
+// otherwise the handler serves an empty response.
+func (c *Client) MetricsHandler() http.Handler {
+	return metricsHandler{metrics: c.transport.metrics}
+}
+
+// Returns a snapshot of the SDK's internal metrics, for exporting them to a metrics registry. Requires
+// MetricsOptions.Enabled, otherwise no metrics are returned.
+func (c *Client) CollectMetrics() []MetricSample {
+	return c.transport.metrics.collect()
+}
+
+// Serves a readiness probe, e.g. for Kubernetes: 200 when the Client has loaded rulesets that are not stale
+// per Options.StaleConfigThreshold, and 503 otherwise
+func (c *Client) ReadyzHandler() http.Handler {
+	return readyzHandler{getClient: func() *Client { return c }}
+}
+
+// Gets a summary of every Feature Gate currently in use, sorted by name
+func (c *Client) GetFeatureGateList() []SpecEntity {
+	return c.getSpecList(isFeatureGate)
+}
+
+// Gets a summary of every Dynamic Config currently in use, excluding experiments, sorted by name
+func (c *Client) GetDynamicConfigList() []SpecEntity {
+	return c.getSpecList(isDynamicConfig)
+}
+
+// Gets a summary of every Experiment currently in use, sorted by name
+func (c *Client) GetExperimentList() []SpecEntity {
+	return c.getSpecList(isExperiment)
+}
+
+// Gets a summary of every Layer currently in use, sorted by name
+func (c *Client) GetLayerList() []SpecEntity {
+	return c.getSpecList(isLayer)
+}
+
+func (c *Client) getSpecList(keep func(SpecEntity) bool) []SpecEntity {
+	entities := make([]SpecEntity, 0)
+	c.errorBoundary.captureVoid(func() {
+		entities = c.evaluator.store.getSpecInventory().filter(keep)
+	})
+	return entities
+}
+
+// Reports whether the rulesets have gone longer than Options.StaleConfigThreshold without a successful sync
+func (c *Client) IsConfigStale() bool {
+	stale := false
+	c.errorBoundary.captureVoid(func() {
+		_, stale = c.evaluator.store.getStaleness()
+	})
+	return stale
+}
+
+// Reports whether the Client is initialized, where its rulesets came from, when they and the ID lists
+// were last synced and how many events wait to be flushed, e.g. for health checks and debugging
+func (c *Client) GetStatus() Status {
+	status := Status{Source: string(reasonUninitialized)}
+	c.errorBoundary.captureVoid(func() {
+		status = c.evaluator.store.getStatus()
+		status.PendingEvents = c.logger.pendingEvents()
+	})
+	return status
+}
+
+// Exports the rulesets and ID lists the Client currently evaluates with. Give the bytes to RestoreSnapshot
+// of this or another Client to evaluate with them again, e.g. to replay a known state in integration tests
+// or to warm up a standby process.
+func (c *Client) ExportSnapshot() ([]byte, error) {
+	var snapshot []byte
+	var err error
+	c.errorBoundary.captureVoid(func() {
+		snapshot, err = c.evaluator.store.exportSnapshot()
 	})
+	return snapshot, err
+}
+
+// Replaces the rulesets and ID lists with those of a snapshot from ExportSnapshot, even if they are older.
+// The next config and ID list syncs replace them again, unless the Client is in LocalMode.
+func (c *Client) RestoreSnapshot(snapshot []byte) error {
+	var err error
+	c.errorBoundary.captureVoid(func() {
+		err = c.evaluator.store.restoreSnapshot(snapshot)
+	})
+	return err
+}
+
+// Synchronously sends all queued events to Statsig, returning an error if the upload fails
+func (c *Client) Flush() error {
+	return c.FlushWithContext(context.Background())
+}
+
+// Synchronously sends all queued events to Statsig, returning an error if the upload fails or ctx is done first
+func (c *Client) FlushWithContext(ctx context.Context) error {
+	var err error
+	c.errorBoundary.captureVoid(func() {
+		_, err = c.logger.flushWithContext(ctx, false)
+	})
+	return err
+}
+
+// Cleans up Statsig like Shutdown, but returns once ctx is done even if events are still being uploaded.
+// Returns the number of events that were dropped and, if any were, the reason they could not be sent.
+// Using any method is undefined after ShutdownWithContext() has been called
+func (c *Client) ShutdownWithContext(ctx context.Context) (int, error) {
+	dropped := 0
+	var err error
+	if !atomic.CompareAndSwapInt32(&c.shutdown, 0, 1) {
+		return dropped, err
+	}
+	c.errorBoundary.captureVoid(func() {
+		c.memoryMonitor.stop()
+		c.evaluator.shutdown()
+		dropped, err = c.logger.flushWithContext(ctx, true)
+		c.transport.metrics.shutdown()
+		c.closeIdleConnections()
+	})
+	return dropped, err
 }
 
 type checkGateOptions struct {
 	logExposure bool
+	// Filled in with the details of the evaluation when set
+	details *EvaluationDetails
+	// Parent of the evaluation span when set
+	ctx context.Context
 }
 
 type getConfigOptions struct {
 	logExposure bool
+	// Filled in with the details of the evaluation when set
+	details *EvaluationDetails
+	// Parent of the evaluation span when set
+	ctx context.Context
 }
 
 type getLayerOptions struct {
 	logExposure bool
+	// Filled in with the details of the evaluation when set
+	details *EvaluationDetails
+	// Parent of the evaluation span when set
+	ctx context.Context
 }
 
 type gateResponse struct {
@@ -262,22 +871,82 @@ type getConfigInput struct {
 
 func (c *Client) checkGateImpl(user User, gate string, options checkGateOptions) bool {
 	return c.errorBoundary.captureCheckGate(func() bool {
+		return c.evalGateImpl(user, gate, options).Value
+	})
+}
+
+func (c *Client) getGateImpl(user User, gate string, options checkGateOptions) FeatureGate {
+	return c.errorBoundary.captureGetGate(func() FeatureGate {
+		return c.evalGateImpl(user, gate, options)
+	})
+}
+
+func (c *Client) evalGateImpl(user User, gate string, options checkGateOptions) FeatureGate {
+	if !c.verifyUser(user) {
+		return FeatureGate{Name: gate, SecondaryExposures: make([]SecondaryExposure, 0)}
+	}
+	span := c.transport.tracing.startEvaluation(options.ctx, "statsig.check_gate", gate)
+	defer span.End()
+	user = c.normalizeUser(user)
+	res := c.evaluator.checkGate(user, gate)
+	if res.FetchFromServer {
+		serverRes := fetchGate(user, gate, c.transport)
+		res = &evalResult{Pass: serverRes.Value, Id: serverRes.RuleID, EvaluationDetails: c.evaluator.createEvaluationDetails(reasonNetwork)}
+	} else {
+		if options.logExposure {
+			context := &logContext{isManualExposure: false}
+			c.logger.logGateExposure(user, gate, res.Pass, res.Id, res.SecondaryExposures, res.EvaluationDetails, context)
+		}
+	}
+	span.SetAttribute("statsig.rule_id", res.Id)
+	span.SetAttribute("statsig.value", res.Pass)
+	c.setEvaluationDetails(options.details, res, res.Id)
+	return FeatureGate{
+		Name:               gate,
+		Value:              res.Pass,
+		RuleID:             res.Id,
+		SecondaryExposures: toSecondaryExposures(res.SecondaryExposures),
+	}
+}
+
+// Evaluates the gates as evalGateImpl does, filling in details when set
+func (c *Client) checkGatesImpl(user User, gates []string, details map[string]EvaluationDetails) map[string]FeatureGate {
+	results := make(map[string]FeatureGate, len(gates))
+	c.errorBoundary.captureVoid(func() {
+		for _, gate := range gates {
+			results[gate] = FeatureGate{Name: gate, SecondaryExposures: make([]SecondaryExposure, 0)}
+		}
 		if !c.verifyUser(user) {
-			return false
+			return
 		}
-		user = normalizeUser(user, *c.options)
-		res := c.evaluator.checkGate(user, gate)
-		if res.FetchFromServer {
-			serverRes := fetchGate(user, gate, c.transport)
-			res = &evalResult{Pass: serverRes.Value, Id: serverRes.RuleID}
-		} else {
-			if options.logExposure {
-				context := &logContext{isManualExposure: false}
+		span := c.transport.tracing.startEvaluation(context.Background(), "statsig.check_gates", strings.Join(gates, ","))
+		defer span.End()
+		user = c.normalizeUser(user)
+		exposures := make([]interface{}, 0, len(gates))
+		// Queued even when an evaluation panics, so the exposures of the gates evaluated before are kept
+		defer func() { c.logger.queueBatch(exposures) }()
+		context := &logContext{isManualExposure: false, batch: &exposures}
+		for _, gate := range gates {
+			res := c.evaluator.checkGate(user, gate)
+			if res.FetchFromServer {
+				serverRes := fetchGate(user, gate, c.transport)
+				res = &evalResult{Pass: serverRes.Value, Id: serverRes.RuleID, EvaluationDetails: c.evaluator.createEvaluationDetails(reasonNetwork)}
+			} else {
 				c.logger.logGateExposure(user, gate, res.Pass, res.Id, res.SecondaryExposures, res.EvaluationDetails, context)
 			}
+			if details != nil {
+				details[gate] = toEvaluationDetails(c.evaluator.getEvaluationDetails(res), res.Id)
+			}
+			results[gate] = FeatureGate{
+				Name:               gate,
+				Value:              res.Pass,
+				RuleID:             res.Id,
+				SecondaryExposures: toSecondaryExposures(res.SecondaryExposures),
+			}
 		}
-		return res.Pass
+		span.SetAttribute("statsig.gate_count", len(gates))
 	})
+	return results
 }
 
 func (c *Client) getConfigImpl(user User, config string, options getConfigOptions) DynamicConfig {
@@ -285,7 +954,9 @@ func (c *Client) getConfigImpl(user User, config string, options getConfigOption
 		if !c.verifyUser(user) {
 			return *NewConfig(config, nil, "")
 		}
-		user = normalizeUser(user, *c.options)
+		span := c.transport.tracing.startEvaluation(options.ctx, "statsig.get_config", config)
+		defer span.End()
+		user = c.normalizeUser(user)
 		res := c.evaluator.getConfig(user, config)
 		if res.FetchFromServer {
 			res = c.fetchConfigFromServer(user, config)
@@ -295,6 +966,28 @@ func (c *Client) getConfigImpl(user User, config string, options getConfigOption
 				c.logger.logConfigExposure(user, config, res.Id, res.SecondaryExposures, res.EvaluationDetails, context)
 			}
 		}
+		span.SetAttribute("statsig.rule_id", res.Id)
+		c.setEvaluationDetails(options.details, res, res.Id)
+		res.ConfigValue.SecondaryExposures = toSecondaryExposures(res.SecondaryExposures)
+		return res.ConfigValue
+	})
+}
+
+func (c *Client) getCMABImpl(user User, cmab string, options getConfigOptions) DynamicConfig {
+	return c.errorBoundary.captureGetConfig(func() DynamicConfig {
+		if !c.verifyUser(user) {
+			return *NewConfig(cmab, nil, "")
+		}
+		span := c.transport.tracing.startEvaluation(options.ctx, "statsig.get_cmab", cmab)
+		defer span.End()
+		user = c.normalizeUser(user)
+		res := c.evaluator.getCMAB(user, cmab)
+		if options.logExposure {
+			context := &logContext{isManualExposure: false}
+			c.logger.logConfigExposure(user, cmab, res.Id, res.SecondaryExposures, res.EvaluationDetails, context)
+		}
+		span.SetAttribute("statsig.rule_id", res.Id)
+		res.ConfigValue.SecondaryExposures = toSecondaryExposures(res.SecondaryExposures)
 		return res.ConfigValue
 	})
 }
@@ -304,8 +997,10 @@ func (c *Client) getLayerImpl(user User, layer string, options getLayerOptions)
 		if !c.verifyUser(user) {
 			return *NewLayer(layer, nil, "", nil)
 		}
+		span := c.transport.tracing.startEvaluation(options.ctx, "statsig.get_layer", layer)
+		defer span.End()
 
-		user = normalizeUser(user, *c.options)
+		user = c.normalizeUser(user)
 		res := c.evaluator.getLayer(user, layer)
 
 		if res.FetchFromServer {
@@ -319,7 +1014,11 @@ func (c *Client) getLayerImpl(user User, layer string, options getLayerOptions)
 			}
 		}
 
-		return *NewLayer(layer, res.ConfigValue.Value, res.ConfigValue.RuleID, &logFunc)
+		span.SetAttribute("statsig.rule_id", res.ConfigValue.RuleID)
+		c.setEvaluationDetails(options.details, res, res.ConfigValue.RuleID)
+		l := newLayerFromResult(layer, res, &logFunc)
+		l.SecondaryExposures = toSecondaryExposures(res.SecondaryExposures)
+		return *l
 	})
 }
 
@@ -358,7 +1057,16 @@ func fetchConfig(user User, configName string, t *transport) configResponse {
 	return res
 }
 
+func (c *Client) normalizeUser(user User) User {
+	return c.stringInterner.internUser(normalizeUser(user, *c.options))
+}
+
+func isEmptyUser(user User) bool {
+	return user.UserID == "" && len(user.CustomIDs) == 0
+}
+
 func normalizeUser(user User, options Options) User {
+	user = transformUser(user, options.UserTransform)
 	env := make(map[string]string)
 	// Copy to avoid data race. We modify the map below.
 	for k, v := range options.Environment.Params {
@@ -371,13 +1079,69 @@ func normalizeUser(user User, options Options) User {
 		env[k] = v
 	}
 	user.StatsigEnvironment = env
+	if len(options.GlobalCustomFields) > 0 {
+		custom := make(map[string]interface{}, len(options.GlobalCustomFields)+len(user.Custom))
+		for k, v := range options.GlobalCustomFields {
+			custom[k] = v
+		}
+		for k, v := range user.Custom {
+			custom[k] = v
+		}
+		user.Custom = custom
+	}
 	return user
 }
 
+// Returns the user unchanged when there is no transform or it panics
+func transformUser(user User, transform func(User) User) (transformed User) {
+	if transform == nil {
+		return user
+	}
+	defer func() {
+		if err := recover(); err != nil {
+			logRecoveredPanic("user transform", err)
+			transformed = user
+		}
+	}()
+	return transform(user)
+}
+
+// The layer holding the value of an evaluation, and the experiment and group the layer allocated the user to
+func newLayerFromResult(name string, res *evalResult, logExposure *func(configBase, string)) *Layer {
+	l := NewLayer(name, res.ConfigValue.Value, res.ConfigValue.RuleID, logExposure)
+	l.rawValue = res.ConfigValue.rawValue
+	l.GroupName = res.ConfigValue.GroupName
+	l.AllocatedExperimentName = res.ConfigValue.AllocatedExperimentName
+	return l
+}
+
 func (c *Client) fetchConfigFromServer(user User, configName string) *evalResult {
 	serverRes := fetchConfig(user, configName, c.transport)
 	return &evalResult{
-		ConfigValue: *NewConfig(configName, serverRes.Value, serverRes.RuleID),
-		Id:          serverRes.RuleID,
+		ConfigValue:       *NewConfig(configName, serverRes.Value, serverRes.RuleID),
+		Id:                serverRes.RuleID,
+		EvaluationDetails: c.evaluator.createEvaluationDetails(reasonNetwork),
+	}
+}
+
+func (c *Client) setEvaluationDetails(out *EvaluationDetails, res *evalResult, ruleID string) {
+	if out != nil {
+		*out = toEvaluationDetails(c.evaluator.getEvaluationDetails(res), ruleID)
+	}
+}
+
+func closeResponse(response *http.Response) {
+	if response != nil && response.Body != nil {
+		response.Body.Close()
+	}
+}
+
+func validateEventValue(eventName string, value float64) error {
+	if eventName == "" {
+		return errors.New("the event name is empty")
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return fmt.Errorf("the value of event %s is not a finite number: %v", eventName, value)
 	}
+	return nil
 }
@@ -14,7 +14,18 @@ type ClientInitializeResponse struct {
 	HasUpdates     bool                                `json:"has_updates"`
 	Generator      string                              `json:"generator"`
 	EvaluatedKeys  map[string]interface{}              `json:"evaluated_keys"`
-	Time           int64                               `json:"time"`
+	// Time of the rulesets the response was evaluated with, set by GetClientInitializeResponseWithOptions
+	Time int64 `json:"time"`
+	// Identifies the gate, config and layer values of the response, set by GetClientInitializeResponseWithOptions
+	Hash string `json:"hash,omitempty"`
+	// Set when the response only holds the entities that changed since ClientInitializeResponseOptions.PreviousHash.
+	// The hashed names of the entities that no longer exist are in the Deleted fields.
+	IsDelta        bool     `json:"is_delta,omitempty"`
+	DeletedGates   []string `json:"deleted_gates,omitempty"`
+	DeletedConfigs []string `json:"deleted_configs,omitempty"`
+	DeletedLayers  []string `json:"deleted_layers,omitempty"`
+	// The unhashed name of each entity, by hashed name, set with ClientInitializeResponseOptions.IncludeUnhashedNames
+	DebugNames map[string]string `json:"debug_names,omitempty"`
 }
 
 type baseSpecInitializeResponse struct {
@@ -169,7 +180,8 @@ func getClientInitializeResponse(
 	featureGates := make(map[string]GateInitializeResponse)
 	dynamicConfigs := make(map[string]ConfigInitializeResponse)
 	layerConfigs := make(map[string]LayerInitializeResponse)
-	for name, spec := range store.featureGates {
+	rulesets := store.getRulesets()
+	for name, spec := range rulesets.featureGates {
 		if !spec.hasTargetAppID(appId) {
 			continue
 		}
@@ -179,14 +191,14 @@ func getClientInitializeResponse(
 			featureGates[hashedName] = res
 		}
 	}
-	for name, spec := range store.dynamicConfigs {
+	for name, spec := range rulesets.dynamicConfigs {
 		if !spec.hasTargetAppID(appId) {
 			continue
 		}
 		hashedName, res := configToResponse(name, spec)
 		dynamicConfigs[hashedName] = res
 	}
-	for name, spec := range store.layerConfigs {
+	for name, spec := range rulesets.layerConfigs {
 		if !spec.hasTargetAppID(appId) {
 			continue
 		}
@@ -0,0 +1,116 @@
+package statsig
+
+import (
+	"container/list"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+const defaultClientInitializeResponseCacheTTL = 10 * time.Second
+
+// Reuses GetClientInitializeResponse results for identical users, such as the many anonymous sessions
+// a BFF server bootstraps, instead of evaluating every spec again on each request.
+// Cached responses are shared between callers, so they must not be modified.
+type ClientInitializeResponseCacheOptions struct {
+	// Maximum number of responses kept, evicting the least recently used. Zero disables the cache.
+	MaxEntries int
+	// How long a response is reused. Defaults to 10 seconds. Responses are always recomputed once a
+	// config sync brings new rulesets or an override is set, but ID list changes are only picked up when
+	// the TTL expires.
+	TTL time.Duration
+}
+
+type clientInitializeResponseCacheEntry struct {
+	key       string
+	rulesets  *rulesetSnapshot
+	overrides int64
+	response  ClientInitializeResponse
+	expiry    time.Time
+}
+
+type clientInitializeResponseCache struct {
+	maxEntries int
+	ttl        time.Duration
+	entries    map[string]*list.Element
+	lru        *list.List
+	mu         sync.Mutex
+}
+
+func newClientInitializeResponseCache(options ClientInitializeResponseCacheOptions) *clientInitializeResponseCache {
+	if options.MaxEntries <= 0 {
+		return nil
+	}
+	ttl := options.TTL
+	if ttl <= 0 {
+		ttl = defaultClientInitializeResponseCacheTTL
+	}
+	return &clientInitializeResponseCache{
+		maxEntries: options.MaxEntries,
+		ttl:        ttl,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// Every user attribute can affect evaluation, so users only share a response when they are identical.
+// Returns false when the user cannot be serialized.
+func getClientInitializeResponseCacheKey(user User, clientKey string) (string, bool) {
+	serialized, err := json.Marshal(user)
+	if err != nil {
+		return "", false
+	}
+	return clientKey + "|" + string(serialized), true
+}
+
+// Only returns responses computed from the given rulesets and generation of overrides
+func (c *clientInitializeResponseCache) get(key string, rulesets *rulesetSnapshot, overrides int64) (ClientInitializeResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, exists := c.entries[key]
+	if !exists {
+		return ClientInitializeResponse{}, false
+	}
+	entry := element.Value.(*clientInitializeResponseCacheEntry)
+	if entry.rulesets != rulesets || entry.overrides != overrides || !time.Now().Before(entry.expiry) {
+		c.lru.Remove(element)
+		delete(c.entries, key)
+		return ClientInitializeResponse{}, false
+	}
+	c.lru.MoveToFront(element)
+	return entry.response, true
+}
+
+func (c *clientInitializeResponseCache) set(key string, rulesets *rulesetSnapshot, overrides int64, response ClientInitializeResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &clientInitializeResponseCacheEntry{
+		key:       key,
+		rulesets:  rulesets,
+		overrides: overrides,
+		response:  response,
+		expiry:    time.Now().Add(c.ttl),
+	}
+	if element, exists := c.entries[key]; exists {
+		element.Value = entry
+		c.lru.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*clientInitializeResponseCacheEntry).key)
+	}
+}
+
+// Drops every cached response, for memory pressure
+func (c *clientInitializeResponseCache) clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+}
@@ -0,0 +1,98 @@
+package statsig
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestClientInitializeResponseCache(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	newTestClient := func(options ClientInitializeResponseCacheOptions) *Client {
+		return NewClientWithOptions("secret-key", &Options{
+			LocalMode:                            true,
+			BootstrapValues:                      string(bytes),
+			ClientInitializeResponseCacheOptions: options,
+			OutputLoggerOptions:                  getOutputLoggerOptionsForTest(t),
+			StatsigLoggerOptions:                 getStatsigLoggerOptionsForTest(t),
+		})
+	}
+	isSameResponse := func(a, b ClientInitializeResponse) bool {
+		return reflect.ValueOf(a.FeatureGates).Pointer() == reflect.ValueOf(b.FeatureGates).Pointer()
+	}
+	anonymous := User{CustomIDs: map[string]string{"stableID": "abc"}}
+	other := User{CustomIDs: map[string]string{"stableID": "abc"}, Country: "US"}
+
+	t.Run("reuses responses for identical users", func(t *testing.T) {
+		c := newTestClient(ClientInitializeResponseCacheOptions{MaxEntries: 10})
+		defer c.Shutdown()
+		first := c.GetClientInitializeResponse(anonymous, "")
+		if !isSameResponse(first, c.GetClientInitializeResponse(anonymous, "")) {
+			t.Errorf("Expected the response to be reused")
+		}
+		if isSameResponse(first, c.GetClientInitializeResponse(other, "")) {
+			t.Errorf("Expected users with different attributes not to share a response")
+		}
+		if isSameResponse(first, c.GetClientInitializeResponse(anonymous, "client-key")) {
+			t.Errorf("Expected different client keys not to share a response")
+		}
+
+		var specs downloadConfigSpecResponse
+		_ = json.Unmarshal(bytes, &specs)
+		specs.Time++
+		c.evaluator.store.setConfigSpecs(specs)
+		if isSameResponse(first, c.GetClientInitializeResponse(anonymous, "")) {
+			t.Errorf("Expected the response to be recomputed after new rulesets")
+		}
+	})
+
+	t.Run("recomputes responses after an override", func(t *testing.T) {
+		c := newTestClient(ClientInitializeResponseCacheOptions{MaxEntries: 10})
+		defer c.Shutdown()
+		first := c.GetClientInitializeResponse(anonymous, "")
+		c.OverrideGate("always_on_gate", false)
+		second := c.GetClientInitializeResponse(anonymous, "")
+		if isSameResponse(first, second) {
+			t.Errorf("Expected the response to be recomputed after OverrideGate")
+		}
+		c.OverrideConfig("test_config", map[string]interface{}{"number": 1})
+		third := c.GetClientInitializeResponse(anonymous, "")
+		if isSameResponse(second, third) {
+			t.Errorf("Expected the response to be recomputed after OverrideConfig")
+		}
+		c.OverrideLayer("a_layer", map[string]interface{}{"number": 1})
+		if isSameResponse(third, c.GetClientInitializeResponse(anonymous, "")) {
+			t.Errorf("Expected the response to be recomputed after OverrideLayer")
+		}
+	})
+
+	t.Run("evicts the least recently used response", func(t *testing.T) {
+		c := newTestClient(ClientInitializeResponseCacheOptions{MaxEntries: 1})
+		defer c.Shutdown()
+		first := c.GetClientInitializeResponse(anonymous, "")
+		c.GetClientInitializeResponse(other, "")
+		if isSameResponse(first, c.GetClientInitializeResponse(anonymous, "")) {
+			t.Errorf("Expected the first response to be evicted")
+		}
+	})
+
+	t.Run("expires responses after the TTL", func(t *testing.T) {
+		c := newTestClient(ClientInitializeResponseCacheOptions{MaxEntries: 10, TTL: 10 * time.Millisecond})
+		defer c.Shutdown()
+		first := c.GetClientInitializeResponse(anonymous, "")
+		time.Sleep(20 * time.Millisecond)
+		if isSameResponse(first, c.GetClientInitializeResponse(anonymous, "")) {
+			t.Errorf("Expected the response to expire")
+		}
+	})
+
+	t.Run("is disabled by default", func(t *testing.T) {
+		c := newTestClient(ClientInitializeResponseCacheOptions{})
+		defer c.Shutdown()
+		if isSameResponse(c.GetClientInitializeResponse(anonymous, ""), c.GetClientInitializeResponse(anonymous, "")) {
+			t.Errorf("Expected every response to be computed")
+		}
+	})
+}
@@ -0,0 +1,171 @@
+package statsig
+
+import (
+	"container/list"
+	"encoding/json"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// How many previous responses deltas can be computed against. Older ones get a full response.
+const maxClientInitializeResponseDeltaBases = 1000
+
+// The hash of each entity of a response, by hashed name
+type clientInitializeResponseHashes struct {
+	featureGates   map[string]uint64
+	dynamicConfigs map[string]uint64
+	layerConfigs   map[string]uint64
+	time           int64
+}
+
+func hashInitializeResponseEntity(entity interface{}) uint64 {
+	h := fnv.New64a()
+	// Maps are serialized with sorted keys, so equal entities always hash the same
+	_ = json.NewEncoder(h).Encode(entity)
+	return h.Sum64()
+}
+
+func getClientInitializeResponseHashes(response ClientInitializeResponse) clientInitializeResponseHashes {
+	hashes := clientInitializeResponseHashes{
+		featureGates:   make(map[string]uint64, len(response.FeatureGates)),
+		dynamicConfigs: make(map[string]uint64, len(response.DynamicConfigs)),
+		layerConfigs:   make(map[string]uint64, len(response.LayerConfigs)),
+		time:           response.Time,
+	}
+	for name, gate := range response.FeatureGates {
+		hashes.featureGates[name] = hashInitializeResponseEntity(gate)
+	}
+	for name, config := range response.DynamicConfigs {
+		hashes.dynamicConfigs[name] = hashInitializeResponseEntity(config)
+	}
+	for name, layer := range response.LayerConfigs {
+		hashes.layerConfigs[name] = hashInitializeResponseEntity(layer)
+	}
+	return hashes
+}
+
+// Identifies the content of a response, whichever user it was computed for
+func (h clientInitializeResponseHashes) sum() string {
+	entries := make([]string, 0, len(h.featureGates)+len(h.dynamicConfigs)+len(h.layerConfigs))
+	add := func(kind string, hashes map[string]uint64) {
+		for name, hash := range hashes {
+			entries = append(entries, kind+"|"+name+"|"+strconv.FormatUint(hash, 16))
+		}
+	}
+	add("g", h.featureGates)
+	add("c", h.dynamicConfigs)
+	add("l", h.layerConfigs)
+	sort.Strings(entries)
+	sum := fnv.New64a()
+	for _, entry := range entries {
+		_, _ = sum.Write([]byte(entry))
+		_, _ = sum.Write([]byte{'\n'})
+	}
+	return strconv.FormatUint(sum.Sum64(), 16)
+}
+
+// Returns the names of the entities that differ from the previous hashes, and those that no longer exist
+func diffInitializeResponseHashes(previous, current map[string]uint64) (changed map[string]bool, deleted []string) {
+	changed = make(map[string]bool)
+	for name, hash := range current {
+		if previousHash, exists := previous[name]; !exists || previousHash != hash {
+			changed[name] = true
+		}
+	}
+	for name := range previous {
+		if _, exists := current[name]; !exists {
+			deleted = append(deleted, name)
+		}
+	}
+	sort.Strings(deleted)
+	return changed, deleted
+}
+
+// Only holds what the response changed since the previous one. The full response may be shared with the
+// response cache, so its maps are copied rather than modified.
+func getClientInitializeResponseDelta(
+	full ClientInitializeResponse,
+	previous clientInitializeResponseHashes,
+	current clientInitializeResponseHashes,
+) ClientInitializeResponse {
+	delta := full
+	delta.IsDelta = true
+	changedGates, deletedGates := diffInitializeResponseHashes(previous.featureGates, current.featureGates)
+	changedConfigs, deletedConfigs := diffInitializeResponseHashes(previous.dynamicConfigs, current.dynamicConfigs)
+	changedLayers, deletedLayers := diffInitializeResponseHashes(previous.layerConfigs, current.layerConfigs)
+	delta.FeatureGates = make(map[string]GateInitializeResponse, len(changedGates))
+	for name := range changedGates {
+		delta.FeatureGates[name] = full.FeatureGates[name]
+	}
+	delta.DynamicConfigs = make(map[string]ConfigInitializeResponse, len(changedConfigs))
+	for name := range changedConfigs {
+		delta.DynamicConfigs[name] = full.DynamicConfigs[name]
+	}
+	delta.LayerConfigs = make(map[string]LayerInitializeResponse, len(changedLayers))
+	for name := range changedLayers {
+		delta.LayerConfigs[name] = full.LayerConfigs[name]
+	}
+	delta.DeletedGates = deletedGates
+	delta.DeletedConfigs = deletedConfigs
+	delta.DeletedLayers = deletedLayers
+	delta.HasUpdates = len(changedGates)+len(changedConfigs)+len(changedLayers)+
+		len(deletedGates)+len(deletedConfigs)+len(deletedLayers) > 0
+	return delta
+}
+
+type clientInitializeResponseHistoryEntry struct {
+	hash   string
+	hashes clientInitializeResponseHashes
+}
+
+// The entity hashes of the responses served most recently, by response hash
+type clientInitializeResponseHistory struct {
+	maxEntries int
+	entries    map[string]*list.Element
+	lru        *list.List
+	mu         sync.Mutex
+}
+
+func newClientInitializeResponseHistory(maxEntries int) *clientInitializeResponseHistory {
+	return &clientInitializeResponseHistory{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+func (h *clientInitializeResponseHistory) get(hash string) (clientInitializeResponseHashes, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	element, exists := h.entries[hash]
+	if !exists {
+		return clientInitializeResponseHashes{}, false
+	}
+	h.lru.MoveToFront(element)
+	return element.Value.(*clientInitializeResponseHistoryEntry).hashes, true
+}
+
+func (h *clientInitializeResponseHistory) set(hash string, hashes clientInitializeResponseHashes) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if element, exists := h.entries[hash]; exists {
+		h.lru.MoveToFront(element)
+		return
+	}
+	h.entries[hash] = h.lru.PushFront(&clientInitializeResponseHistoryEntry{hash: hash, hashes: hashes})
+	for h.lru.Len() > h.maxEntries {
+		oldest := h.lru.Back()
+		h.lru.Remove(oldest)
+		delete(h.entries, oldest.Value.(*clientInitializeResponseHistoryEntry).hash)
+	}
+}
+
+// Forgets every response, for memory pressure. Clients asking for a delta against them get a full response.
+func (h *clientInitializeResponseHistory) clear() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries = make(map[string]*list.Element)
+	h.lru.Init()
+}
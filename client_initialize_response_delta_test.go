@@ -0,0 +1,88 @@
+package statsig
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func TestClientInitializeResponseDelta(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	c := NewClientWithOptions("secret-key", &Options{
+		LocalMode:            true,
+		BootstrapValues:      string(bytes),
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+	user := User{UserID: "123", Email: "jane@statsig.com"}
+
+	full := c.GetClientInitializeResponseWithOptions(user, "", nil)
+	if full.Hash == "" || full.IsDelta || full.Time != configSyncTime || len(full.FeatureGates) == 0 {
+		t.Fatalf("Expected a full response with a hash, got %+v", full)
+	}
+	since := &ClientInitializeResponseOptions{SinceTime: full.Time, PreviousHash: full.Hash}
+
+	t.Run("returns nothing when nothing changed", func(t *testing.T) {
+		delta := c.GetClientInitializeResponseWithOptions(user, "", since)
+		if !delta.IsDelta || delta.HasUpdates || delta.Hash != full.Hash {
+			t.Errorf("Expected an empty delta, got %+v", delta)
+		}
+		if len(delta.FeatureGates)+len(delta.DynamicConfigs)+len(delta.LayerConfigs) != 0 {
+			t.Errorf("Expected no entities, got %+v", delta)
+		}
+	})
+
+	t.Run("returns full responses for unknown previous responses", func(t *testing.T) {
+		unknown := c.GetClientInitializeResponseWithOptions(user, "", &ClientInitializeResponseOptions{SinceTime: full.Time, PreviousHash: "unknown"})
+		mismatched := c.GetClientInitializeResponseWithOptions(user, "", &ClientInitializeResponseOptions{SinceTime: 1, PreviousHash: full.Hash})
+		for _, response := range []ClientInitializeResponse{unknown, mismatched} {
+			if response.IsDelta || len(response.FeatureGates) != len(full.FeatureGates) {
+				t.Errorf("Expected a full response, got %+v", response)
+			}
+		}
+	})
+
+	t.Run("returns changed and deleted entities", func(t *testing.T) {
+		var specs map[string]interface{}
+		_ = json.Unmarshal(bytes, &specs)
+		gates := specs["feature_gates"].([]interface{})
+		kept := make([]interface{}, 0, len(gates))
+		for _, gate := range gates {
+			if gate.(map[string]interface{})["name"] != "on_for_id_list" {
+				kept = append(kept, gate)
+			}
+		}
+		specs["feature_gates"] = kept
+		for _, config := range specs["dynamic_configs"].([]interface{}) {
+			if config := config.(map[string]interface{}); config["name"] == "test_config" {
+				config["rules"] = []interface{}{}
+			}
+		}
+		specs["time"] = full.Time + 1
+		updated, _ := json.Marshal(specs)
+		var response downloadConfigSpecResponse
+		_ = json.Unmarshal(updated, &response)
+		c.evaluator.store.setConfigSpecs(response)
+
+		delta := c.GetClientInitializeResponseWithOptions(user, "", since)
+		if !delta.IsDelta || !delta.HasUpdates || delta.Hash == full.Hash || delta.Time != full.Time+1 {
+			t.Fatalf("Expected a delta with updates, got %+v", delta)
+		}
+		if len(delta.FeatureGates) != 0 || len(delta.LayerConfigs) != 0 {
+			t.Errorf("Expected no unchanged gates or layers, got %+v", delta)
+		}
+		config, changed := delta.DynamicConfigs[getHashBase64StringEncoding("test_config")]
+		if len(delta.DynamicConfigs) != 1 || !changed || config.RuleID != "default" {
+			t.Errorf("Expected only the changed config, got %+v", delta.DynamicConfigs)
+		}
+		if len(delta.DeletedGates) != 1 || delta.DeletedGates[0] != getHashBase64StringEncoding("on_for_id_list") {
+			t.Errorf("Expected the deleted gate, got %v", delta.DeletedGates)
+		}
+
+		next := c.GetClientInitializeResponseWithOptions(user, "", &ClientInitializeResponseOptions{SinceTime: delta.Time, PreviousHash: delta.Hash})
+		if !next.IsDelta || next.HasUpdates {
+			t.Errorf("Expected deltas to chain, got %+v", next)
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package statsig
+
+// Controls the ClientInitializeResponse returned by GetClientInitializeResponseWithOptions.
+// The zero value gives the same response as GetClientInitializeResponse.
+type ClientInitializeResponseOptions struct {
+	// The Time and Hash of the response the client was last bootstrapped with. When this Client served that
+	// response recently, only the entities that changed since are returned, with the names of the deleted
+	// ones, and IsDelta is set. Otherwise the full response is returned.
+	SinceTime    int64
+	PreviousHash string
+	// Leaves the secondary exposures out, for clients that do not log exposures
+	ExcludeSecondaryExposures bool
+	// Sets DebugNames to the unhashed name of each entity, e.g. to inspect responses in development.
+	// The names of every gate, config and layer are then visible to the client.
+	IncludeUnhashedNames bool
+	// Applies the values set with OverrideGate, OverrideConfig and OverrideLayer. Experiment group overrides
+	// always apply.
+	IncludeLocalOverrides bool
+}
+
+func (e *evaluator) getClientInitializeResponseWithOptions(
+	user User,
+	clientKey string,
+	options *ClientInitializeResponseOptions,
+) ClientInitializeResponse {
+	if options == nil {
+		options = &ClientInitializeResponseOptions{}
+	}
+	// Taken before evaluating, so the response is never stamped with rulesets newer than it was computed from
+	rulesets := e.store.getRulesets()
+	response := e.getClientInitializeResponse(user, clientKey)
+	response = e.shapeClientInitializeResponse(response, options)
+	response.Time = rulesets.time
+	hashes := getClientInitializeResponseHashes(response)
+	response.Hash = hashes.sum()
+	defer e.cirHistory.set(response.Hash, hashes)
+	if options.PreviousHash == "" {
+		return response
+	}
+	previous, exists := e.cirHistory.get(options.PreviousHash)
+	if !exists || previous.time != options.SinceTime {
+		return response
+	}
+	return getClientInitializeResponseDelta(response, previous, hashes)
+}
+
+// Applies the inclusion options. The response may be shared with the response cache, so the maps of
+// a response that changes are copied rather than modified.
+func (e *evaluator) shapeClientInitializeResponse(
+	response ClientInitializeResponse,
+	options *ClientInitializeResponseOptions,
+) ClientInitializeResponse {
+	if !options.ExcludeSecondaryExposures && !options.IncludeUnhashedNames && !options.IncludeLocalOverrides {
+		return response
+	}
+	shaped := response
+	shaped.FeatureGates = make(map[string]GateInitializeResponse, len(response.FeatureGates))
+	for name, gate := range response.FeatureGates {
+		shaped.FeatureGates[name] = gate
+	}
+	shaped.DynamicConfigs = make(map[string]ConfigInitializeResponse, len(response.DynamicConfigs))
+	for name, config := range response.DynamicConfigs {
+		shaped.DynamicConfigs[name] = config
+	}
+	shaped.LayerConfigs = make(map[string]LayerInitializeResponse, len(response.LayerConfigs))
+	for name, layer := range response.LayerConfigs {
+		shaped.LayerConfigs[name] = layer
+	}
+	names := make(map[string]string)
+	if options.IncludeUnhashedNames {
+		rulesets := e.store.getRulesets()
+		for _, specs := range []map[string]configSpec{rulesets.featureGates, rulesets.dynamicConfigs, rulesets.layerConfigs} {
+			for name := range specs {
+				names[getHashBase64StringEncoding(name)] = name
+			}
+		}
+	}
+	if options.IncludeLocalOverrides {
+		e.applyLocalOverrides(&shaped, names)
+	}
+	if options.ExcludeSecondaryExposures {
+		for name, gate := range shaped.FeatureGates {
+			gate.SecondaryExposures = make([]map[string]string, 0)
+			shaped.FeatureGates[name] = gate
+		}
+		for name, config := range shaped.DynamicConfigs {
+			config.SecondaryExposures = make([]map[string]string, 0)
+			shaped.DynamicConfigs[name] = config
+		}
+		for name, layer := range shaped.LayerConfigs {
+			layer.SecondaryExposures = make([]map[string]string, 0)
+			layer.UndelegatedSecondaryExposures = make([]map[string]string, 0)
+			shaped.LayerConfigs[name] = layer
+		}
+	}
+	if options.IncludeUnhashedNames {
+		shaped.DebugNames = make(map[string]string)
+		addName := func(hashedName string) {
+			if name, exists := names[hashedName]; exists {
+				shaped.DebugNames[hashedName] = name
+			}
+		}
+		for hashedName := range shaped.FeatureGates {
+			addName(hashedName)
+		}
+		for hashedName := range shaped.DynamicConfigs {
+			addName(hashedName)
+		}
+		for hashedName := range shaped.LayerConfigs {
+			addName(hashedName)
+		}
+	}
+	return shaped
+}
+
+// Serves the overridden values the way CheckGate, GetConfig and GetLayer do, with the "override" rule ID.
+// Records the names of the overridden entities, which may not be in the rulesets.
+func (e *evaluator) applyLocalOverrides(response *ClientInitializeResponse, names map[string]string) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	base := func(name string) baseSpecInitializeResponse {
+		hashedName := getHashBase64StringEncoding(name)
+		names[hashedName] = name
+		return baseSpecInitializeResponse{
+			Name:               hashedName,
+			RuleID:             "override",
+			SecondaryExposures: make([]map[string]string, 0),
+		}
+	}
+	for name, value := range e.gateOverrides {
+		overridden := base(name)
+		response.FeatureGates[overridden.Name] = GateInitializeResponse{baseSpecInitializeResponse: overridden, Value: value}
+	}
+	for name, value := range e.configOverrides {
+		overridden := base(name)
+		config := response.DynamicConfigs[overridden.Name]
+		config.baseSpecInitializeResponse = overridden
+		config.Value = value
+		config.Group = "override"
+		response.DynamicConfigs[overridden.Name] = config
+	}
+	for name, value := range e.layerOverrides {
+		overridden := base(name)
+		layer := response.LayerConfigs[overridden.Name]
+		layer.baseSpecInitializeResponse = overridden
+		layer.Value = value
+		layer.Group = "override"
+		layer.UndelegatedSecondaryExposures = make([]map[string]string, 0)
+		response.LayerConfigs[overridden.Name] = layer
+	}
+}
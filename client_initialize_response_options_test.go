@@ -0,0 +1,72 @@
+package statsig
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestClientInitializeResponseOptions(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	c := NewClientWithOptions("secret-key", &Options{
+		LocalMode:                            true,
+		BootstrapValues:                      string(bytes),
+		ClientInitializeResponseCacheOptions: ClientInitializeResponseCacheOptions{MaxEntries: 10},
+		OutputLoggerOptions:                  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions:                 getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+	user := User{UserID: "123", Email: "jane@statsig.com"}
+	layerName := getHashBase64StringEncoding("c_layer_with_holdout")
+	gateName := getHashBase64StringEncoding("always_on_gate")
+	configName := getHashBase64StringEncoding("test_config")
+
+	t.Run("matches GetClientInitializeResponse by default", func(t *testing.T) {
+		response := c.GetClientInitializeResponseWithOptions(user, "", &ClientInitializeResponseOptions{})
+		expected := c.GetClientInitializeResponse(user, "")
+		if !reflect.DeepEqual(response.LayerConfigs, expected.LayerConfigs) || response.DebugNames != nil {
+			t.Errorf("Expected the default response, got %+v", response)
+		}
+	})
+
+	t.Run("excludes secondary exposures", func(t *testing.T) {
+		response := c.GetClientInitializeResponseWithOptions(user, "", &ClientInitializeResponseOptions{ExcludeSecondaryExposures: true})
+		layer := response.LayerConfigs[layerName]
+		if len(layer.SecondaryExposures) != 0 || len(layer.UndelegatedSecondaryExposures) != 0 || layer.SecondaryExposures == nil {
+			t.Errorf("Expected empty secondary exposures, got %+v", layer)
+		}
+		if len(c.GetClientInitializeResponse(user, "").LayerConfigs[layerName].SecondaryExposures) == 0 {
+			t.Errorf("Expected the cached response to keep its secondary exposures")
+		}
+	})
+
+	t.Run("includes unhashed names", func(t *testing.T) {
+		response := c.GetClientInitializeResponseWithOptions(user, "", &ClientInitializeResponseOptions{IncludeUnhashedNames: true})
+		if response.DebugNames[gateName] != "always_on_gate" || response.DebugNames[layerName] != "c_layer_with_holdout" {
+			t.Errorf("Expected the unhashed names, got %v", response.DebugNames)
+		}
+		if len(response.DebugNames) != len(response.FeatureGates)+len(response.DynamicConfigs)+len(response.LayerConfigs) {
+			t.Errorf("Expected a name for each entity, got %v", response.DebugNames)
+		}
+	})
+
+	t.Run("includes local overrides", func(t *testing.T) {
+		c.OverrideGate("always_on_gate", false)
+		c.OverrideConfig("test_config", map[string]interface{}{"overridden": true})
+		c.OverrideGate("local_only_gate", true)
+
+		if !c.GetClientInitializeResponse(user, "").FeatureGates[gateName].Value {
+			t.Errorf("Expected overrides to be left out by default")
+		}
+		response := c.GetClientInitializeResponseWithOptions(user, "", &ClientInitializeResponseOptions{IncludeLocalOverrides: true, IncludeUnhashedNames: true})
+		if gate := response.FeatureGates[gateName]; gate.Value || gate.RuleID != "override" {
+			t.Errorf("Expected the overridden gate, got %+v", gate)
+		}
+		if config := response.DynamicConfigs[configName]; config.Value["overridden"] != true || config.Group != "override" {
+			t.Errorf("Expected the overridden config, got %+v", config)
+		}
+		if !response.FeatureGates[getHashBase64StringEncoding("local_only_gate")].Value || response.DebugNames[getHashBase64StringEncoding("local_only_gate")] != "local_only_gate" {
+			t.Errorf("Expected overrides of unknown gates to be included")
+		}
+	})
+}
@@ -1,6 +1,14 @@
 package statsig
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -32,3 +40,189 @@ func TestNormalizeUserDataRace(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestNormalizeUserGlobalCustomFields(t *testing.T) {
+	options := Options{
+		GlobalCustomFields: map[string]interface{}{
+			"region":  "us-west-2",
+			"service": "checkout",
+		},
+	}
+	custom := map[string]interface{}{"service": "payments"}
+	user := normalizeUser(User{UserID: "123", Custom: custom}, options)
+	if user.Custom["region"] != "us-west-2" {
+		t.Errorf("Expected global custom field to be merged into the user")
+	}
+	if user.Custom["service"] != "payments" {
+		t.Errorf("Expected user custom field to take precedence")
+	}
+	if _, ok := custom["region"]; ok {
+		t.Errorf("Expected the caller's custom map not to be modified")
+	}
+}
+
+func TestUserTransform(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	c := NewClientWithOptions("secret-key", &Options{
+		LocalMode:            true,
+		BootstrapValues:      string(bytes),
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+		UserTransform: func(user User) User {
+			if user.Custom["panic"] == true {
+				panic("transform failed")
+			}
+			if account, ok := user.Custom["accountID"].(string); ok {
+				user.UserID = "account-" + account
+			}
+			user.Email = strings.ToLower(user.Email)
+			return user
+		},
+	})
+	defer c.Shutdown()
+
+	user := User{Email: "Jane@Statsig.com", Custom: map[string]interface{}{"accountID": "42"}}
+	if !c.CheckGate(user, "always_on_gate") {
+		t.Errorf("Expected a user whose ID is derived by the transform to be evaluated")
+	}
+	c.LogEvent(Event{EventName: "custom_event", User: user})
+	c.logger.mu.Lock()
+	logged := c.logger.events[len(c.logger.events)-1].(Event)
+	c.logger.mu.Unlock()
+	if logged.User.UserID != "account-42" || logged.User.Email != "jane@statsig.com" {
+		t.Errorf("Expected the transformed user to be logged, got %+v", logged.User)
+	}
+	if user.UserID != "" {
+		t.Errorf("Expected the caller's user not to be modified")
+	}
+	if c.CheckGate(User{Email: "jane@statsig.com"}, "always_on_gate") {
+		t.Errorf("Expected a user still without IDs to be rejected")
+	}
+	if !c.CheckGate(User{UserID: "123", Custom: map[string]interface{}{"panic": true}}, "always_on_gate") {
+		t.Errorf("Expected the untransformed user to be evaluated when the transform panics")
+	}
+}
+
+func TestShutdownWithContext(t *testing.T) {
+	release := make(chan struct{})
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "log_event") {
+			<-release
+		}
+		res.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+	defer close(release)
+
+	options := &Options{
+		API:                  testServer.URL,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	}
+
+	t.Run("returns when the context deadline expires", func(t *testing.T) {
+		c := NewClientWithOptions("secret-key", options)
+		c.LogEvent(Event{EventName: "test_event", User: User{UserID: "123"}})
+		c.LogEvent(Event{EventName: "test_event", User: User{UserID: "456"}})
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		dropped, err := c.ShutdownWithContext(ctx)
+		if time.Since(start) > time.Second {
+			t.Errorf("Expected shutdown to return shortly after the deadline")
+		}
+		if dropped != 2 {
+			t.Errorf("Expected 2 dropped events, got %d", dropped)
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected deadline exceeded error, got %v", err)
+		}
+	})
+
+	t.Run("reports no dropped events when there is nothing to flush", func(t *testing.T) {
+		c := NewClientWithOptions("secret-key", options)
+		dropped, err := c.ShutdownWithContext(context.Background())
+		if dropped != 0 || err != nil {
+			t.Errorf("Expected no dropped events and no error, got %d %v", dropped, err)
+		}
+	})
+}
+
+func TestSecondaryExposures(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	dataAdapter := dataAdapterExample{store: make(map[string]string)}
+	dataAdapter.Set(CONFIG_SPECS_KEY, string(bytes))
+	options := &Options{
+		DataAdapter:          &dataAdapter,
+		LocalMode:            true,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	}
+	InitializeWithOptions("secret-key", options)
+	defer ShutdownAndDangerouslyClearInstance()
+	user := User{UserID: "a-user"}
+
+	gate := GetGate(user, "always_on_gate")
+	if !gate.Value || gate.RuleID != "6N6Z8ODekNYZ7F8gFdoLP5" || gate.Name != "always_on_gate" {
+		t.Errorf("Unexpected gate result %+v", gate)
+	}
+	if gate.SecondaryExposures == nil || len(gate.SecondaryExposures) != 0 {
+		t.Errorf("Expected no secondary exposures for a gate without dependencies, got %v", gate.SecondaryExposures)
+	}
+
+	layer := GetLayerWithExposureLoggingDisabled(user, "c_layer_with_holdout")
+	expected := []SecondaryExposure{{Gate: "always_on_gate", GateValue: "true", RuleID: "6N6Z8ODekNYZ7F8gFdoLP5"}}
+	if !reflect.DeepEqual(layer.SecondaryExposures, expected) {
+		t.Errorf("Expected the holdout gate as a secondary exposure, got %v", layer.SecondaryExposures)
+	}
+
+	config := GetConfigWithExposureLoggingDisabled(user, "test_config")
+	if config.SecondaryExposures == nil || len(config.SecondaryExposures) != 0 {
+		t.Errorf("Expected no secondary exposures for a config without dependencies, got %v", config.SecondaryExposures)
+	}
+}
+
+func TestCheckGates(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	c := NewClientWithOptions("secret-key", &Options{
+		LocalMode:            true,
+		BootstrapValues:      string(bytes),
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+	user := User{UserID: "123", Email: "jane@example.com"}
+	gates := []string{"always_on_gate", "on_for_statsig_email", "unknown_gate"}
+
+	values, details := c.CheckGatesWithDetails(user, gates)
+	for _, gate := range gates {
+		if values[gate] != c.CheckGateWithExposureLoggingDisabled(user, gate) {
+			t.Errorf("Expected %s to match CheckGate", gate)
+		}
+	}
+	if !values["always_on_gate"] || values["on_for_statsig_email"] || len(values) != len(gates) {
+		t.Errorf("Expected a value for each gate, got %v", values)
+	}
+	if details["always_on_gate"].Reason != "Bootstrap" || details["unknown_gate"].Reason != "Unrecognized" {
+		t.Errorf("Expected the details of each evaluation, got %+v", details)
+	}
+
+	c.logger.mu.Lock()
+	logged := make([]exposureEvent, 0)
+	for _, event := range c.logger.events {
+		logged = append(logged, event.(exposureEvent))
+	}
+	c.logger.mu.Unlock()
+	if len(logged) != len(gates) {
+		t.Fatalf("Expected an exposure for each gate, got %d", len(logged))
+	}
+	for i, gate := range gates {
+		if logged[i].Metadata["gate"] != gate || logged[i].Metadata["gateValue"] != strconv.FormatBool(values[gate]) {
+			t.Errorf("Expected the exposure of %s, got %v", gate, logged[i].Metadata)
+		}
+	}
+
+	if values := c.CheckGates(User{}, gates); len(values) != len(gates) || values["always_on_gate"] {
+		t.Errorf("Expected every gate to fail for an empty user, got %v", values)
+	}
+}
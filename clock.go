@@ -0,0 +1,75 @@
+package statsig
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Tells the SDK the current time. Set Options.Clock to freeze or advance time in tests, e.g. to check that a
+// rollout scheduled with a current_time condition starts when expected.
+type Clock interface {
+	Now() time.Time
+}
+
+// Where a Client reads event and diagnostics timestamps from
+type timeSource interface {
+	nowUnixMilli() int64
+}
+
+// The monotonic system clock, unless the options set a Clock
+func getTimeSource(options *Options) timeSource {
+	if options.Clock == nil {
+		return clock
+	}
+	return optionsClock{options.Clock}
+}
+
+type optionsClock struct {
+	clock Clock
+}
+
+func (c optionsClock) nowUnixMilli() int64 {
+	return c.clock.Now().UnixNano() / int64(time.Millisecond)
+}
+
+// Differences between wall clock and monotonic elapsed time larger than this mean the system clock was stepped
+const clockJumpThreshold = 5 * time.Second
+
+// Produces unix millis that advance with the monotonic clock, so timestamps and durations derived from them
+// stay ordered when the system clock is slewed. A step of the wall clock larger than clockJumpThreshold
+// (NTP correction, VM resume) is reported and the clock re-anchors to the new wall time.
+type monotonicClock struct {
+	baseWallMilli int64
+	baseMono      time.Time
+	wallNow       func() int64
+	mu            sync.Mutex
+}
+
+var clock = newMonotonicClock(getUnixMilli)
+
+func newMonotonicClock(wallNow func() int64) *monotonicClock {
+	return &monotonicClock{
+		baseWallMilli: wallNow(),
+		baseMono:      time.Now(),
+		wallNow:       wallNow,
+	}
+}
+
+func (c *monotonicClock) nowUnixMilli() int64 {
+	c.mu.Lock()
+	monoMilli := c.baseWallMilli + int64(time.Since(c.baseMono)/time.Millisecond)
+	wallMilli := c.wallNow()
+	drift := time.Duration(wallMilli-monoMilli) * time.Millisecond
+	jumped := drift > clockJumpThreshold || drift < -clockJumpThreshold
+	if jumped {
+		c.baseWallMilli = wallMilli
+		c.baseMono = time.Now()
+	}
+	c.mu.Unlock()
+	if jumped {
+		global.Logger().LogWarning(fmt.Sprintf("[Statsig] System clock jumped by %s, re-anchoring event timestamps\n", drift), "drift", drift)
+		return wallMilli
+	}
+	return monoMilli
+}
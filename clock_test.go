@@ -0,0 +1,140 @@
+package statsig
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMonotonicClock(t *testing.T) {
+	var offset int64
+	wallNow := func() int64 {
+		return getUnixMilli() + atomic.LoadInt64(&offset)
+	}
+	c := newMonotonicClock(wallNow)
+
+	first := c.nowUnixMilli()
+	// A small backward step is absorbed, so timestamps keep increasing
+	atomic.StoreInt64(&offset, -2000)
+	time.Sleep(10 * time.Millisecond)
+	second := c.nowUnixMilli()
+	if second < first {
+		t.Errorf("Expected timestamps to stay ordered across a small clock step, got %d then %d", first, second)
+	}
+
+	// A large step re-anchors to the new wall time
+	atomic.StoreInt64(&offset, int64(time.Hour/time.Millisecond))
+	jumped := c.nowUnixMilli()
+	expected := wallNow()
+	if jumped < expected-1000 || jumped > expected+1000 {
+		t.Errorf("Expected the clock to re-anchor after a jump, got %d, wall clock %d", jumped, expected)
+	}
+	if after := c.nowUnixMilli(); after < jumped {
+		t.Errorf("Expected timestamps to keep increasing after re-anchoring")
+	}
+}
+
+type fakeClock struct {
+	now time.Time
+	mu  sync.Mutex
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestOptionsClock(t *testing.T) {
+	launch := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	var specs map[string]interface{}
+	_ = json.Unmarshal(bytes, &specs)
+	specs["feature_gates"] = append(specs["feature_gates"].([]interface{}), map[string]interface{}{
+		"name": "scheduled_gate", "type": "feature_gate", "salt": "scheduled", "enabled": true, "idType": "userID",
+		"entity": "feature_gate", "defaultValue": false,
+		"rules": []map[string]interface{}{{
+			"name": "launch", "id": "launch", "salt": "launch", "passPercentage": 100, "idType": "userID", "returnValue": true,
+			"conditions": []map[string]interface{}{{"type": "current_time", "operator": "after", "targetValue": launch.UnixNano() / int64(time.Millisecond)}},
+		}},
+	})
+	bootstrap, _ := json.Marshal(specs)
+	fake := &fakeClock{now: launch.Add(-time.Hour)}
+	c := NewClientWithOptions("secret-key", &Options{
+		LocalMode:            true,
+		BootstrapValues:      string(bootstrap),
+		Clock:                fake,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+	user := User{UserID: "123"}
+
+	if c.CheckGate(user, "scheduled_gate") {
+		t.Errorf("Expected the gate to be off before the launch")
+	}
+	fake.advance(2 * time.Hour)
+	if !c.CheckGate(user, "scheduled_gate") {
+		t.Errorf("Expected the gate to be on after the launch")
+	}
+
+	c.LogEvent(Event{EventName: "custom_event", User: user})
+	expected := fake.Now().UnixNano() / int64(time.Millisecond)
+	c.logger.mu.Lock()
+	logged := c.logger.events[len(c.logger.events)-1].(Event)
+	c.logger.mu.Unlock()
+	if logged.Time != expected {
+		t.Errorf("Expected the event to be timestamped by the clock, got %d, expected %d", logged.Time, expected)
+	}
+	c.diagnostics.initDiagnostics.mu.RLock()
+	defer c.diagnostics.initDiagnostics.mu.RUnlock()
+	markers := c.diagnostics.initDiagnostics.markers
+	if len(markers) == 0 || markers[0].Timestamp != launch.Add(-time.Hour).UnixNano()/int64(time.Millisecond) {
+		t.Errorf("Expected diagnostics to be timestamped by the clock")
+	}
+}
+
+func TestLogEventWithTime(t *testing.T) {
+	fake := &fakeClock{now: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}
+	c := NewClientWithOptions("secret-key", &Options{
+		LocalMode:            true,
+		Clock:                fake,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+	user := User{UserID: "123"}
+	toMillis := func(t time.Time) int64 { return t.UnixNano() / int64(time.Millisecond) }
+	now := fake.Now()
+
+	tests := []struct {
+		name      string
+		eventTime time.Time
+		expected  int64
+	}{
+		{"keeps times within the window", now.Add(-48 * time.Hour), toMillis(now.Add(-48 * time.Hour))},
+		{"uses the current time for the zero time", time.Time{}, toMillis(now)},
+		{"clamps times too far in the past", now.Add(-30 * 24 * time.Hour), toMillis(now.Add(-maxEventTimeBackfill))},
+		{"clamps times in the future", now.Add(24 * time.Hour), toMillis(now.Add(maxEventTimeSkew))},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c.LogEventWithTime(Event{EventName: "backfilled_event", User: user}, test.eventTime)
+			c.logger.mu.Lock()
+			logged := c.logger.events[len(c.logger.events)-1].(Event)
+			c.logger.mu.Unlock()
+			if logged.Time != test.expected {
+				t.Errorf("Expected the event time %d, got %d", test.expected, logged.Time)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package statsig
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+const (
+	cmabRuleIDPrestart        = "prestart"
+	cmabRuleIDTargetingFailed = "inlineTargetingRules"
+	cmabRuleIDSuffixExplore   = ":explore"
+	cmabRuleIDSuffixRanked    = ":ranked"
+)
+
+// A contextual multi-armed bandit, from the cmab_configs of download_config_specs.
+// Each group (arm) has a set of parameter values, and a linear model per group scores users on their attributes.
+type cmabConfig struct {
+	Name              string                     `json:"name"`
+	Salt              string                     `json:"salt"`
+	Enabled           bool                       `json:"enabled"`
+	IDType            string                     `json:"idType"`
+	TargetAppIDs      []string                   `json:"targetAppIDs,omitempty"`
+	Groups            []cmabGroup                `json:"groups"`
+	Config            map[string]cmabGroupConfig `json:"config"`
+	HigherIsBetter    bool                       `json:"higherIsBetter"`
+	TargetingGateName string                     `json:"targetingGateName,omitempty"`
+}
+
+type cmabGroup struct {
+	ID              string          `json:"id"`
+	Name            string          `json:"name"`
+	ParameterValues json.RawMessage `json:"parameterValues"`
+}
+
+// The trained model of a group. Users are scored as intercept + the numerical weights times the
+// user's values + the weight of the user's value of each categorical attribute.
+type cmabGroupConfig struct {
+	Intercept          float64                       `json:"intercept"`
+	Records            int                           `json:"records"`
+	WeightsNumerical   map[string]float64            `json:"weightsNumerical"`
+	WeightsCategorical map[string]map[string]float64 `json:"weightsCategorical"`
+}
+
+func (e *evaluator) getCMAB(user User, name string) *evalResult {
+	e.metrics.increment(metricEvaluations, "cmab", 1)
+	return e.evalWithLatencyBudget(func() *evalResult {
+		return e.evalCMAB(user, e.store.getRulesets(), name)
+	}, func() *evalResult {
+		return &evalResult{
+			ConfigValue:        *NewConfig(name, nil, ""),
+			EvaluationDetails:  e.createEvaluationDetails(reasonTimeout),
+			SecondaryExposures: make([]map[string]string, 0),
+		}
+	})
+}
+
+func (e *evaluator) evalCMAB(user User, rulesets *rulesetSnapshot, name string) *evalResult {
+	cmab, exists := rulesets.cmabConfigs[name]
+	if !exists {
+		return &evalResult{
+			ConfigValue:        *NewConfig(name, nil, ""),
+			EvaluationDetails:  e.createEvaluationDetailsFor(rulesets, reasonUnrecognized),
+			SecondaryExposures: make([]map[string]string, 0),
+		}
+	}
+	result := &evalResult{
+		ConfigValue:        *NewConfig(name, nil, cmabRuleIDPrestart),
+		Id:                 cmabRuleIDPrestart,
+		EvaluationDetails:  e.createEvaluationDetailsFor(rulesets, rulesets.initReason),
+		SecondaryExposures: make([]map[string]string, 0),
+	}
+	if !cmab.Enabled || len(cmab.Groups) == 0 {
+		return result
+	}
+	if cmab.TargetingGateName != "" {
+		gate := e.evalGate(user, rulesets, cmab.TargetingGateName, 1)
+		result.SecondaryExposures = append(result.SecondaryExposures, map[string]string{
+			"gate":      cmab.TargetingGateName,
+			"gateValue": strconv.FormatBool(gate.Pass),
+			"ruleID":    gate.Id,
+		})
+		if !gate.Pass {
+			result.Id = cmabRuleIDTargetingFailed
+			result.ConfigValue.RuleID = cmabRuleIDTargetingFailed
+			return result
+		}
+	}
+
+	group, ranked := selectCMABGroup(user, cmab)
+	ruleID := group.ID + cmabRuleIDSuffixExplore
+	if ranked {
+		ruleID = group.ID + cmabRuleIDSuffixRanked
+	}
+	var value map[string]interface{}
+	config := NewConfig(name, nil, ruleID)
+	if json.Unmarshal(group.ParameterValues, &value) == nil {
+		config = NewConfig(name, value, ruleID)
+		config.rawValue = group.ParameterValues
+	}
+	result.Pass = true
+	result.Id = ruleID
+	result.ConfigValue = *config
+	result.UndelegatedSecondaryExposures = result.SecondaryExposures
+	return result
+}
+
+// Ranks the groups with their trained models. Until every group has a model trained on some records,
+// users are spread evenly across the groups to explore them. Returns whether the group was ranked.
+func selectCMABGroup(user User, cmab cmabConfig) (cmabGroup, bool) {
+	for _, group := range cmab.Groups {
+		if model, exists := cmab.Config[group.ID]; !exists || model.Records <= 0 {
+			hash := getHashUint64Encoding(cmab.Salt + "." + getUnitID(user, cmab.IDType))
+			return cmab.Groups[hash%uint64(len(cmab.Groups))], false
+		}
+	}
+	best := cmab.Groups[0]
+	bestScore := math.Inf(-1)
+	if !cmab.HigherIsBetter {
+		bestScore = math.Inf(1)
+	}
+	for _, group := range cmab.Groups {
+		score := scoreCMABGroup(user, cmab.Config[group.ID])
+		if (cmab.HigherIsBetter && score > bestScore) || (!cmab.HigherIsBetter && score < bestScore) {
+			best, bestScore = group, score
+		}
+	}
+	return best, true
+}
+
+// Attributes the user does not have, or that are not numbers, do not contribute to the score
+func scoreCMABGroup(user User, model cmabGroupConfig) float64 {
+	score := model.Intercept
+	for field, weight := range model.WeightsNumerical {
+		if value, ok := getNumericValue(getFromUser(user, field)); ok {
+			score += weight * value
+		}
+	}
+	for field, weights := range model.WeightsCategorical {
+		value := getFromUser(user, field)
+		if value == nil {
+			continue
+		}
+		score += weights[fmt.Sprint(value)]
+	}
+	return score
+}
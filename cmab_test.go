@@ -0,0 +1,92 @@
+package statsig
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCMAB(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	var specs map[string]interface{}
+	_ = json.Unmarshal(bytes, &specs)
+	groups := []map[string]interface{}{
+		{"id": "group_a", "name": "A", "parameterValues": map[string]interface{}{"color": "red"}},
+		{"id": "group_b", "name": "B", "parameterValues": map[string]interface{}{"color": "blue"}},
+	}
+	trained := map[string]interface{}{
+		"group_a": map[string]interface{}{"intercept": 1, "records": 100, "weightsNumerical": map[string]float64{"age": 0.5}},
+		"group_b": map[string]interface{}{"intercept": 10, "records": 100, "weightsCategorical": map[string]map[string]float64{"plan": {"pro": 5}}},
+	}
+	specs["cmab_configs"] = map[string]interface{}{
+		"untrained_cmab": map[string]interface{}{"salt": "salt", "enabled": true, "groups": groups},
+		"trained_cmab":   map[string]interface{}{"salt": "salt", "enabled": true, "groups": groups, "config": trained, "higherIsBetter": true},
+		"lower_cmab":     map[string]interface{}{"salt": "salt", "enabled": true, "groups": groups, "config": trained},
+		"disabled_cmab":  map[string]interface{}{"salt": "salt", "enabled": false, "groups": groups},
+		"targeted_cmab":  map[string]interface{}{"salt": "salt", "enabled": true, "groups": groups, "targetingGateName": "on_for_statsig_email"},
+	}
+	bootstrap, _ := json.Marshal(specs)
+	c := NewClientWithOptions("secret-key", &Options{
+		LocalMode:            true,
+		BootstrapValues:      string(bootstrap),
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+	user := User{UserID: "123", Custom: map[string]interface{}{"age": 30, "plan": "pro"}}
+
+	t.Run("explores evenly until every group is trained", func(t *testing.T) {
+		cmab := c.GetCMAB(user, "untrained_cmab")
+		if !strings.HasSuffix(cmab.RuleID, ":explore") || cmab.GetString("color", "") == "" {
+			t.Errorf("Expected an explored group, got %s %v", cmab.RuleID, cmab.Value)
+		}
+		if again := c.GetCMAB(user, "untrained_cmab"); again.RuleID != cmab.RuleID {
+			t.Errorf("Expected the same user to get the same group")
+		}
+	})
+
+	t.Run("ranks groups by their trained models", func(t *testing.T) {
+		// group_a scores 1 + 0.5 * 30 = 16, group_b scores 10 + 5 = 15
+		if cmab := c.GetCMAB(user, "trained_cmab"); cmab.RuleID != "group_a:ranked" || cmab.GetString("color", "") != "red" {
+			t.Errorf("Expected the highest scoring group, got %s %v", cmab.RuleID, cmab.Value)
+		}
+		if cmab := c.GetCMAB(user, "lower_cmab"); cmab.RuleID != "group_b:ranked" {
+			t.Errorf("Expected the lowest scoring group, got %s", cmab.RuleID)
+		}
+	})
+
+	t.Run("serves no parameters when disabled, untargeted or unknown", func(t *testing.T) {
+		if cmab := c.GetCMAB(user, "disabled_cmab"); cmab.RuleID != "prestart" || len(cmab.Value) != 0 {
+			t.Errorf("Expected a disabled CMAB to be prestart, got %s", cmab.RuleID)
+		}
+		cmab := c.GetCMAB(user, "targeted_cmab")
+		if cmab.RuleID != "inlineTargetingRules" || len(cmab.SecondaryExposures) != 1 {
+			t.Errorf("Expected the targeting gate to fail, got %s", cmab.RuleID)
+		}
+		targeted := User{UserID: "123", Email: "test@statsig.com"}
+		if cmab := c.GetCMAB(targeted, "targeted_cmab"); !strings.HasSuffix(cmab.RuleID, ":explore") {
+			t.Errorf("Expected targeted users to get a group, got %s", cmab.RuleID)
+		}
+		if cmab := c.GetCMAB(user, "unknown_cmab"); cmab.RuleID != "" || len(cmab.Value) != 0 {
+			t.Errorf("Expected an unknown CMAB to be empty")
+		}
+	})
+
+	t.Run("logs config exposures", func(t *testing.T) {
+		c.logger.mu.Lock()
+		c.logger.events = c.logger.events[:0]
+		c.logger.mu.Unlock()
+		c.GetCMAB(user, "trained_cmab")
+		c.GetCMABWithExposureLoggingDisabled(user, "trained_cmab")
+		c.logger.mu.Lock()
+		defer c.logger.mu.Unlock()
+		if len(c.logger.events) != 1 {
+			t.Fatalf("Expected a single exposure, got %d", len(c.logger.events))
+		}
+		evt := c.logger.events[0].(exposureEvent)
+		if evt.EventName != configExposureEventName || evt.Metadata["config"] != "trained_cmab" || evt.Metadata["ruleID"] != "group_a:ranked" {
+			t.Errorf("Expected a config exposure for the CMAB, got %+v", evt)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package statsig
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// Merges a delta download_config_specs response into the specs currently in the store. A delta only
+// carries the entities changed since the sinceTime of the request and the names of the deleted ones.
+// Returns the merged specs, or false if there is nothing to merge into or the merged specs do not
+// match the checksum of the delta.
+func (s *store) mergeConfigSpecsDelta(delta downloadConfigSpecResponse) (downloadConfigSpecResponse, bool) {
+	rulesets := s.getRulesets()
+	if rulesets.time == 0 {
+		return delta, false
+	}
+	gates := mergeSpecs(rulesets.featureGates, delta.FeatureGates, delta.DeletedGates)
+	configs := mergeSpecs(rulesets.dynamicConfigs, delta.DynamicConfigs, delta.DeletedConfigs)
+	layerConfigs := mergeSpecs(rulesets.layerConfigs, delta.LayerConfigs, delta.DeletedLayers)
+	layers := delta.Layers
+	if layers == nil {
+		layers = make(map[string][]string)
+		for experiment, layer := range rulesets.experimentToLayer {
+			layers[layer] = append(layers[layer], experiment)
+		}
+	}
+	sdkKeysToAppID := delta.SDKKeysToAppID
+	if sdkKeysToAppID == nil {
+		sdkKeysToAppID = rulesets.sdkKeysToAppID
+	}
+	cmabConfigs := delta.CMABConfigs
+	if cmabConfigs == nil {
+		cmabConfigs = rulesets.cmabConfigs
+	}
+	paramStores := delta.ParamStores
+	if paramStores == nil {
+		paramStores = rulesets.paramStores
+	}
+
+	merged := downloadConfigSpecResponse{
+		HasUpdates:             true,
+		Time:                   delta.Time,
+		FeatureGates:           gates,
+		DynamicConfigs:         configs,
+		LayerConfigs:           layerConfigs,
+		Layers:                 layers,
+		IDLists:                delta.IDLists,
+		DiagnosticsSampleRates: delta.DiagnosticsSampleRates,
+		SDKKeysToAppID:         sdkKeysToAppID,
+		CMABConfigs:            cmabConfigs,
+		ParamStores:            paramStores,
+		HashedSDKKeyUsed:       delta.HashedSDKKeyUsed,
+	}
+	if delta.Checksum != "" && getConfigSpecsChecksum(merged) != delta.Checksum {
+		return delta, false
+	}
+	return merged, true
+}
+
+// Applies the changed and deleted specs to a copy of current, sorted by name
+func mergeSpecs(current map[string]configSpec, changed []configSpec, deleted []string) []configSpec {
+	merged := make(map[string]configSpec, len(current)+len(changed))
+	for name, spec := range current {
+		merged[name] = spec
+	}
+	for _, spec := range changed {
+		merged[spec.Name] = spec
+	}
+	for _, name := range deleted {
+		delete(merged, name)
+	}
+	specs := make([]configSpec, 0, len(merged))
+	for _, spec := range merged {
+		specs = append(specs, spec)
+	}
+	sort.Slice(specs, func(i, j int) bool {
+		return specs[i].Name < specs[j].Name
+	})
+	return specs
+}
+
+// The DJB2 hash of the feature_gates, dynamic_configs and layer_configs, each sorted by name, serialized as JSON
+func getConfigSpecsChecksum(specs downloadConfigSpecResponse) string {
+	sorted := func(specs []configSpec) []configSpec {
+		sorted := append([]configSpec{}, specs...)
+		sort.Slice(sorted, func(i, j int) bool {
+			return sorted[i].Name < sorted[j].Name
+		})
+		return sorted
+	}
+	canonical, err := json.Marshal(struct {
+		FeatureGates   []configSpec `json:"feature_gates"`
+		DynamicConfigs []configSpec `json:"dynamic_configs"`
+		LayerConfigs   []configSpec `json:"layer_configs"`
+	}{sorted(specs.FeatureGates), sorted(specs.DynamicConfigs), sorted(specs.LayerConfigs)})
+	if err != nil {
+		return ""
+	}
+	return getDJB2Hash(string(canonical))
+}
@@ -0,0 +1,144 @@
+package statsig
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConfigSpecDeltas(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	var full downloadConfigSpecResponse
+	_ = json.Unmarshal(bytes, &full)
+
+	// Turns always_on_gate off and deletes test_config
+	delta := downloadConfigSpecResponse{HasUpdates: true, IsDelta: true, Time: full.Time + 1, DeletedConfigs: []string{"test_config"}}
+	merged := downloadConfigSpecResponse{DynamicConfigs: []configSpec{}}
+	for _, gate := range full.FeatureGates {
+		if gate.Name == "always_on_gate" {
+			gate.Enabled = false
+			delta.FeatureGates = append(delta.FeatureGates, gate)
+		}
+		merged.FeatureGates = append(merged.FeatureGates, gate)
+	}
+	for _, config := range full.DynamicConfigs {
+		if config.Name != "test_config" {
+			merged.DynamicConfigs = append(merged.DynamicConfigs, config)
+		}
+	}
+	merged.LayerConfigs = full.LayerConfigs
+
+	var mu sync.Mutex
+	var requests []downloadConfigsInput
+	checksum := ""
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "download_config_specs") {
+			var input downloadConfigsInput
+			_ = json.NewDecoder(req.Body).Decode(&input)
+			mu.Lock()
+			requests = append(requests, input)
+			response := delta
+			response.Checksum = checksum
+			mu.Unlock()
+			res.WriteHeader(http.StatusOK)
+			if input.SinceTime == 0 {
+				_, _ = res.Write(bytes)
+			} else {
+				_ = json.NewEncoder(res).Encode(response)
+			}
+			return
+		}
+		res.WriteHeader(http.StatusOK)
+		_, _ = res.Write([]byte("{}"))
+	}))
+	defer testServer.Close()
+
+	options := &Options{
+		API:                    testServer.URL,
+		ConfigSyncInterval:     time.Hour,
+		EnableConfigSpecDeltas: true,
+		OutputLoggerOptions:    getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions:   getStatsigLoggerOptionsForTest(t),
+	}
+	InitializeWithOptions("secret-key", options)
+	defer ShutdownAndDangerouslyClearInstance()
+	store := instance.evaluator.store
+	user := User{UserID: "123", Email: "test@statsig.com"}
+
+	t.Run("merges a delta with a matching checksum", func(t *testing.T) {
+		mu.Lock()
+		checksum = getConfigSpecsChecksum(merged)
+		mu.Unlock()
+		store.fetchConfigSpecsFromServer(false)
+		if CheckGate(user, "always_on_gate") {
+			t.Errorf("Expected always_on_gate to be turned off by the delta")
+		}
+		if _, exists := store.getDynamicConfig("test_config"); exists {
+			t.Errorf("Expected test_config to be deleted by the delta")
+		}
+		if _, exists := store.getDynamicConfig("sample_experiment"); !exists {
+			t.Errorf("Expected sample_experiment to be kept")
+		}
+		if store.getLastSyncTime() != delta.Time {
+			t.Errorf("Expected the sync time of the delta, got %d", store.getLastSyncTime())
+		}
+		mu.Lock()
+		last := requests[len(requests)-1]
+		mu.Unlock()
+		if !last.AcceptsDeltas || last.SinceTime != full.Time {
+			t.Errorf("Expected a delta request since the last sync, got %+v", last)
+		}
+	})
+
+	t.Run("falls back to a full sync on a checksum mismatch", func(t *testing.T) {
+		mu.Lock()
+		checksum = "mismatch"
+		requests = nil
+		mu.Unlock()
+		store.fetchConfigSpecsFromServer(false)
+		mu.Lock()
+		defer mu.Unlock()
+		if len(requests) != 2 || requests[1].SinceTime != 0 || requests[1].AcceptsDeltas {
+			t.Errorf("Expected a full sync after the mismatched delta, got %+v", requests)
+		}
+		if !CheckGate(user, "always_on_gate") {
+			t.Errorf("Expected the full sync to restore always_on_gate")
+		}
+	})
+}
+
+func TestConfigSpecDeltasDisabledByDefault(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	var acceptsDeltas int32
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "download_config_specs") {
+			var input downloadConfigsInput
+			_ = json.NewDecoder(req.Body).Decode(&input)
+			if input.AcceptsDeltas {
+				atomic.AddInt32(&acceptsDeltas, 1)
+			}
+			_, _ = res.Write(bytes)
+			return
+		}
+		res.WriteHeader(http.StatusOK)
+		_, _ = res.Write([]byte("{}"))
+	}))
+	defer testServer.Close()
+
+	c := NewClientWithOptions("secret-key", &Options{
+		API:                  testServer.URL,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+	c.evaluator.store.fetchConfigSpecsFromServer(false)
+	if atomic.LoadInt32(&acceptsDeltas) != 0 {
+		t.Errorf("Expected no deltas to be requested unless EnableConfigSpecDeltas is set")
+	}
+}
@@ -0,0 +1,134 @@
+package statsig
+
+import "encoding/json"
+
+// Deduplicates the values that repeat across a download_config_specs response. After JSON unmarshal every
+// rule ID, operator, field name and target value is its own allocation, so large spec sets hold many
+// copies of the same strings and conditions. The tables only live for a single sync.
+type configSpecInterner struct {
+	strings    map[string]string
+	values     map[string]json.RawMessage
+	conditions map[string][]configCondition
+}
+
+// Keeps the raw JSON of the conditions while decoding a rule, so identical conditions can be found
+// by their bytes rather than by encoding them again
+func (r *configRule) UnmarshalJSON(data []byte) error {
+	type rule configRule
+	decoded := struct {
+		*rule
+		Conditions json.RawMessage `json:"conditions"`
+	}{rule: (*rule)(r)}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	r.Conditions = nil
+	if len(decoded.Conditions) > 0 && string(decoded.Conditions) != "null" {
+		if err := json.Unmarshal(decoded.Conditions, &r.Conditions); err != nil {
+			return err
+		}
+		r.conditionsJSON = string(decoded.Conditions)
+	}
+	return nil
+}
+
+// Interns the specs in place as they are decoded, so it must only be called on specs that were just
+// decoded and are not shared with the store yet
+func internConfigSpecs(specs *downloadConfigSpecResponse) {
+	i := &configSpecInterner{
+		strings:    make(map[string]string),
+		values:     make(map[string]json.RawMessage),
+		conditions: make(map[string][]configCondition),
+	}
+	i.internSpecs(specs.FeatureGates)
+	i.internSpecs(specs.DynamicConfigs)
+	i.internSpecs(specs.LayerConfigs)
+}
+
+func (i *configSpecInterner) intern(s string) string {
+	if interned, exists := i.strings[s]; exists {
+		return interned
+	}
+	i.strings[s] = s
+	return s
+}
+
+func (i *configSpecInterner) internStrings(strings []string) {
+	for idx, s := range strings {
+		strings[idx] = i.intern(s)
+	}
+}
+
+func (i *configSpecInterner) internValue(value json.RawMessage) json.RawMessage {
+	if value == nil {
+		return nil
+	}
+	if interned, exists := i.values[string(value)]; exists {
+		return interned
+	}
+	i.values[string(value)] = value
+	return value
+}
+
+func (i *configSpecInterner) internSpecs(specs []configSpec) {
+	for idx := range specs {
+		spec := &specs[idx]
+		spec.Type = i.intern(spec.Type)
+		spec.IDType = i.intern(spec.IDType)
+		spec.Entity = i.intern(spec.Entity)
+		spec.DefaultValue = i.internValue(spec.DefaultValue)
+		i.internStrings(spec.ExplicitParameters)
+		i.internStrings(spec.TargetAppIDs)
+		for r := range spec.Rules {
+			rule := &spec.Rules[r]
+			rule.Name = i.intern(rule.Name)
+			rule.ID = i.intern(rule.ID)
+			rule.IDType = i.intern(rule.IDType)
+			rule.ConfigDelegate = i.intern(rule.ConfigDelegate)
+			rule.GroupName = i.intern(rule.GroupName)
+			rule.ReturnValue = i.internValue(rule.ReturnValue)
+			rule.Conditions = i.internConditions(rule.Conditions, rule.conditionsJSON)
+			rule.conditionsJSON = ""
+		}
+	}
+}
+
+// Rules with identical conditions, such as the same targeting shared by several gates, get the same slice.
+// Conditions are only ever read during evaluation, so sharing them is safe. Conditions decoded by a
+// JSONCodec that does not call UnmarshalJSON have no raw JSON, and only get their strings interned.
+func (i *configSpecInterner) internConditions(conditions []configCondition, raw string) []configCondition {
+	if len(conditions) == 0 {
+		return conditions
+	}
+	if raw != "" {
+		if interned, exists := i.conditions[raw]; exists {
+			return interned
+		}
+		i.conditions[raw] = conditions
+	}
+	for idx := range conditions {
+		cond := &conditions[idx]
+		cond.Type = i.intern(cond.Type)
+		cond.Operator = i.intern(cond.Operator)
+		cond.Field = i.intern(cond.Field)
+		cond.IDType = i.intern(cond.IDType)
+		cond.TargetValue = i.internTargetValue(cond.TargetValue)
+	}
+	return conditions
+}
+
+func (i *configSpecInterner) internTargetValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return i.intern(v)
+	case []interface{}:
+		for idx, element := range v {
+			if s, isString := element.(string); isString {
+				v[idx] = i.intern(s)
+			}
+		}
+		return v
+	default:
+		return value
+	}
+}
@@ -0,0 +1,59 @@
+package statsig
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestConfigSpecInterning(t *testing.T) {
+	raw := `{
+		"has_updates": true,
+		"feature_gates": [
+			{"name": "gate_a", "type": "feature_gate", "rules": [{"id": "rule_a", "returnValue": true, "conditions": [{"type": "user_field", "operator": "any", "field": "country", "targetValue": ["US", "CA"]}]}]},
+			{"name": "gate_b", "type": "feature_gate", "rules": [{"id": "rule_b", "returnValue": true, "conditions": [{"type": "user_field", "operator": "any", "field": "country", "targetValue": ["US", "CA"]}]}]},
+			{"name": "gate_c", "type": "feature_gate", "rules": [{"id": "rule_c", "returnValue": true, "conditions": [{"type": "user_field", "operator": "none", "field": "country", "targetValue": ["US"]}]}]}
+		]
+	}`
+	var specs downloadConfigSpecResponse
+	if err := json.Unmarshal([]byte(raw), &specs); err != nil {
+		t.Fatal(err)
+	}
+	if specs.FeatureGates[0].Rules[0].conditionsJSON == "" {
+		t.Fatalf("Expected the raw JSON of the conditions to be kept while decoding")
+	}
+	var expected downloadConfigSpecResponse
+	_ = json.Unmarshal([]byte(raw), &expected)
+	internConfigSpecs(&specs)
+	a := specs.FeatureGates[0].Rules[0]
+	b := specs.FeatureGates[1].Rules[0]
+	c := specs.FeatureGates[2].Rules[0]
+
+	if &a.Conditions[0] != &b.Conditions[0] {
+		t.Errorf("Expected identical conditions to be shared")
+	}
+	if &a.Conditions[0] == &c.Conditions[0] {
+		t.Errorf("Expected different conditions not to be shared")
+	}
+	if stringData(a.Conditions[0].Field) != stringData(c.Conditions[0].Field) {
+		t.Errorf("Expected field names to be interned")
+	}
+	if stringData(a.Conditions[0].TargetValue.([]interface{})[0].(string)) != stringData(c.Conditions[0].TargetValue.([]interface{})[0].(string)) {
+		t.Errorf("Expected target values to be interned")
+	}
+	if stringData(specs.FeatureGates[0].Type) != stringData(specs.FeatureGates[2].Type) {
+		t.Errorf("Expected spec types to be interned")
+	}
+	if &a.ReturnValue[0] != &c.ReturnValue[0] {
+		t.Errorf("Expected identical return values to be shared")
+	}
+	if a.conditionsJSON != "" {
+		t.Errorf("Expected the raw JSON of the conditions to be dropped once interned")
+	}
+	for _, gate := range expected.FeatureGates {
+		gate.Rules[0].conditionsJSON = ""
+	}
+	if !reflect.DeepEqual(specs, expected) {
+		t.Errorf("Expected interning to preserve the specs")
+	}
+}
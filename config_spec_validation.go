@@ -0,0 +1,160 @@
+package statsig
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Reported when config specs cannot be parsed or break an invariant evaluation relies on. The specs are
+// rejected as a whole, and the current rulesets keep being served until valid ones are synced.
+type InvalidConfigSpecsError struct {
+	Source string
+	Err    error
+}
+
+func (e *InvalidConfigSpecsError) Error() string {
+	return fmt.Sprintf("config specs from %s are invalid: %s", e.Source, e.Err)
+}
+
+func (e *InvalidConfigSpecsError) Unwrap() error {
+	return e.Err
+}
+
+// Returned when config specs break the schema or an invariant evaluation relies on, listing each problem
+// with the path to it, e.g. feature_gates[2] "my_gate": rules[0]: passPercentage 120 is not between 0 and 100
+type ConfigSpecsValidationError struct {
+	Problems []string
+}
+
+// How many problems the error message lists, the rest are only counted
+const maxListedValidationProblems = 10
+
+func (e *ConfigSpecsValidationError) Error() string {
+	listed := e.Problems
+	if len(listed) > maxListedValidationProblems {
+		listed = listed[:maxListedValidationProblems]
+	}
+	message := fmt.Sprintf("%d problems: %s", len(e.Problems), strings.Join(listed, "; "))
+	if len(e.Problems) > len(listed) {
+		message += fmt.Sprintf("; and %d more", len(e.Problems)-len(listed))
+	}
+	return message
+}
+
+type configSpecsValidator struct {
+	problems []string
+}
+
+func (v *configSpecsValidator) add(path string, format string, args ...interface{}) {
+	v.problems = append(v.problems, path+": "+fmt.Sprintf(format, args...))
+}
+
+// Checks the structure of the specs before they are applied: the type of each spec, percentage bounds, and the
+// target values of conditions whose operators are known, along with unique names, salts to bucket users with,
+// and config values that are JSON objects. Unknown condition types and operators are left for evaluation to
+// resolve, as newer servers may send them. Returns a *ConfigSpecsValidationError listing every problem found.
+func validateConfigSpecs(specs downloadConfigSpecResponse) error {
+	sections := []struct {
+		name     string
+		specs    []configSpec
+		specType string
+	}{
+		{"feature_gates", specs.FeatureGates, featureGateType},
+		{"dynamic_configs", specs.DynamicConfigs, dynamicConfigType},
+		{"layer_configs", specs.LayerConfigs, dynamicConfigType},
+	}
+	v := &configSpecsValidator{}
+	for _, section := range sections {
+		seen := make(map[string]bool, len(section.specs))
+		for i, spec := range section.specs {
+			path := fmt.Sprintf("%s[%d] %q", section.name, i, spec.Name)
+			v.validateConfigSpec(path, spec, section.specType)
+			if spec.Name != "" && seen[spec.Name] {
+				v.add(path, "duplicate name")
+			}
+			seen[spec.Name] = true
+		}
+	}
+	if len(v.problems) > 0 {
+		return &ConfigSpecsValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+func (v *configSpecsValidator) validateConfigSpec(path string, spec configSpec, specType string) {
+	if spec.Name == "" {
+		v.add(path, "missing name")
+	}
+	if spec.Type != "" && !strings.EqualFold(spec.Type, specType) {
+		v.add(path, "type %q is not %q", spec.Type, specType)
+	}
+	objectValue := specType == dynamicConfigType
+	if objectValue && !isJSONObjectOrEmpty(spec.DefaultValue) {
+		v.add(path, "defaultValue is not an object")
+	}
+	for i, rule := range spec.Rules {
+		rulePath := fmt.Sprintf("%s: rules[%d]", path, i)
+		if rule.PassPercentage < 0 || rule.PassPercentage > 100 {
+			v.add(rulePath, "passPercentage %v is not between 0 and 100", rule.PassPercentage)
+		}
+		// Partial rollouts hash the unit ID with the rule salt, which falls back to the rule ID
+		if rule.PassPercentage > 0 && rule.PassPercentage < 100 && rule.Salt == "" && rule.ID == "" {
+			v.add(rulePath, "missing salt for a partial rollout")
+		}
+		if objectValue && rule.ConfigDelegate == "" && !isJSONObjectOrEmpty(rule.ReturnValue) {
+			v.add(rulePath, "returnValue is not an object")
+		}
+		for j, cond := range rule.Conditions {
+			v.validateCondition(fmt.Sprintf("%s.conditions[%d]", rulePath, j), cond)
+		}
+	}
+}
+
+func (v *configSpecsValidator) validateCondition(path string, cond configCondition) {
+	condType := strings.ToLower(cond.Type)
+	switch condType {
+	case "":
+		v.add(path, "missing type")
+	case "user_bucket":
+		if salt, ok := cond.AdditionalValues["salt"]; !ok || salt == nil {
+			v.add(path, "missing salt for user_bucket")
+		}
+	case "pass_gate", "fail_gate":
+		if gate, ok := cond.TargetValue.(string); !ok || gate == "" {
+			v.add(path, "missing gate name for %s", cond.Type)
+		}
+		return
+	}
+	op := strings.ToLower(cond.Operator)
+	switch op {
+	case "gt", "gte", "lt", "lte":
+		if _, ok := getNumericValue(cond.TargetValue); !ok {
+			v.add(path, "targetValue of %s is not a number", op)
+		}
+	case "any", "none", "any_case_sensitive", "none_case_sensitive",
+		"str_starts_with_any", "str_ends_with_any", "str_contains_any", "str_contains_none":
+		if _, ok := cond.TargetValue.([]interface{}); !ok {
+			v.add(path, "targetValue of %s is not an array", op)
+		}
+	case "in_segment_list", "not_in_segment_list":
+		if _, ok := cond.TargetValue.(string); !ok {
+			v.add(path, "targetValue of %s is not an ID list name", op)
+		}
+	case "str_matches":
+		if _, ok := cond.TargetValue.(string); !ok && cond.TargetValue != nil {
+			v.add(path, "targetValue of %s is not a string", op)
+		}
+	case "before", "after", "on":
+		if _, ok := cond.TargetValue.(string); !ok {
+			if _, ok := getNumericValue(cond.TargetValue); !ok {
+				v.add(path, "targetValue of %s is not a time", op)
+			}
+		}
+	}
+}
+
+func isJSONObjectOrEmpty(value []byte) bool {
+	value = bytes.TrimSpace(value)
+	return len(value) == 0 || bytes.Equal(value, []byte("null")) || value[0] == '{'
+}
@@ -0,0 +1,214 @@
+package statsig
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestValidateConfigSpecs(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	var fixture downloadConfigSpecResponse
+	_ = json.Unmarshal(bytes, &fixture)
+	if err := validateConfigSpecs(fixture); err != nil {
+		t.Errorf("Expected the fixture specs to be valid, got %s", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(specs *downloadConfigSpecResponse)
+		error  string
+	}{
+		{"missing name", func(specs *downloadConfigSpecResponse) {
+			specs.FeatureGates[0].Name = ""
+		}, "feature_gates[0] \"\": missing name"},
+		{"duplicate name", func(specs *downloadConfigSpecResponse) {
+			specs.DynamicConfigs = append(specs.DynamicConfigs, specs.DynamicConfigs[0])
+		}, "duplicate name"},
+		{"partial rollout without salt", func(specs *downloadConfigSpecResponse) {
+			specs.FeatureGates[0].Rules[0].PassPercentage = 50
+			specs.FeatureGates[0].Rules[0].Salt = ""
+			specs.FeatureGates[0].Rules[0].ID = ""
+		}, "rules[0]: missing salt for a partial rollout"},
+		{"user_bucket without salt", func(specs *downloadConfigSpecResponse) {
+			specs.FeatureGates[0].Rules[0].Conditions[0] = configCondition{Type: "user_bucket", Operator: "lt", TargetValue: 500.0}
+		}, "missing salt for user_bucket"},
+		{"pass_gate without gate", func(specs *downloadConfigSpecResponse) {
+			specs.FeatureGates[0].Rules[0].Conditions[0] = configCondition{Type: "pass_gate", TargetValue: 1.0}
+		}, "missing gate name for pass_gate"},
+		{"config value not an object", func(specs *downloadConfigSpecResponse) {
+			specs.DynamicConfigs[0].Rules[0].ReturnValue = json.RawMessage("true")
+		}, "returnValue is not an object"},
+		{"wrong type", func(specs *downloadConfigSpecResponse) {
+			specs.LayerConfigs[0].Type = "feature_gate"
+		}, "layer_configs[0] \"a_layer\": type \"feature_gate\" is not \"dynamic_config\""},
+		{"pass percentage out of bounds", func(specs *downloadConfigSpecResponse) {
+			specs.FeatureGates[0].Rules[0].PassPercentage = 120
+		}, "rules[0]: passPercentage 120 is not between 0 and 100"},
+		{"condition without type", func(specs *downloadConfigSpecResponse) {
+			specs.FeatureGates[0].Rules[0].Conditions[0].Type = ""
+		}, "rules[0].conditions[0]: missing type"},
+		{"numeric operator without number", func(specs *downloadConfigSpecResponse) {
+			specs.FeatureGates[0].Rules[0].Conditions[0] = configCondition{Type: "user_field", Field: "age", Operator: "gt", TargetValue: []interface{}{}}
+		}, "targetValue of gt is not a number"},
+		{"array operator without array", func(specs *downloadConfigSpecResponse) {
+			specs.FeatureGates[0].Rules[0].Conditions[0] = configCondition{Type: "user_field", Field: "email", Operator: "any", TargetValue: "a@b.c"}
+		}, "targetValue of any is not an array"},
+		{"segment operator without list", func(specs *downloadConfigSpecResponse) {
+			specs.FeatureGates[0].Rules[0].Conditions[0] = configCondition{Type: "unit_id", Operator: "in_segment_list"}
+		}, "targetValue of in_segment_list is not an ID list name"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var specs downloadConfigSpecResponse
+			_ = json.Unmarshal(bytes, &specs)
+			test.modify(&specs)
+			err := validateConfigSpecs(specs)
+			if err == nil || !strings.Contains(err.Error(), test.error) {
+				t.Errorf("Expected an error containing %q, got %v", test.error, err)
+			}
+		})
+	}
+}
+
+func TestConfigSpecsValidationErrorListsProblems(t *testing.T) {
+	specs := downloadConfigSpecResponse{HasUpdates: true}
+	for i := 0; i < 12; i++ {
+		specs.FeatureGates = append(specs.FeatureGates, configSpec{
+			Name:  fmt.Sprintf("gate_%d", i),
+			Type:  "feature_gate",
+			Rules: []configRule{{ID: "rule", PassPercentage: -1}},
+		})
+	}
+	specs.FeatureGates[0].Rules[0].Conditions = []configCondition{{Type: "user_field", Operator: "lte", TargetValue: "x"}}
+	err := validateConfigSpecs(specs)
+	var validationErr *ConfigSpecsValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Problems) != 13 {
+		t.Fatalf("Expected every problem to be listed, got %v", err)
+	}
+	expected := `feature_gates[0] "gate_0": rules[0].conditions[0]: targetValue of lte is not a number`
+	if validationErr.Problems[1] != expected {
+		t.Errorf("Expected %q, got %q", expected, validationErr.Problems[1])
+	}
+	if !strings.HasPrefix(err.Error(), "13 problems: ") || !strings.HasSuffix(err.Error(), "; and 3 more") {
+		t.Errorf("Expected the message to list the first problems and count the rest, got %s", err)
+	}
+}
+
+func TestInvalidConfigSpecsRollback(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	var invalid downloadConfigSpecResponse
+	_ = json.Unmarshal(bytes, &invalid)
+	invalid.Time += 1
+	for i, gate := range invalid.FeatureGates {
+		if gate.Name == "always_on_gate" {
+			invalid.FeatureGates[i].Enabled = false
+			invalid.FeatureGates = append(invalid.FeatureGates, invalid.FeatureGates[i])
+			break
+		}
+	}
+	invalidBytes, _ := json.Marshal(invalid)
+
+	var mu sync.Mutex
+	serveInvalid := false
+	var sinceTimes []int64
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "download_config_specs") {
+			var input downloadConfigsInput
+			_ = json.NewDecoder(req.Body).Decode(&input)
+			mu.Lock()
+			defer mu.Unlock()
+			sinceTimes = append(sinceTimes, input.SinceTime)
+			res.WriteHeader(http.StatusOK)
+			if serveInvalid {
+				_, _ = res.Write(invalidBytes)
+			} else {
+				_, _ = res.Write(bytes)
+			}
+			return
+		}
+		res.WriteHeader(http.StatusOK)
+		_, _ = res.Write([]byte("{}"))
+	}))
+	defer testServer.Close()
+
+	errs := make(chan error, 100)
+	c := NewClientWithOptions("secret-key", &Options{
+		API:                testServer.URL,
+		ConfigSyncInterval: 10 * time.Millisecond,
+		ErrorCallback: func(err error, context string) {
+			if context == ErrorContextConfigSync {
+				errs <- err
+			}
+		},
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+	store := c.evaluator.store
+	syncTime := store.getRulesets().time
+
+	mu.Lock()
+	serveInvalid = true
+	mu.Unlock()
+	var invalidErr *InvalidConfigSpecsError
+	select {
+	case err := <-errs:
+		if !errors.As(err, &invalidErr) || invalidErr.Source != "network" || !strings.Contains(err.Error(), "duplicate name") {
+			t.Errorf("Expected an InvalidConfigSpecsError for the duplicate gate, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the invalid specs to be reported")
+	}
+	if store.getRulesets().time != syncTime || !c.CheckGate(User{UserID: "123"}, "always_on_gate") {
+		t.Errorf("Expected the previous rulesets to still be served")
+	}
+	waitForCondition(t, func() bool { return store.getConfigSyncDelay() >= 40*time.Millisecond })
+	mu.Lock()
+	lastSinceTime := sinceTimes[len(sinceTimes)-1]
+	mu.Unlock()
+	if lastSinceTime != 0 {
+		t.Errorf("Expected retries to request all the specs, got sinceTime %d", lastSinceTime)
+	}
+
+	mu.Lock()
+	serveInvalid = false
+	invalid.FeatureGates = invalid.FeatureGates[:len(invalid.FeatureGates)-1]
+	bytes, _ = json.Marshal(invalid)
+	mu.Unlock()
+	waitForCondition(t, func() bool { return store.getRulesets().time == syncTime+1 })
+	if c.CheckGate(User{UserID: "123"}, "always_on_gate") {
+		t.Errorf("Expected the valid specs to be applied once synced")
+	}
+	if delay := store.getConfigSyncDelay(); delay != 10*time.Millisecond {
+		t.Errorf("Expected the backoff to reset once specs are applied, got %s", delay)
+	}
+}
+
+func TestInvalidBootstrapValues(t *testing.T) {
+	var reported error
+	c := NewClientWithOptions("secret-key", &Options{
+		LocalMode:       true,
+		BootstrapValues: `{"has_updates": true, "time": 1, "feature_gates": [{"name": ""}]}`,
+		ErrorCallback: func(err error, context string) {
+			reported = err
+		},
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+	var invalidErr *InvalidConfigSpecsError
+	if !errors.As(reported, &invalidErr) || invalidErr.Source != "BootstrapValues" {
+		t.Errorf("Expected an InvalidConfigSpecsError from BootstrapValues, got %v", reported)
+	}
+	if c.GetStatus().Initialized {
+		t.Errorf("Expected the invalid bootstrap values not to be applied")
+	}
+}
@@ -0,0 +1,73 @@
+package statsig
+
+import (
+	"strings"
+)
+
+// Evaluates condition types and operators the SDK does not know, such as proprietary ones a company pushes
+// through custom fields. Built-in condition types and operators cannot be replaced. Conditions with a type or
+// operator that is neither built in nor registered here are still fetched from Statsig's servers.
+type CustomConditionOptions struct {
+	// Compares the value of the condition's field with the condition's target value, by operator name.
+	// Used with the value of any condition type, e.g. "has_entitlement" on a user_field condition.
+	Operators map[string]CustomOperator
+	// Resolves the value of the condition's field, by condition type, e.g. "entitlement" to look the
+	// field up in an internal service. The value is then compared with the condition's operator.
+	FieldResolvers map[string]CustomFieldResolver
+}
+
+// Reports whether the value passes the condition. Called on every evaluation, so it must be fast and safe for
+// concurrent use. A panic fails the condition.
+type CustomOperator func(value interface{}, targetValue interface{}) bool
+
+// Returns the value of the field for the user. Called on every evaluation, so it must be fast and safe for
+// concurrent use. A panic resolves the field to nil.
+type CustomFieldResolver func(user User, field string) interface{}
+
+type customConditions struct {
+	operators      map[string]CustomOperator
+	fieldResolvers map[string]CustomFieldResolver
+}
+
+// Condition types and operators are matched case insensitively, like the built-in ones
+func newCustomConditions(options CustomConditionOptions) customConditions {
+	conditions := customConditions{
+		operators:      make(map[string]CustomOperator, len(options.Operators)),
+		fieldResolvers: make(map[string]CustomFieldResolver, len(options.FieldResolvers)),
+	}
+	for name, operator := range options.Operators {
+		conditions.operators[strings.ToLower(name)] = operator
+	}
+	for condType, resolver := range options.FieldResolvers {
+		conditions.fieldResolvers[strings.ToLower(condType)] = resolver
+	}
+	return conditions
+}
+
+func (c customConditions) resolveField(condType string, user User, field string) (value interface{}, ok bool) {
+	resolver, exists := c.fieldResolvers[condType]
+	if !exists {
+		return nil, false
+	}
+	defer func() {
+		if err := recover(); err != nil {
+			logRecoveredPanic("custom field resolver for "+condType, err)
+			value, ok = nil, true
+		}
+	}()
+	return resolver(user, field), true
+}
+
+func (c customConditions) evalOperator(op string, value interface{}, targetValue interface{}) (pass bool, ok bool) {
+	operator, exists := c.operators[op]
+	if !exists {
+		return false, false
+	}
+	defer func() {
+		if err := recover(); err != nil {
+			logRecoveredPanic("custom operator "+op, err)
+			pass, ok = false, true
+		}
+	}()
+	return operator(value, targetValue), true
+}
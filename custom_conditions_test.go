@@ -0,0 +1,87 @@
+package statsig
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func TestCustomConditions(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	var specs map[string]interface{}
+	_ = json.Unmarshal(bytes, &specs)
+	newGate := func(name string, condition map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"name": name, "type": "feature_gate", "salt": name, "enabled": true, "idType": "userID", "entity": "feature_gate",
+			"defaultValue": false,
+			"rules": []map[string]interface{}{{
+				"name": "rule", "id": "rule_id", "salt": "rule", "passPercentage": 100, "idType": "userID",
+				"returnValue": true, "conditions": []map[string]interface{}{condition},
+			}},
+		}
+	}
+	gates := specs["feature_gates"].([]interface{})
+	gates = append(gates,
+		newGate("entitled_gate", map[string]interface{}{"type": "entitlement", "field": "reports", "operator": "eq", "targetValue": "pro"}),
+		newGate("divisible_gate", map[string]interface{}{"type": "user_field", "field": "seats", "operator": "divisible_by", "targetValue": 5}),
+		newGate("panicking_gate", map[string]interface{}{"type": "user_field", "field": "seats", "operator": "panics", "targetValue": 5}),
+	)
+	specs["feature_gates"] = gates
+	bootstrap, _ := json.Marshal(specs)
+
+	options := NewOptions(
+		WithLocalMode(),
+		WithCustomFieldResolver("Entitlement", func(user User, field string) interface{} {
+			if user.UserID == "123" && field == "reports" {
+				return "pro"
+			}
+			return "free"
+		}),
+		WithCustomOperator("divisible_by", func(value interface{}, targetValue interface{}) bool {
+			v, ok1 := getNumericValue(value)
+			d, ok2 := getNumericValue(targetValue)
+			return ok1 && ok2 && d != 0 && int64(v)%int64(d) == 0
+		}),
+		WithCustomOperator("panics", func(value interface{}, targetValue interface{}) bool {
+			panic("operator failed")
+		}),
+	)
+	options.BootstrapValues = string(bootstrap)
+	options.OutputLoggerOptions = getOutputLoggerOptionsForTest(t)
+	options.StatsigLoggerOptions = getStatsigLoggerOptionsForTest(t)
+	c := NewClientWithOptions("secret-key", options)
+	defer c.Shutdown()
+
+	if !c.CheckGate(User{UserID: "123"}, "entitled_gate") || c.CheckGate(User{UserID: "456"}, "entitled_gate") {
+		t.Errorf("Expected the custom field resolver to decide the condition")
+	}
+	if !c.CheckGate(User{UserID: "123", Custom: map[string]interface{}{"seats": 10}}, "divisible_gate") {
+		t.Errorf("Expected the custom operator to pass")
+	}
+	if c.CheckGate(User{UserID: "123", Custom: map[string]interface{}{"seats": 7}}, "divisible_gate") {
+		t.Errorf("Expected the custom operator to fail")
+	}
+	if c.CheckGate(User{UserID: "123", Custom: map[string]interface{}{"seats": 10}}, "panicking_gate") {
+		t.Errorf("Expected a panicking operator to fail the condition")
+	}
+}
+
+func TestCustomConditionPanicsAreHandledLocally(t *testing.T) {
+	conditions := newCustomConditions(CustomConditionOptions{
+		Operators: map[string]CustomOperator{
+			"panics": func(value interface{}, targetValue interface{}) bool { panic("operator failed") },
+		},
+		FieldResolvers: map[string]CustomFieldResolver{
+			"panics": func(user User, field string) interface{} { panic("resolver failed") },
+		},
+	})
+	swallow_stderr(func() {
+		// A panic must not fall back to fetching the evaluation from the server
+		if pass, ok := conditions.evalOperator("panics", 1, 1); pass || !ok {
+			t.Errorf("Expected a panicking operator to fail the condition, got pass=%t ok=%t", pass, ok)
+		}
+		if value, ok := conditions.resolveField("panics", User{}, "field"); value != nil || !ok {
+			t.Errorf("Expected a panicking resolver to resolve to nil, got %v ok=%t", value, ok)
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package statsig
+
+import (
+	"encoding/json"
+)
+
+// Combines DataAdapters, e.g. a local file in front of Redis, into one. Reads return the first valid
+// value in order, writes go to every adapter, and a panic in one adapter does not affect the others.
+type dataAdapterChain struct {
+	adapters []IDataAdapter
+}
+
+// Returns a DataAdapter that reads from the first of adapters holding a valid value, so the SDK
+// bootstraps from the first source that has specs, and writes fresh specs back to all of them.
+// Polls them for updates when any of them should be used for querying updates, reading only from
+// those. Values failing the checks of IntegrityOptions fall through to the next adapter.
+func NewDataAdapterChain(adapters ...IDataAdapter) IDataAdapter {
+	return &dataAdapterChain{adapters: adapters}
+}
+
+func (c *dataAdapterChain) Get(key string) string {
+	return c.get(key, false, nil)
+}
+
+// Returns the first valid value accepted by accept, if set. When polling, only the adapters that should
+// be used for querying updates of key are read, so a cache in front of them does not hide updates.
+func (c *dataAdapterChain) get(key string, polling bool, accept func(value string) bool) string {
+	for _, adapter := range c.adapters {
+		if polling && !adapter.ShouldBeUsedForQueryingUpdates(key) {
+			continue
+		}
+		value := getFromAdapter(adapter, key)
+		if value == "" || !json.Valid([]byte(value)) {
+			continue
+		}
+		if accept == nil || accept(value) {
+			return value
+		}
+	}
+	return ""
+}
+
+func (c *dataAdapterChain) Set(key string, value string) {
+	for _, adapter := range c.adapters {
+		callAdapter("set", func() { adapter.Set(key, value) })
+	}
+}
+
+func (c *dataAdapterChain) Initialize() {
+	for _, adapter := range c.adapters {
+		callAdapter("initialize", adapter.Initialize)
+	}
+}
+
+func (c *dataAdapterChain) Shutdown() {
+	for _, adapter := range c.adapters {
+		callAdapter("shutdown", adapter.Shutdown)
+	}
+}
+
+func (c *dataAdapterChain) ShouldBeUsedForQueryingUpdates(key string) bool {
+	for _, adapter := range c.adapters {
+		if adapter.ShouldBeUsedForQueryingUpdates(key) {
+			return true
+		}
+	}
+	return false
+}
+
+func getFromAdapter(adapter IDataAdapter, key string) (value string) {
+	callAdapter("get", func() { value = adapter.Get(key) })
+	return value
+}
+
+func callAdapter(method string, fn func()) {
+	defer func() {
+		if err := recover(); err != nil {
+			logRecoveredPanic("data adapter "+method, err)
+		}
+	}()
+	fn()
+}
@@ -2,6 +2,14 @@ package statsig
 
 const CONFIG_SPECS_KEY = "statsig.cache"
 
+// Key the ID lists are saved under. Return true from ShouldBeUsedForQueryingUpdates(ID_LISTS_KEY)
+// to read ID lists from the adapter instead of downloading them.
+const ID_LISTS_KEY = "statsig.id_lists"
+
+// Key the index of undelivered event batches is saved under when EventSpoolOptions.UseDataAdapter is set.
+// Each batch is saved under its own key, the index key followed by a dot and the batch ID.
+const EVENT_SPOOL_KEY = "statsig.event_spool"
+
 /**
  * An adapter for implementing custom storage of config specs.
  * Can be used to bootstrap Statsig (priority over bootstrapValues if both provided)
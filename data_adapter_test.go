@@ -8,6 +8,8 @@ import (
 	"net/http/httptest"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -146,6 +148,51 @@ func TestAdapterWithPolling(t *testing.T) {
 	})
 }
 
+func TestAdapterRevalidatesWithNetwork(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	release := make(chan struct{})
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "download_config_specs") {
+			<-release
+			res.WriteHeader(http.StatusOK)
+			_, _ = res.Write(bytes)
+			return
+		}
+		res.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+	dataAdapter := dataAdapterExample{store: make(map[string]string)}
+	dataAdapter.Set(CONFIG_SPECS_KEY, "{\"feature_gates\":[],\"dynamic_configs\":[],\"layer_configs\":[],\"layers\":{},\"id_lists\":{},\"has_updates\":true,\"time\":1}")
+	options := &Options{
+		DataAdapter:          dataAdapter,
+		API:                  testServer.URL,
+		Environment:          Environment{Tier: "test"},
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	}
+	InitializeWithOptions("secret-key", options)
+	defer ShutdownAndDangerouslyClearInstance()
+	user := User{UserID: "statsig_user", Email: "statsiguser@statsig.com"}
+
+	t.Run("serves adapter values then refreshes from network in the background", func(t *testing.T) {
+		if CheckGateWithExposureLoggingDisabled(user, "always_on_gate") {
+			t.Errorf("Expected gate to return false from cached adapter values")
+		}
+		close(release)
+		waitForCondition(t, func() bool {
+			return CheckGateWithExposureLoggingDisabled(user, "always_on_gate")
+		})
+		markers := instance.diagnostics.initDiagnostics.serializeWithSampling()["markers"].([]marker)
+		keys := make(map[DiagnosticsKey]bool)
+		for _, m := range markers {
+			keys[*m.Key] = true
+		}
+		if !keys[DataStoreConfigSpecsKey] || !keys[DownloadConfigSpecsKey] {
+			t.Errorf("Expected both adapter and network markers in init diagnostics")
+		}
+	})
+}
+
 func TestIncorrectlyImplementedAdapter(t *testing.T) {
 	events := []Event{}
 	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
@@ -170,18 +217,22 @@ func TestIncorrectlyImplementedAdapter(t *testing.T) {
 		}
 	}))
 	dataAdapter := brokenDataAdapterExample{}
+	var adapterErrors int32
 	options := &Options{
-		DataAdapter:          dataAdapter,
-		API:                  testServer.URL,
-		Environment:          Environment{Tier: "test"},
-		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		DataAdapter: dataAdapter,
+		API:         testServer.URL,
+		Environment: Environment{Tier: "test"},
+		OutputLoggerOptions: OutputLoggerOptions{LogCallback: func(message string, err error) {
+			if err != nil && strings.Contains(err.Error(), "Error calling data adapter") {
+				atomic.AddInt32(&adapterErrors, 1)
+			}
+		}},
 		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
 	}
-	stderrLogs := swallow_stderr(func() {
-		InitializeWithOptions("secret-key", options)
-	})
-	if stderrLogs == "" {
-		t.Errorf("Expected output to stderr")
+	InitializeWithOptions("secret-key", options)
+	defer InitializeGlobalOutputLogger(getOutputLoggerOptionsForTest(t))
+	if atomic.LoadInt32(&adapterErrors) == 0 {
+		t.Errorf("Expected the adapter panics to be logged")
 	}
 	user := User{UserID: "statsig_user", Email: "statsiguser@statsig.com"}
 
@@ -230,3 +281,186 @@ func contains_spec(specs []configSpec, name string, specType string) bool {
 	}
 	return false
 }
+
+func TestRulesUpdatedCallbackFromAdapter(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	dataAdapter := dataAdapterWithPollingExample{store: make(map[string]string)}
+	dataAdapter.Set(CONFIG_SPECS_KEY, string(bytes))
+	var mu sync.Mutex
+	times := make([]int64, 0)
+	rules := ""
+	options := &Options{
+		DataAdapter:        &dataAdapter,
+		LocalMode:          true,
+		ConfigSyncInterval: 20 * time.Millisecond,
+		RulesUpdatedCallback: func(rulesString string, time int64) {
+			mu.Lock()
+			defer mu.Unlock()
+			times = append(times, time)
+			rules = rulesString
+		},
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	}
+	InitializeWithOptions("secret-key", options)
+	defer ShutdownAndDangerouslyClearInstance()
+
+	time.Sleep(100 * time.Millisecond)
+	mu.Lock()
+	if len(times) != 1 || times[0] != configSyncTime || rules != string(bytes) {
+		t.Errorf("Expected a single callback with the adapter rules, got times %v", times)
+	}
+	mu.Unlock()
+
+	dataAdapter.clearStore(CONFIG_SPECS_KEY)
+	waitForCondition(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(times) == 2 && times[1] == 1
+	})
+}
+
+func TestIDListsFromAdapter(t *testing.T) {
+	dcs, _ := os.ReadFile("download_config_specs.json")
+	hashedID := getHashBase64StringEncoding("123")[:8]
+	writerServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+		if strings.Contains(req.URL.Path, "download_config_specs") {
+			_, _ = res.Write(dcs)
+		} else if strings.Contains(req.URL.Path, "get_id_lists") {
+			baseURL := "http://" + req.Host
+			v, _ := json.Marshal(map[string]idList{
+				"list_1": {Name: "list_1", Size: 10, URL: baseURL + "/list_1", CreationTime: 1, FileID: "file_id_1"},
+			})
+			_, _ = res.Write(v)
+		} else if strings.Contains(req.URL.Path, "list_1") {
+			_, _ = res.Write([]byte("+" + hashedID + "\n"))
+		}
+	}))
+	defer writerServer.Close()
+	readerServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if !strings.Contains(req.URL.Path, "log_event") {
+			t.Errorf("Expected the reader not to sync from the network, got a request to %s", req.URL.Path)
+		}
+		res.WriteHeader(http.StatusBadRequest)
+	}))
+	defer readerServer.Close()
+
+	writerAdapter := dataAdapterExample{store: make(map[string]string)}
+	InitializeWithOptions("secret-key", &Options{
+		DataAdapter:          writerAdapter,
+		API:                  writerServer.URL,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	ShutdownAndDangerouslyClearInstance()
+	if writerAdapter.Get(ID_LISTS_KEY) == "" {
+		t.Fatalf("Expected the writer to save ID lists to the adapter")
+	}
+
+	readerAdapter := dataAdapterWithPollingExample{store: make(map[string]string)}
+	readerAdapter.Set(CONFIG_SPECS_KEY, writerAdapter.Get(CONFIG_SPECS_KEY))
+	readerAdapter.Set(ID_LISTS_KEY, writerAdapter.Get(ID_LISTS_KEY))
+	InitializeWithOptions("secret-key", &Options{
+		DataAdapter:          &readerAdapter,
+		API:                  readerServer.URL,
+		IDListSyncInterval:   20 * time.Millisecond,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer ShutdownAndDangerouslyClearInstance()
+
+	if !CheckGate(User{UserID: "123"}, "on_for_id_list") {
+		t.Errorf("Expected the reader to load ID lists from the adapter")
+	}
+	if CheckGate(User{UserID: "456"}, "on_for_id_list") {
+		t.Errorf("Expected users missing from the list to fail")
+	}
+
+	readerAdapter.Set(ID_LISTS_KEY, "{}")
+	waitForCondition(t, func() bool {
+		return !CheckGate(User{UserID: "123"}, "on_for_id_list")
+	})
+}
+
+func TestDataAdapterChain(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "download_config_specs") {
+			_, _ = res.Write(bytes)
+			return
+		}
+		res.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	if !NewDataAdapterChain(dataAdapterExample{}, &dataAdapterWithPollingExample{}).ShouldBeUsedForQueryingUpdates(CONFIG_SPECS_KEY) {
+		t.Errorf("Expected the chain to poll when one of its adapters does")
+	}
+
+	empty := &cachingDataAdapterExample{store: make(map[string]string)}
+	corrupt := &cachingDataAdapterExample{store: map[string]string{CONFIG_SPECS_KEY: `{"feature_gates": [`}}
+	valid := &cachingDataAdapterExample{store: map[string]string{CONFIG_SPECS_KEY: string(bytes)}}
+	chain := NewDataAdapterChain(brokenDataAdapterExample{}, empty, corrupt, valid)
+	if chain.Get(CONFIG_SPECS_KEY) != string(bytes) {
+		t.Errorf("Expected the specs of the first adapter holding valid specs")
+	}
+
+	c := NewClient("secret-key",
+		WithAPI(testServer.URL),
+		WithDataAdapters(brokenDataAdapterExample{}, empty, corrupt, valid),
+		WithOutputLoggerOptions(getOutputLoggerOptionsForTest(t)),
+		WithStatsigLoggerOptions(getStatsigLoggerOptionsForTest(t)),
+	)
+	defer c.Shutdown()
+	user := User{UserID: "123"}
+	if !c.CheckGate(user, "always_on_gate") {
+		t.Errorf("Expected the specs to be loaded")
+	}
+	waitForCondition(t, func() bool {
+		return empty.Get(CONFIG_SPECS_KEY) != "" && corrupt.Get(CONFIG_SPECS_KEY) != `{"feature_gates": [`
+	})
+}
+
+func TestDataAdapterChainReads(t *testing.T) {
+	stale, fresh := `{"time": 1}`, `{"time": 2}`
+	cache := &cachingDataAdapterExample{store: map[string]string{CONFIG_SPECS_KEY: stale}}
+	source := &dataAdapterWithPollingExample{store: map[string]string{CONFIG_SPECS_KEY: fresh}}
+	chain := NewDataAdapterChain(cache, source).(*dataAdapterChain)
+	if value := chain.get(CONFIG_SPECS_KEY, false, nil); value != stale {
+		t.Errorf("Expected the cached specs on initialize, got %s", value)
+	}
+	if value := chain.get(CONFIG_SPECS_KEY, true, nil); value != fresh {
+		t.Errorf("Expected polling to skip adapters not used for querying updates, got %s", value)
+	}
+	rejectStale := func(value string) bool { return value != stale }
+	if value := chain.get(CONFIG_SPECS_KEY, false, rejectStale); value != fresh {
+		t.Errorf("Expected rejected specs to fall through to the next adapter, got %s", value)
+	}
+}
+
+// Caches specs without being polled for updates, safe for concurrent use
+type cachingDataAdapterExample struct {
+	store map[string]string
+	mu    sync.RWMutex
+}
+
+func (d *cachingDataAdapterExample) Get(key string) string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.store[key]
+}
+
+func (d *cachingDataAdapterExample) Set(key string, value string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.store[key] = value
+}
+
+func (d *cachingDataAdapterExample) Initialize() {}
+
+func (d *cachingDataAdapterExample) Shutdown() {}
+
+func (d *cachingDataAdapterExample) ShouldBeUsedForQueryingUpdates(key string) bool {
+	return false
+}
@@ -49,12 +49,17 @@ type diagnosticsBase struct {
 	markers       []marker
 	mu            sync.RWMutex
 	samplingRates map[string]int
+	// Set before any marker is added. The monotonic system clock when nil.
+	clock timeSource
+	// Set before any marker is added. Markers are then only used to log the initialization steps.
+	disabled bool
 }
 
 type diagnostics struct {
 	initDiagnostics *diagnosticsBase
 	syncDiagnostics *diagnosticsBase
 	apiDiagnostics  *diagnosticsBase
+	disabled        bool
 }
 
 type marker struct {
@@ -91,6 +96,20 @@ func newDiagnostics() *diagnostics {
 	}
 }
 
+func (d *diagnostics) setTimeSource(clock timeSource) {
+	d.initDiagnostics.clock = clock
+	d.syncDiagnostics.clock = clock
+	d.apiDiagnostics.clock = clock
+}
+
+// Stops collecting markers, for Options.DisableDiagnostics. API call markers are not even allocated.
+func (d *diagnostics) disable() {
+	d.disabled = true
+	d.initDiagnostics.disabled = true
+	d.syncDiagnostics.disabled = true
+	d.apiDiagnostics.disabled = true
+}
+
 func (d *diagnosticsBase) logProcess(msg string) {
 	var process StatsigProcess
 	switch d.context {
@@ -145,6 +164,12 @@ func (d *diagnosticsBase) clearMarkers() {
 	d.markers = nil
 }
 
+func (d *diagnostics) clearMarkers() {
+	d.initDiagnostics.clearMarkers()
+	d.syncDiagnostics.clearMarkers()
+	d.apiDiagnostics.clearMarkers()
+}
+
 /* Context */
 func (d *diagnostics) initialize() *marker {
 	return &marker{diagnostics: d.initDiagnostics}
@@ -154,60 +179,91 @@ func (d *diagnostics) configSync() *marker {
 	return &marker{diagnostics: d.syncDiagnostics}
 }
 
+// Nil when diagnostics are disabled, which every marker method accepts
 func (d *diagnostics) api() *marker {
+	if d.disabled {
+		return nil
+	}
 	return &marker{diagnostics: d.apiDiagnostics}
 }
 
 /* Keys */
 func (m *marker) downloadConfigSpecs() *marker {
+	if m == nil {
+		return nil
+	}
 	m.Key = new(DiagnosticsKey)
 	*m.Key = DownloadConfigSpecsKey
 	return m
 }
 
 func (m *marker) bootstrap() *marker {
+	if m == nil {
+		return nil
+	}
 	m.Key = new(DiagnosticsKey)
 	*m.Key = BootstrapKey
 	return m
 }
 
 func (m *marker) getIdListSources() *marker {
+	if m == nil {
+		return nil
+	}
 	m.Key = new(DiagnosticsKey)
 	*m.Key = GetIDListSourcesKey
 	return m
 }
 
 func (m *marker) getIdList() *marker {
+	if m == nil {
+		return nil
+	}
 	m.Key = new(DiagnosticsKey)
 	*m.Key = GetIDListKey
 	return m
 }
 
 func (m *marker) overall() *marker {
+	if m == nil {
+		return nil
+	}
 	m.Key = new(DiagnosticsKey)
 	*m.Key = OverallKey
 	return m
 }
 
 func (m *marker) dataStoreConfigSpecs() *marker {
+	if m == nil {
+		return nil
+	}
 	m.Key = new(DiagnosticsKey)
 	*m.Key = DataStoreConfigSpecsKey
 	return m
 }
 
 func (m *marker) checkGate() *marker {
+	if m == nil {
+		return nil
+	}
 	m.Key = new(DiagnosticsKey)
 	*m.Key = CheckGateApiKey
 	return m
 }
 
 func (m *marker) getConfig() *marker {
+	if m == nil {
+		return nil
+	}
 	m.Key = new(DiagnosticsKey)
 	*m.Key = GetConfigApiKey
 	return m
 }
 
 func (m *marker) getLayer() *marker {
+	if m == nil {
+		return nil
+	}
 	m.Key = new(DiagnosticsKey)
 	*m.Key = GetLayerApiKey
 	return m
@@ -215,18 +271,27 @@ func (m *marker) getLayer() *marker {
 
 /* Steps */
 func (m *marker) networkRequest() *marker {
+	if m == nil {
+		return nil
+	}
 	m.Step = new(DiagnosticsStep)
 	*m.Step = NetworkRequestStep
 	return m
 }
 
 func (m *marker) fetch() *marker {
+	if m == nil {
+		return nil
+	}
 	m.Step = new(DiagnosticsStep)
 	*m.Step = FetchStep
 	return m
 }
 
 func (m *marker) process() *marker {
+	if m == nil {
+		return nil
+	}
 	m.Step = new(DiagnosticsStep)
 	*m.Step = ProcessStep
 	return m
@@ -234,12 +299,18 @@ func (m *marker) process() *marker {
 
 /* Actions */
 func (m *marker) start() *marker {
+	if m == nil {
+		return nil
+	}
 	m.Action = new(DiagnosticsAction)
 	*m.Action = StartAction
 	return m
 }
 
 func (m *marker) end() *marker {
+	if m == nil {
+		return nil
+	}
 	m.Action = new(DiagnosticsAction)
 	*m.Action = EndAction
 	return m
@@ -247,30 +318,45 @@ func (m *marker) end() *marker {
 
 /* Tags */
 func (m *marker) success(val bool) *marker {
+	if m == nil {
+		return nil
+	}
 	m.Success = new(bool)
 	*m.Success = val
 	return m
 }
 
 func (m *marker) statusCode(val int) *marker {
+	if m == nil {
+		return nil
+	}
 	m.StatusCode = new(int)
 	*m.StatusCode = val
 	return m
 }
 
 func (m *marker) sdkRegion(val string) *marker {
+	if m == nil {
+		return nil
+	}
 	m.SDKRegion = new(string)
 	*m.SDKRegion = val
 	return m
 }
 
 func (m *marker) idListCount(val int) *marker {
+	if m == nil {
+		return nil
+	}
 	m.IDListCount = new(int)
 	*m.IDListCount = val
 	return m
 }
 
 func (m *marker) url(val string) *marker {
+	if m == nil {
+		return nil
+	}
 	m.URL = new(string)
 	*m.URL = val
 	return m
@@ -278,7 +364,18 @@ func (m *marker) url(val string) *marker {
 
 /* End of chain */
 func (m *marker) mark() {
-	m.Timestamp = time.Now().UnixNano() / 1000000.0
+	if m == nil {
+		return
+	}
+	if m.diagnostics.disabled {
+		m.logProcess()
+		return
+	}
+	if m.diagnostics.clock != nil {
+		m.Timestamp = m.diagnostics.clock.nowUnixMilli()
+	} else {
+		m.Timestamp = clock.nowUnixMilli()
+	}
 	m.diagnostics.mu.Lock()
 	defer m.diagnostics.mu.Unlock()
 	m.diagnostics.markers = append(m.diagnostics.markers, *m)
@@ -438,3 +438,153 @@ func waitForCondition(t *testing.T, condition func() bool) {
 
 	t.Errorf("Timeout Expired")
 }
+
+func TestDiagnosticsSamplingRate(t *testing.T) {
+	testServer := getTestServer(true, nil, false)
+	defer testServer.Close()
+
+	countQueuedDiagnostics := func(rate float64, loggerOptions StatsigLoggerOptions) (int, []diagnosticsEvent) {
+		options := &Options{
+			API:                     testServer.URL,
+			DiagnosticsSamplingRate: rate,
+			StatsigLoggerOptions:    loggerOptions,
+			OutputLoggerOptions:     getOutputLoggerOptionsForTest(t),
+			ConfigSyncInterval:      time.Millisecond * 99999,
+			IDListSyncInterval:      time.Millisecond * 99999,
+			LoggingInterval:         time.Millisecond * 99999,
+			LoggingMaxBufferSize:    1000,
+		}
+		InitializeWithOptions("secret-key", options)
+		defer ShutdownAndDangerouslyClearInstance()
+		for i := 0; i < 40; i++ {
+			instance.evaluator.store.fetchConfigSpecsFromServer(false)
+			instance.logger.logDiagnosticsEvents(instance.diagnostics)
+		}
+		instance.logger.mu.Lock()
+		defer instance.logger.mu.Unlock()
+		sampled := make([]diagnosticsEvent, 0)
+		for _, event := range instance.logger.events {
+			if diagnosticsEvent, ok := event.(diagnosticsEvent); ok && diagnosticsEvent.Metadata["context"] == ConfigSyncContext {
+				sampled = append(sampled, diagnosticsEvent)
+			}
+		}
+		return len(sampled), sampled
+	}
+
+	if count, _ := countQueuedDiagnostics(0, StatsigLoggerOptions{}); count != 40 {
+		t.Errorf("Expected every config sync diagnostics event by default, got %d", count)
+	}
+	if count, _ := countQueuedDiagnostics(-1, StatsigLoggerOptions{}); count != 40 {
+		t.Errorf("Expected every config sync diagnostics event with a rate out of range, got %d", count)
+	}
+	if count, _ := countQueuedDiagnostics(0, StatsigLoggerOptions{DisableSyncDiagnostics: true}); count != 0 {
+		t.Errorf("Expected no config sync diagnostics events once disabled, got %d", count)
+	}
+	count, sampled := countQueuedDiagnostics(0.5, StatsigLoggerOptions{})
+	if count == 0 || count == 40 {
+		t.Errorf("Expected some diagnostics events to be sampled out, got %d of 40", count)
+	}
+	for _, event := range sampled {
+		if event.Metadata["samplingRate"] != 0.5 {
+			t.Errorf("Expected the sampling rate in the diagnostics metadata, got %v", event.Metadata)
+		}
+	}
+}
+
+func TestDiagnosticsCallback(t *testing.T) {
+	var events Events
+	var mu sync.Mutex
+	testServer := getTestServer(true, func(newEvents Events) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, newEvents...)
+	}, false)
+	defer testServer.Close()
+
+	payloads := make(map[DiagnosticsContext][]map[string]interface{})
+	options := &Options{
+		API: testServer.URL,
+		DiagnosticsCallback: func(context DiagnosticsContext, payload []byte) {
+			var parsed map[string]interface{}
+			if err := json.Unmarshal(payload, &parsed); err != nil {
+				t.Errorf("Expected a JSON payload, got %s", string(payload))
+			}
+			payloads[context] = append(payloads[context], parsed)
+		},
+		OutputLoggerOptions: getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: StatsigLoggerOptions{
+			DisableSyncDiagnostics: true,
+		},
+		ConfigSyncInterval: time.Millisecond * 99999,
+		IDListSyncInterval: time.Millisecond * 99999,
+		LoggingInterval:    time.Millisecond * 99999,
+	}
+	InitializeWithOptions("secret-key", options)
+	instance.evaluator.store.fetchConfigSpecsFromServer(false)
+	ShutdownAndDangerouslyClearInstance()
+
+	if len(payloads[InitializeContext]) != 1 || len(payloads[ConfigSyncContext]) != 1 {
+		t.Fatalf("Expected initialize and config sync payloads, got %v", payloads)
+	}
+	initialize := payloads[InitializeContext][0]
+	if initialize["context"] != string(InitializeContext) || len(initialize["markers"].([]interface{})) == 0 {
+		t.Errorf("Expected initialize markers in the payload, got %v", initialize)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	contexts := make([]interface{}, 0)
+	for _, event := range events {
+		if event["eventName"] == diagnosticsEventName {
+			contexts = append(contexts, event["metadata"].(map[string]interface{})["context"])
+		}
+	}
+	if len(contexts) != 1 || contexts[0] != string(InitializeContext) {
+		t.Errorf("Expected only initialize diagnostics to be sent to Statsig, got %v", contexts)
+	}
+}
+
+func TestDiagnosticsDisabled(t *testing.T) {
+	var events Events
+	var mu sync.Mutex
+	testServer := getTestServer(true, func(newEvents Events) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, newEvents...)
+	}, false)
+	defer testServer.Close()
+
+	callbacks := 0
+	options := &Options{
+		API:                testServer.URL,
+		DisableDiagnostics: true,
+		DiagnosticsCallback: func(context DiagnosticsContext, payload []byte) {
+			callbacks++
+		},
+		OutputLoggerOptions: getOutputLoggerOptionsForTest(t),
+		ConfigSyncInterval:  time.Millisecond * 99999,
+		IDListSyncInterval:  time.Millisecond * 99999,
+		LoggingInterval:     time.Millisecond * 99999,
+	}
+	InitializeWithOptions("secret-key", options)
+	CheckGate(User{UserID: "123"}, "always_on_gate")
+	instance.evaluator.store.fetchConfigSpecsFromServer(false)
+	allocs := testing.AllocsPerRun(10, func() {
+		instance.diagnostics.api().checkGate().start().mark()
+	})
+	if allocs != 0 {
+		t.Errorf("Expected no allocations for API call markers, got %v", allocs)
+	}
+	ShutdownAndDangerouslyClearInstance()
+
+	if callbacks != 0 {
+		t.Errorf("Expected the diagnostics callback not to be called, got %d calls", callbacks)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, event := range events {
+		if event["eventName"] == diagnosticsEventName {
+			t.Errorf("Expected no diagnostics events, got %v", event)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package statsig
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// The environment variables Options are read from, so deployments can be configured without code changes.
+// A variable only applies when the Options leave the setting unset, and booleans can only turn a setting on.
+// Durations use Go's format, such as 30s or 1m30s.
+const (
+	EnvAPI                    = "STATSIG_API"
+	EnvAPIDownloadConfigSpecs = "STATSIG_API_DOWNLOAD_CONFIG_SPECS"
+	EnvAPILogEvent            = "STATSIG_API_LOG_EVENT"
+	EnvAPIIDLists             = "STATSIG_API_ID_LISTS"
+	EnvLocalMode              = "STATSIG_LOCAL_MODE"
+	EnvEnvironmentTier        = "STATSIG_ENVIRONMENT_TIER"
+	EnvConfigSyncInterval     = "STATSIG_CONFIG_SYNC_INTERVAL"
+	EnvIDListSyncInterval     = "STATSIG_ID_LIST_SYNC_INTERVAL"
+	EnvLoggingInterval        = "STATSIG_LOGGING_INTERVAL"
+	EnvLoggingMaxBufferSize   = "STATSIG_LOGGING_MAX_BUFFER_SIZE"
+)
+
+// Fills the settings the Options leave unset from the environment. Invalid values are ignored with a warning.
+func applyEnvironmentVariables(options *Options) {
+	setString := func(name string, field *string) {
+		if *field == "" {
+			*field = os.Getenv(name)
+		}
+	}
+	setString(EnvAPI, &options.API)
+	setString(EnvAPIDownloadConfigSpecs, &options.APIOverrides.DownloadConfigSpecs)
+	setString(EnvAPILogEvent, &options.APIOverrides.LogEvent)
+	setString(EnvAPIIDLists, &options.APIOverrides.IDLists)
+	setString(EnvEnvironmentTier, &options.Environment.Tier)
+
+	if value, ok := lookupEnvironmentVariable(EnvLocalMode); ok && !options.LocalMode {
+		localMode, err := strconv.ParseBool(value)
+		if err != nil {
+			warnInvalidEnvironmentVariable(EnvLocalMode, value, err)
+		} else {
+			options.LocalMode = localMode
+		}
+	}
+
+	setDuration := func(name string, field *time.Duration) {
+		value, ok := lookupEnvironmentVariable(name)
+		if !ok || *field != 0 {
+			return
+		}
+		duration, err := time.ParseDuration(value)
+		if err == nil && duration <= 0 {
+			err = fmt.Errorf("not a positive duration")
+		}
+		if err != nil {
+			warnInvalidEnvironmentVariable(name, value, err)
+			return
+		}
+		*field = duration
+	}
+	setDuration(EnvConfigSyncInterval, &options.ConfigSyncInterval)
+	setDuration(EnvIDListSyncInterval, &options.IDListSyncInterval)
+	setDuration(EnvLoggingInterval, &options.LoggingInterval)
+
+	if value, ok := lookupEnvironmentVariable(EnvLoggingMaxBufferSize); ok && options.LoggingMaxBufferSize == 0 {
+		size, err := strconv.Atoi(value)
+		if err == nil && size <= 0 {
+			err = fmt.Errorf("not a positive size")
+		}
+		if err != nil {
+			warnInvalidEnvironmentVariable(EnvLoggingMaxBufferSize, value, err)
+		} else {
+			options.LoggingMaxBufferSize = size
+		}
+	}
+}
+
+func lookupEnvironmentVariable(name string) (string, bool) {
+	value := os.Getenv(name)
+	return value, value != ""
+}
+
+func warnInvalidEnvironmentVariable(name string, value string, err error) {
+	global.Logger().LogWarning(fmt.Sprintf("[Statsig] Ignoring %s=%q: %s\n", name, value, err), "variable", name)
+}
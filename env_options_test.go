@@ -0,0 +1,152 @@
+package statsig
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setEnvironmentVariablesForTest(t *testing.T, variables map[string]string) {
+	for name, value := range variables {
+		previous, existed := os.LookupEnv(name)
+		_ = os.Setenv(name, value)
+		name := name
+		t.Cleanup(func() {
+			if existed {
+				_ = os.Setenv(name, previous)
+			} else {
+				_ = os.Unsetenv(name)
+			}
+		})
+	}
+}
+
+func TestEnvironmentVariables(t *testing.T) {
+	setEnvironmentVariablesForTest(t, map[string]string{
+		EnvAPI:                    "https://env.example.com/v1",
+		EnvAPIDownloadConfigSpecs: "https://specs.example.com/v1",
+		EnvLocalMode:              "true",
+		EnvEnvironmentTier:        "staging",
+		EnvConfigSyncInterval:     "30s",
+		EnvIDListSyncInterval:     "2m",
+		EnvLoggingInterval:        "5s",
+		EnvLoggingMaxBufferSize:   "250",
+	})
+
+	options := &Options{}
+	applyEnvironmentVariables(options)
+	if options.API != "https://env.example.com/v1" || options.APIOverrides.DownloadConfigSpecs != "https://specs.example.com/v1" {
+		t.Errorf("Expected the API URLs to be read from the environment, got %s and %s", options.API, options.APIOverrides.DownloadConfigSpecs)
+	}
+	if options.APIOverrides.LogEvent != "" || options.APIOverrides.IDLists != "" {
+		t.Errorf("Expected unset variables to leave the options unset")
+	}
+	if !options.LocalMode || options.Environment.Tier != "staging" {
+		t.Errorf("Expected LocalMode and the environment tier to be read from the environment")
+	}
+	if options.ConfigSyncInterval != 30*time.Second || options.IDListSyncInterval != 2*time.Minute {
+		t.Errorf("Expected the sync intervals to be read from the environment, got %s and %s", options.ConfigSyncInterval, options.IDListSyncInterval)
+	}
+	if options.LoggingInterval != 5*time.Second || options.LoggingMaxBufferSize != 250 {
+		t.Errorf("Expected the logging options to be read from the environment, got %s and %d", options.LoggingInterval, options.LoggingMaxBufferSize)
+	}
+
+	options = &Options{
+		API:                  "https://options.example.com/v1",
+		Environment:          Environment{Tier: "production"},
+		ConfigSyncInterval:   time.Minute,
+		LoggingMaxBufferSize: 10,
+	}
+	applyEnvironmentVariables(options)
+	if options.API != "https://options.example.com/v1" || options.Environment.Tier != "production" {
+		t.Errorf("Expected the Options to take precedence over the environment")
+	}
+	if options.ConfigSyncInterval != time.Minute || options.LoggingMaxBufferSize != 10 || options.IDListSyncInterval != 2*time.Minute {
+		t.Errorf("Expected only the unset settings to be read from the environment")
+	}
+
+	options = NewOptions(WithPolling(time.Minute))
+	if options.API != "https://env.example.com/v1" || options.LoggingInterval != 5*time.Second {
+		t.Errorf("Expected the environment to take precedence over the defaults")
+	}
+	if options.ConfigSyncInterval != time.Minute || options.IDListSyncInterval != time.Minute {
+		t.Errorf("Expected functional options to take precedence over the environment")
+	}
+}
+
+func TestInvalidEnvironmentVariables(t *testing.T) {
+	setEnvironmentVariablesForTest(t, map[string]string{
+		EnvLocalMode:            "sometimes",
+		EnvConfigSyncInterval:   "30",
+		EnvLoggingInterval:      "-5s",
+		EnvLoggingMaxBufferSize: "many",
+	})
+	var warnings []string
+	InitializeGlobalOutputLogger(OutputLoggerOptions{
+		LogCallback: func(message string, err error) {
+			warnings = append(warnings, message)
+		},
+	})
+	defer InitializeGlobalOutputLogger(getOutputLoggerOptionsForTest(t))
+
+	options := NewOptions()
+	if options.LocalMode || options.ConfigSyncInterval != DefaultConfigSyncInterval ||
+		options.LoggingInterval != DefaultLoggingInterval || options.LoggingMaxBufferSize != DefaultLoggingMaxBufferSize {
+		t.Errorf("Expected invalid values to be ignored")
+	}
+	logged := strings.Join(warnings, "")
+	for _, name := range []string{EnvLocalMode, EnvConfigSyncInterval, EnvLoggingInterval, EnvLoggingMaxBufferSize} {
+		if !strings.Contains(logged, name) {
+			t.Errorf("Expected a warning for %s, got %s", name, logged)
+		}
+	}
+}
+
+func TestClientReadsEnvironmentVariables(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+		if strings.Contains(req.URL.Path, "download_config_specs") {
+			_, _ = res.Write(bytes)
+		}
+	}))
+	defer testServer.Close()
+	setEnvironmentVariablesForTest(t, map[string]string{
+		EnvAPI:             testServer.URL,
+		EnvEnvironmentTier: "staging",
+	})
+
+	c := NewClientWithOptions("secret-key", &Options{
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+	if !c.CheckGate(User{UserID: "123"}, "always_on_gate") {
+		t.Errorf("Expected the rulesets to be downloaded from the API set in the environment")
+	}
+	if c.options.Environment.Tier != "staging" {
+		t.Errorf("Expected the environment tier to be read from the environment, got %q", c.options.Environment.Tier)
+	}
+}
+
+func TestNewClientReadsEnvironmentVariables(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+		if strings.Contains(req.URL.Path, "download_config_specs") {
+			_, _ = res.Write(bytes)
+		}
+	}))
+	defer testServer.Close()
+	setEnvironmentVariablesForTest(t, map[string]string{EnvAPI: testServer.URL})
+
+	InitializeGlobalOutputLogger(getOutputLoggerOptionsForTest(t))
+	c := NewClient("secret-key", WithStatsigLoggerOptions(getStatsigLoggerOptionsForTest(t)))
+	defer c.Shutdown()
+	if c.options.API != testServer.URL || !c.CheckGate(User{UserID: "123"}, "always_on_gate") {
+		t.Errorf("Expected the rulesets to be downloaded from the API set in the environment, got %s", c.options.API)
+	}
+}
@@ -3,11 +3,11 @@ package statsig
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"runtime"
 	"strconv"
 	"sync"
-	"time"
 )
 
 type errorBoundary struct {
@@ -18,6 +18,10 @@ type errorBoundary struct {
 	seen        map[string]bool
 	seenLock    sync.RWMutex
 	diagnostics *diagnostics
+	// Exceptions are still recovered and logged locally, but not sent to Statsig
+	disableReporting bool
+	errorCallback    func(err error, context string)
+	piiScrubber      piiScrubber
 }
 
 type logExceptionRequestBody struct {
@@ -33,19 +37,96 @@ var ErrorBoundaryAPI = "https://statsigapi.net/v1"
 var ErrorBoundaryEndpoint = "/sdk_exception"
 
 const (
-	InvalidSDKKeyError  string = "Must provide a valid SDK key."
-	EmptyUserError      string = "A non-empty StatsigUser.UserID or StatsigUser.CustomIDs is required. See https://docs.statsig.com/messages/serverRequiredUserID"
+	InvalidSDKKeyError string = "Must provide a valid SDK key."
+	EmptyUserError     string = "A non-empty StatsigUser.UserID or StatsigUser.CustomIDs is required. See https://docs.statsig.com/messages/serverRequiredUserID"
+	// Deprecated: LogImmediate now splits larger slices into batches of 500 instead of rejecting them
 	EventBatchSizeError string = "The max number of events supported in one batch is 500. Please reduce the slice size and try again."
 )
 
+// Passed to Options.ErrorCallback to say where the SDK swallowed an error
+const (
+	ErrorContextConfigSync  = "config_sync"
+	ErrorContextIDListSync  = "id_list_sync"
+	ErrorContextFlush       = "flush"
+	ErrorContextDataAdapter = "data_adapter"
+	// A panic recovered inside a public SDK method
+	ErrorContextAPICall = "api_call"
+)
+
+// Reported when the rulesets loaded from BootstrapValues or a DataAdapter were downloaded with a different SDK key.
+// If that key belongs to another project, every gate and config evaluates to its default value.
+type SDKKeyMismatchError struct {
+	Source           string
+	HashedSDKKey     string
+	HashedSDKKeyUsed string
+}
+
+func (e *SDKKeyMismatchError) Error() string {
+	return fmt.Sprintf("[Statsig] The rulesets loaded from %s were downloaded with a different SDK key (hash %s) "+
+		"than the one this SDK was initialized with (hash %s). If that key belongs to another project, "+
+		"every gate and config will evaluate to its default value.", e.Source, e.HashedSDKKeyUsed, e.HashedSDKKey)
+}
+
+// Reported when BootstrapValues could not be loaded, e.g. because they are not a download_config_specs response.
+// The SDK then initializes from the network.
+type InvalidBootstrapValuesError struct {
+	// Why the values were rejected, nil when they hold no rulesets
+	Err error
+}
+
+func (e *InvalidBootstrapValuesError) Error() string {
+	message := "[Statsig] Failed to initialize from BootstrapValues, they are not a valid download_config_specs response. " +
+		"Falling back to the network"
+	if e.Err != nil {
+		message += ": " + e.Err.Error()
+	}
+	return message
+}
+
+func (e *InvalidBootstrapValuesError) Unwrap() error {
+	return e.Err
+}
+
+// Reported when download_config_specs rejects the SDK key with a 401 or 403 on initialize.
+// Until a valid key is used, every gate and config evaluates to its default value.
+type SDKKeyRejectedError struct {
+	StatusCode int
+}
+
+func (e *SDKKeyRejectedError) Error() string {
+	return fmt.Sprintf("[Statsig] Statsig rejected the SDK key with status %d. Check that the SDK is initialized with "+
+		"a Server Secret Key (secret-...) of the right project, copied in full from the Statsig Console. "+
+		"Until then, every gate and config will evaluate to its default value.", e.StatusCode)
+}
+
+// Returned by LogImmediate when some of its batches could not be sent, even after retries.
+// FailedEvents holds the events of those batches, so they can be logged again.
+type EventBatchError struct {
+	Batches      int
+	FailedEvents []Event
+	// The error of the first batch that failed
+	Err error
+}
+
+func (e *EventBatchError) Error() string {
+	return fmt.Sprintf("[Statsig] Failed to log %d events in %d batches: %s", len(e.FailedEvents), e.Batches, e.Err)
+}
+
+func (e *EventBatchError) Unwrap() error {
+	return e.Err
+}
+
 func newErrorBoundary(sdkKey string, options *Options, diagnostics *diagnostics) *errorBoundary {
 	errorBoundary := &errorBoundary{
-		api:         ErrorBoundaryAPI,
-		endpoint:    ErrorBoundaryEndpoint,
-		sdkKey:      sdkKey,
-		client:      &http.Client{Timeout: time.Second * 3},
-		seen:        make(map[string]bool),
-		diagnostics: diagnostics,
+		api:              ErrorBoundaryAPI,
+		endpoint:         ErrorBoundaryEndpoint,
+		sdkKey:           sdkKey,
+		client:           newHTTPClient(options),
+		seen:             make(map[string]bool),
+		diagnostics:      diagnostics,
+		disableReporting: options.DisableErrorBoundaryReporting || options.DisableNetwork,
+		errorCallback:    options.ErrorCallback,
+		piiScrubber:      newPIIScrubber(options),
 	}
 	if options.API != "" {
 		errorBoundary.api = options.API
@@ -73,6 +154,16 @@ func (e *errorBoundary) captureCheckGate(task func() bool) bool {
 	return res
 }
 
+func (e *errorBoundary) captureGetGate(task func() FeatureGate) FeatureGate {
+	defer e.ebRecover(func() {
+		e.diagnostics.api().checkGate().end().success(false).mark()
+	})
+	e.diagnostics.api().checkGate().start().mark()
+	res := task()
+	e.diagnostics.api().checkGate().end().success(true).mark()
+	return res
+}
+
 func (e *errorBoundary) captureGetConfig(task func() DynamicConfig) DynamicConfig {
 	defer e.ebRecover(func() {
 		e.diagnostics.api().getConfig().end().success(false).mark()
@@ -107,11 +198,31 @@ func (e *errorBoundary) ebRecover(recoverCallback func()) {
 	if err := recover(); err != nil {
 		e.logException(toError(err))
 		global.Logger().LogError(err)
+		e.reportError(toError(err), ErrorContextAPICall)
 		recoverCallback()
 	}
 }
 
+func (e *errorBoundary) reportError(err error, context string) {
+	notifyErrorCallback(e.errorCallback, err, context)
+}
+
+func notifyErrorCallback(callback func(err error, context string), err error, context string) {
+	if callback == nil || err == nil {
+		return
+	}
+	defer func() {
+		if err := recover(); err != nil {
+			logRecoveredPanic("error callback", err)
+		}
+	}()
+	callback(err, context)
+}
+
 func (e *errorBoundary) logException(exception error) {
+	if e.disableReporting {
+		return
+	}
 	var exceptionString string
 	if exception == nil {
 		exceptionString = "Unknown"
@@ -124,8 +235,8 @@ func (e *errorBoundary) logException(exception error) {
 	stack := make([]byte, 1024)
 	runtime.Stack(stack, false)
 	body := &logExceptionRequestBody{
-		Exception: exceptionString,
-		Info:      string(stack),
+		Exception: e.piiScrubber.scrubText(exceptionString),
+		Info:      e.piiScrubber.scrubText(string(stack)),
 	}
 	bodyString, err := json.Marshal(body)
 	if err != nil {
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -47,6 +48,29 @@ func TestLogException(t *testing.T) {
 	}
 }
 
+func TestDisableErrorBoundaryReporting(t *testing.T) {
+	hit := false
+	testServer := mock_server(t, nil, &hit)
+	defer testServer.Close()
+	opt := &Options{
+		API:                           testServer.URL,
+		DisableErrorBoundaryReporting: true,
+	}
+	errorBoundary := newErrorBoundary("client-key", opt, newDiagnostics())
+	errorBoundary.logException(errors.New("test error boundary log exception"))
+	recovered := false
+	errorBoundary.captureVoid(func() {
+		defer func() { recovered = true }()
+		panic("test panic")
+	})
+	if !recovered {
+		t.Error("Expected the panic to be recovered")
+	}
+	if hit {
+		t.Error("Expected sdk_exception endpoint not to be hit")
+	}
+}
+
 func TestDCSError(t *testing.T) {
 	hit := false
 	testServer := mock_server(t, nil, &hit)
@@ -63,6 +87,37 @@ func TestDCSError(t *testing.T) {
 	}
 }
 
+func TestErrorCallback(t *testing.T) {
+	hit := false
+	testServer := mock_server(t, nil, &hit)
+	defer testServer.Close()
+	var mu sync.Mutex
+	contexts := make(map[string]int)
+	opt := &Options{
+		API:                  testServer.URL,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+		ErrorCallback: func(err error, context string) {
+			mu.Lock()
+			defer mu.Unlock()
+			contexts[context]++
+		},
+	}
+	InitializeWithOptions("secret-key", opt)
+	defer ShutdownAndDangerouslyClearInstance()
+	instance.errorBoundary.captureVoid(func() {
+		panic("test panic")
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	if contexts[ErrorContextConfigSync] != 1 {
+		t.Errorf("Expected the config sync failure to be reported once, got %d", contexts[ErrorContextConfigSync])
+	}
+	if contexts[ErrorContextAPICall] != 1 {
+		t.Errorf("Expected the recovered panic to be reported once, got %d", contexts[ErrorContextAPICall])
+	}
+}
+
 func TestRepeatedError(t *testing.T) {
 	err := errors.New("common error")
 	hit := false
@@ -83,3 +138,48 @@ func TestRepeatedError(t *testing.T) {
 		t.Error("Expected sdk_exception endpoint to NOT be hit")
 	}
 }
+
+func TestSDKKeyRejected(t *testing.T) {
+	status := http.StatusUnauthorized
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "/download_config_specs") {
+			res.WriteHeader(status)
+			return
+		}
+		res.WriteHeader(http.StatusOK)
+		_, _ = res.Write([]byte("{}"))
+	}))
+	defer testServer.Close()
+	var mu sync.Mutex
+	var callbackErrs []error
+	opt := &Options{
+		API:                           testServer.URL,
+		DisableIDLists:                true,
+		DisableErrorBoundaryReporting: true,
+		OutputLoggerOptions:           getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions:          getStatsigLoggerOptionsForTest(t),
+		ErrorCallback: func(err error, context string) {
+			mu.Lock()
+			defer mu.Unlock()
+			callbackErrs = append(callbackErrs, err)
+		},
+	}
+	result := <-InitializeAsync("secret-typo", opt)
+	var keyErr *SDKKeyRejectedError
+	if result.Success || !errors.As(result.Error, &keyErr) || keyErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected the rejected key to be reported in the InitResult, got %+v", result)
+	}
+	mu.Lock()
+	if len(callbackErrs) != 1 || !errors.As(callbackErrs[0], &keyErr) {
+		t.Errorf("Expected the rejected key to be passed to the ErrorCallback, got %v", callbackErrs)
+	}
+	mu.Unlock()
+	ShutdownAndDangerouslyClearInstance()
+
+	status = http.StatusInternalServerError
+	result = <-InitializeAsync("secret-key", opt)
+	if result.Error != nil {
+		t.Errorf("Expected server errors not to be reported as a rejected key, got %v", result.Error)
+	}
+	ShutdownAndDangerouslyClearInstance()
+}
@@ -0,0 +1,49 @@
+package statsig
+
+// The values of every Feature Gate, Dynamic Config, Experiment and Layer for a single user
+type UserEvaluation struct {
+	User User
+	// The lcut (last config update time) of the rulesets used, in unix millis
+	LastConfigSyncTime int64
+	FeatureGates       map[string]FeatureGate
+	// Experiments are included with the Dynamic Configs
+	DynamicConfigs map[string]DynamicConfig
+	Layers         map[string]Layer
+}
+
+func (c *Client) evaluateAll(user User) UserEvaluation {
+	user = c.normalizeUser(user)
+	// Every entity is evaluated with the same rulesets, even if a config sync lands midway
+	rulesets := c.evaluator.store.getRulesets()
+	inventory := getSpecInventory(rulesets)
+	evaluation := UserEvaluation{
+		User:               user,
+		LastConfigSyncTime: inventory.LastConfigSyncTime,
+		FeatureGates:       make(map[string]FeatureGate),
+		DynamicConfigs:     make(map[string]DynamicConfig),
+		Layers:             make(map[string]Layer),
+	}
+	for _, entity := range inventory.Entities {
+		switch entity.Type {
+		case SpecTypeFeatureGate:
+			res := c.evaluator.checkGateWithRulesets(user, rulesets, entity.Name)
+			evaluation.FeatureGates[entity.Name] = FeatureGate{
+				Name:               entity.Name,
+				Value:              res.Pass,
+				RuleID:             res.Id,
+				SecondaryExposures: toSecondaryExposures(res.SecondaryExposures),
+			}
+		case SpecTypeDynamicConfig:
+			res := c.evaluator.getConfigWithRulesets(user, rulesets, entity.Name)
+			config := res.ConfigValue
+			config.SecondaryExposures = toSecondaryExposures(res.SecondaryExposures)
+			evaluation.DynamicConfigs[entity.Name] = config
+		case SpecTypeLayer:
+			res := c.evaluator.getLayerWithRulesets(user, rulesets, entity.Name)
+			layer := newLayerFromResult(entity.Name, res, nil)
+			layer.SecondaryExposures = toSecondaryExposures(res.SecondaryExposures)
+			evaluation.Layers[entity.Name] = *layer
+		}
+	}
+	return evaluation
+}
@@ -0,0 +1,110 @@
+package statsig
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestEvaluateAll(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	InitializeWithOptions("secret-key", &Options{
+		BootstrapValues:      string(bytes),
+		LocalMode:            true,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer ShutdownAndDangerouslyClearInstance()
+
+	evaluation := EvaluateAll(User{UserID: "123", Email: "jkw@statsig.com"})
+	if evaluation.LastConfigSyncTime != configSyncTime {
+		t.Errorf("Expected lcut %d, got %d", configSyncTime, evaluation.LastConfigSyncTime)
+	}
+	if len(evaluation.FeatureGates) != 4 || len(evaluation.DynamicConfigs) != 2 || len(evaluation.Layers) != 3 {
+		t.Errorf("Expected every spec to be evaluated, got %d gates, %d configs and %d layers",
+			len(evaluation.FeatureGates), len(evaluation.DynamicConfigs), len(evaluation.Layers))
+	}
+	if !evaluation.FeatureGates["on_for_statsig_email"].Value {
+		t.Errorf("Expected on_for_statsig_email to pass")
+	}
+	config := evaluation.DynamicConfigs["test_config"]
+	if config.RuleID != "1kNmlB23wylPFZi1M0Divl" || config.GetString("string", "") != "statsig" {
+		t.Errorf("Unexpected test_config value %+v", config)
+	}
+	if len(evaluation.Layers["c_layer_with_holdout"].SecondaryExposures) != 1 {
+		t.Errorf("Expected the layer holdout as a secondary exposure")
+	}
+	layer := evaluation.Layers["a_layer"]
+	_ = layer.GetBool("layer_param", false)
+
+	instance.logger.mu.Lock()
+	queued := len(instance.logger.events)
+	instance.logger.mu.Unlock()
+	if queued != 0 {
+		t.Errorf("Expected no exposures to be logged, got %d events", queued)
+	}
+
+	empty := EvaluateAll(User{})
+	if len(empty.FeatureGates) != 0 {
+		t.Errorf("Expected an empty evaluation for an invalid user")
+	}
+}
+
+// Runs swap on the first lookup, in the middle of an evaluation
+type swappingCountryLookup struct {
+	once *sync.Once
+	swap func()
+}
+
+func (l swappingCountryLookup) LookupIp(ip string) (string, bool) {
+	l.once.Do(l.swap)
+	return "NZ", true
+}
+
+func TestEvaluateAllUsesOneRulesetSnapshot(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	var specs downloadConfigSpecResponse
+	_ = json.Unmarshal(bytes, &specs)
+	// Sorted before always_on_gate, so it is evaluated first
+	specs.FeatureGates = append(specs.FeatureGates, configSpec{
+		Name:    "a_country_gate",
+		Type:    "feature_gate",
+		Enabled: true,
+		Rules: []configRule{{
+			ID:             "country_rule",
+			PassPercentage: 100,
+			Conditions:     []configCondition{{Type: "ip_based", Operator: "any", Field: "country", TargetValue: []interface{}{"NZ"}}},
+		}},
+	})
+
+	var c *Client
+	c = NewClientWithOptions("secret-key", &Options{
+		LocalMode:            true,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+		CountryLookupOptions: CountryLookupOptions{Lookup: swappingCountryLookup{once: &sync.Once{}, swap: func() {
+			var updated downloadConfigSpecResponse
+			_ = json.Unmarshal(bytes, &updated)
+			updated.Time = specs.Time + 1
+			for i := range updated.FeatureGates {
+				updated.FeatureGates[i].Enabled = false
+			}
+			c.evaluator.store.setConfigSpecs(updated)
+		}}},
+	})
+	defer c.Shutdown()
+	c.evaluator.store.setConfigSpecs(specs)
+
+	user := User{UserID: "123", IpAddress: "1.2.3.4"}
+	evaluation := c.evaluateAll(user)
+	if !evaluation.FeatureGates["a_country_gate"].Value {
+		t.Errorf("Expected a_country_gate to pass")
+	}
+	if !evaluation.FeatureGates["always_on_gate"].Value || evaluation.LastConfigSyncTime != specs.Time {
+		t.Errorf("Expected every gate to be evaluated with the rulesets the evaluation started with")
+	}
+	if c.CheckGate(user, "always_on_gate") {
+		t.Errorf("Expected the updated rulesets to be used afterwards")
+	}
+}
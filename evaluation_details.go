@@ -9,6 +9,7 @@ const (
 	reasonUnrecognized  evaluationReason = "Unrecognized"
 	reasonUninitialized evaluationReason = "Uninitialized"
 	reasonDataAdapter   evaluationReason = "DataAdapter"
+	reasonTimeout       evaluationReason = "Timeout"
 )
 
 type evaluationDetails struct {
@@ -30,3 +31,27 @@ func newEvaluationDetails(
 		serverTime:     getUnixMilli(),
 	}
 }
+
+// Why an evaluation returned its value, as returned by CheckGateWithDetails and the other *WithDetails methods.
+// The zero value is returned for users without a UserID or custom ID.
+type EvaluationDetails struct {
+	// Where the rulesets came from, e.g. "Network", "Bootstrap" or "DataAdapter", "LocalOverride" for an override,
+	// or "Unrecognized" when the gate, config or layer does not exist
+	Reason string
+	RuleID string
+	// When the rulesets were last updated and when the SDK was initialized, in Unix milliseconds
+	ConfigSyncTime int64
+	InitTime       int64
+	// When the value was evaluated, in Unix milliseconds
+	ServerTime int64
+}
+
+func toEvaluationDetails(details *evaluationDetails, ruleID string) EvaluationDetails {
+	return EvaluationDetails{
+		Reason:         string(details.reason),
+		RuleID:         ruleID,
+		ConfigSyncTime: details.configSyncTime,
+		InitTime:       details.initTime,
+		ServerTime:     details.serverTime,
+	}
+}
@@ -199,4 +199,34 @@ func TestEvaluationDetails(t *testing.T) {
 			"reason": "LocalOverride",
 		}, configSyncTime)
 	})
+
+	t.Run("with details variants", func(t *testing.T) {
+		startWithBootstrap()
+		OverrideGate("always_on_gate", false)
+		value, gateDetails := CheckGateWithDetails(user, "always_on_gate")
+		_, unknownDetails := CheckGateWithDetails(user, "unknown_gate")
+		config, configDetails := GetConfigWithDetails(user, "test_config")
+		experiment, experimentDetails := GetExperimentWithDetails(user, "sample_experiment")
+		layer, layerDetails := GetLayerWithDetails(user, "a_layer")
+		layer.GetString("experiment_param", "")
+		ShutdownAndDangerouslyClearInstance()
+
+		if value || gateDetails.Reason != "LocalOverride" || gateDetails.RuleID != "override" {
+			t.Errorf("Expected the details of the override, got %+v", gateDetails)
+		}
+		if unknownDetails.Reason != "Unrecognized" {
+			t.Errorf("Expected an unrecognized gate, got %+v", unknownDetails)
+		}
+		for _, details := range []EvaluationDetails{configDetails, experimentDetails, layerDetails} {
+			if details.Reason != "Bootstrap" || details.ConfigSyncTime != configSyncTime || details.InitTime == 0 || details.ServerTime == 0 {
+				t.Errorf("Expected the details of the bootstrapped rulesets, got %+v", details)
+			}
+		}
+		if configDetails.RuleID != config.RuleID || experimentDetails.RuleID != experiment.RuleID || layerDetails.RuleID != layer.RuleID {
+			t.Errorf("Expected the rule IDs of the evaluations")
+		}
+		if len(events) != 5 {
+			t.Errorf("Expected the *WithDetails variants to log exposures, got %d events", len(events))
+		}
+	})
 }
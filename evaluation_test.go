@@ -93,6 +93,9 @@ func TestMain(m *testing.M) {
 		secret = string(bytes)
 	}
 	os.Remove(debugLogFile)
+	// Tests poll far more often than production allows, and rely on polls landing on time
+	minConfigSyncInterval = 0
+	maxConfigSyncJitter = 0
 	swallow_stderr(func() {
 		os.Exit(m.Run())
 	})
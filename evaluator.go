@@ -1,8 +1,6 @@
 package statsig
 
 import (
-	"crypto/sha256"
-	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/statsig-io/ip3country-go/pkg/countrylookup"
@@ -22,9 +21,24 @@ type evaluator struct {
 	gateOverrides   map[string]bool
 	configOverrides map[string]map[string]interface{}
 	layerOverrides  map[string]map[string]interface{}
-	countryLookup   *countrylookup.CountryLookup
-	uaParser        *uaparser.Parser
-	mu              sync.RWMutex
+	groupOverrides  map[string]map[string]string // Group names by unit ID, by experiment name
+	// Incremented by every override, so cached ClientInitializeResponses computed before it are not reused
+	overrideGeneration int64
+	countryLookup      CountryLookup
+	uaParser           *uaparser.Parser
+	// Closed once countryLookup and uaParser are set, when they load in the background. Nil otherwise.
+	lookupsLoaded chan struct{}
+	latencyBudget time.Duration
+	timeoutCount  int64
+	// Evaluations over the latency budget still running in the background
+	abandonedCount int64
+	metrics        *metrics
+	cirCache       *clientInitializeResponseCache
+	cirHistory     *clientInitializeResponseHistory
+	custom         customConditions
+	// Current time for current_time conditions
+	now func() time.Time
+	mu  sync.RWMutex
 }
 
 type evalResult struct {
@@ -40,6 +54,7 @@ type evalResult struct {
 	IsExperimentGroup             *bool
 }
 
+const featureGateType = "feature_gate"
 const dynamicConfigType = "dynamic_config"
 const maxRecursiveDepth = 300
 
@@ -48,24 +63,59 @@ func newEvaluator(
 	errorBoundary *errorBoundary,
 	options *Options,
 	diagnostics *diagnostics,
+	initOpts initOptions,
 ) *evaluator {
-	store := newStore(transport, errorBoundary, options, diagnostics)
-	parser := uaparser.NewFromSaved()
-	countryLookup := countrylookup.New()
+	e := &evaluator{
+		latencyBudget:   options.EvaluationLatencyBudget,
+		metrics:         transport.metrics,
+		gateOverrides:   make(map[string]bool),
+		configOverrides: make(map[string]map[string]interface{}),
+		layerOverrides:  make(map[string]map[string]interface{}),
+		groupOverrides:  make(map[string]map[string]string),
+		cirCache:        newClientInitializeResponseCache(options.ClientInitializeResponseCacheOptions),
+		cirHistory:      newClientInitializeResponseHistory(maxClientInitializeResponseDeltaBases),
+		custom:          newCustomConditions(options.CustomConditionOptions),
+		now:             time.Now,
+	}
+	if options.Clock != nil {
+		e.now = options.Clock.Now
+	}
+	// Loading the user agent parser takes tens of milliseconds, so when initialize should not wait for it,
+	// it loads alongside the store and the first ip_based or ua_based condition waits for it instead
+	if options.InitTimeout > 0 || initOpts.background {
+		e.lookupsLoaded = make(chan struct{})
+		go func() {
+			defer close(e.lookupsLoaded)
+			e.loadLookups(errorBoundary, options)
+		}()
+	}
+	e.store = newStore(transport, errorBoundary, options, diagnostics, initOpts)
+	if e.lookupsLoaded == nil {
+		e.loadLookups(errorBoundary, options)
+	}
+	return e
+}
+
+func (e *evaluator) loadLookups(errorBoundary *errorBoundary, options *Options) {
 	defer func() {
 		if err := recover(); err != nil {
 			errorBoundary.logException(toError(err))
 			global.Logger().LogError(err)
 		}
 	}()
+	if !options.UAParserOptions.Disabled {
+		e.uaParser = uaparser.NewFromSaved()
+	}
+	if options.CountryLookupOptions.Lookup != nil {
+		e.countryLookup = options.CountryLookupOptions.Lookup
+	} else if !options.CountryLookupOptions.Disabled {
+		e.countryLookup = countrylookup.New()
+	}
+}
 
-	return &evaluator{
-		store:           store,
-		countryLookup:   countryLookup,
-		uaParser:        parser,
-		gateOverrides:   make(map[string]bool),
-		configOverrides: make(map[string]map[string]interface{}),
-		layerOverrides:  make(map[string]map[string]interface{}),
+func (e *evaluator) waitForLookups() {
+	if e.lookupsLoaded != nil {
+		<-e.lookupsLoaded
 	}
 }
 
@@ -77,18 +127,45 @@ func (e *evaluator) shutdown() {
 }
 
 func (e *evaluator) createEvaluationDetails(reason evaluationReason) *evaluationDetails {
-	e.store.mu.RLock()
-	defer e.store.mu.RUnlock()
-	return newEvaluationDetails(reason, e.store.lastSyncTime, e.store.initialSyncTime)
+	return e.createEvaluationDetailsFor(e.store.getRulesets(), reason)
+}
+
+func (e *evaluator) createEvaluationDetailsFor(rulesets *rulesetSnapshot, reason evaluationReason) *evaluationDetails {
+	// Nothing is recognized while the first specs are still loading in the background
+	if reason == reasonUnrecognized && rulesets.loadingInitialSpecs {
+		reason = reasonUninitialized
+	}
+	return newEvaluationDetails(reason, rulesets.time, rulesets.initialSyncTime)
+}
+
+// Details for results evaluated without them, such as gates that fall through to their default rule
+func (e *evaluator) getEvaluationDetails(res *evalResult) *evaluationDetails {
+	if res.EvaluationDetails != nil {
+		return res.EvaluationDetails
+	}
+	rulesets := e.store.getRulesets()
+	return e.createEvaluationDetailsFor(rulesets, rulesets.initReason)
 }
 
 func (e *evaluator) checkGate(user User, gateName string) *evalResult {
-	return e.evalGate(user, gateName, 0)
+	return e.checkGateWithRulesets(user, e.store.getRulesets(), gateName)
 }
 
-func (e *evaluator) evalGate(user User, gateName string, depth int) *evalResult {
+func (e *evaluator) checkGateWithRulesets(user User, rulesets *rulesetSnapshot, gateName string) *evalResult {
+	e.metrics.increment(metricEvaluations, "gate", 1)
+	return e.evalWithLatencyBudget(func() *evalResult {
+		return e.evalGate(user, rulesets, gateName, 0)
+	}, func() *evalResult {
+		return &evalResult{
+			EvaluationDetails:  e.createEvaluationDetailsFor(rulesets, reasonTimeout),
+			SecondaryExposures: make([]map[string]string, 0),
+		}
+	})
+}
+
+func (e *evaluator) evalGate(user User, rulesets *rulesetSnapshot, gateName string, depth int) *evalResult {
 	if gateOverride, hasOverride := e.getGateOverride(gateName); hasOverride {
-		evalDetails := e.createEvaluationDetails(reasonLocalOverride)
+		evalDetails := e.createEvaluationDetailsFor(rulesets, reasonLocalOverride)
 		return &evalResult{
 			Pass:               gateOverride,
 			Id:                 "override",
@@ -96,22 +173,35 @@ func (e *evaluator) evalGate(user User, gateName string, depth int) *evalResult
 			SecondaryExposures: make([]map[string]string, 0),
 		}
 	}
-	if gate, hasGate := e.store.getGate(gateName); hasGate {
-		return e.eval(user, gate, depth+1)
+	if gate, hasGate := rulesets.featureGates[gateName]; hasGate {
+		return e.eval(user, rulesets, gate, depth+1)
 	}
 	emptyEvalResult := new(evalResult)
-	emptyEvalResult.EvaluationDetails = e.createEvaluationDetails(reasonUnrecognized)
+	emptyEvalResult.EvaluationDetails = e.createEvaluationDetailsFor(rulesets, reasonUnrecognized)
 	emptyEvalResult.SecondaryExposures = make([]map[string]string, 0)
 	return emptyEvalResult
 }
 
 func (e *evaluator) getConfig(user User, configName string) *evalResult {
-	return e.evalConfig(user, configName, 0)
+	return e.getConfigWithRulesets(user, e.store.getRulesets(), configName)
+}
+
+func (e *evaluator) getConfigWithRulesets(user User, rulesets *rulesetSnapshot, configName string) *evalResult {
+	e.metrics.increment(metricEvaluations, "config", 1)
+	return e.evalWithLatencyBudget(func() *evalResult {
+		return e.evalConfig(user, rulesets, configName, 0)
+	}, func() *evalResult {
+		return &evalResult{
+			ConfigValue:        *NewConfig(configName, nil, ""),
+			EvaluationDetails:  e.createEvaluationDetailsFor(rulesets, reasonTimeout),
+			SecondaryExposures: make([]map[string]string, 0),
+		}
+	})
 }
 
-func (e *evaluator) evalConfig(user User, configName string, depth int) *evalResult {
+func (e *evaluator) evalConfig(user User, rulesets *rulesetSnapshot, configName string, depth int) *evalResult {
 	if configOverride, hasOverride := e.getConfigOverride(configName); hasOverride {
-		evalDetails := e.createEvaluationDetails(reasonLocalOverride)
+		evalDetails := e.createEvaluationDetailsFor(rulesets, reasonLocalOverride)
 		return &evalResult{
 			Pass:               true,
 			ConfigValue:        *NewConfig(configName, configOverride, "override"),
@@ -120,22 +210,102 @@ func (e *evaluator) evalConfig(user User, configName string, depth int) *evalRes
 			SecondaryExposures: make([]map[string]string, 0),
 		}
 	}
-	if config, hasConfig := e.store.getDynamicConfig(configName); hasConfig {
-		return e.eval(user, config, depth+1)
+	if config, hasConfig := rulesets.dynamicConfigs[configName]; hasConfig {
+		return e.eval(user, rulesets, config, depth+1)
 	}
 	emptyEvalResult := new(evalResult)
-	emptyEvalResult.EvaluationDetails = e.createEvaluationDetails(reasonUnrecognized)
+	emptyEvalResult.EvaluationDetails = e.createEvaluationDetailsFor(rulesets, reasonUnrecognized)
 	emptyEvalResult.SecondaryExposures = make([]map[string]string, 0)
 	return emptyEvalResult
 }
 
 func (e *evaluator) getLayer(user User, name string) *evalResult {
-	return e.evalLayer(user, name, 0)
+	return e.getLayerWithRulesets(user, e.store.getRulesets(), name)
 }
 
-func (e *evaluator) evalLayer(user User, name string, depth int) *evalResult {
+func (e *evaluator) getLayerWithRulesets(user User, rulesets *rulesetSnapshot, name string) *evalResult {
+	e.metrics.increment(metricEvaluations, "layer", 1)
+	return e.evalWithLatencyBudget(func() *evalResult {
+		return e.evalLayer(user, rulesets, name, 0)
+	}, func() *evalResult {
+		return &evalResult{
+			ConfigValue:        *NewConfig(name, nil, ""),
+			EvaluationDetails:  e.createEvaluationDetailsFor(rulesets, reasonTimeout),
+			SecondaryExposures: make([]map[string]string, 0),
+		}
+	})
+}
+
+// Evaluations over the latency budget keep running in the background. Once this many are, further evaluations
+// return the fallback right away instead of piling up more goroutines.
+const maxAbandonedEvaluations = 1000
+
+const (
+	evalRunning int32 = iota
+	evalAbandoned
+	evalFinished
+)
+
+// Runs the evaluation within the configured latency budget, returning the fallback if it runs over.
+// An evaluation that runs over keeps going in the background, but its result is discarded.
+func (e *evaluator) evalWithLatencyBudget(evalFunc func() *evalResult, fallback func() *evalResult) *evalResult {
+	if e.latencyBudget <= 0 {
+		return evalFunc()
+	}
+	if atomic.LoadInt64(&e.abandonedCount) >= maxAbandonedEvaluations {
+		e.countTimeout()
+		return fallback()
+	}
+	type evalOutcome struct {
+		result *evalResult
+		panic  interface{}
+	}
+	channel := make(chan evalOutcome, 1)
+	state := evalRunning
+	go func() {
+		defer func() {
+			if !atomic.CompareAndSwapInt32(&state, evalRunning, evalFinished) {
+				e.metrics.setGauge(metricEvaluationsAbandoned, "", float64(atomic.AddInt64(&e.abandonedCount, -1)))
+			}
+		}()
+		defer func() {
+			if err := recover(); err != nil {
+				channel <- evalOutcome{panic: err}
+			}
+		}()
+		channel <- evalOutcome{result: evalFunc()}
+	}()
+	timer := time.NewTimer(e.latencyBudget)
+	defer timer.Stop()
+	select {
+	case outcome := <-channel:
+		if outcome.panic != nil {
+			// Re-panic on the calling goroutine so the error boundary can handle it
+			panic(outcome.panic)
+		}
+		return outcome.result
+	case <-timer.C:
+		if atomic.CompareAndSwapInt32(&state, evalRunning, evalAbandoned) {
+			e.metrics.setGauge(metricEvaluationsAbandoned, "", float64(atomic.AddInt64(&e.abandonedCount, 1)))
+		}
+		e.countTimeout()
+		global.Logger().LogStep(StatsigProcessEvaluate, "Evaluation exceeded the latency budget")
+		return fallback()
+	}
+}
+
+func (e *evaluator) countTimeout() {
+	atomic.AddInt64(&e.timeoutCount, 1)
+	e.metrics.increment(metricEvaluationTimeouts, "", 1)
+}
+
+func (e *evaluator) getTimeoutCount() int64 {
+	return atomic.LoadInt64(&e.timeoutCount)
+}
+
+func (e *evaluator) evalLayer(user User, rulesets *rulesetSnapshot, name string, depth int) *evalResult {
 	if layerOverride, hasOverride := e.getLayerOverride(name); hasOverride {
-		evalDetails := e.createEvaluationDetails(reasonLocalOverride)
+		evalDetails := e.createEvaluationDetailsFor(rulesets, reasonLocalOverride)
 		return &evalResult{
 			Pass:               true,
 			ConfigValue:        *NewConfig(name, layerOverride, "override"),
@@ -144,11 +314,11 @@ func (e *evaluator) evalLayer(user User, name string, depth int) *evalResult {
 			SecondaryExposures: make([]map[string]string, 0),
 		}
 	}
-	if config, hasConfig := e.store.getLayerConfig(name); hasConfig {
-		return e.eval(user, config, depth+1)
+	if config, hasConfig := rulesets.layerConfigs[name]; hasConfig {
+		return e.eval(user, rulesets, config, depth+1)
 	}
 	emptyEvalResult := new(evalResult)
-	emptyEvalResult.EvaluationDetails = e.createEvaluationDetails(reasonUnrecognized)
+	emptyEvalResult.EvaluationDetails = e.createEvaluationDetailsFor(rulesets, reasonUnrecognized)
 	emptyEvalResult.SecondaryExposures = make([]map[string]string, 0)
 	return emptyEvalResult
 }
@@ -179,6 +349,7 @@ func (e *evaluator) OverrideGate(gate string, val bool) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 	e.gateOverrides[gate] = val
+	atomic.AddInt64(&e.overrideGeneration, 1)
 }
 
 // Override the DynamicConfig value for the given user
@@ -186,6 +357,7 @@ func (e *evaluator) OverrideConfig(config string, val map[string]interface{}) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 	e.configOverrides[config] = val
+	atomic.AddInt64(&e.overrideGeneration, 1)
 }
 
 // Override the Layer value for the given user
@@ -193,28 +365,98 @@ func (e *evaluator) OverrideLayer(layer string, val map[string]interface{}) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 	e.layerOverrides[layer] = val
+	atomic.AddInt64(&e.overrideGeneration, 1)
+}
+
+// Puts the user in the named group of the experiment, as if the user had been assigned to it.
+// An empty group removes the override.
+func (e *evaluator) OverrideExperimentGroup(user User, experiment string, group string) error {
+	spec, exists := e.store.getDynamicConfig(experiment)
+	if !exists {
+		return fmt.Errorf("no experiment named %s", experiment)
+	}
+	unitID := getUnitID(user, spec.IDType)
+	if unitID == "" {
+		return fmt.Errorf("the user has no %s for experiment %s", defaultString(spec.IDType, "userID"), experiment)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	atomic.AddInt64(&e.overrideGeneration, 1)
+	if group == "" {
+		delete(e.groupOverrides[experiment], unitID)
+		return nil
+	}
+	if _, exists := findExperimentGroup(spec, group); !exists {
+		return fmt.Errorf("experiment %s has no group named %s", experiment, group)
+	}
+	if e.groupOverrides[experiment] == nil {
+		e.groupOverrides[experiment] = make(map[string]string)
+	}
+	e.groupOverrides[experiment][unitID] = group
+	return nil
+}
+
+func (e *evaluator) getExperimentGroupOverride(user User, spec configSpec) (configRule, bool) {
+	e.mu.RLock()
+	group, exists := e.groupOverrides[spec.Name][getUnitID(user, spec.IDType)]
+	e.mu.RUnlock()
+	if !exists {
+		return configRule{}, false
+	}
+	return findExperimentGroup(spec, group)
+}
+
+// Group names are matched case insensitively
+func findExperimentGroup(spec configSpec, group string) (configRule, bool) {
+	for _, rule := range spec.Rules {
+		if rule.GroupName != "" && strings.EqualFold(rule.GroupName, group) {
+			return rule, true
+		}
+	}
+	return configRule{}, false
 }
 
 // Gets all evaluated values for the given user.
 // These values can then be given to a Statsig Client SDK via bootstrapping.
 func (e *evaluator) getClientInitializeResponse(user User, clientKey string) ClientInitializeResponse {
-	return getClientInitializeResponse(user, e.store, e.eval, clientKey)
+	// Taken before evaluating, so a response is never cached under rulesets or overrides newer than it was computed from
+	rulesets := e.store.getRulesets()
+	evalFunc := func(user User, spec configSpec, depth int) *evalResult {
+		return e.eval(user, rulesets, spec, depth)
+	}
+	if e.cirCache == nil {
+		return getClientInitializeResponse(user, e.store, evalFunc, clientKey)
+	}
+	key, cacheable := getClientInitializeResponseCacheKey(user, clientKey)
+	if !cacheable {
+		return getClientInitializeResponse(user, e.store, evalFunc, clientKey)
+	}
+	overrides := atomic.LoadInt64(&e.overrideGeneration)
+	if response, exists := e.cirCache.get(key, rulesets, overrides); exists {
+		return response
+	}
+	response := getClientInitializeResponse(user, e.store, evalFunc, clientKey)
+	e.cirCache.set(key, rulesets, overrides, response)
+	return response
 }
 
-func (e *evaluator) eval(user User, spec configSpec, depth int) *evalResult {
+func (e *evaluator) eval(user User, rulesets *rulesetSnapshot, spec configSpec, depth int) *evalResult {
 	if depth > maxRecursiveDepth {
 		panic(errors.New("Statsig Evaluation Depth Exceeded"))
 	}
 	var configValue map[string]interface{}
-	e.store.mu.RLock()
-	reason := e.store.initReason
-	e.store.mu.RUnlock()
-	evalDetails := e.createEvaluationDetails(reason)
+	var rawValue json.RawMessage
+	evalDetails := e.createEvaluationDetailsFor(rulesets, rulesets.initReason)
 	isDynamicConfig := strings.ToLower(spec.Type) == dynamicConfigType
 	if isDynamicConfig {
+		if rule, overridden := e.getExperimentGroupOverride(user, spec); overridden {
+			return e.evalExperimentGroupOverride(rulesets, spec, rule)
+		}
 		err := json.Unmarshal(spec.DefaultValue, &configValue)
 		if err != nil {
 			configValue = make(map[string]interface{})
+		} else {
+			rawValue = spec.DefaultValue
 		}
 	}
 
@@ -222,14 +464,14 @@ func (e *evaluator) eval(user User, spec configSpec, depth int) *evalResult {
 	defaultRuleID := "default"
 	if spec.Enabled {
 		for _, rule := range spec.Rules {
-			r := e.evalRule(user, rule, depth+1)
+			r := e.evalRule(user, rulesets, rule, depth+1)
 			if r.FetchFromServer {
 				return r
 			}
 			exposures = append(exposures, r.SecondaryExposures...)
 			if r.Pass {
 
-				delegatedResult := e.evalDelegate(user, rule, exposures, depth+1)
+				delegatedResult := e.evalDelegate(user, rulesets, rule, exposures, depth+1)
 				if delegatedResult != nil {
 					return delegatedResult
 				}
@@ -241,12 +483,20 @@ func (e *evaluator) eval(user User, spec configSpec, depth int) *evalResult {
 						err := json.Unmarshal(rule.ReturnValue, &ruleConfigValue)
 						if err != nil {
 							ruleConfigValue = make(map[string]interface{})
+							rawValue = nil
+						} else {
+							rawValue = rule.ReturnValue
 						}
 						configValue = ruleConfigValue
 					}
+					config := NewConfig(spec.Name, configValue, rule.ID)
+					config.rawValue = rawValue
+					if pass {
+						config.GroupName = rule.GroupName
+					}
 					result := &evalResult{
 						Pass:                          pass,
-						ConfigValue:                   *NewConfig(spec.Name, configValue, rule.ID),
+						ConfigValue:                   *config,
 						Id:                            rule.ID,
 						SecondaryExposures:            exposures,
 						UndelegatedSecondaryExposures: exposures,
@@ -271,9 +521,11 @@ func (e *evaluator) eval(user User, spec configSpec, depth int) *evalResult {
 	}
 
 	if isDynamicConfig {
+		config := NewConfig(spec.Name, configValue, defaultRuleID)
+		config.rawValue = rawValue
 		return &evalResult{
 			Pass:                          false,
-			ConfigValue:                   *NewConfig(spec.Name, configValue, defaultRuleID),
+			ConfigValue:                   *config,
 			Id:                            defaultRuleID,
 			SecondaryExposures:            exposures,
 			UndelegatedSecondaryExposures: exposures,
@@ -283,14 +535,35 @@ func (e *evaluator) eval(user User, spec configSpec, depth int) *evalResult {
 	return &evalResult{Pass: false, Id: defaultRuleID, SecondaryExposures: exposures}
 }
 
-func (e *evaluator) evalDelegate(user User, rule configRule, exposures []map[string]string, depth int) *evalResult {
-	config, hasConfig := e.store.getDynamicConfig(rule.ConfigDelegate)
+// Serves the group's value the way a passing experiment group rule would
+func (e *evaluator) evalExperimentGroupOverride(rulesets *rulesetSnapshot, spec configSpec, rule configRule) *evalResult {
+	var value map[string]interface{}
+	config := NewConfig(spec.Name, nil, rule.ID)
+	if json.Unmarshal(rule.ReturnValue, &value) == nil {
+		config = NewConfig(spec.Name, value, rule.ID)
+		config.rawValue = rule.ReturnValue
+	}
+	config.GroupName = rule.GroupName
+	return &evalResult{
+		Pass:                          true,
+		ConfigValue:                   *config,
+		Id:                            rule.ID,
+		SecondaryExposures:            make([]map[string]string, 0),
+		UndelegatedSecondaryExposures: make([]map[string]string, 0),
+		EvaluationDetails:             e.createEvaluationDetailsFor(rulesets, reasonLocalOverride),
+		IsExperimentGroup:             rule.IsExperimentGroup,
+	}
+}
+
+func (e *evaluator) evalDelegate(user User, rulesets *rulesetSnapshot, rule configRule, exposures []map[string]string, depth int) *evalResult {
+	config, hasConfig := rulesets.dynamicConfigs[rule.ConfigDelegate]
 	if !hasConfig {
 		return nil
 	}
 
-	result := e.eval(user, config, depth+1)
+	result := e.eval(user, rulesets, config, depth+1)
 	result.ConfigDelegate = rule.ConfigDelegate
+	result.ConfigValue.AllocatedExperimentName = rule.ConfigDelegate
 	result.SecondaryExposures = append(exposures, result.SecondaryExposures...)
 	result.UndelegatedSecondaryExposures = exposures
 
@@ -303,13 +576,16 @@ func (e *evaluator) evalDelegate(user User, rule configRule, exposures []map[str
 }
 
 func evalPassPercent(user User, rule configRule, spec configSpec) bool {
+	return float64(getPassPercentBucket(user, rule, spec)) < (rule.PassPercentage * 100)
+}
+
+// The bucket of the user's unit for the rule, from 0 to 9999
+func getPassPercentBucket(user User, rule configRule, spec configSpec) uint64 {
 	ruleSalt := rule.Salt
 	if ruleSalt == "" {
 		ruleSalt = rule.ID
 	}
-	hash := getHashUint64Encoding(spec.Salt + "." + ruleSalt + "." + getUnitID(user, rule.IDType))
-
-	return float64(hash%10000) < (rule.PassPercentage * 100)
+	return getHashUint64Encoding(spec.Salt+"."+ruleSalt+"."+getUnitID(user, rule.IDType)) % 10000
 }
 
 func getUnitID(user User, idType string) string {
@@ -325,11 +601,11 @@ func getUnitID(user User, idType string) string {
 	return user.UserID
 }
 
-func (e *evaluator) evalRule(user User, rule configRule, depth int) *evalResult {
+func (e *evaluator) evalRule(user User, rulesets *rulesetSnapshot, rule configRule, depth int) *evalResult {
 	var exposures = make([]map[string]string, 0)
 	var finalResult = &evalResult{Pass: true, FetchFromServer: false}
 	for _, cond := range rule.Conditions {
-		res := e.evalCondition(user, cond, depth+1)
+		res := e.evalCondition(user, rulesets, cond, depth+1)
 		if !res.Pass {
 			finalResult.Pass = false
 		}
@@ -342,8 +618,7 @@ func (e *evaluator) evalRule(user User, rule configRule, depth int) *evalResult
 	return finalResult
 }
 
-func (e *evaluator) evalCondition(user User, cond configCondition, depth int) *evalResult {
-	var value interface{}
+func (e *evaluator) evalCondition(user User, rulesets *rulesetSnapshot, cond configCondition, depth int) *evalResult {
 	condType := strings.ToLower(cond.Type)
 	op := strings.ToLower(cond.Operator)
 	switch condType {
@@ -354,7 +629,7 @@ func (e *evaluator) evalCondition(user User, cond configCondition, depth int) *e
 		if !ok {
 			return &evalResult{Pass: false}
 		}
-		result := e.evalGate(user, dependentGateName, depth+1)
+		result := e.evalGate(user, rulesets, dependentGateName, depth+1)
 		if result.FetchFromServer {
 			return &evalResult{FetchFromServer: true}
 		}
@@ -369,29 +644,9 @@ func (e *evaluator) evalCondition(user User, cond configCondition, depth int) *e
 		} else {
 			return &evalResult{Pass: !result.Pass, SecondaryExposures: allExposures}
 		}
-	case "ip_based":
-		value = getFromUser(user, cond.Field)
-		if value == nil || value == "" {
-			value = getFromIP(user, cond.Field, e.countryLookup)
-		}
-	case "ua_based":
-		value = getFromUser(user, cond.Field)
-		if value == nil || value == "" {
-			value = getFromUserAgent(user, cond.Field, e.uaParser)
-		}
-	case "user_field":
-		value = getFromUser(user, cond.Field)
-	case "environment_field":
-		value = getFromEnvironment(user, cond.Field)
-	case "current_time":
-		value = time.Now().Unix() // time in seconds
-	case "user_bucket":
-		if salt, ok := cond.AdditionalValues["salt"]; ok {
-			value = int64(getHashUint64Encoding(fmt.Sprintf("%s.%s", salt, getUnitID(user, cond.IDType))) % 1000)
-		}
-	case "unit_id":
-		value = getUnitID(user, cond.IDType)
-	default:
+	}
+	value, known := e.getConditionValue(user, cond, condType)
+	if !known {
 		return &evalResult{FetchFromServer: true}
 	}
 
@@ -491,8 +746,7 @@ func (e *evaluator) evalCondition(user User, cond configCondition, depth int) *e
 		if reflect.TypeOf(cond.TargetValue).String() == "string" && reflect.TypeOf(value).String() == "string" {
 			list := e.store.getIDList(toString(cond.TargetValue))
 			if list != nil {
-				h := sha256.Sum256([]byte(toString(value)))
-				_, inlist = list.ids.Load(base64.StdEncoding.EncodeToString(h[:])[:8])
+				inlist = list.contains(getIDListEntry(toString(value)))
 			}
 		}
 		if op == "in_segment_list" {
@@ -501,12 +755,48 @@ func (e *evaluator) evalCondition(user User, cond configCondition, depth int) *e
 			pass = !inlist
 		}
 	default:
-		pass = false
-		server = true
+		var custom bool
+		pass, custom = e.custom.evalOperator(op, value, cond.TargetValue)
+		server = !custom
 	}
 	return &evalResult{Pass: pass, FetchFromServer: server}
 }
 
+// Resolves the value the condition compares with its target value, e.g. a field of the user.
+// Returns false for unknown condition types, which only Statsig's servers can evaluate.
+func (e *evaluator) getConditionValue(user User, cond configCondition, condType string) (interface{}, bool) {
+	var value interface{}
+	switch condType {
+	case "ip_based":
+		value = getFromUser(user, cond.Field)
+		if value == nil || value == "" {
+			e.waitForLookups()
+			value = getFromIP(user, cond.Field, e.countryLookup)
+		}
+	case "ua_based":
+		value = getFromUser(user, cond.Field)
+		if value == nil || value == "" {
+			e.waitForLookups()
+			value = getFromUserAgent(user, cond.Field, e.uaParser)
+		}
+	case "user_field":
+		value = getFromUser(user, cond.Field)
+	case "environment_field":
+		value = getFromEnvironment(user, cond.Field)
+	case "current_time":
+		value = e.now().Unix() // time in seconds
+	case "user_bucket":
+		if salt, ok := cond.AdditionalValues["salt"]; ok {
+			value = int64(getHashUint64Encoding(fmt.Sprintf("%s.%s", salt, getUnitID(user, cond.IDType))) % 1000)
+		}
+	case "unit_id":
+		value = getUnitID(user, cond.IDType)
+	default:
+		return e.custom.resolveField(condType, user, cond.Field)
+	}
+	return value, true
+}
+
 func getFromUser(user User, field string) interface{} {
 	var value interface{}
 	// 1. Try to get from top level user field first
@@ -557,6 +847,9 @@ func getFromEnvironment(user User, field string) string {
 }
 
 func getFromUserAgent(user User, field string, parser *uaparser.Parser) string {
+	if parser == nil {
+		return ""
+	}
 	ua := getFromUser(user, "useragent")
 	uaStr, ok := ua.(string)
 	if !ok {
@@ -576,14 +869,14 @@ func getFromUserAgent(user User, field string, parser *uaparser.Parser) string {
 	return ""
 }
 
-func getFromIP(user User, field string, lookup *countrylookup.CountryLookup) string {
-	if strings.ToLower(field) != "country" {
+func getFromIP(user User, field string, lookup CountryLookup) string {
+	if lookup == nil || strings.ToLower(field) != "country" {
 		return ""
 	}
 
 	ip := getFromUser(user, "ip")
 	if ipStr, ok := ip.(string); ok {
-		if res, lookupOK := lookup.LookupIp(ipStr); lookupOK {
+		if res, lookupOK := lookupCountry(lookup, ipStr); lookupOK {
 			return res
 		}
 	}
@@ -591,6 +884,16 @@ func getFromIP(user User, field string, lookup *countrylookup.CountryLookup) str
 	return ""
 }
 
+func lookupCountry(lookup CountryLookup, ip string) (country string, ok bool) {
+	defer func() {
+		if err := recover(); err != nil {
+			logRecoveredPanic("CountryLookup", err)
+			country, ok = "", false
+		}
+	}()
+	return lookup.LookupIp(ip)
+}
+
 func removeEmptyStrings(s []string) []string {
 	var r []string
 	for _, str := range s {
@@ -0,0 +1,222 @@
+package statsig
+
+import (
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ua-parser/uap-go/uaparser"
+)
+
+const chromeOnMacUserAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/96.0.4664.110 Safari/537.36"
+
+func TestGetFromUserAgent(t *testing.T) {
+	user := User{UserID: "123", UserAgent: chromeOnMacUserAgent}
+	parser := uaparser.NewFromSaved()
+
+	if v := getFromUserAgent(user, "browser_name", parser); v != "Chrome" {
+		t.Errorf("Expected browser_name to be Chrome, got %s", v)
+	}
+	if v := getFromUserAgent(user, "browser_version", parser); v != "96.0.4664" {
+		t.Errorf("Expected browser_version to be 96.0.4664, got %s", v)
+	}
+	if v := getFromUserAgent(user, "os_name", parser); v != "Mac OS X" {
+		t.Errorf("Expected os_name to be Mac OS X, got %s", v)
+	}
+	if v := getFromUserAgent(user, "os_version", parser); v != "10.15.7" {
+		t.Errorf("Expected os_version to be 10.15.7, got %s", v)
+	}
+	if v := getFromUserAgent(user, "browser_name", nil); v != "" {
+		t.Errorf("Expected no value when the parser is disabled, got %s", v)
+	}
+}
+
+func TestUAParserDisabled(t *testing.T) {
+	InitializeGlobalOutputLogger(getOutputLoggerOptionsForTest(t))
+	c := NewClientWithOptions("secret-key", &Options{
+		LocalMode:       true,
+		UAParserOptions: UAParserOptions{Disabled: true},
+	})
+	defer c.Shutdown()
+	if c.evaluator.uaParser != nil {
+		t.Errorf("Expected the user agent parser not to be loaded")
+	}
+	cond := configCondition{Type: "ua_based", Operator: "any", Field: "browser_name", TargetValue: []interface{}{"Chrome"}}
+	if c.evaluator.evalCondition(User{UserID: "123", UserAgent: chromeOnMacUserAgent}, c.evaluator.store.getRulesets(), cond, 0).Pass {
+		t.Errorf("Expected ua_based condition to fail without a parser")
+	}
+	user := User{UserID: "123", Custom: map[string]interface{}{"browser_name": "Chrome"}}
+	if !c.evaluator.evalCondition(user, c.evaluator.store.getRulesets(), cond, 0).Pass {
+		t.Errorf("Expected ua_based condition to use explicitly provided values")
+	}
+}
+
+type staticCountryLookup map[string]string
+
+func (s staticCountryLookup) LookupIp(ip string) (string, bool) {
+	country, ok := s[ip]
+	return country, ok
+}
+
+func TestCountryLookup(t *testing.T) {
+	cond := configCondition{Type: "ip_based", Operator: "any", Field: "country", TargetValue: []interface{}{"NZ"}}
+	user := User{UserID: "123", IpAddress: "10.0.0.1"}
+
+	InitializeGlobalOutputLogger(getOutputLoggerOptionsForTest(t))
+	c := NewClientWithOptions("secret-key", &Options{
+		LocalMode:            true,
+		CountryLookupOptions: CountryLookupOptions{Lookup: staticCountryLookup{"10.0.0.1": "NZ"}},
+	})
+	defer c.Shutdown()
+	if !c.evaluator.evalCondition(user, c.evaluator.store.getRulesets(), cond, 0).Pass {
+		t.Errorf("Expected country to be resolved with the custom lookup")
+	}
+	user.Country = "US"
+	if c.evaluator.evalCondition(user, c.evaluator.store.getRulesets(), cond, 0).Pass {
+		t.Errorf("Expected User.Country to take precedence over the IP address")
+	}
+
+	disabled := NewClientWithOptions("secret-key", &Options{
+		LocalMode:            true,
+		CountryLookupOptions: CountryLookupOptions{Disabled: true},
+	})
+	defer disabled.Shutdown()
+	if disabled.evaluator.countryLookup != nil {
+		t.Errorf("Expected the built-in country lookup not to be loaded")
+	}
+	if disabled.evaluator.evalCondition(User{UserID: "123", IpAddress: "10.0.0.1"}, disabled.evaluator.store.getRulesets(), cond, 0).Pass {
+		t.Errorf("Expected ip_based condition to fail without a lookup")
+	}
+}
+
+type panickingCountryLookup struct{}
+
+func (panickingCountryLookup) LookupIp(ip string) (string, bool) {
+	panic("geo service unavailable")
+}
+
+// Resolves the addresses it knows, and falls back to the built-in table for the others
+type chainedCountryLookup struct {
+	known    map[string]string
+	fallback CountryLookup
+}
+
+func (c chainedCountryLookup) LookupIp(ip string) (string, bool) {
+	if country, ok := c.known[ip]; ok {
+		return country, true
+	}
+	return c.fallback.LookupIp(ip)
+}
+
+func TestCustomCountryLookupProviders(t *testing.T) {
+	cond := configCondition{Type: "ip_based", Operator: "any", Field: "country", TargetValue: []interface{}{"NZ", "FR"}}
+	InitializeGlobalOutputLogger(getOutputLoggerOptionsForTest(t))
+
+	chained := NewClientWithOptions("secret-key", &Options{
+		LocalMode: true,
+		CountryLookupOptions: CountryLookupOptions{Lookup: chainedCountryLookup{
+			known:    map[string]string{"10.0.0.1": "NZ"},
+			fallback: NewBuiltinCountryLookup(),
+		}},
+	})
+	defer chained.Shutdown()
+	for _, ip := range []string{"10.0.0.1", "2.2.2.2"} {
+		if !chained.evaluator.evalCondition(User{UserID: "123", IpAddress: ip}, chained.evaluator.store.getRulesets(), cond, 0).Pass {
+			t.Errorf("Expected the country of %s to be resolved", ip)
+		}
+	}
+	if chained.evaluator.evalCondition(User{UserID: "123", IpAddress: "1.1.1.1"}, chained.evaluator.store.getRulesets(), cond, 0).Pass {
+		t.Errorf("Expected 1.1.1.1 to resolve to the US")
+	}
+
+	panicking := NewClientWithOptions("secret-key", &Options{
+		LocalMode:            true,
+		CountryLookupOptions: CountryLookupOptions{Lookup: panickingCountryLookup{}},
+	})
+	defer panicking.Shutdown()
+	logs := captureOutputLogs(t, func() {
+		if panicking.evaluator.evalCondition(User{UserID: "123", IpAddress: "10.0.0.1"}, panicking.evaluator.store.getRulesets(), cond, 0).Pass {
+			t.Errorf("Expected a panicking lookup to resolve no country")
+		}
+	})
+	if !strings.Contains(logs, "Error calling CountryLookup: geo service unavailable") {
+		t.Errorf("Expected the panic to be reported, got %q", logs)
+	}
+	user := User{UserID: "123", IpAddress: "10.0.0.1", Country: "NZ"}
+	if !panicking.evaluator.evalCondition(user, panicking.evaluator.store.getRulesets(), cond, 0).Pass {
+		t.Errorf("Expected User.Country to be used without calling the lookup")
+	}
+}
+
+func TestEvalWithLatencyBudget(t *testing.T) {
+	s := &store{}
+	s.rulesets.Store(&rulesetSnapshot{})
+	e := &evaluator{store: s, latencyBudget: 20 * time.Millisecond}
+	slow := func() *evalResult {
+		time.Sleep(200 * time.Millisecond)
+		return &evalResult{Pass: true}
+	}
+	fast := func() *evalResult {
+		return &evalResult{Pass: true}
+	}
+	fallback := func() *evalResult {
+		return &evalResult{EvaluationDetails: e.createEvaluationDetails(reasonTimeout)}
+	}
+
+	if res := e.evalWithLatencyBudget(fast, fallback); !res.Pass {
+		t.Errorf("Expected evaluation within the budget to return its result")
+	}
+	res := e.evalWithLatencyBudget(slow, fallback)
+	if res.Pass || res.EvaluationDetails.reason != reasonTimeout {
+		t.Errorf("Expected evaluation over the budget to return the fallback with reason Timeout")
+	}
+	if e.getTimeoutCount() != 1 {
+		t.Errorf("Expected timeout to be counted")
+	}
+	if atomic.LoadInt64(&e.abandonedCount) != 1 {
+		t.Errorf("Expected the slow evaluation to be counted as abandoned")
+	}
+	waitForCondition(t, func() bool { return atomic.LoadInt64(&e.abandonedCount) == 0 })
+
+	atomic.StoreInt64(&e.abandonedCount, maxAbandonedEvaluations)
+	if res := e.evalWithLatencyBudget(fast, fallback); res.Pass {
+		t.Errorf("Expected the fallback once too many evaluations are abandoned")
+	}
+	atomic.StoreInt64(&e.abandonedCount, 0)
+
+	defer func() {
+		if err := recover(); err == nil {
+			t.Errorf("Expected evaluation panic to be raised on the calling goroutine")
+		}
+	}()
+	e.evalWithLatencyBudget(func() *evalResult { panic("eval failed") }, fallback)
+}
+
+func TestEvaluationDoesNotTakeStoreLock(t *testing.T) {
+	bootstrap, _ := os.ReadFile("download_config_specs.json")
+	c := NewClientWithOptions("secret-key", &Options{
+		LocalMode:            true,
+		BootstrapValues:      string(bootstrap),
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+
+	// A sync holding the lock must not hold up evaluations
+	c.evaluator.store.mu.Lock()
+	defer c.evaluator.store.mu.Unlock()
+	done := make(chan *evalResult, 1)
+	go func() {
+		done <- c.evaluator.evalGate(User{UserID: "123"}, c.evaluator.store.getRulesets(), "always_on_gate", 0)
+	}()
+	select {
+	case res := <-done:
+		if !res.Pass || res.EvaluationDetails.reason != reasonBootstrap {
+			t.Errorf("Expected always_on_gate to pass with the reason Bootstrap")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected the evaluation to finish while the store is locked")
+	}
+}
@@ -0,0 +1,82 @@
+package statsig
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Services logging tens of thousands of exposures per second otherwise allocate an event, a metadata map
+// and a share of the queue for each one. These pools hand them back once the events have been delivered
+// or spooled, when nothing references them anymore.
+var (
+	exposureEventPool = sync.Pool{
+		New: func() interface{} { return new(exposureEvent) },
+	}
+	exposureMetadataPool = sync.Pool{
+		New: func() interface{} { return make(map[string]string, 10) },
+	}
+	eventBufferPool = sync.Pool{}
+)
+
+func getExposureEvent() *exposureEvent {
+	return exposureEventPool.Get().(*exposureEvent)
+}
+
+// The event is copied into the queue by logExposure, so the scratch struct can be reused right away
+func releaseExposureEvent(evt *exposureEvent) {
+	*evt = exposureEvent{}
+	exposureEventPool.Put(evt)
+}
+
+func getExposureMetadata() map[string]string {
+	return exposureMetadataPool.Get().(map[string]string)
+}
+
+// Only for metadata created by the SDK, never maps owned by the caller
+func releaseExposureMetadata(metadata map[string]string) {
+	if metadata == nil {
+		return
+	}
+	for k := range metadata {
+		delete(metadata, k)
+	}
+	exposureMetadataPool.Put(metadata)
+}
+
+// Hooks may replace the pooled metadata of an exposure with a map of their own, which must not end up in
+// the pool. Its entries are copied into the pooled map instead. Returns nil, releasing the pooled map,
+// when the hook removed the metadata.
+func reclaimExposureMetadata(pooled map[string]string, metadata map[string]string) map[string]string {
+	if metadata == nil {
+		releaseExposureMetadata(pooled)
+		return nil
+	}
+	if pooled == nil || reflect.ValueOf(metadata).Pointer() == reflect.ValueOf(pooled).Pointer() {
+		return metadata
+	}
+	for k := range pooled {
+		delete(pooled, k)
+	}
+	for k, v := range metadata {
+		pooled[k] = v
+	}
+	return pooled
+}
+
+func getEventBuffer(capacity int) []interface{} {
+	if events, ok := eventBufferPool.Get().([]interface{}); ok && cap(events) >= capacity {
+		return events
+	}
+	return make([]interface{}, 0, capacity)
+}
+
+// Returns the queue and the metadata of its exposures to their pools. The events must not be used afterwards.
+func releaseEvents(events []interface{}) {
+	for i, evt := range events {
+		if exposure, ok := evt.(exposureEvent); ok {
+			releaseExposureMetadata(exposure.Metadata)
+		}
+		events[i] = nil
+	}
+	eventBufferPool.Put(events[:0])
+}
@@ -0,0 +1,76 @@
+package statsig
+
+import (
+	"sync/atomic"
+)
+
+// The state of the event queue, passed to Options.EventQueueMetricsCallback so operators can alert when
+// events are lost, e.g. because the flush cannot keep up with the rate exposures are logged at
+type EventQueueMetrics struct {
+	// Events waiting to be flushed
+	QueueDepth int
+	// Events taken off the queue by a flush and still being sent, including retries. Keeps growing when
+	// delivery cannot keep up with the rate events are logged at.
+	InFlightEvents int
+	// Batches the in-flight events are sent in, one per flush
+	InFlightBatches int
+	// Events that could not be queued since the Client started. The queue never rejects events while the
+	// Client runs, so these are the events logged after it was shut down.
+	EnqueueFailures int64
+	// Events that could not be delivered or spooled since the Client started
+	DroppedEvents int64
+}
+
+func (l *logger) setQueueDepth(depth int) {
+	atomic.StoreInt64(&l.queueDepth, int64(depth))
+	l.transport.metrics.setGauge(metricEventQueueDepth, "", float64(depth))
+}
+
+// Counts a batch taken off the queue as in flight until finishSending
+func (l *logger) startSending(count int) {
+	atomic.AddInt64(&l.inFlightBatches, 1)
+	l.transport.metrics.setGauge(metricEventsInFlight, "", float64(atomic.AddInt64(&l.inFlightEvents, int64(count))))
+}
+
+// Called once the batch was delivered, spooled or dropped
+func (l *logger) finishSending(count int) {
+	atomic.AddInt64(&l.inFlightBatches, -1)
+	l.transport.metrics.setGauge(metricEventsInFlight, "", float64(atomic.AddInt64(&l.inFlightEvents, -int64(count))))
+}
+
+func (l *logger) getEventQueueMetrics() EventQueueMetrics {
+	return EventQueueMetrics{
+		QueueDepth:      int(atomic.LoadInt64(&l.queueDepth)),
+		InFlightEvents:  int(atomic.LoadInt64(&l.inFlightEvents)),
+		InFlightBatches: int(atomic.LoadInt64(&l.inFlightBatches)),
+		EnqueueFailures: atomic.LoadInt64(&l.enqueueFailures),
+		DroppedEvents:   atomic.LoadInt64(&l.droppedEvents),
+	}
+}
+
+// Counts events logged after the logger closed, which are discarded. Must not be called with l.mu held.
+func (l *logger) recordEnqueueFailures(count int) {
+	atomic.AddInt64(&l.enqueueFailures, int64(count))
+	l.transport.metrics.increment(metricEventEnqueueFailures, "", float64(count))
+	l.reportQueueMetrics()
+}
+
+// Counts events that could not be delivered or spooled. Reported with the next queue metrics.
+func (l *logger) recordDroppedEvents(count int) {
+	atomic.AddInt64(&l.droppedEvents, int64(count))
+	l.transport.metrics.increment(metricEventsDropped, "", float64(count))
+}
+
+// Calls the EventQueueMetricsCallback, once queued events are flushed and whenever events could not be queued.
+// Must not be called with l.mu held, so a slow callback does not hold up logging.
+func (l *logger) reportQueueMetrics() {
+	if l.queueMetricsCallback == nil {
+		return
+	}
+	defer func() {
+		if err := recover(); err != nil {
+			logRecoveredPanic("EventQueueMetricsCallback", err)
+		}
+	}()
+	l.queueMetricsCallback(l.getEventQueueMetrics())
+}
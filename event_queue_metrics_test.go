@@ -0,0 +1,126 @@
+package statsig
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestEventQueueMetricsCallback(t *testing.T) {
+	dcs, _ := os.ReadFile("download_config_specs.json")
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		switch {
+		case strings.Contains(req.URL.Path, "download_config_specs"):
+			res.WriteHeader(http.StatusOK)
+			_, _ = res.Write(dcs)
+		case strings.Contains(req.URL.Path, "log_event"):
+			res.WriteHeader(http.StatusBadRequest)
+		default:
+			res.WriteHeader(http.StatusOK)
+			_, _ = res.Write([]byte("{}"))
+		}
+	}))
+	defer testServer.Close()
+
+	var mu sync.Mutex
+	var reported []EventQueueMetrics
+	c := NewClientWithOptions("secret-key", &Options{
+		API:            testServer.URL,
+		MetricsOptions: MetricsOptions{Enabled: true},
+		EventQueueMetricsCallback: func(metrics EventQueueMetrics) {
+			mu.Lock()
+			defer mu.Unlock()
+			reported = append(reported, metrics)
+		},
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	lastReported := func() EventQueueMetrics {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(reported) == 0 {
+			return EventQueueMetrics{}
+		}
+		return reported[len(reported)-1]
+	}
+
+	user := User{UserID: "123"}
+	c.CheckGate(user, "always_on_gate")
+	c.LogEvent(Event{EventName: "purchase", User: user})
+	if metrics := c.logger.getEventQueueMetrics(); metrics.QueueDepth != 2 {
+		t.Errorf("Expected 2 queued events, got %+v", metrics)
+	}
+	_ = c.FlushWithContext(context.Background())
+	if metrics := lastReported(); metrics != (EventQueueMetrics{DroppedEvents: 2}) {
+		t.Errorf("Expected the undelivered events to be reported as dropped, got %+v", metrics)
+	}
+
+	c.Shutdown()
+	c.LogEvent(Event{EventName: "purchase", User: user})
+	c.CheckGate(user, "always_on_gate")
+	if metrics := lastReported(); metrics != (EventQueueMetrics{EnqueueFailures: 2, DroppedEvents: 2}) {
+		t.Errorf("Expected the events logged after Shutdown to be reported as enqueue failures, got %+v", metrics)
+	}
+	recorder := httptest.NewRecorder()
+	c.MetricsHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.Contains(recorder.Body.String(), "statsig_event_enqueue_failures_total 2\n") {
+		t.Errorf("Expected the enqueue failures metric, got\n%s", recorder.Body.String())
+	}
+}
+
+func TestEventQueueMetricsCallbackPanic(t *testing.T) {
+	c := NewClientWithOptions("secret-key", &Options{
+		LocalMode: true,
+		EventQueueMetricsCallback: func(metrics EventQueueMetrics) {
+			panic("callback panic")
+		},
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	c.Shutdown()
+	logs := captureOutputLogs(t, func() {
+		c.LogEvent(Event{EventName: "purchase", User: User{UserID: "123"}})
+	})
+	if !strings.Contains(logs, "Error calling EventQueueMetricsCallback") {
+		t.Errorf("Expected the panic to be recovered and logged, got %q", logs)
+	}
+}
+
+func TestEventQueueMetricsInFlight(t *testing.T) {
+	release := make(chan struct{})
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "log_event") {
+			<-release
+		}
+		res.WriteHeader(http.StatusOK)
+		_, _ = res.Write([]byte("{}"))
+	}))
+	defer testServer.Close()
+
+	c := NewClientWithOptions("secret-key", &Options{
+		API:                  testServer.URL,
+		DisableIDLists:       true,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+
+	user := User{UserID: "123"}
+	c.LogEvent(Event{EventName: "purchase", User: user})
+	c.LogEvent(Event{EventName: "purchase", User: user})
+	c.logger.flush(false)
+	c.LogEvent(Event{EventName: "purchase", User: user})
+	c.logger.flush(false)
+	if metrics := c.logger.getEventQueueMetrics(); metrics.QueueDepth != 0 || metrics.InFlightEvents != 3 || metrics.InFlightBatches != 2 {
+		t.Errorf("Expected 3 events in 2 batches to be in flight, got %+v", metrics)
+	}
+	close(release)
+	waitForCondition(t, func() bool {
+		metrics := c.logger.getEventQueueMetrics()
+		return metrics.InFlightEvents == 0 && metrics.InFlightBatches == 0
+	})
+}
@@ -0,0 +1,402 @@
+package statsig
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultSpoolMaxFileSize = 1 << 20
+	defaultSpoolMaxFileAge  = time.Hour
+	defaultSpoolMaxSize     = 10 << 20
+	spoolFilePrefix         = "statsig-events-"
+	spoolFileSuffix         = ".spool"
+)
+
+// Writes log_event batches that could not be delivered to disk, and retries them on the next flush
+// and when the SDK starts again, so events are not lost to an outage or a restart
+type EventSpoolOptions struct {
+	// Directory the spool files are written to. The spool is disabled when empty.
+	Dir string
+	// 16, 24 or 32 byte AES key. When set, every spooled batch is encrypted with AES-GCM.
+	EncryptionKey []byte
+	// Size in bytes after which a new spool file is started. Defaults to 1MB.
+	MaxFileSize int64
+	// Age after which a new spool file is started. Defaults to 1 hour.
+	MaxFileAge time.Duration
+	// Total size in bytes of the spool files. Once it is reached, the oldest files are dropped to make
+	// room for new batches. Defaults to 10MB.
+	MaxTotalSize int64
+	// Spools to the DataAdapter instead of Dir, e.g. on hosts without a persistent disk, and replays the
+	// batches once Statsig is reachable again. MaxTotalSize and EncryptionKey still apply.
+	UseDataAdapter bool
+	// Key the batches are spooled under in the DataAdapter. Defaults to EVENT_SPOOL_KEY. Processes that
+	// share an adapter must each use their own key, or they overwrite each other's batches.
+	DataAdapterKey string
+}
+
+// Where undelivered event batches wait to be replayed: files in a directory, or the DataAdapter
+type eventSpooler interface {
+	write(events []interface{}) error
+	// Sends every spooled batch in the order it was written, keeping those not delivered.
+	// Only one replay runs at a time.
+	replay(send func(events []interface{}) error) error
+	// Must not block, as the logger calls it with its lock held
+	hasPending() bool
+	close()
+}
+
+// Nil when neither a directory nor the DataAdapter is configured
+func newEventSpooler(options *Options, codec JSONCodec) (eventSpooler, error) {
+	if options.EventSpoolOptions.UseDataAdapter && options.DataAdapter != nil {
+		return newAdapterEventSpool(options.EventSpoolOptions, options.DataAdapter, codec)
+	}
+	spool, err := newEventSpool(options.EventSpoolOptions, codec)
+	if spool == nil {
+		return nil, err
+	}
+	return spool, err
+}
+
+// Encodes spooled batches with the JSONCodec, and encrypts them when an EncryptionKey is set
+type spoolSealer struct {
+	aead  cipher.AEAD
+	codec JSONCodec
+}
+
+func newSpoolSealer(key []byte, codec JSONCodec) (spoolSealer, error) {
+	if len(key) == 0 {
+		return spoolSealer{codec: codec}, nil
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return spoolSealer{}, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return spoolSealer{}, err
+	}
+	return spoolSealer{aead: aead, codec: codec}, nil
+}
+
+// Serializes a batch of events into a single line record, encrypted when a key is configured
+func (s spoolSealer) encode(events []interface{}) ([]byte, error) {
+	payload, err := s.codec.Marshal(events)
+	if err != nil {
+		return nil, err
+	}
+	return s.seal(payload)
+}
+
+// Tracks whether spooled batches are waiting to be replayed, and lets only one replay run at a time.
+// It takes no lock, as the logger checks it with its own lock held.
+type spoolReplayState struct {
+	pending   int32
+	replaying int32
+}
+
+// Returns false when a replay is already running
+func (r *spoolReplayState) startReplay() bool {
+	if !atomic.CompareAndSwapInt32(&r.replaying, 0, 1) {
+		return false
+	}
+	// Batches spooled from here on are left for the next replay
+	atomic.StoreInt32(&r.pending, 0)
+	return true
+}
+
+func (r *spoolReplayState) finishReplay() {
+	atomic.StoreInt32(&r.replaying, 0)
+}
+
+func (r *spoolReplayState) setPending() {
+	atomic.StoreInt32(&r.pending, 1)
+}
+
+// Whether there are spooled batches and no replay is already running to send them
+func (r *spoolReplayState) hasPending() bool {
+	return atomic.LoadInt32(&r.pending) == 1 && atomic.LoadInt32(&r.replaying) == 0
+}
+
+type eventSpool struct {
+	spoolReplayState
+	spoolSealer
+	options     EventSpoolOptions
+	file        *os.File
+	fileSize    int64
+	fileCreated time.Time
+	// Guards the spool files. Never held while batches are sent.
+	mu sync.Mutex
+}
+
+func newEventSpool(options EventSpoolOptions, codec JSONCodec) (*eventSpool, error) {
+	if options.Dir == "" {
+		return nil, nil
+	}
+	if options.MaxFileSize <= 0 {
+		options.MaxFileSize = defaultSpoolMaxFileSize
+	}
+	if options.MaxFileAge <= 0 {
+		options.MaxFileAge = defaultSpoolMaxFileAge
+	}
+	if options.MaxTotalSize <= 0 {
+		options.MaxTotalSize = defaultSpoolMaxSize
+	}
+	sealer, err := newSpoolSealer(options.EncryptionKey, codec)
+	if err != nil {
+		return nil, err
+	}
+	spool := &eventSpool{spoolSealer: sealer, options: options}
+	if err := os.MkdirAll(options.Dir, 0700); err != nil {
+		return nil, err
+	}
+	// Batches spooled before a restart are replayed like any other
+	files, err := spool.listFiles()
+	if err != nil {
+		return nil, err
+	}
+	if len(files) > 0 {
+		spool.setPending()
+	}
+	return spool, nil
+}
+
+// Appends a batch of events to the current spool file, rotating it first if it is too big or too old
+func (s *eventSpool) write(events []interface{}) error {
+	record, err := s.encode(events)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file != nil && (s.fileSize >= s.options.MaxFileSize || time.Since(s.fileCreated) >= s.options.MaxFileAge) {
+		s.closeFile()
+	}
+	if err := s.makeRoom(int64(len(record)) + 1); err != nil {
+		return err
+	}
+	if s.file == nil {
+		now := time.Now()
+		name := filepath.Join(s.options.Dir, fmt.Sprintf("%s%d%s", spoolFilePrefix, now.UnixNano(), spoolFileSuffix))
+		file, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return err
+		}
+		s.file = file
+		s.fileSize = 0
+		s.fileCreated = now
+	}
+	n, err := s.file.Write(append(record, '\n'))
+	s.fileSize += int64(n)
+	if n > 0 {
+		s.setPending()
+	}
+	return err
+}
+
+// Removes the oldest spool files until size more bytes fit under MaxTotalSize.
+// The file being written to is never removed; if it alone is too big, the batch is rejected.
+func (s *eventSpool) makeRoom(size int64) error {
+	files, err := s.listFiles()
+	if err != nil {
+		return err
+	}
+	sizes := make([]int64, len(files))
+	var total int64
+	for i, name := range files {
+		if info, err := os.Stat(name); err == nil {
+			sizes[i] = info.Size()
+			total += sizes[i]
+		}
+	}
+	dropped := 0
+	for i, name := range files {
+		if total+size <= s.options.MaxTotalSize {
+			break
+		}
+		if s.file != nil && s.file.Name() == name {
+			continue
+		}
+		if os.Remove(name) == nil {
+			total -= sizes[i]
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		global.Logger().LogError(fmt.Errorf("Event spool reached its %d byte limit, dropped the %d oldest spool files", s.options.MaxTotalSize, dropped))
+	}
+	if total+size > s.options.MaxTotalSize {
+		return fmt.Errorf("event spool is full, a batch of %d bytes does not fit in %d bytes", size, s.options.MaxTotalSize)
+	}
+	return nil
+}
+
+// Sends every spooled batch in the order it was written. Files are removed once all of their
+// batches are delivered. On the first failure, the undelivered batches are kept for the next replay.
+// Returns right away when another replay is running.
+func (s *eventSpool) replay(send func(events []interface{}) error) error {
+	if !s.startReplay() {
+		return nil
+	}
+	defer s.finishReplay()
+	s.mu.Lock()
+	// Batches written from now on go to a new file, which is left for the next replay
+	s.closeFile()
+	files, err := s.listFiles()
+	s.mu.Unlock()
+	if err != nil {
+		s.setPending()
+		return err
+	}
+	for _, name := range files {
+		records, err := readSpoolRecords(name)
+		if os.IsNotExist(err) {
+			// Dropped to make room for newer batches
+			continue
+		}
+		if err != nil {
+			s.setPending()
+			return err
+		}
+		for i, record := range records {
+			events, err := s.open(record)
+			if err != nil {
+				global.Logger().LogError(fmt.Errorf("Dropping unreadable spooled event batch: %w", err))
+				continue
+			}
+			if err := send(events); err != nil {
+				s.keepRecords(name, records[i:])
+				s.setPending()
+				return err
+			}
+		}
+		s.mu.Lock()
+		_ = os.Remove(name)
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+// Rewrites a replayed file with its undelivered batches, unless it was dropped to make room in the meantime
+func (s *eventSpool) keepRecords(name string, records [][]byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := os.Stat(name); err == nil {
+		_ = writeSpoolRecords(name, records)
+	}
+}
+
+func (s *eventSpool) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeFile()
+}
+
+func (s *eventSpool) closeFile() {
+	if s.file != nil {
+		_ = s.file.Close()
+		s.file = nil
+	}
+}
+
+func (s *eventSpool) listFiles() ([]string, error) {
+	entries, err := os.ReadDir(s.options.Dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, spoolFilePrefix) && strings.HasSuffix(name, spoolFileSuffix) {
+			files = append(files, filepath.Join(s.options.Dir, name))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Encrypts the payload when a key is configured. Records are base64 encoded so each fits on one line.
+func (s spoolSealer) seal(payload []byte) ([]byte, error) {
+	if s.aead == nil {
+		return payload, nil
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	sealed := s.aead.Seal(nonce, nonce, payload, nil)
+	record := make([]byte, base64.StdEncoding.EncodedLen(len(sealed)))
+	base64.StdEncoding.Encode(record, sealed)
+	return record, nil
+}
+
+func (s spoolSealer) open(record []byte) ([]interface{}, error) {
+	payload := record
+	if s.aead != nil {
+		sealed := make([]byte, base64.StdEncoding.DecodedLen(len(record)))
+		n, err := base64.StdEncoding.Decode(sealed, record)
+		if err != nil {
+			return nil, err
+		}
+		sealed = sealed[:n]
+		nonceSize := s.aead.NonceSize()
+		if len(sealed) < nonceSize {
+			return nil, errors.New("spooled record is too short")
+		}
+		payload, err = s.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var raw []json.RawMessage
+	if err := s.codec.Unmarshal(payload, &raw); err != nil {
+		return nil, err
+	}
+	events := make([]interface{}, len(raw))
+	for i, event := range raw {
+		events[i] = event
+	}
+	return events, nil
+}
+
+func readSpoolRecords(name string) ([][]byte, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	records := make([][]byte, 0)
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			records = append(records, line)
+		}
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func writeSpoolRecords(name string, records [][]byte) error {
+	return os.WriteFile(name, append(bytes.Join(records, []byte("\n")), '\n'), 0600)
+}
@@ -0,0 +1,186 @@
+package statsig
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// Spools event batches to the DataAdapter. Each batch is stored under its own key, and the key the spool
+// was configured with holds the index of the batches, so a write never rewrites batches already spooled.
+type adapterEventSpool struct {
+	spoolReplayState
+	spoolSealer
+	adapter      IDataAdapter
+	key          string
+	maxTotalSize int64
+	// Guards the index. Never held while batches are sent.
+	mu      sync.Mutex
+	loaded  bool
+	batches []spooledBatch
+	nextID  int64
+}
+
+// An entry of the spool index
+type spooledBatch struct {
+	ID   int64 `json:"id"`
+	Size int64 `json:"size"`
+}
+
+func newAdapterEventSpool(options EventSpoolOptions, adapter IDataAdapter, codec JSONCodec) (*adapterEventSpool, error) {
+	sealer, err := newSpoolSealer(options.EncryptionKey, codec)
+	if err != nil {
+		return nil, err
+	}
+	spool := &adapterEventSpool{
+		spoolSealer:  sealer,
+		adapter:      adapter,
+		key:          defaultString(options.DataAdapterKey, EVENT_SPOOL_KEY),
+		maxTotalSize: options.MaxTotalSize,
+	}
+	if spool.maxTotalSize <= 0 {
+		spool.maxTotalSize = defaultSpoolMaxSize
+	}
+	// The adapter is not initialized yet, so batches spooled before a restart are looked for on the first replay
+	spool.setPending()
+	return spool, nil
+}
+
+// Stores a batch under its own key, dropping the oldest batches if the spool would grow over its maximum size
+func (s *adapterEventSpool) write(events []interface{}) error {
+	record, err := s.encode(events)
+	if err != nil {
+		return err
+	}
+	size := int64(len(record))
+	if size > s.maxTotalSize {
+		return fmt.Errorf("event spool is full, a batch of %d bytes does not fit in %d bytes", size, s.maxTotalSize)
+	}
+	s.mu.Lock()
+	s.loadIndex()
+	id := s.nextID
+	s.nextID++
+	s.mu.Unlock()
+	if err := s.setValue(s.batchKey(id), string(record)); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	batches := append(s.batches, spooledBatch{ID: id, Size: size})
+	var total int64
+	for _, batch := range batches {
+		total += batch.Size
+	}
+	dropped := 0
+	for total > s.maxTotalSize {
+		total -= batches[dropped].Size
+		dropped++
+	}
+	droppedBatches := append([]spooledBatch(nil), batches[:dropped]...)
+	s.batches = batches[dropped:]
+	err = s.writeIndex()
+	s.mu.Unlock()
+	s.setPending()
+
+	if dropped > 0 {
+		global.Logger().LogError(fmt.Errorf("Event spool reached its %d byte limit, dropped the %d oldest batches", s.maxTotalSize, dropped))
+		for _, batch := range droppedBatches {
+			_ = s.setValue(s.batchKey(batch.ID), "")
+		}
+	}
+	return err
+}
+
+func (s *adapterEventSpool) replay(send func(events []interface{}) error) error {
+	if !s.startReplay() {
+		return nil
+	}
+	defer s.finishReplay()
+	s.mu.Lock()
+	s.loadIndex()
+	batches := append([]spooledBatch(nil), s.batches...)
+	s.mu.Unlock()
+	for _, batch := range batches {
+		// Empty when the batch was dropped to make room in the meantime
+		if record := getFromAdapter(s.adapter, s.batchKey(batch.ID)); record != "" {
+			events, err := s.open([]byte(record))
+			if err != nil {
+				global.Logger().LogError(fmt.Errorf("Dropping unreadable spooled event batch: %w", err))
+			} else if err := send(events); err != nil {
+				s.setPending()
+				return err
+			}
+		}
+		if err := s.removeBatch(batch.ID); err != nil {
+			s.setPending()
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *adapterEventSpool) close() {}
+
+func (s *adapterEventSpool) batchKey(id int64) string {
+	return s.key + "." + strconv.FormatInt(id, 10)
+}
+
+func (s *adapterEventSpool) removeBatch(id int64) error {
+	s.mu.Lock()
+	for i, batch := range s.batches {
+		if batch.ID == id {
+			s.batches = append(s.batches[:i:i], s.batches[i+1:]...)
+			break
+		}
+	}
+	err := s.writeIndex()
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.setValue(s.batchKey(id), "")
+}
+
+// Reads the index of the batches spooled before a restart, once the adapter is initialized. Called with s.mu held.
+func (s *adapterEventSpool) loadIndex() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+	value := getFromAdapter(s.adapter, s.key)
+	if value == "" {
+		return
+	}
+	if err := s.codec.Unmarshal([]byte(value), &s.batches); err != nil {
+		global.Logger().LogError(fmt.Errorf("Dropping unreadable spooled events: %w", err))
+		s.batches = nil
+	}
+	for _, batch := range s.batches {
+		if batch.ID >= s.nextID {
+			s.nextID = batch.ID + 1
+		}
+	}
+}
+
+// Called with s.mu held
+func (s *adapterEventSpool) writeIndex() error {
+	value := ""
+	if len(s.batches) > 0 {
+		encoded, err := s.codec.Marshal(s.batches)
+		if err != nil {
+			return err
+		}
+		value = string(encoded)
+	}
+	return s.setValue(s.key, value)
+}
+
+func (s *adapterEventSpool) setValue(key string, value string) (err error) {
+	defer func() {
+		if panicked := recover(); panicked != nil {
+			err = fmt.Errorf("data adapter set failed: %w", toError(panicked))
+		}
+	}()
+	s.adapter.Set(key, value)
+	return nil
+}
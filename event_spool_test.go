@@ -0,0 +1,328 @@
+package statsig
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestEventSpool(t *testing.T) {
+	var failing int32 = 1
+	var mu sync.Mutex
+	received := make([]string, 0)
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if !strings.Contains(req.URL.Path, "log_event") {
+			res.WriteHeader(http.StatusOK)
+			return
+		}
+		if atomic.LoadInt32(&failing) == 1 {
+			res.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var input struct {
+			Events []Event `json:"events"`
+		}
+		_ = json.NewDecoder(req.Body).Decode(&input)
+		mu.Lock()
+		for _, event := range input.Events {
+			received = append(received, event.EventName)
+		}
+		mu.Unlock()
+		res.WriteHeader(http.StatusOK)
+		_, _ = res.Write([]byte("{}"))
+	}))
+	defer testServer.Close()
+
+	dir := t.TempDir()
+	options := &Options{
+		API:                  testServer.URL,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+		EventSpoolOptions: EventSpoolOptions{
+			Dir:           dir,
+			EncryptionKey: []byte("0123456789abcdef0123456789abcdef"),
+		},
+	}
+	InitializeWithOptions("secret-key", options)
+	defer ShutdownAndDangerouslyClearInstance()
+
+	LogEvent(Event{EventName: "spooled_event", User: User{UserID: "123", Email: "pii@statsig.com"}})
+	if err := Flush(); err == nil {
+		t.Errorf("Expected flush to fail")
+	}
+
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 spool file, got %d", len(files))
+	}
+	contents, _ := os.ReadFile(dir + "/" + files[0].Name())
+	if bytes.Contains(contents, []byte("spooled_event")) || bytes.Contains(contents, []byte("pii@statsig.com")) {
+		t.Errorf("Spooled events should be encrypted")
+	}
+
+	atomic.StoreInt32(&failing, 0)
+	LogEvent(Event{EventName: "live_event", User: User{UserID: "123"}})
+	if err := Flush(); err != nil {
+		t.Errorf("Expected flush to succeed, got %s", err.Error())
+	}
+
+	mu.Lock()
+	if len(received) != 2 || received[0] != "live_event" || received[1] != "spooled_event" {
+		t.Errorf("Expected live and spooled events to be delivered, got %v", received)
+	}
+	mu.Unlock()
+	files, _ = os.ReadDir(dir)
+	if len(files) != 0 {
+		t.Errorf("Expected spool files to be removed after replay, got %d", len(files))
+	}
+}
+
+func TestEventSpoolRotation(t *testing.T) {
+	dir := t.TempDir()
+	spool, err := newEventSpool(EventSpoolOptions{Dir: dir, MaxFileSize: 1}, standardJSONCodec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		if err := spool.write([]interface{}{Event{EventName: name}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, _ := os.ReadDir(dir)
+	if len(files) != 3 {
+		t.Errorf("Expected a new file per batch, got %d", len(files))
+	}
+
+	sent := make([]string, 0)
+	err = spool.replay(func(events []interface{}) error {
+		var event Event
+		_ = json.Unmarshal(events[0].(json.RawMessage), &event)
+		if event.EventName == "c" {
+			return os.ErrDeadlineExceeded
+		}
+		sent = append(sent, event.EventName)
+		return nil
+	})
+	if err == nil || len(sent) != 2 || sent[0] != "a" || sent[1] != "b" {
+		t.Errorf("Expected batches to replay in order until the failure, got %v", sent)
+	}
+	files, _ = os.ReadDir(dir)
+	if len(files) != 1 {
+		t.Errorf("Expected the undelivered batch to be kept, got %d files", len(files))
+	}
+
+	if _, err := newEventSpool(EventSpoolOptions{Dir: dir, EncryptionKey: []byte("short")}, standardJSONCodec{}); err == nil {
+		t.Errorf("Expected an invalid key length to be rejected")
+	}
+}
+
+func TestEventSpoolMaxTotalSize(t *testing.T) {
+	dir := t.TempDir()
+	batch := []interface{}{Event{EventName: "event"}}
+	record, _ := json.Marshal(batch)
+	recordSize := int64(len(record)) + 1
+	spool, err := newEventSpool(EventSpoolOptions{Dir: dir, MaxFileSize: 1, MaxTotalSize: 2 * recordSize}, standardJSONCodec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := spool.write(batch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, _ := os.ReadDir(dir)
+	if len(files) != 2 {
+		t.Errorf("Expected the oldest file to be dropped to stay under the limit, got %d files", len(files))
+	}
+
+	small, _ := newEventSpool(EventSpoolOptions{Dir: t.TempDir(), MaxTotalSize: 1}, standardJSONCodec{})
+	if err := small.write(batch); err == nil {
+		t.Errorf("Expected a batch bigger than the limit to be rejected")
+	}
+}
+
+func TestEventSpoolReplayOnStartup(t *testing.T) {
+	dir := t.TempDir()
+	spool, _ := newEventSpool(EventSpoolOptions{Dir: dir}, standardJSONCodec{})
+	_ = spool.write([]interface{}{Event{EventName: "spooled_before_restart"}})
+	spool.close()
+
+	var mu sync.Mutex
+	received := make([]string, 0)
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "log_event") {
+			var input struct {
+				Events []Event `json:"events"`
+			}
+			_ = json.NewDecoder(req.Body).Decode(&input)
+			mu.Lock()
+			for _, event := range input.Events {
+				received = append(received, event.EventName)
+			}
+			mu.Unlock()
+		}
+		res.WriteHeader(http.StatusOK)
+		_, _ = res.Write([]byte("{}"))
+	}))
+	defer testServer.Close()
+
+	c := NewClientWithOptions("secret-key", &Options{
+		API:                  testServer.URL,
+		DisableIDLists:       true,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+		EventSpoolOptions:    EventSpoolOptions{Dir: dir},
+	})
+	defer c.Shutdown()
+
+	waitForCondition(t, func() bool {
+		files, _ := os.ReadDir(dir)
+		return len(files) == 0
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 1 || received[0] != "spooled_before_restart" {
+		t.Errorf("Expected the batch spooled before the restart to be sent, got %v", received)
+	}
+}
+
+func TestEventSpoolDataAdapter(t *testing.T) {
+	var failing int32 = 1
+	var mu sync.Mutex
+	received := make([]string, 0)
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if !strings.Contains(req.URL.Path, "log_event") {
+			res.WriteHeader(http.StatusOK)
+			return
+		}
+		if atomic.LoadInt32(&failing) == 1 {
+			res.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var input struct {
+			Events []Event `json:"events"`
+		}
+		_ = json.NewDecoder(req.Body).Decode(&input)
+		mu.Lock()
+		for _, event := range input.Events {
+			received = append(received, event.EventName)
+		}
+		mu.Unlock()
+		_, _ = res.Write([]byte("{}"))
+	}))
+	defer testServer.Close()
+
+	adapter := &cachingDataAdapterExample{store: make(map[string]string)}
+	options := &Options{
+		API:                  testServer.URL,
+		DataAdapter:          adapter,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+		EventSpoolOptions:    EventSpoolOptions{UseDataAdapter: true, DataAdapterKey: "events_of_this_host"},
+	}
+	c := NewClientWithOptions("secret-key", options)
+	c.LogEvent(Event{EventName: "spooled_event", User: User{UserID: "123"}})
+	if err := c.Flush(); err == nil {
+		t.Errorf("Expected flush to fail")
+	}
+	c.Shutdown()
+	if adapter.Get("events_of_this_host") == "" || !strings.Contains(adapter.Get("events_of_this_host.0"), "spooled_event") {
+		t.Fatalf("Expected the batch to be spooled to the adapter under its own key")
+	}
+
+	// A restarted process replays the batches it spooled before
+	atomic.StoreInt32(&failing, 0)
+	c = NewClientWithOptions("secret-key", options)
+	defer c.Shutdown()
+	waitForCondition(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 1 && received[0] == "spooled_event"
+	})
+	waitForCondition(t, func() bool {
+		return adapter.Get("events_of_this_host") == "" && adapter.Get("events_of_this_host.0") == ""
+	})
+}
+
+func TestAdapterEventSpoolMaxTotalSize(t *testing.T) {
+	adapter := &cachingDataAdapterExample{store: make(map[string]string)}
+	batch := []interface{}{Event{EventName: "event"}}
+	record, _ := json.Marshal(batch)
+	spool, err := newAdapterEventSpool(EventSpoolOptions{MaxTotalSize: 2 * int64(len(record))}, adapter, standardJSONCodec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := spool.write(batch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if adapter.Get(EVENT_SPOOL_KEY+".0") != "" || adapter.Get(EVENT_SPOOL_KEY+".1") == "" || adapter.Get(EVENT_SPOOL_KEY+".2") == "" {
+		t.Errorf("Expected only the oldest batch to be dropped, got %v", adapter.store)
+	}
+
+	sent := 0
+	if err := spool.replay(func(events []interface{}) error { sent++; return nil }); err != nil || sent != 2 {
+		t.Errorf("Expected the 2 kept batches to be replayed, got %d", sent)
+	}
+	if spool.hasPending() || adapter.Get(EVENT_SPOOL_KEY) != "" {
+		t.Errorf("Expected the index to be cleared after the replay")
+	}
+}
+
+func TestEventSpoolReplayDoesNotBlockWrites(t *testing.T) {
+	spool, err := newEventSpool(EventSpoolOptions{Dir: t.TempDir()}, standardJSONCodec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = spool.write([]interface{}{Event{EventName: "a"}})
+
+	sending := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- spool.replay(func(events []interface{}) error {
+			close(sending)
+			<-release
+			return os.ErrDeadlineExceeded
+		})
+	}()
+	<-sending
+
+	if spool.hasPending() {
+		t.Errorf("Expected no pending replay while one is running")
+	}
+	if err := spool.write([]interface{}{Event{EventName: "b"}}); err != nil {
+		t.Errorf("Expected writes to go through during a replay, got %s", err.Error())
+	}
+	if err := spool.replay(func(events []interface{}) error { return nil }); err != nil {
+		t.Errorf("Expected a concurrent replay to return right away, got %s", err.Error())
+	}
+	close(release)
+	if err := <-done; err == nil {
+		t.Errorf("Expected the replay to fail")
+	}
+	if !spool.hasPending() {
+		t.Errorf("Expected undelivered batches to be pending once the replay finished")
+	}
+
+	sent := make([]string, 0)
+	_ = spool.replay(func(events []interface{}) error {
+		var event Event
+		_ = json.Unmarshal(events[0].(json.RawMessage), &event)
+		sent = append(sent, event.EventName)
+		return nil
+	})
+	if len(sent) != 2 || sent[0] != "a" || sent[1] != "b" {
+		t.Errorf("Expected both batches to be replayed in order, got %v", sent)
+	}
+	if spool.hasPending() {
+		t.Errorf("Expected nothing pending after a successful replay")
+	}
+}
@@ -0,0 +1,23 @@
+package statsig
+
+import "strings"
+
+// Whether the rule that assigned the user to the experiment is one of its groups, rather than a rule serving
+// users outside the experiment, e.g. a targeting or layer allocation rule
+func (e *evaluator) isUserInExperiment(user User, experiment string) bool {
+	if spec, exists := e.store.getDynamicConfig(experiment); !exists || !isExperimentSpec(spec) {
+		return false
+	}
+	res := e.getConfig(user, experiment)
+	return res.IsExperimentGroup != nil && *res.IsExperimentGroup
+}
+
+// Whether the experiment has started and is not yet stopped or launched
+func (e *evaluator) isExperimentActive(experiment string) bool {
+	spec, exists := e.store.getDynamicConfig(experiment)
+	return exists && isExperimentSpec(spec) && spec.IsActive != nil && *spec.IsActive
+}
+
+func isExperimentSpec(spec configSpec) bool {
+	return strings.ToLower(spec.Entity) == EntityExperiment
+}
@@ -0,0 +1,80 @@
+package statsig
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func TestExperimentMembership(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	var specs map[string]interface{}
+	_ = json.Unmarshal(bytes, &specs)
+	experiment := func(name string, active bool) map[string]interface{} {
+		return map[string]interface{}{
+			"name":         name,
+			"type":         "dynamic_config",
+			"entity":       "experiment",
+			"salt":         name,
+			"enabled":      true,
+			"isActive":     active,
+			"defaultValue": map[string]interface{}{},
+			"rules": []interface{}{
+				map[string]interface{}{
+					"id":             "targeting",
+					"passPercentage": 0,
+					"conditions":     []interface{}{map[string]interface{}{"type": "user_field", "field": "email", "operator": "str_contains_any", "targetValue": []string{"@example.com"}}},
+					"returnValue":    map[string]interface{}{},
+				},
+				map[string]interface{}{
+					"id":                "treatment",
+					"groupName":         "Treatment",
+					"isExperimentGroup": true,
+					"passPercentage":    100,
+					"conditions":        []interface{}{map[string]interface{}{"type": "public"}},
+					"returnValue":       map[string]interface{}{"enabled": true},
+				},
+			},
+		}
+	}
+	specs["dynamic_configs"] = append(specs["dynamic_configs"].([]interface{}),
+		experiment("running_experiment", true), experiment("stopped_experiment", false))
+	bootstrap, _ := json.Marshal(specs)
+	c := NewClientWithOptions("secret-key", &Options{
+		LocalMode:            true,
+		BootstrapValues:      string(bootstrap),
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+	inGroup := User{UserID: "123", Email: "jane@statsig.com"}
+	targetedOut := User{UserID: "456", Email: "jane@example.com"}
+
+	if !c.IsUserInExperiment(inGroup, "running_experiment") {
+		t.Errorf("Expected the user to be in the experiment group")
+	}
+	if c.IsUserInExperiment(targetedOut, "running_experiment") {
+		t.Errorf("Expected a user failing the targeting rule not to be in the experiment")
+	}
+	if c.IsUserInExperiment(inGroup, "test_config") || c.IsUserInExperiment(inGroup, "unknown_experiment") {
+		t.Errorf("Expected dynamic configs and unknown experiments to have no members")
+	}
+	if !c.IsExperimentActive("running_experiment") || c.IsExperimentActive("stopped_experiment") {
+		t.Errorf("Expected the active flag of the experiments")
+	}
+	if c.IsExperimentActive("test_config") || c.IsExperimentActive("unknown_experiment") {
+		t.Errorf("Expected dynamic configs and unknown experiments not to be active")
+	}
+
+	c.OverrideExperimentGroup(targetedOut, "running_experiment", "Treatment")
+	if !c.IsUserInExperiment(targetedOut, "running_experiment") {
+		t.Errorf("Expected the user overridden into a group to be in the experiment")
+	}
+
+	c.logger.mu.Lock()
+	logged := len(c.logger.events)
+	c.logger.mu.Unlock()
+	if logged != 0 {
+		t.Errorf("Expected no exposures, got %d events", logged)
+	}
+}
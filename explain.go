@@ -0,0 +1,145 @@
+package statsig
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Why a gate passed or failed for a user, as returned by ExplainGate
+type GateExplanation struct {
+	Name  string
+	Value bool
+	// The ID of the rule that decided the value, "default" when no rule passed,
+	// "disabled" for a disabled gate or "override" for a local override
+	RuleID string
+	// Where the rulesets came from, e.g. "Network", or "Unrecognized" when the gate does not exist
+	Reason string
+	// The rules evaluated, in order, up to the rule whose conditions passed
+	Rules []RuleExplanation
+}
+
+// How a rule of a gate was evaluated for a user
+type RuleExplanation struct {
+	RuleID string
+	// Whether every condition passed. The first rule whose conditions pass decides the value of the gate.
+	ConditionsPass bool
+	Conditions     []ConditionExplanation
+	// The bucket of the user's unit for the rule, from 0 to 9999. The rule only passes for the
+	// units whose bucket is below PassPercentage * 100.
+	Bucket         uint64
+	PassPercentage float64
+	// Whether the conditions and the pass percentage passed
+	Pass bool
+}
+
+// How a condition of a rule was evaluated for a user
+type ConditionExplanation struct {
+	Type        string
+	Operator    string
+	Field       string
+	TargetValue interface{}
+	// The value compared with the target value, e.g. the user's email for a user_field condition on email
+	Value interface{}
+	Pass  bool
+	// Set when the SDK cannot evaluate the condition locally, so only Statsig's servers can
+	Unsupported bool
+	// The explanation of the gate a pass_gate or fail_gate condition depends on
+	DependentGate *GateExplanation
+}
+
+func (e *evaluator) explainGate(user User, rulesets *rulesetSnapshot, gateName string, depth int) GateExplanation {
+	if depth > maxRecursiveDepth {
+		panic(errors.New("Statsig Evaluation Depth Exceeded"))
+	}
+	result := e.evalGate(user, rulesets, gateName, depth)
+	explanation := GateExplanation{
+		Name:   gateName,
+		Value:  result.Pass,
+		RuleID: result.Id,
+		Reason: string(e.getEvaluationDetails(result).reason),
+	}
+	if _, overridden := e.getGateOverride(gateName); overridden {
+		return explanation
+	}
+	gate, exists := rulesets.featureGates[gateName]
+	if !exists || !gate.Enabled {
+		return explanation
+	}
+	for _, rule := range gate.Rules {
+		ruleExplanation := e.explainRule(user, rulesets, gate, rule, depth+1)
+		explanation.Rules = append(explanation.Rules, ruleExplanation)
+		if ruleExplanation.ConditionsPass {
+			break
+		}
+	}
+	return explanation
+}
+
+func (e *evaluator) explainRule(user User, rulesets *rulesetSnapshot, spec configSpec, rule configRule, depth int) RuleExplanation {
+	explanation := RuleExplanation{
+		RuleID:         rule.ID,
+		ConditionsPass: true,
+		Bucket:         getPassPercentBucket(user, rule, spec),
+		PassPercentage: rule.PassPercentage,
+	}
+	for _, cond := range rule.Conditions {
+		condType := strings.ToLower(cond.Type)
+		result := e.evalCondition(user, rulesets, cond, depth+1)
+		condExplanation := ConditionExplanation{
+			Type:        cond.Type,
+			Operator:    cond.Operator,
+			Field:       cond.Field,
+			TargetValue: cond.TargetValue,
+			Pass:        result.Pass && !result.FetchFromServer,
+			Unsupported: result.FetchFromServer,
+		}
+		switch condType {
+		case "public":
+		case "fail_gate", "pass_gate":
+			if dependentGateName, ok := cond.TargetValue.(string); ok {
+				dependentGate := e.explainGate(user, rulesets, dependentGateName, depth+1)
+				condExplanation.Value = dependentGate.Value
+				condExplanation.DependentGate = &dependentGate
+			}
+		default:
+			condExplanation.Value, _ = e.getConditionValue(user, cond, condType)
+		}
+		if !condExplanation.Pass {
+			explanation.ConditionsPass = false
+		}
+		explanation.Conditions = append(explanation.Conditions, condExplanation)
+	}
+	explanation.Pass = explanation.ConditionsPass && evalPassPercent(user, rule, spec)
+	return explanation
+}
+
+// Describes the evaluation on several lines, e.g. to paste in a support ticket
+func (g GateExplanation) String() string {
+	var b strings.Builder
+	g.write(&b, "")
+	return b.String()
+}
+
+func (g GateExplanation) write(b *strings.Builder, indent string) {
+	fmt.Fprintf(b, "%sgate %s: %t (rule %s, reason %s)\n", indent, g.Name, g.Value, g.RuleID, g.Reason)
+	for i, rule := range g.Rules {
+		outcome := "conditions failed"
+		if rule.ConditionsPass {
+			outcome = fmt.Sprintf("conditions passed, bucket %d of pass percentage %g: %t", rule.Bucket, rule.PassPercentage, rule.Pass)
+		}
+		fmt.Fprintf(b, "%s  rule %d (%s): %s\n", indent, i+1, rule.RuleID, outcome)
+		for _, cond := range rule.Conditions {
+			result := "fail"
+			if cond.Unsupported {
+				result = "unsupported"
+			} else if cond.Pass {
+				result = "pass"
+			}
+			fmt.Fprintf(b, "%s    %s %s %s %v: %#v -> %s\n", indent, cond.Type, cond.Field, cond.Operator, cond.TargetValue, cond.Value, result)
+			if cond.DependentGate != nil {
+				cond.DependentGate.write(b, indent+"      ")
+			}
+		}
+	}
+}
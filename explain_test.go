@@ -0,0 +1,95 @@
+package statsig
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExplainGate(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	var specs map[string]interface{}
+	_ = json.Unmarshal(bytes, &specs)
+	specs["feature_gates"] = append(specs["feature_gates"].([]interface{}), map[string]interface{}{
+		"name":         "depends_on_email_gate",
+		"type":         "feature_gate",
+		"salt":         "depends_on_email_gate",
+		"enabled":      true,
+		"defaultValue": false,
+		"rules": []interface{}{map[string]interface{}{
+			"id":             "dependent_rule",
+			"passPercentage": 100,
+			"conditions":     []interface{}{map[string]interface{}{"type": "pass_gate", "targetValue": "on_for_statsig_email"}},
+			"returnValue":    true,
+		}},
+	})
+	bootstrap, _ := json.Marshal(specs)
+	c := NewClientWithOptions("secret-key", &Options{
+		LocalMode:            true,
+		BootstrapValues:      string(bootstrap),
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+	statsigUser := User{UserID: "123", Email: "jane@statsig.com"}
+	otherUser := User{UserID: "456", Email: "jane@example.com"}
+
+	t.Run("explains failing conditions", func(t *testing.T) {
+		explanation := c.ExplainGate(otherUser, "on_for_statsig_email")
+		if explanation.Value || explanation.RuleID != "default" || explanation.Reason != "Bootstrap" {
+			t.Errorf("Expected the gate to fail with the default rule, got %+v", explanation)
+		}
+		if len(explanation.Rules) != 1 || explanation.Rules[0].ConditionsPass {
+			t.Fatalf("Expected the one rule to fail, got %+v", explanation.Rules)
+		}
+		cond := explanation.Rules[0].Conditions[0]
+		if cond.Pass || cond.Value != "jane@example.com" || cond.Operator != "str_contains_any" || cond.Field != "email" {
+			t.Errorf("Expected the email condition to fail on the user's email, got %+v", cond)
+		}
+	})
+
+	t.Run("explains passing rules and their bucket", func(t *testing.T) {
+		explanation := c.ExplainGate(statsigUser, "on_for_statsig_email")
+		rule := explanation.Rules[0]
+		if !explanation.Value || explanation.RuleID != rule.RuleID || !rule.ConditionsPass || !rule.Pass {
+			t.Errorf("Expected the rule to pass, got %+v", explanation)
+		}
+		fractional := c.ExplainGate(statsigUser, "fractional_gate")
+		rule = fractional.Rules[0]
+		if rule.Bucket >= 10000 || rule.PassPercentage != 0.5 || rule.Pass != fractional.Value {
+			t.Errorf("Expected the pass percentage to decide the value, got %+v", rule)
+		}
+		if fractional.Value != c.CheckGateWithExposureLoggingDisabled(statsigUser, "fractional_gate") {
+			t.Errorf("Expected the explained value to match CheckGate")
+		}
+	})
+
+	t.Run("explains dependent gates", func(t *testing.T) {
+		explanation := c.ExplainGate(otherUser, "depends_on_email_gate")
+		cond := explanation.Rules[0].Conditions[0]
+		if cond.Pass || cond.DependentGate == nil || cond.DependentGate.Name != "on_for_statsig_email" {
+			t.Fatalf("Expected the explanation of the dependent gate, got %+v", cond)
+		}
+		if len(cond.DependentGate.Rules) != 1 {
+			t.Errorf("Expected the rules of the dependent gate")
+		}
+		text := explanation.String()
+		if !strings.Contains(text, "gate depends_on_email_gate: false") || !strings.Contains(text, `"jane@example.com" -> fail`) {
+			t.Errorf("Expected a readable explanation, got:\n%s", text)
+		}
+	})
+
+	t.Run("explains unknown gates without logging exposures", func(t *testing.T) {
+		explanation := c.ExplainGate(statsigUser, "unknown_gate")
+		if explanation.Value || explanation.Reason != "Unrecognized" || len(explanation.Rules) != 0 {
+			t.Errorf("Expected an unrecognized gate, got %+v", explanation)
+		}
+		c.logger.mu.Lock()
+		logged := len(c.logger.events)
+		c.logger.mu.Unlock()
+		if logged != 0 {
+			t.Errorf("Expected no exposures, got %d events", logged)
+		}
+	})
+}
@@ -24,3 +24,13 @@ func InitializeGlobalOutputLogger(options OutputLoggerOptions) {
 		options: options,
 	}
 }
+
+// Changes the level of the output logger in place, so settings made since by InitializeGlobalOutputLogger are kept
+func (g *GlobalState) updateLoggerLevel(level *LogLevel, enableDebug *bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.logger == nil {
+		g.logger = &OutputLogger{}
+	}
+	g.logger.setLevel(level, enableDebug)
+}
@@ -1,10 +1,11 @@
 module github.com/statsig-io/go-sdk
 
-go 1.16
+go 1.18
 
 require (
 	github.com/google/uuid v1.3.0
 	github.com/statsig-io/ip3country-go v0.2.0
 	github.com/ua-parser/uap-go v0.0.0-20211112212520-00c877edfe0f
-	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
+
+require gopkg.in/yaml.v2 v2.4.0 // indirect
@@ -0,0 +1,100 @@
+package statsig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"time"
+)
+
+const defaultRequestTimeout = 3 * time.Second
+
+// Tunes the connections of the HTTP client shared by every request to Statsig, e.g. so that high
+// volume event logging reuses connections instead of opening new ones, or sets up mutual TLS.
+// Zero values keep the defaults of http.DefaultTransport.
+type HTTPOptions struct {
+	// Used as is for every request when set, and the other options and Options.UnixSocketPath are then ignored
+	Transport *http.Transport
+	// Maximum idle connections kept open, in total and to each host. Go keeps only 2 per host by default.
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	// How long an idle connection is kept open
+	IdleConnTimeout time.Duration
+	// Presented to servers and proxies that require mutual TLS, e.g. loaded with tls.LoadX509KeyPair
+	ClientCertificates []tls.Certificate
+	// Certificate authorities trusted instead of the system ones, e.g. that of a TLS intercepting egress proxy
+	RootCAs *x509.CertPool
+}
+
+func newHTTPClient(options *Options) *http.Client {
+	transport := newHTTPTransport(options)
+	if len(options.AdditionalHeaders) > 0 {
+		headers := make(map[string]string, len(options.AdditionalHeaders))
+		for name, value := range options.AdditionalHeaders {
+			headers[name] = value
+		}
+		transport = headerTransport{base: transport, headers: headers}
+	}
+	return &http.Client{Timeout: defaultRequestTimeout, Transport: transport}
+}
+
+// Nil, so that http.DefaultTransport is used, when no option is set
+func newHTTPTransport(options *Options) http.RoundTripper {
+	httpOptions := options.HTTPOptions
+	if httpOptions.Transport != nil {
+		return httpOptions.Transport
+	}
+	if httpOptions.MaxIdleConns == 0 && httpOptions.MaxIdleConnsPerHost == 0 && httpOptions.IdleConnTimeout == 0 &&
+		len(httpOptions.ClientCertificates) == 0 && httpOptions.RootCAs == nil && options.UnixSocketPath == "" {
+		return nil
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if options.UnixSocketPath != "" {
+		// The proxy receives the requests as they are, so the host of their URL still routes them
+		socketPath := options.UnixSocketPath
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socketPath)
+		}
+	}
+	if len(httpOptions.ClientCertificates) > 0 || httpOptions.RootCAs != nil {
+		transport.TLSClientConfig = &tls.Config{
+			Certificates: httpOptions.ClientCertificates,
+			RootCAs:      httpOptions.RootCAs,
+		}
+	}
+	if httpOptions.MaxIdleConns > 0 {
+		transport.MaxIdleConns = httpOptions.MaxIdleConns
+	}
+	if httpOptions.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = httpOptions.MaxIdleConnsPerHost
+	}
+	if httpOptions.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = httpOptions.IdleConnTimeout
+	}
+	return transport
+}
+
+// Adds Options.AdditionalHeaders to every request, without replacing the headers the SDK sets itself
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]string
+}
+
+func (t headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the request it is given
+	req = req.Clone(req.Context())
+	for name, value := range t.headers {
+		if req.Header.Get(name) == "" {
+			req.Header.Set(name, value)
+		}
+	}
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
@@ -0,0 +1,229 @@
+package statsig
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPOptions(t *testing.T) {
+	var connections int32
+	testServer := httptest.NewUnstartedServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		_, _ = res.Write([]byte("{}"))
+	}))
+	testServer.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	testServer.Start()
+	defer testServer.Close()
+
+	t.Run("keeps the default transport without options", func(t *testing.T) {
+		if newHTTPClient(&Options{}).Transport != nil {
+			t.Errorf("Expected http.DefaultTransport to be used")
+		}
+	})
+
+	t.Run("tunes the connection pool", func(t *testing.T) {
+		transport := newHTTPClient(&Options{HTTPOptions: HTTPOptions{MaxIdleConnsPerHost: 50, IdleConnTimeout: time.Minute}}).Transport.(*http.Transport)
+		if transport.MaxIdleConnsPerHost != 50 || transport.IdleConnTimeout != time.Minute || transport.MaxIdleConns != 100 {
+			t.Errorf("Expected the pool options on top of the defaults, got %d, %d, %s",
+				transport.MaxIdleConns, transport.MaxIdleConnsPerHost, transport.IdleConnTimeout)
+		}
+	})
+
+	t.Run("reuses connections of a preconfigured transport", func(t *testing.T) {
+		atomic.StoreInt32(&connections, 0)
+		var dials int32
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				atomic.AddInt32(&dials, 1)
+				return (&net.Dialer{}).DialContext(ctx, network, addr)
+			},
+		}
+		tr := newTransport("secret-key", &Options{API: testServer.URL, HTTPOptions: HTTPOptions{Transport: transport}})
+		for i := 0; i < 5; i++ {
+			var out map[string]interface{}
+			if _, err := tr.postRequest("/log_event", map[string]interface{}{}, &out); err != nil {
+				t.Fatalf("Expected the request to succeed, got %s", err)
+			}
+		}
+		if atomic.LoadInt32(&dials) != 1 || atomic.LoadInt32(&connections) != 1 {
+			t.Errorf("Expected one connection through the transport, got %d dials and %d connections", dials, connections)
+		}
+	})
+}
+
+func TestUnixSocketPath(t *testing.T) {
+	dir, err := os.MkdirTemp("", "statsig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socketPath := filepath.Join(dir, "proxy.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Skipf("Unix domain sockets are not supported: %s", err)
+	}
+	var hosts sync.Map
+	server := &http.Server{Handler: http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		hosts.Store(req.Host, true)
+		if strings.Contains(req.URL.Path, "download_config_specs") {
+			bytes, _ := os.ReadFile("download_config_specs.json")
+			_, _ = res.Write(bytes)
+			return
+		}
+		_, _ = res.Write([]byte("{}"))
+	})}
+	go func() { _ = server.Serve(listener) }()
+	defer server.Close()
+
+	c := NewClientWithOptions("secret-key", &Options{
+		API:                  "http://statsig-proxy/v1",
+		UnixSocketPath:       socketPath,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+	if !c.CheckGate(User{UserID: "123"}, "always_on_gate") {
+		t.Errorf("Expected the rulesets to be downloaded over the socket")
+	}
+	if _, ok := hosts.Load("statsig-proxy"); !ok {
+		t.Errorf("Expected the proxy to receive the host of the API")
+	}
+}
+
+func TestAdditionalHeaders(t *testing.T) {
+	var mu sync.Mutex
+	headers := make(map[string]http.Header)
+	var testServer *httptest.Server
+	testServer = httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		headers[req.URL.Path] = req.Header.Clone()
+		mu.Unlock()
+		switch {
+		case strings.Contains(req.URL.Path, "download_config_specs"):
+			bytes, _ := os.ReadFile("download_config_specs.json")
+			_, _ = res.Write(bytes)
+		case strings.Contains(req.URL.Path, "get_id_lists"):
+			_, _ = fmt.Fprintf(res, `{"my_id_list": {"name": "my_id_list", "size": 5, "url": "%s/my_id_list", "creationTime": 1, "fileID": "file"}}`, testServer.URL)
+		case strings.Contains(req.URL.Path, "my_id_list"):
+			_, _ = res.Write([]byte("+abc\n"))
+		default:
+			_, _ = res.Write([]byte("{}"))
+		}
+	}))
+	defer testServer.Close()
+
+	options := &Options{
+		API:                  testServer.URL,
+		AdditionalHeaders:    map[string]string{"X-Gateway-Auth": "token", "STATSIG-API-KEY": "replaced"},
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	}
+	c := NewClientWithOptions("secret-key", options)
+	c.LogEvent(Event{User: User{UserID: "123"}, EventName: "test_event"})
+	c.Shutdown()
+	errorBoundary := newErrorBoundary("secret-key", options, nil)
+	errorBoundary.logException(errors.New("test additional headers"))
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, path := range []string{"/download_config_specs", "/get_id_lists", "/my_id_list", "/log_event", "/sdk_exception"} {
+		header, ok := headers[path]
+		if !ok {
+			t.Errorf("Expected a request to %s, got %v", path, headers)
+			continue
+		}
+		if header.Get("X-Gateway-Auth") != "token" {
+			t.Errorf("Expected the additional header on %s", path)
+		}
+		if path != "/my_id_list" && header.Get("STATSIG-API-KEY") != "secret-key" {
+			t.Errorf("Expected the SDK key not to be replaced on %s, got %s", path, header.Get("STATSIG-API-KEY"))
+		}
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	clientCertificate, clientCAs := newTestClientCertificate(t)
+	testServer := httptest.NewUnstartedServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "download_config_specs") {
+			bytes, _ := os.ReadFile("download_config_specs.json")
+			_, _ = res.Write(bytes)
+			return
+		}
+		_, _ = res.Write([]byte("{}"))
+	}))
+	testServer.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	testServer.StartTLS()
+	defer testServer.Close()
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(testServer.Certificate())
+
+	t.Run("presents the client certificate", func(t *testing.T) {
+		c := NewClient("secret-key",
+			WithAPI(testServer.URL),
+			WithClientCertificate(clientCertificate, rootCAs),
+			WithOutputLoggerOptions(getOutputLoggerOptionsForTest(t)),
+			WithStatsigLoggerOptions(getStatsigLoggerOptionsForTest(t)),
+		)
+		defer c.Shutdown()
+		if !c.CheckGate(User{UserID: "123"}, "always_on_gate") {
+			t.Errorf("Expected the rulesets to be downloaded over mutual TLS")
+		}
+	})
+
+	t.Run("fails without a client certificate", func(t *testing.T) {
+		tr := newTransport("secret-key", &Options{API: testServer.URL, HTTPOptions: HTTPOptions{RootCAs: rootCAs}})
+		var out map[string]interface{}
+		if _, err := tr.postRequest("/download_config_specs", map[string]interface{}{}, &out); err == nil {
+			t.Errorf("Expected the server to reject the request")
+		}
+	})
+}
+
+func newTestClientCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "statsig-test-client"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(certificate)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: certificate}, pool
+}
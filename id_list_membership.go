@@ -0,0 +1,35 @@
+package statsig
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"sort"
+)
+
+// The form IDs are stored in ID lists: the first 8 characters of their base64 encoded SHA-256
+func getIDListEntry(id string) string {
+	h := sha256.Sum256([]byte(id))
+	return base64.StdEncoding.EncodeToString(h[:])[:8]
+}
+
+// Whether the ID of the user of the given type is in the ID list, as in_segment_list conditions check it
+func (e *evaluator) isUserInIDList(user User, name string, idType string) bool {
+	list := e.store.getIDList(name)
+	if list == nil {
+		return false
+	}
+	id := getUnitID(user, idType)
+	return id != "" && list.contains(getIDListEntry(id))
+}
+
+// The names of the synced ID lists, sorted
+func (s *store) getIDListNames() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.idLists))
+	for name := range s.idLists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,76 @@
+package statsig
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIsUserInIDList(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+		switch {
+		case strings.Contains(req.URL.Path, "download_config_specs"):
+			_, _ = res.Write(bytes)
+		case strings.Contains(req.URL.Path, "get_id_lists"):
+			baseURL := "http://" + req.Host
+			lists, _ := json.Marshal(map[string]idList{
+				"employees":      {Name: "employees", Size: 20, URL: baseURL + "/employees", CreationTime: 1, FileID: "file_1"},
+				"beta_companies": {Name: "beta_companies", Size: 10, URL: baseURL + "/beta_companies", CreationTime: 1, FileID: "file_2"},
+			})
+			_, _ = res.Write(lists)
+		case strings.Contains(req.URL.Path, "/employees"):
+			_, _ = res.Write([]byte("+" + getIDListEntry("user-1") + "\n+" + getIDListEntry("user-2") + "\n"))
+		case strings.Contains(req.URL.Path, "/beta_companies"):
+			_, _ = res.Write([]byte("+" + getIDListEntry("company-9") + "\n"))
+		}
+	}))
+	defer testServer.Close()
+
+	c := NewClientWithOptions("secret-key", &Options{
+		API:                  testServer.URL,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+
+	if names := c.GetIDListNames(); !reflect.DeepEqual(names, []string{"beta_companies", "employees"}) {
+		t.Errorf("Expected the synced ID list names, got %v", names)
+	}
+	if !c.IsUserInIDList(User{UserID: "user-1"}, "employees", "userID") {
+		t.Errorf("Expected user-1 to be in employees")
+	}
+	if c.IsUserInIDList(User{UserID: "user-3"}, "employees", "userID") {
+		t.Errorf("Expected user-3 not to be in employees")
+	}
+	user := User{UserID: "user-3", CustomIDs: map[string]string{"companyID": "company-9"}}
+	if !c.IsUserInIDList(user, "beta_companies", "companyID") {
+		t.Errorf("Expected the custom ID to be checked")
+	}
+	if c.IsUserInIDList(user, "beta_companies", "userID") || c.IsUserInIDList(user, "beta_companies", "teamID") {
+		t.Errorf("Expected only the ID of the given type to be checked")
+	}
+	if c.IsUserInIDList(User{UserID: "user-1"}, "unknown_list", "userID") {
+		t.Errorf("Expected unknown lists to contain no one")
+	}
+	if c.IsUserInIDList(User{}, "employees", "userID") {
+		t.Errorf("Expected a user without IDs to be in no list")
+	}
+}
+
+func TestGetIDListNamesWithoutIDLists(t *testing.T) {
+	c := NewClientWithOptions("secret-key", &Options{
+		LocalMode:            true,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+	if names := c.GetIDListNames(); names == nil || len(names) != 0 {
+		t.Errorf("Expected no ID list names, got %v", names)
+	}
+}
@@ -0,0 +1,495 @@
+package statsig
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Where the IDs of ID lists are kept. By default they live in the heap, which large segments can exhaust.
+type IDListStorageOptions struct {
+	// Keeps the IDs of each list in sorted, memory-mapped files in this directory instead of the heap, and
+	// checks membership with a binary search. Downloads are streamed to disk and sorted in bounded chunks, so
+	// lists of any size stay out of the heap. The files are unlinked once mapped, so nothing is left behind.
+	// Only supported on Linux and the BSDs, including macOS, and ignored elsewhere. ID lists are kept in the
+	// heap when empty.
+	MemoryMappedDir string
+}
+
+// The IDs of an ID list, safe for concurrent use
+type idListStorage interface {
+	contains(id string) bool
+	// Starts a batch of changes. Memory-mapped storage only shows them once they are committed.
+	update() idListUpdate
+	ids() []string
+}
+
+// Changes to an ID list, in order, so the last change to an ID decides whether it is in the list.
+// The ID slices are not kept, so they may be reused once add or remove returns.
+type idListUpdate interface {
+	add(id []byte)
+	remove(id []byte)
+	commit() error
+	// Discards the changes that are not visible yet
+	abort()
+}
+
+func (s *store) newIDListStorage() idListStorage {
+	dir := s.transport.options.IDListStorageOptions.MemoryMappedDir
+	if dir == "" || !mmapSupported {
+		return &memoryIDListStorage{}
+	}
+	return newMmapIDListStorage(dir)
+}
+
+// Applies the lines of an ID list file, "+" followed by an ID to add it and "-" to remove it, as they are read
+func applyIDListLines(r io.Reader, update idListUpdate) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) <= 1 {
+			continue
+		}
+		switch line[0] {
+		case '+':
+			update.add(line[1:])
+		case '-':
+			update.remove(line[1:])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		update.abort()
+		return err
+	}
+	return update.commit()
+}
+
+type memoryIDListStorage struct {
+	entries sync.Map
+}
+
+func (m *memoryIDListStorage) contains(id string) bool {
+	_, ok := m.entries.Load(id)
+	return ok
+}
+
+func (m *memoryIDListStorage) update() idListUpdate {
+	return memoryIDListUpdate{storage: m}
+}
+
+func (m *memoryIDListStorage) ids() []string {
+	ids := make([]string, 0)
+	m.entries.Range(func(key, _ interface{}) bool {
+		ids = append(ids, key.(string))
+		return true
+	})
+	return ids
+}
+
+// Applies each change right away, as the entries are safe for concurrent use
+type memoryIDListUpdate struct {
+	storage *memoryIDListStorage
+}
+
+func (u memoryIDListUpdate) add(id []byte) {
+	u.storage.entries.Store(string(id), true)
+}
+
+func (u memoryIDListUpdate) remove(id []byte) {
+	u.storage.entries.Delete(string(id))
+}
+
+func (u memoryIDListUpdate) commit() error {
+	return nil
+}
+
+func (u memoryIDListUpdate) abort() {}
+
+// ID lists hold the first 8 characters of the base64 SHA-256 of each ID. The IDs are kept in segments,
+// memory-mapped files of records sorted by ID: the ID followed by '+' when it was added or '-' when it was
+// removed. Each sync writes its changes to a new segment, so the IDs already stored are not rewritten, and
+// lookups go from the newest segment to the oldest. IDs of any other length, which only come from data
+// adapters, stay in the heap.
+const (
+	mmapIDListIDSize     = 8
+	mmapIDListRecordSize = mmapIDListIDSize + 1
+)
+
+// Changes are sorted in chunks of this many records, spilling each to disk, so a sync of any size takes
+// a bounded amount of heap
+var idListSortChunkRecords = 1 << 20
+
+type idListRecord [mmapIDListRecordSize]byte
+
+func (r *idListRecord) id() []byte {
+	return r[:mmapIDListIDSize]
+}
+
+func (r *idListRecord) added() bool {
+	return r[mmapIDListIDSize] == '+'
+}
+
+type mmapIDListStorage struct {
+	dir string
+	// Held while changes are committed and segments merged
+	applyMu sync.Mutex
+	mu      sync.RWMutex
+	// Oldest first. Only read with mu or applyMu held, since they are unmapped as soon as a merge replaces them.
+	segments [][]byte
+	overflow map[string]bool
+}
+
+func newMmapIDListStorage(dir string) *mmapIDListStorage {
+	storage := &mmapIDListStorage{dir: dir, overflow: make(map[string]bool)}
+	runtime.SetFinalizer(storage, func(storage *mmapIDListStorage) {
+		for _, segment := range storage.segments {
+			_ = munmapFile(segment)
+		}
+	})
+	return storage
+}
+
+func (m *mmapIDListStorage) contains(id string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(id) != mmapIDListIDSize {
+		return m.overflow[id]
+	}
+	for i := len(m.segments) - 1; i >= 0; i-- {
+		segment := m.segments[i]
+		count := len(segment) / mmapIDListRecordSize
+		j := sort.Search(count, func(j int) bool {
+			return string(segment[j*mmapIDListRecordSize:j*mmapIDListRecordSize+mmapIDListIDSize]) >= id
+		})
+		if j < count && string(segment[j*mmapIDListRecordSize:j*mmapIDListRecordSize+mmapIDListIDSize]) == id {
+			return segment[j*mmapIDListRecordSize+mmapIDListIDSize] == '+'
+		}
+	}
+	return false
+}
+
+func (m *mmapIDListStorage) update() idListUpdate {
+	return &mmapIDListUpdate{storage: m, overflow: make(map[string]bool)}
+}
+
+func (m *mmapIDListStorage) ids() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ids := make([]string, 0, len(m.overflow))
+	sources := make([]idListRecordSource, len(m.segments))
+	for i, segment := range m.segments {
+		sources[i] = &segmentRecordSource{data: segment}
+	}
+	mergeIDListRecords(sources, func(record *idListRecord) {
+		if record.added() {
+			ids = append(ids, string(record.id()))
+		}
+	})
+	for id := range m.overflow {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Writes the merged records of the sources to a new segment and maps it. Removals are dropped when
+// there is no older segment they could hide an ID in. Returns nil when no records are left.
+func (m *mmapIDListStorage) writeSegment(sources []idListRecordSource, dropRemovals bool) ([]byte, error) {
+	file, err := os.CreateTemp(m.dir, "statsig-id-list-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ID list file: %w", err)
+	}
+	defer func() {
+		file.Close()
+		os.Remove(file.Name())
+	}()
+	writer := bufio.NewWriter(file)
+	size := 0
+	mergeIDListRecords(sources, func(record *idListRecord) {
+		if dropRemovals && !record.added() {
+			return
+		}
+		_, _ = writer.Write(record[:])
+		size += mmapIDListRecordSize
+	})
+	for _, source := range sources {
+		if err := source.err(); err != nil {
+			return nil, fmt.Errorf("failed to read ID list changes: %w", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to write ID list file: %w", err)
+	}
+	if size == 0 {
+		return nil, nil
+	}
+	segment, err := mmapFile(file, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to map ID list file: %w", err)
+	}
+	return segment, nil
+}
+
+// Merges the newest segment into the one before it while it is at least half as big, so there are only
+// logarithmically many segments and each record is rewritten a logarithmic number of times.
+// Called with applyMu held.
+func (m *mmapIDListStorage) compact() error {
+	for {
+		n := len(m.segments)
+		if n < 2 || len(m.segments[n-1])*2 < len(m.segments[n-2]) {
+			return nil
+		}
+		older, newer := m.segments[n-2], m.segments[n-1]
+		merged, err := m.writeSegment([]idListRecordSource{&segmentRecordSource{data: older}, &segmentRecordSource{data: newer}}, n == 2)
+		if err != nil {
+			return err
+		}
+		segments := append(make([][]byte, 0, n-1), m.segments[:n-2]...)
+		if merged != nil {
+			segments = append(segments, merged)
+		}
+		m.mu.Lock()
+		m.segments = segments
+		m.mu.Unlock()
+		_ = munmapFile(older)
+		_ = munmapFile(newer)
+	}
+}
+
+// Collects changes in sorted chunks, spilled to temporary files as they fill up, which commit merges into a new segment
+type mmapIDListUpdate struct {
+	storage  *mmapIDListStorage
+	chunk    []idListRecord
+	runs     []*os.File
+	overflow map[string]bool
+	err      error
+}
+
+func (u *mmapIDListUpdate) add(id []byte) {
+	u.append(id, '+')
+}
+
+func (u *mmapIDListUpdate) remove(id []byte) {
+	u.append(id, '-')
+}
+
+func (u *mmapIDListUpdate) append(id []byte, op byte) {
+	if len(id) != mmapIDListIDSize {
+		u.overflow[string(id)] = op == '+'
+		return
+	}
+	if u.err != nil {
+		return
+	}
+	var record idListRecord
+	copy(record[:], id)
+	record[mmapIDListIDSize] = op
+	u.chunk = append(u.chunk, record)
+	if len(u.chunk) >= idListSortChunkRecords {
+		u.err = u.spill()
+	}
+}
+
+// Writes the sorted chunk to a temporary file, to be merged on commit
+func (u *mmapIDListUpdate) spill() error {
+	file, err := os.CreateTemp(u.storage.dir, "statsig-id-list-changes-")
+	if err != nil {
+		return fmt.Errorf("failed to create ID list file: %w", err)
+	}
+	u.runs = append(u.runs, file)
+	writer := bufio.NewWriter(file)
+	for _, record := range sortIDListRecords(u.chunk) {
+		_, _ = writer.Write(record[:])
+	}
+	u.chunk = u.chunk[:0]
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write ID list file: %w", err)
+	}
+	return nil
+}
+
+func (u *mmapIDListUpdate) commit() error {
+	defer u.abort()
+	if u.err != nil {
+		return u.err
+	}
+	sources := make([]idListRecordSource, 0, len(u.runs)+1)
+	for _, run := range u.runs {
+		if _, err := run.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to read ID list changes: %w", err)
+		}
+		sources = append(sources, &fileRecordSource{reader: bufio.NewReader(run)})
+	}
+	sources = append(sources, &chunkRecordSource{records: sortIDListRecords(u.chunk)})
+
+	storage := u.storage
+	storage.applyMu.Lock()
+	defer storage.applyMu.Unlock()
+	segment, err := storage.writeSegment(sources, len(storage.segments) == 0)
+	if err != nil {
+		return err
+	}
+	storage.mu.Lock()
+	if segment != nil {
+		storage.segments = append(storage.segments, segment)
+	}
+	for id, add := range u.overflow {
+		if add {
+			storage.overflow[id] = true
+		} else {
+			delete(storage.overflow, id)
+		}
+	}
+	storage.mu.Unlock()
+	return storage.compact()
+}
+
+func (u *mmapIDListUpdate) abort() {
+	for _, run := range u.runs {
+		run.Close()
+		os.Remove(run.Name())
+	}
+	u.runs = nil
+	u.chunk = nil
+}
+
+// Sorts the records by ID in place, keeping only the last record of each ID
+func sortIDListRecords(records []idListRecord) []idListRecord {
+	sort.SliceStable(records, func(i, j int) bool {
+		return bytes.Compare(records[i].id(), records[j].id()) < 0
+	})
+	kept := records[:0]
+	for i := range records {
+		if i+1 < len(records) && bytes.Equal(records[i].id(), records[i+1].id()) {
+			continue
+		}
+		kept = append(kept, records[i])
+	}
+	return kept
+}
+
+// Records sorted by ID, each ID at most once
+type idListRecordSource interface {
+	next(record *idListRecord) bool
+	err() error
+}
+
+type segmentRecordSource struct {
+	data []byte
+}
+
+func (s *segmentRecordSource) next(record *idListRecord) bool {
+	if len(s.data) < mmapIDListRecordSize {
+		return false
+	}
+	copy(record[:], s.data[:mmapIDListRecordSize])
+	s.data = s.data[mmapIDListRecordSize:]
+	return true
+}
+
+func (s *segmentRecordSource) err() error {
+	return nil
+}
+
+type chunkRecordSource struct {
+	records []idListRecord
+}
+
+func (s *chunkRecordSource) next(record *idListRecord) bool {
+	if len(s.records) == 0 {
+		return false
+	}
+	*record = s.records[0]
+	s.records = s.records[1:]
+	return true
+}
+
+func (s *chunkRecordSource) err() error {
+	return nil
+}
+
+type fileRecordSource struct {
+	reader  *bufio.Reader
+	readErr error
+}
+
+func (s *fileRecordSource) next(record *idListRecord) bool {
+	if _, err := io.ReadFull(s.reader, record[:]); err != nil {
+		if err != io.EOF {
+			s.readErr = err
+		}
+		return false
+	}
+	return true
+}
+
+func (s *fileRecordSource) err() error {
+	return s.readErr
+}
+
+type idListMergeHead struct {
+	record idListRecord
+	source int
+}
+
+// Orders the heads by ID, and the heads of newer sources first for the same ID
+type idListMergeHeap []idListMergeHead
+
+func (h idListMergeHeap) Len() int {
+	return len(h)
+}
+
+func (h idListMergeHeap) Less(i, j int) bool {
+	if c := bytes.Compare(h[i].record.id(), h[j].record.id()); c != 0 {
+		return c < 0
+	}
+	return h[i].source > h[j].source
+}
+
+func (h idListMergeHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *idListMergeHeap) Push(x interface{}) {
+	*h = append(*h, x.(idListMergeHead))
+}
+
+func (h *idListMergeHeap) Pop() interface{} {
+	old := *h
+	head := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return head
+}
+
+// Calls write with the records of the sources, oldest source first, in ID order. When several sources
+// hold an ID, only the record of the newest one is written.
+func mergeIDListRecords(sources []idListRecordSource, write func(record *idListRecord)) {
+	h := make(idListMergeHeap, 0, len(sources))
+	for i, source := range sources {
+		head := idListMergeHead{source: i}
+		if source.next(&head.record) {
+			h = append(h, head)
+		}
+	}
+	heap.Init(&h)
+	var last idListRecord
+	written := false
+	for h.Len() > 0 {
+		head := &h[0]
+		if !written || !bytes.Equal(head.record.id(), last.id()) {
+			last = head.record
+			written = true
+			write(&last)
+		}
+		if sources[head.source].next(&head.record) {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package statsig
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMmapIDListStorage(t *testing.T) {
+	if !mmapSupported {
+		t.Skip("Memory-mapped files are not supported on this platform")
+	}
+	defer func(records int) { idListSortChunkRecords = records }(idListSortChunkRecords)
+	// Spills the changes of a round to several sorted files
+	idListSortChunkRecords = 16
+
+	dir := t.TempDir()
+	storage := newMmapIDListStorage(dir)
+	reference := &memoryIDListStorage{}
+	random := rand.New(rand.NewSource(1))
+	for round := 0; round < 20; round++ {
+		var lines strings.Builder
+		for i := 0; i < 50; i++ {
+			id := fmt.Sprintf("%08d", random.Intn(200))
+			if i%10 == 0 {
+				id = strconv.Itoa(random.Intn(20))
+			}
+			op := "+"
+			if random.Intn(3) == 0 {
+				op = "-"
+			}
+			lines.WriteString(op + id + "\n")
+		}
+		if err := applyIDListLines(strings.NewReader(lines.String()), storage.update()); err != nil {
+			t.Fatalf("Failed to apply the changes: %s", err)
+		}
+		_ = applyIDListLines(strings.NewReader(lines.String()), reference.update())
+	}
+
+	expected := reference.ids()
+	actual := storage.ids()
+	sort.Strings(expected)
+	sort.Strings(actual)
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected the IDs %v, got %v", expected, actual)
+	}
+	for i := 0; i < 200; i++ {
+		id := fmt.Sprintf("%08d", i)
+		if storage.contains(id) != reference.contains(id) {
+			t.Errorf("Expected contains(%s) to be %t", id, reference.contains(id))
+		}
+	}
+	if len(storage.segments) > 5 {
+		t.Errorf("Expected the segments to be merged, got %d", len(storage.segments))
+	}
+	if err := storage.update().commit(); err != nil || len(storage.ids()) != len(actual) {
+		t.Errorf("Expected no change without changes")
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Errorf("Expected the sorted files to be removed, got %d files", len(files))
+	}
+}
+
+func TestMmapIDListStorageAbort(t *testing.T) {
+	if !mmapSupported {
+		t.Skip("Memory-mapped files are not supported on this platform")
+	}
+	defer func(records int) { idListSortChunkRecords = records }(idListSortChunkRecords)
+	idListSortChunkRecords = 2
+
+	dir := t.TempDir()
+	storage := newMmapIDListStorage(dir)
+	update := storage.update()
+	for i := 0; i < 5; i++ {
+		update.add([]byte(fmt.Sprintf("%08d", i)))
+	}
+	update.abort()
+	if len(storage.ids()) != 0 || storage.contains("00000001") {
+		t.Errorf("Expected aborted changes not to be applied")
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Errorf("Expected the sorted files to be removed, got %d files", len(files))
+	}
+}
+
+func TestMemoryMappedIDLists(t *testing.T) {
+	if !mmapSupported {
+		t.Skip("Memory-mapped files are not supported on this platform")
+	}
+	hash := func(id string) string {
+		h := sha256.Sum256([]byte(id))
+		return base64.StdEncoding.EncodeToString(h[:])[:8]
+	}
+	var mu sync.Mutex
+	content := fmt.Sprintf("+%s\n+%s\n", hash("abc"), hash("def"))
+	var testServer *httptest.Server
+	testServer = httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case strings.Contains(req.URL.Path, "download_config_specs"):
+			bytes, _ := os.ReadFile("download_config_specs.json")
+			_, _ = res.Write(bytes)
+		case strings.Contains(req.URL.Path, "get_id_lists"):
+			_, _ = fmt.Fprintf(res, `{"list_1": {"name": "list_1", "size": %d, "url": "%s/list_1", "creationTime": 1, "fileID": "file_1"}}`,
+				len(content), testServer.URL)
+		case strings.Contains(req.URL.Path, "list_1"):
+			start, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(req.Header.Get("Range"), "bytes="), "-"))
+			_, _ = res.Write([]byte(content[start:]))
+		default:
+			res.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer testServer.Close()
+
+	dir := t.TempDir()
+	c := NewClientWithOptions("secret-key", &Options{
+		API:                  testServer.URL,
+		IDListStorageOptions: IDListStorageOptions{MemoryMappedDir: dir},
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+	inList := func(userID string) bool {
+		return c.CheckGateWithExposureLoggingDisabled(User{UserID: userID}, "on_for_id_list")
+	}
+	if _, ok := c.evaluator.store.getIDList("list_1").ids.(*mmapIDListStorage); !ok {
+		t.Fatalf("Expected the ID list to be memory-mapped")
+	}
+	if !inList("abc") || !inList("def") || inList("ghi") {
+		t.Errorf("Expected abc and def to be in the list")
+	}
+
+	mu.Lock()
+	content += fmt.Sprintf("-%s\n+%s\n", hash("def"), hash("ghi"))
+	mu.Unlock()
+	c.evaluator.store.syncIDLists()
+	if !inList("abc") || inList("def") || !inList("ghi") {
+		t.Errorf("Expected the changes to the list to be applied")
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Errorf("Expected the mapped files to be unlinked, got %d files", len(files))
+	}
+}
@@ -0,0 +1,64 @@
+package statsig
+
+import (
+	"context"
+	"time"
+)
+
+// How the store loads its first rulesets and ID lists
+type initOptions struct {
+	// Cancelling it aborts the initial requests. Nil means context.Background().
+	ctx    context.Context
+	budget time.Duration
+	// Load in the background instead of before the store is returned
+	background bool
+}
+
+// Bounds the total time spent initializing the store. Nil means no budget.
+type initBudget struct {
+	deadline time.Time
+}
+
+func newInitBudget(budget time.Duration) *initBudget {
+	if budget <= 0 {
+		return nil
+	}
+	return &initBudget{deadline: time.Now().Add(budget)}
+}
+
+// Runs fn and waits for it for at most the remaining budget, or until ctx is done.
+// Returns false if fn is still running in the background when either happens.
+func (b *initBudget) wait(ctx context.Context, fn func()) bool {
+	if b == nil && ctx.Done() == nil {
+		fn()
+		return true
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	var timeout <-chan time.Time
+	if b != nil {
+		remaining := time.Until(b.deadline)
+		if remaining <= 0 {
+			select {
+			case <-done:
+				return true
+			default:
+				return false
+			}
+		}
+		timer := time.NewTimer(remaining)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-done:
+		return true
+	case <-timeout:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
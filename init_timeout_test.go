@@ -1,9 +1,13 @@
 package statsig
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -45,6 +49,8 @@ func TestInitTimeout(t *testing.T) {
 		options := &Options{
 			API:                  testServer.URL,
 			InitTimeout:          5 * time.Second,
+			UAParserOptions:      UAParserOptions{Disabled: true},
+			CountryLookupOptions: CountryLookupOptions{Disabled: true},
 			OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
 			StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
 		}
@@ -70,6 +76,8 @@ func TestInitTimeout(t *testing.T) {
 		options := &Options{
 			API:                  testServer.URL,
 			InitTimeout:          100 * time.Millisecond,
+			UAParserOptions:      UAParserOptions{Disabled: true},
+			CountryLookupOptions: CountryLookupOptions{Disabled: true},
 			OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
 			StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
 		}
@@ -80,11 +88,239 @@ func TestInitTimeout(t *testing.T) {
 			t.Errorf("Initalize exceeded timeout %s", elapsed)
 		}
 		defer func() {
-			if err := recover(); err == nil {
-				t.Errorf("Expected initialize to fail")
+			if err := recover(); err != nil {
+				t.Errorf("Expected initialize to fall back to an uninitialized client")
 			}
 		}()
 		CheckGate(user, "nonexistent-gate")
 		ShutdownAndDangerouslyClearInstance()
 	})
 }
+
+func TestInitTimeoutContinuesInBackground(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "download_config_specs") {
+			time.Sleep(200 * time.Millisecond)
+			bytes, _ := os.ReadFile("download_config_specs.json")
+			res.WriteHeader(http.StatusOK)
+			_, _ = res.Write(bytes)
+			return
+		}
+		res.WriteHeader(http.StatusOK)
+		_, _ = res.Write([]byte("{}"))
+	}))
+	defer testServer.Close()
+
+	options := &Options{
+		API:                  testServer.URL,
+		InitTimeout:          50 * time.Millisecond,
+		UAParserOptions:      UAParserOptions{Disabled: true},
+		CountryLookupOptions: CountryLookupOptions{Disabled: true},
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	}
+	start := time.Now()
+	InitializeWithOptions("secret-key", options)
+	elapsed := time.Since(start)
+	defer ShutdownAndDangerouslyClearInstance()
+	if elapsed > 150*time.Millisecond {
+		t.Errorf("Expected initialize to return once the timeout ran out, took %s", elapsed)
+	}
+	user := User{UserID: "some_user_id"}
+	res := instance.evaluator.checkGate(user, "always_on_gate")
+	if res.Pass || res.EvaluationDetails.reason != reasonUninitialized {
+		t.Errorf("Expected an uninitialized evaluation before the specs arrive, got %v (%s)", res.Pass, res.EvaluationDetails.reason)
+	}
+	waitForCondition(t, func() bool {
+		return instance.evaluator.checkGate(user, "always_on_gate").EvaluationDetails.reason == reasonNetwork
+	})
+	if !CheckGate(user, "always_on_gate") {
+		t.Errorf("Expected always_on_gate to pass once the specs arrive")
+	}
+}
+
+func TestInitBudget(t *testing.T) {
+	var idListsRequested int32
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "download_config_specs") {
+			time.Sleep(50 * time.Millisecond)
+		}
+		if strings.Contains(req.URL.Path, "get_id_lists") {
+			atomic.StoreInt32(&idListsRequested, 1)
+			time.Sleep(500 * time.Millisecond)
+		}
+		res.WriteHeader(http.StatusOK)
+		_, _ = res.Write([]byte("{}"))
+	}))
+	defer testServer.Close()
+
+	options := &Options{
+		API:                  testServer.URL,
+		InitBudget:           150 * time.Millisecond,
+		UAParserOptions:      UAParserOptions{Disabled: true},
+		CountryLookupOptions: CountryLookupOptions{Disabled: true},
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	}
+	start := time.Now()
+	InitializeWithOptions("secret-key", options)
+	elapsed := time.Since(start)
+	defer ShutdownAndDangerouslyClearInstance()
+	if elapsed < 50*time.Millisecond {
+		t.Errorf("Expected initialize to wait for config specs")
+	}
+	if elapsed > 400*time.Millisecond {
+		t.Errorf("Expected initialize to stop waiting for ID lists once the budget was spent, took %s", elapsed)
+	}
+	// The ID list download continues in the background
+	waitForCondition(t, func() bool {
+		return atomic.LoadInt32(&idListsRequested) == 1
+	})
+	defer func() {
+		if err := recover(); err != nil {
+			t.Errorf("Expected initialize to succeed")
+		}
+	}()
+	CheckGate(User{UserID: "some_user_id"}, "nonexistent-gate")
+}
+
+func TestInitializeAsync(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "download_config_specs") {
+			time.Sleep(200 * time.Millisecond)
+			bytes, _ := os.ReadFile("download_config_specs.json")
+			res.WriteHeader(http.StatusOK)
+			_, _ = res.Write(bytes)
+			return
+		}
+		res.WriteHeader(http.StatusOK)
+		_, _ = res.Write([]byte("{}"))
+	}))
+	defer testServer.Close()
+
+	options := &Options{
+		API:                  testServer.URL,
+		UAParserOptions:      UAParserOptions{Disabled: true},
+		CountryLookupOptions: CountryLookupOptions{Disabled: true},
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	}
+	start := time.Now()
+	initialized := InitializeAsync("secret-key", options)
+	defer ShutdownAndDangerouslyClearInstance()
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Expected InitializeAsync to return without waiting for the network, took %s", elapsed)
+	}
+	user := User{UserID: "some_user_id"}
+	res := instance.evaluator.checkGate(user, "always_on_gate")
+	if res.Pass || res.EvaluationDetails.reason != reasonUninitialized {
+		t.Errorf("Expected an uninitialized evaluation before the specs arrive, got %v (%s)", res.Pass, res.EvaluationDetails.reason)
+	}
+
+	select {
+	case result := <-initialized:
+		if !result.Success || result.Source != string(reasonNetwork) {
+			t.Errorf("Expected initialization from the network to succeed, got %+v", result)
+		}
+		if result.Duration < 200*time.Millisecond {
+			t.Errorf("Expected the duration to include the config download, got %s", result.Duration)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the InitResult")
+	}
+	if !CheckGate(user, "always_on_gate") {
+		t.Errorf("Expected always_on_gate to pass once initialized")
+	}
+}
+
+func TestInitializeWithContext(t *testing.T) {
+	var exceptionsLogged int32
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "download_config_specs") {
+			select {
+			case <-req.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		}
+		if strings.Contains(req.URL.Path, "sdk_exception") {
+			atomic.AddInt32(&exceptionsLogged, 1)
+		}
+		res.WriteHeader(http.StatusOK)
+		_, _ = res.Write([]byte("{}"))
+	}))
+	defer testServer.Close()
+
+	options := &Options{
+		API:                  testServer.URL,
+		UAParserOptions:      UAParserOptions{Disabled: true},
+		CountryLookupOptions: CountryLookupOptions{Disabled: true},
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := InitializeWithContextAndOptions(ctx, "secret-key", options)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected initialize to stop once the context was done, took %s", elapsed)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the context's error, got %v", err)
+	}
+	if IsInitialized() {
+		ShutdownAndDangerouslyClearInstance()
+		t.Errorf("Expected statsig to be left uninitialized")
+	}
+	if atomic.LoadInt32(&exceptionsLogged) != 0 {
+		t.Errorf("Expected the cancelled requests not to be reported as exceptions")
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	options.API = "http://localhost:1" // fails right away
+	if err := InitializeWithContextAndOptions(ctx, "secret-key", options); err != nil {
+		t.Errorf("Expected initialize to succeed when the context is not done, got %v", err)
+	}
+	defer ShutdownAndDangerouslyClearInstance()
+	if !IsInitialized() {
+		t.Errorf("Expected statsig to be initialized")
+	}
+}
+
+// Blocks in Initialize until released
+type slowDataAdapterExample struct {
+	cachingDataAdapterExample
+	release chan struct{}
+}
+
+func (d *slowDataAdapterExample) Initialize() {
+	<-d.release
+}
+
+func TestInitializeWithContextSlowDataAdapter(t *testing.T) {
+	adapter := &slowDataAdapterExample{
+		cachingDataAdapterExample: cachingDataAdapterExample{store: make(map[string]string)},
+		release:                   make(chan struct{}),
+	}
+	defer close(adapter.release)
+	options := &Options{
+		API:                  "http://localhost:1",
+		DataAdapter:          adapter,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := InitializeWithContextAndOptions(ctx, "secret-key", options)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected initialize to stop waiting for the DataAdapter once the context was done, took %s", elapsed)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the context's error, got %v", err)
+	}
+	if IsInitialized() {
+		ShutdownAndDangerouslyClearInstance()
+		t.Errorf("Expected statsig to be left uninitialized")
+	}
+}
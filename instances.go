@@ -0,0 +1,67 @@
+package statsig
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Named Clients, for processes that talk to several projects or environments at once
+var instances = struct {
+	clients map[string]*Client
+	mu      sync.RWMutex
+}{clients: make(map[string]*Client)}
+
+// Initializes a Client with the given sdkKey and options and registers it under name. Each instance has its
+// own store, logger and pollers, so instances with different keys or environments run side by side, and
+// independently of the global instance. The output logger is shared by every instance; the first one
+// initialized sets it up. Returns an error if an instance is already registered under name.
+func InitializeInstance(name string, sdkKey string, options *Options) (*Client, error) {
+	if global.Logger() == nil {
+		InitializeGlobalOutputLogger(options.OutputLoggerOptions)
+	}
+	if _, exists := GetInstance(name); exists {
+		return nil, fmt.Errorf("an instance named %q is already initialized", name)
+	}
+	client := NewClientWithOptions(sdkKey, options)
+	instances.mu.Lock()
+	defer instances.mu.Unlock()
+	// Another goroutine may have registered the name while this client initialized
+	if _, exists := instances.clients[name]; exists {
+		client.Shutdown()
+		return nil, fmt.Errorf("an instance named %q is already initialized", name)
+	}
+	instances.clients[name] = client
+	return client, nil
+}
+
+// Gets the Client registered under name
+func GetInstance(name string) (*Client, bool) {
+	instances.mu.RLock()
+	defer instances.mu.RUnlock()
+	client, exists := instances.clients[name]
+	return client, exists
+}
+
+// Names of the registered instances, sorted
+func GetInstanceNames() []string {
+	instances.mu.RLock()
+	defer instances.mu.RUnlock()
+	names := make([]string, 0, len(instances.clients))
+	for name := range instances.clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Shuts down the Client registered under name and unregisters it, so the name can be initialized again
+func ShutdownInstance(name string) {
+	instances.mu.Lock()
+	client, exists := instances.clients[name]
+	delete(instances.clients, name)
+	instances.mu.Unlock()
+	if exists {
+		client.Shutdown()
+	}
+}
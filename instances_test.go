@@ -0,0 +1,91 @@
+package statsig
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestInstances(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	var specs downloadConfigSpecResponse
+	_ = json.Unmarshal(bytes, &specs)
+	for i, gate := range specs.FeatureGates {
+		if gate.Name == "always_on_gate" {
+			specs.FeatureGates[i].Enabled = false
+		}
+	}
+	disabled, _ := json.Marshal(specs)
+
+	newServer := func(dcs []byte, logged *int32) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			res.WriteHeader(http.StatusOK)
+			if strings.Contains(req.URL.Path, "download_config_specs") {
+				_, _ = res.Write(dcs)
+				return
+			}
+			if strings.Contains(req.URL.Path, "log_event") {
+				atomic.AddInt32(logged, 1)
+			}
+			_, _ = res.Write([]byte("{}"))
+		}))
+	}
+	var loggedA, loggedB int32
+	serverA := newServer(bytes, &loggedA)
+	defer serverA.Close()
+	serverB := newServer(disabled, &loggedB)
+	defer serverB.Close()
+	newOptions := func(api string) *Options {
+		return &Options{
+			API:                  api,
+			DisableIDLists:       true,
+			OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+			StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+		}
+	}
+
+	a, err := InitializeInstance("a", "secret-a", newOptions(serverA.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ShutdownInstance("a")
+	b, err := InitializeInstance("b", "secret-b", newOptions(serverB.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ShutdownInstance("b")
+
+	user := User{UserID: "123"}
+	if !a.CheckGate(user, "always_on_gate") || b.CheckGate(user, "always_on_gate") {
+		t.Errorf("Expected each instance to evaluate with its own rulesets")
+	}
+	_ = a.Flush()
+	if atomic.LoadInt32(&loggedA) != 1 || atomic.LoadInt32(&loggedB) != 0 {
+		t.Errorf("Expected events to be sent by the instance that logged them")
+	}
+	if got, exists := GetInstance("b"); !exists || got != b {
+		t.Errorf("Expected to get the instance by name")
+	}
+	if names := GetInstanceNames(); !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Errorf("Expected both instances to be registered, got %v", names)
+	}
+	if IsInitialized() {
+		t.Errorf("Expected named instances not to initialize the global instance")
+	}
+	if _, err := InitializeInstance("a", "secret-a", newOptions(serverA.URL)); err == nil {
+		t.Errorf("Expected an error when the name is taken")
+	}
+
+	ShutdownInstance("a")
+	if _, exists := GetInstance("a"); exists {
+		t.Errorf("Expected a shut down instance to be unregistered")
+	}
+	if !b.CheckGate(User{UserID: "123", Email: "a@statsig.com"}, "on_for_statsig_email") {
+		t.Errorf("Expected the other instance to keep working")
+	}
+}
@@ -0,0 +1,29 @@
+package statsig
+
+import "encoding/json"
+
+// Encodes and decodes the JSON exchanged with Statsig: download_config_specs and get_id_lists responses,
+// logged events, and the values kept in a data adapter. Parsing download_config_specs dominates the CPU
+// time of initialize for big projects, so it can be swapped for a faster drop-in replacement of
+// encoding/json. jsoniter.ConfigCompatibleWithStandardLibrary and sonic.ConfigStd satisfy it as is.
+type JSONCodec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type standardJSONCodec struct{}
+
+func (standardJSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (standardJSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func getJSONCodec(options *Options) JSONCodec {
+	if options.JSONCodec == nil {
+		return standardJSONCodec{}
+	}
+	return options.JSONCodec
+}
@@ -0,0 +1,100 @@
+package statsig
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+type countingJSONCodec struct {
+	marshals   int32
+	unmarshals int32
+}
+
+func (c *countingJSONCodec) Marshal(v interface{}) ([]byte, error) {
+	atomic.AddInt32(&c.marshals, 1)
+	return json.Marshal(v)
+}
+
+func (c *countingJSONCodec) Unmarshal(data []byte, v interface{}) error {
+	atomic.AddInt32(&c.unmarshals, 1)
+	return json.Unmarshal(data, v)
+}
+
+func TestJSONCodec(t *testing.T) {
+	dcs, _ := os.ReadFile("download_config_specs.json")
+	var logged int32
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+		if strings.Contains(req.URL.Path, "download_config_specs") {
+			_, _ = res.Write(dcs)
+			return
+		}
+		if strings.Contains(req.URL.Path, "log_event") {
+			atomic.AddInt32(&logged, 1)
+		}
+		_, _ = res.Write([]byte("{}"))
+	}))
+	defer testServer.Close()
+
+	codec := &countingJSONCodec{}
+	InitializeWithOptions("secret-key", &Options{
+		API:                  testServer.URL,
+		JSONCodec:            codec,
+		DisableIDLists:       true,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer ShutdownAndDangerouslyClearInstance()
+
+	if !CheckGate(User{UserID: "123"}, "always_on_gate") {
+		t.Errorf("Expected the specs parsed by the codec to be used")
+	}
+	unmarshals := atomic.LoadInt32(&codec.unmarshals)
+	if unmarshals == 0 {
+		t.Errorf("Expected download_config_specs to be parsed by the codec")
+	}
+	marshals := atomic.LoadInt32(&codec.marshals)
+	if err := Flush(); err != nil {
+		t.Errorf("Expected flush to succeed, got %v", err)
+	}
+	if atomic.LoadInt32(&logged) != 1 || atomic.LoadInt32(&codec.marshals) <= marshals {
+		t.Errorf("Expected logged events to be serialized by the codec")
+	}
+}
+
+func TestJSONCodecSpoolsAndExportsDiagnostics(t *testing.T) {
+	codec := &countingJSONCodec{}
+	spool, err := newEventSpool(EventSpoolOptions{Dir: t.TempDir()}, codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spool.close()
+	if err := spool.write([]interface{}{Event{EventName: "a"}}); err != nil {
+		t.Fatal(err)
+	}
+	var replayed []interface{}
+	_ = spool.replay(func(events []interface{}) error {
+		replayed = events
+		return nil
+	})
+	if len(replayed) != 1 || atomic.LoadInt32(&codec.marshals) != 1 || atomic.LoadInt32(&codec.unmarshals) != 1 {
+		t.Errorf("Expected spooled batches to be encoded and decoded by the codec")
+	}
+
+	var exported []byte
+	options := &Options{
+		JSONCodec:           codec,
+		DisableNetwork:      true,
+		DiagnosticsCallback: func(context DiagnosticsContext, payload []byte) { exported = payload },
+	}
+	logger := newLogger(newTransport("secret", options), options, nil)
+	logger.exportDiagnostics(InitializeContext, map[string]interface{}{"context": "initialize"})
+	if string(exported) != `{"context":"initialize"}` || atomic.LoadInt32(&codec.marshals) != 2 {
+		t.Errorf("Expected the diagnostics payload to be serialized by the codec, got %s", exported)
+	}
+}
@@ -1,8 +1,12 @@
 package statsig
 
 import (
+	"context"
 	"fmt"
+	"math/rand"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -12,6 +16,11 @@ const (
 	configExposureEventName = "statsig::config_exposure"
 	layerExposureEventName  = "statsig::layer_exposure"
 	diagnosticsEventName    = "statsig::diagnostics"
+	maxDedupedExposures     = 100000
+	// How far before and after the current time LogEventWithTime accepts event times. Times outside
+	// the window are clamped to its nearest edge.
+	maxEventTimeBackfill = 7 * 24 * time.Hour
+	maxEventTimeSkew     = time.Hour
 )
 
 type exposureEvent struct {
@@ -23,6 +32,15 @@ type exposureEvent struct {
 	Time               int64               `json:"time"`
 }
 
+// A custom event whose value is a number, as logged by LogEventValue
+type numericEvent struct {
+	EventName string            `json:"eventName"`
+	User      User              `json:"user"`
+	Value     float64           `json:"value"`
+	Metadata  map[string]string `json:"metadata"`
+	Time      int64             `json:"time"`
+}
+
 type diagnosticsEvent struct {
 	EventName string                 `json:"eventName"`
 	Metadata  map[string]interface{} `json:"metadata"`
@@ -38,21 +56,45 @@ type logEventResponse struct{}
 
 type logContext struct {
 	isManualExposure bool
+	// When set, exposures are collected here rather than queued one at a time, for queueBatch
+	batch *[]interface{}
 }
 
 type logger struct {
-	events      []interface{}
-	transport   *transport
-	tick        *time.Ticker
-	mu          sync.Mutex
-	maxEvents   int
-	diagnostics *diagnostics
-	options     *Options
+	// Updated atomically, so they are first to be 64-bit aligned on 32-bit platforms
+	queueDepth           int64
+	inFlightEvents       int64
+	inFlightBatches      int64
+	enqueueFailures      int64
+	droppedEvents        int64
+	events               []interface{}
+	transport            *transport
+	tick                 *time.Ticker
+	stopped              chan struct{} // closed when the logger closes, ending the background flush
+	mu                   sync.Mutex
+	maxEvents            int
+	configuredMaxEvents  int
+	diagnostics          *diagnostics
+	statsigLoggerOptions StatsigLoggerOptions
+	spool                eventSpooler
+	dedupeWindow         time.Duration
+	dedupedExposures     map[string]time.Time
+	eventSamplingRates   map[string]float64
+	exposureSamplingRate float64
+	// Fraction of diagnostics events to keep. Only applies between 0 and 1, exclusive.
+	diagnosticsSamplingRate float64
+	diagnosticsCallback     func(context DiagnosticsContext, payload []byte)
+	errorCallback           func(err error, context string)
+	eventEnrichmentHook     func(event *Event)
+	exposureInterceptor     func(exposure *Event) bool
+	piiScrubber             piiScrubber
+	clock                   timeSource
+	queueMetricsCallback    func(metrics EventQueueMetrics)
 }
 
 func newLogger(transport *transport, options *Options, diagnostics *diagnostics) *logger {
-	loggingInterval := time.Minute
-	maxEvents := 1000
+	loggingInterval := DefaultLoggingInterval
+	maxEvents := DefaultLoggingMaxBufferSize
 	if options.LoggingInterval > 0 {
 		loggingInterval = options.LoggingInterval
 	}
@@ -60,12 +102,33 @@ func newLogger(transport *transport, options *Options, diagnostics *diagnostics)
 		maxEvents = options.LoggingMaxBufferSize
 	}
 	log := &logger{
-		events:      make([]interface{}, 0),
-		transport:   transport,
-		tick:        time.NewTicker(loggingInterval),
-		maxEvents:   maxEvents,
-		diagnostics: diagnostics,
-		options:     options,
+		events:                  getEventBuffer(maxEvents),
+		transport:               transport,
+		tick:                    time.NewTicker(loggingInterval),
+		stopped:                 make(chan struct{}),
+		maxEvents:               maxEvents,
+		configuredMaxEvents:     maxEvents,
+		diagnostics:             diagnostics,
+		statsigLoggerOptions:    options.StatsigLoggerOptions,
+		dedupeWindow:            options.ExposureDedupeWindow,
+		dedupedExposures:        make(map[string]time.Time),
+		eventSamplingRates:      options.EventSamplingRates,
+		exposureSamplingRate:    options.ExposureSamplingRate,
+		diagnosticsSamplingRate: options.DiagnosticsSamplingRate,
+		diagnosticsCallback:     options.DiagnosticsCallback,
+		errorCallback:           options.ErrorCallback,
+		eventEnrichmentHook:     options.EventEnrichmentHook,
+		exposureInterceptor:     options.ExposureInterceptor,
+		piiScrubber:             newPIIScrubber(options),
+		clock:                   getTimeSource(options),
+		queueMetricsCallback:    options.EventQueueMetricsCallback,
+	}
+	if !options.LocalMode && !options.DisableNetwork {
+		spool, err := newEventSpooler(options, transport.codec)
+		if err != nil {
+			global.Logger().LogError(fmt.Errorf("Failed to set up the event spool, undelivered events will be dropped: %w", err))
+		}
+		log.spool = spool
 	}
 
 	go log.backgroundFlush()
@@ -74,48 +137,305 @@ func newLogger(transport *transport, options *Options, diagnostics *diagnostics)
 }
 
 func (l *logger) backgroundFlush() {
-	for range l.tick.C {
-		l.flush(false)
+	for {
+		select {
+		case <-l.tick.C:
+			l.flush(false)
+		case <-l.stopped:
+			return
+		}
+	}
+}
+
+// Stops the background flush. Called with l.mu held.
+func (l *logger) stopBackgroundFlush() {
+	l.tick.Stop()
+	select {
+	case <-l.stopped:
+	default:
+		close(l.stopped)
 	}
 }
 
 func (l *logger) logCustom(evt Event) {
-	evt.User.PrivateAttributes = nil
+	if evt, keep := l.prepareCustom(evt); keep {
+		l.logInternal(evt)
+	}
+}
+
+// Logs a custom event with a numeric value. The EventEnrichmentHook sees the value formatted as a string.
+func (l *logger) logCustomValue(evt Event, value float64) {
+	evt.Value = strconv.FormatFloat(value, 'f', -1, 64)
+	if evt, keep := l.prepareCustom(evt); keep {
+		l.logInternal(numericEvent{
+			EventName: evt.EventName,
+			User:      evt.User,
+			Value:     value,
+			Metadata:  evt.Metadata,
+			Time:      evt.Time,
+		})
+	}
+}
+
+// Converts a caller-supplied event time to unix millis within [now - maxEventTimeBackfill, now + maxEventTimeSkew].
+// The zero time is the current time.
+func (l *logger) clampEventTime(eventTime time.Time) int64 {
+	now := l.clock.nowUnixMilli()
+	if eventTime.IsZero() {
+		return now
+	}
+	millis := eventTime.UnixNano() / int64(time.Millisecond)
+	if earliest := now - int64(maxEventTimeBackfill/time.Millisecond); millis < earliest {
+		return earliest
+	}
+	if latest := now + int64(maxEventTimeSkew/time.Millisecond); millis > latest {
+		return latest
+	}
+	return millis
+}
+
+// Timestamps, samples, enriches and scrubs a custom event. Returns false when it is sampled out.
+func (l *logger) prepareCustom(evt Event) (Event, bool) {
 	if evt.Time == 0 {
-		evt.Time = getUnixMilli()
+		evt.Time = l.clock.nowUnixMilli()
 	}
-	l.logInternal(evt)
+	rate, sampled := l.getSamplingRate(evt.EventName, false)
+	if sampled {
+		if !shouldKeepSample(rate) {
+			return evt, false
+		}
+		evt.Metadata = copyMetadataWithSamplingRate(evt.Metadata, rate)
+	} else if l.eventEnrichmentHook != nil {
+		// The hook may add to the metadata, which belongs to the caller
+		evt.Metadata = copyMetadata(evt.Metadata)
+	}
+	l.enrichEvent(&evt)
+	evt.User.PrivateAttributes = nil
+	evt.User = l.piiScrubber.scrubUser(evt.User)
+	evt.Metadata = l.piiScrubber.scrubMetadata(evt.Metadata, false)
+	return evt, true
 }
 
 func (l *logger) logExposureWithEvaluationDetails(
 	evt *exposureEvent,
 	evalDetails *evaluationDetails,
+	context *logContext,
 ) {
+	if l.isDuplicateExposure(evt) {
+		releaseExposureMetadata(evt.Metadata)
+		return
+	}
+	if evt.Metadata["isManualExposure"] != "true" {
+		rate, sampled := l.getSamplingRate(evt.EventName, true)
+		if sampled {
+			if !shouldKeepSample(rate) {
+				releaseExposureMetadata(evt.Metadata)
+				return
+			}
+			evt.Metadata["samplingRate"] = strconv.FormatFloat(rate, 'f', -1, 64)
+		}
+	}
 	if evalDetails != nil {
 		evt.Metadata["reason"] = string(evalDetails.reason)
 		evt.Metadata["configSyncTime"] = fmt.Sprint(evalDetails.configSyncTime)
 		evt.Metadata["initTime"] = fmt.Sprint(evalDetails.initTime)
 		evt.Metadata["serverTime"] = fmt.Sprint(evalDetails.serverTime)
 	}
-	l.logExposure(*evt)
+	l.logExposure(*evt, context)
 }
 
-func (l *logger) logExposure(evt exposureEvent) {
-	evt.User.PrivateAttributes = nil
+func (l *logger) logExposure(evt exposureEvent, context *logContext) {
 	if evt.Time == 0 {
-		evt.Time = getUnixMilli()
+		evt.Time = l.clock.nowUnixMilli()
+	}
+	if l.eventEnrichmentHook != nil || l.exposureInterceptor != nil {
+		event := Event{EventName: evt.EventName, User: evt.User, Value: evt.Value, Metadata: evt.Metadata, Time: evt.Time}
+		l.enrichEvent(&event)
+		if !l.interceptExposure(&event) {
+			releaseExposureMetadata(evt.Metadata)
+			return
+		}
+		metadata := reclaimExposureMetadata(evt.Metadata, event.Metadata)
+		evt.EventName, evt.User, evt.Value, evt.Metadata, evt.Time = event.EventName, event.User, event.Value, metadata, event.Time
+	}
+	evt.User.PrivateAttributes = nil
+	evt.User = l.piiScrubber.scrubUser(evt.User)
+	// Exposure metadata belongs to the SDK once reclaimed
+	evt.Metadata = l.piiScrubber.scrubMetadata(evt.Metadata, true)
+	if context != nil && context.batch != nil {
+		*context.batch = append(*context.batch, evt)
+		return
 	}
 	l.logInternal(evt)
 }
 
+// Reports whether the exposure should be queued. Exposures are kept when the interceptor panics.
+func (l *logger) interceptExposure(exposure *Event) (keep bool) {
+	if l.exposureInterceptor == nil {
+		return true
+	}
+	defer func() {
+		if err := recover(); err != nil {
+			logRecoveredPanic("exposure interceptor", err)
+			keep = true
+		}
+	}()
+	return l.exposureInterceptor(exposure)
+}
+
+func (l *logger) enrichEvent(evt *Event) {
+	if l.eventEnrichmentHook == nil {
+		return
+	}
+	defer func() {
+		if err := recover(); err != nil {
+			logRecoveredPanic("event enrichment hook", err)
+		}
+	}()
+	l.eventEnrichmentHook(evt)
+}
+
+// Returns the fraction of events with the given name to keep, and whether sampling applies at all.
+// A rate set for the event name takes precedence over the exposure sampling rate.
+func (l *logger) getSamplingRate(eventName string, isExposure bool) (float64, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if rate, exists := l.eventSamplingRates[eventName]; exists && rate < 1 {
+		return rate, true
+	}
+	if isExposure && l.exposureSamplingRate > 0 && l.exposureSamplingRate < 1 {
+		return l.exposureSamplingRate, true
+	}
+	return 1, false
+}
+
+func shouldKeepSample(rate float64) bool {
+	return rate > 0 && rand.Float64() < rate
+}
+
+// Custom event metadata belongs to the caller, so it is copied before the sampling rate is added
+func copyMetadataWithSamplingRate(metadata map[string]string, rate float64) map[string]string {
+	copied := copyMetadata(metadata)
+	copied["samplingRate"] = strconv.FormatFloat(rate, 'f', -1, 64)
+	return copied
+}
+
+func copyMetadata(metadata map[string]string) map[string]string {
+	copied := make(map[string]string, len(metadata)+1)
+	for k, v := range metadata {
+		copied[k] = v
+	}
+	return copied
+}
+
+// Reports whether an identical exposure was logged within the dedupe window.
+// Manual exposures are never deduplicated.
+func (l *logger) isDuplicateExposure(evt *exposureEvent) bool {
+	if l.dedupeWindow <= 0 || evt.Metadata["isManualExposure"] == "true" {
+		return false
+	}
+	key := getExposureDedupeKey(evt)
+	// time.Now carries a monotonic reading, so the window is unaffected by system clock jumps
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if expiry, exists := l.dedupedExposures[key]; exists && now.Before(expiry) {
+		return true
+	}
+	if len(l.dedupedExposures) >= maxDedupedExposures {
+		l.dedupedExposures = make(map[string]time.Time)
+	}
+	l.dedupedExposures[key] = now.Add(l.dedupeWindow)
+	return false
+}
+
+func getExposureDedupeKey(evt *exposureEvent) string {
+	parts := []string{evt.EventName, evt.User.UserID}
+	customIDs := make([]string, 0, len(evt.User.CustomIDs))
+	for idType, id := range evt.User.CustomIDs {
+		customIDs = append(customIDs, idType+"="+id)
+	}
+	sort.Strings(customIDs)
+	parts = append(parts, customIDs...)
+	for _, field := range []string{"gate", "config", "ruleID", "gateValue", "parameterName", "allocatedExperiment"} {
+		parts = append(parts, evt.Metadata[field])
+	}
+	return strings.Join(parts, "|")
+}
+
+// Forgets the exposures logged within the dedupe window, for memory pressure. They may be logged again.
+func (l *logger) clearDedupedExposures() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.dedupedExposures = make(map[string]time.Time)
+}
+
+// Drops dedupe entries whose window has passed
+func (l *logger) pruneDedupedExposures() {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, expiry := range l.dedupedExposures {
+		if !now.Before(expiry) {
+			delete(l.dedupedExposures, key)
+		}
+	}
+}
+
 func (l *logger) logInternal(evt interface{}) {
 	l.mu.Lock()
+	if l.isClosed() {
+		l.mu.Unlock()
+		l.rejectEvents([]interface{}{evt})
+		return
+	}
 	defer l.mu.Unlock()
 
 	l.events = append(l.events, evt)
 	if len(l.events) >= l.maxEvents {
 		l.flushInternal(false)
 	}
+	l.setQueueDepth(len(l.events))
+}
+
+// Queues the events at once, e.g. the exposures of a batch of evaluations
+func (l *logger) queueBatch(evts []interface{}) {
+	if len(evts) == 0 {
+		return
+	}
+	l.mu.Lock()
+	if l.isClosed() {
+		l.mu.Unlock()
+		l.rejectEvents(evts)
+		return
+	}
+	defer l.mu.Unlock()
+
+	l.events = append(l.events, evts...)
+	if len(l.events) >= l.maxEvents {
+		l.flushInternal(false)
+	}
+	l.setQueueDepth(len(l.events))
+}
+
+// Whether the logger was closed by Shutdown, after which events are no longer flushed. Called with l.mu held.
+func (l *logger) isClosed() bool {
+	select {
+	case <-l.stopped:
+		return true
+	default:
+		return false
+	}
+}
+
+// Discards events that can no longer be queued, returning their exposure metadata to the pool
+func (l *logger) rejectEvents(evts []interface{}) {
+	for _, evt := range evts {
+		if exposure, ok := evt.(exposureEvent); ok {
+			releaseExposureMetadata(exposure.Metadata)
+		}
+	}
+	l.recordEnqueueFailures(len(evts))
 }
 
 func (l *logger) logGateExposure(
@@ -127,21 +447,20 @@ func (l *logger) logGateExposure(
 	evalDetails *evaluationDetails,
 	context *logContext,
 ) {
-	metadata := map[string]string{
-		"gate":      gateName,
-		"gateValue": strconv.FormatBool(value),
-		"ruleID":    ruleID,
-	}
+	metadata := getExposureMetadata()
+	metadata["gate"] = gateName
+	metadata["gateValue"] = strconv.FormatBool(value)
+	metadata["ruleID"] = ruleID
 	if context != nil && context.isManualExposure {
 		metadata["isManualExposure"] = "true"
 	}
-	evt := &exposureEvent{
-		User:               user,
-		EventName:          gateExposureEventName,
-		Metadata:           metadata,
-		SecondaryExposures: exposures,
-	}
-	l.logExposureWithEvaluationDetails(evt, evalDetails)
+	evt := getExposureEvent()
+	evt.User = user
+	evt.EventName = gateExposureEventName
+	evt.Metadata = metadata
+	evt.SecondaryExposures = exposures
+	l.logExposureWithEvaluationDetails(evt, evalDetails, context)
+	releaseExposureEvent(evt)
 }
 
 func (l *logger) logConfigExposure(
@@ -152,20 +471,19 @@ func (l *logger) logConfigExposure(
 	evalDetails *evaluationDetails,
 	context *logContext,
 ) {
-	metadata := map[string]string{
-		"config": configName,
-		"ruleID": ruleID,
-	}
+	metadata := getExposureMetadata()
+	metadata["config"] = configName
+	metadata["ruleID"] = ruleID
 	if context != nil && context.isManualExposure {
 		metadata["isManualExposure"] = "true"
 	}
-	evt := &exposureEvent{
-		User:               user,
-		EventName:          configExposureEventName,
-		Metadata:           metadata,
-		SecondaryExposures: exposures,
-	}
-	l.logExposureWithEvaluationDetails(evt, evalDetails)
+	evt := getExposureEvent()
+	evt.User = user
+	evt.EventName = configExposureEventName
+	evt.Metadata = metadata
+	evt.SecondaryExposures = exposures
+	l.logExposureWithEvaluationDetails(evt, evalDetails, context)
+	releaseExposureEvent(evt)
 }
 
 func (l *logger) logLayerExposure(
@@ -184,58 +502,216 @@ func (l *logger) logLayerExposure(
 		allocatedExperiment = evalResult.ConfigDelegate
 		exposures = evalResult.SecondaryExposures
 	}
-	metadata := map[string]string{
-		"config":              config.Name,
-		"ruleID":              config.RuleID,
-		"allocatedExperiment": allocatedExperiment,
-		"parameterName":       parameterName,
-		"isExplicitParameter": strconv.FormatBool(isExplicit),
-	}
+	metadata := getExposureMetadata()
+	metadata["config"] = config.Name
+	metadata["ruleID"] = config.RuleID
+	metadata["allocatedExperiment"] = allocatedExperiment
+	metadata["parameterName"] = parameterName
+	metadata["isExplicitParameter"] = strconv.FormatBool(isExplicit)
 	if context != nil && context.isManualExposure {
 		metadata["isManualExposure"] = "true"
 	}
 
-	evt := &exposureEvent{
-		User:               user,
-		EventName:          layerExposureEventName,
-		Metadata:           metadata,
-		SecondaryExposures: exposures,
+	evt := getExposureEvent()
+	evt.User = user
+	evt.EventName = layerExposureEventName
+	evt.Metadata = metadata
+	evt.SecondaryExposures = exposures
+	l.logExposureWithEvaluationDetails(evt, evalDetails, context)
+	releaseExposureEvent(evt)
+}
+
+// Takes effect on the next tick of the flush interval
+func (l *logger) setLoggingInterval(interval time.Duration) {
+	if interval > 0 {
+		l.tick.Reset(interval)
+	}
+}
+
+func (l *logger) setMaxEvents(maxEvents int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if maxEvents > 0 {
+		l.maxEvents = maxEvents
+		l.configuredMaxEvents = maxEvents
+	}
+}
+
+// Shrinks the event buffer and flushes what is currently queued
+func (l *logger) tightenQueue(divisor int, minEvents int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.maxEvents = l.configuredMaxEvents / divisor
+	if l.maxEvents < minEvents {
+		l.maxEvents = minEvents
+	}
+	l.flushInternal(false)
+}
+
+func (l *logger) restoreQueue() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.maxEvents = l.configuredMaxEvents
+}
+
+// Replaces the sampling rates that are set, see RuntimeOptions
+func (l *logger) setSamplingRates(exposure *float64, events map[string]float64, diagnostics *float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if exposure != nil {
+		l.exposureSamplingRate = *exposure
+	}
+	if events != nil {
+		l.eventSamplingRates = make(map[string]float64, len(events))
+		for name, rate := range events {
+			l.eventSamplingRates[name] = rate
+		}
+	}
+	if diagnostics != nil {
+		l.diagnosticsSamplingRate = *diagnostics
 	}
-	l.logExposureWithEvaluationDetails(evt, evalDetails)
+}
+
+func (l *logger) setStatsigLoggerOptions(options StatsigLoggerOptions) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.statsigLoggerOptions = options
 }
 
 func (l *logger) flush(closing bool) {
 	l.logDiagnosticsEvents(l.diagnostics)
+	l.pruneDedupedExposures()
+	// Deferred first so it runs once l.mu is released. Events sent in the background report once they are delivered.
+	if closing {
+		defer l.reportQueueMetrics()
+	}
 	l.mu.Lock()
 	defer l.mu.Unlock()
-
 	l.flushInternal(closing)
+	if closing && l.spool != nil {
+		l.spool.close()
+	}
 }
 
 func (l *logger) flushInternal(closing bool) {
 	if closing {
-		l.tick.Stop()
+		l.stopBackgroundFlush()
 	}
 	if len(l.events) == 0 {
+		// Batches spooled during an outage are otherwise only retried once there are new events to send
+		if !closing {
+			l.replayPendingSpool()
+		}
 		return
 	}
 
+	l.startSending(len(l.events))
 	if closing {
 		l.sendEvents(l.events)
 	} else {
-		go l.sendEvents(l.events)
+		go func(events []interface{}) {
+			l.sendEvents(events)
+			l.reportQueueMetrics()
+		}(l.events)
 	}
 
-	l.events = make([]interface{}, 0)
+	l.events = getEventBuffer(l.maxEvents)
+	l.setQueueDepth(0)
 }
 
 func (l *logger) sendEvents(events []interface{}) {
+	_ = l.deliverEvents(context.Background(), events)
+	l.finishSending(len(events))
+	releaseEvents(events)
+}
+
+// Sends the events, spooling them to disk if they cannot be delivered.
+// After a successful send, previously spooled events are retried.
+func (l *logger) deliverEvents(ctx context.Context, events []interface{}) error {
+	err := l.sendEventsWithContext(ctx, events)
+	notifyErrorCallback(l.errorCallback, err, ErrorContextFlush)
+	if l.spool == nil {
+		if err != nil {
+			l.recordDroppedEvents(len(events))
+		}
+		return err
+	}
+	if err != nil {
+		if spoolErr := l.spool.write(events); spoolErr != nil {
+			l.recordDroppedEvents(len(events))
+			global.Logger().LogError(fmt.Errorf("Failed to spool undelivered events: %w", spoolErr))
+		}
+		return err
+	}
+	_ = l.replaySpool(ctx)
+	return nil
+}
+
+// Replays spooled batches in the background, if there are any and no replay is already running
+func (l *logger) replayPendingSpool() {
+	if l.spool != nil && l.spool.hasPending() {
+		go func() { _ = l.replaySpool(context.Background()) }()
+	}
+}
+
+func (l *logger) replaySpool(ctx context.Context) error {
+	return l.spool.replay(func(spooled []interface{}) error {
+		return l.sendEventsWithContext(ctx, spooled)
+	})
+}
+
+func (l *logger) sendEventsWithContext(ctx context.Context, events []interface{}) error {
+	ctx, span := l.transport.tracing.start(ctx, "statsig.log_event")
+	defer span.End()
+	span.SetAttribute("statsig.event_count", len(events))
 	input := &logEventInput{
 		Events:          events,
 		StatsigMetadata: l.transport.metadata,
 	}
 	var res logEventResponse
-	_, _ = l.transport.retryablePostRequest("/log_event", input, &res, maxRetries)
+	_, err := l.transport.retryablePostRequestWithContext(ctx, "/log_event", input, &res, maxRetries)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return err
+}
+
+// Sends all queued events, giving up once ctx is done. Stops background flushing when closing.
+// Returns the number of events that could not be delivered.
+func (l *logger) flushWithContext(ctx context.Context, closing bool) (int, error) {
+	l.logDiagnosticsEvents(l.diagnostics)
+	l.mu.Lock()
+	if closing {
+		l.stopBackgroundFlush()
+	}
+	events := l.events
+	l.events = getEventBuffer(l.maxEvents)
+	l.setQueueDepth(0)
+	l.mu.Unlock()
+	if len(events) == 0 {
+		l.reportQueueMetrics()
+		return 0, nil
+	}
+
+	count := len(events)
+	done := make(chan error, 1)
+	l.startSending(count)
+	go func() {
+		err := l.deliverEvents(ctx, events)
+		l.finishSending(count)
+		releaseEvents(events)
+		l.reportQueueMetrics()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return count, err
+		}
+		return 0, nil
+	case <-ctx.Done():
+		return count, ctx.Err()
+	}
 }
 
 func (l *logger) logDiagnosticsEvents(d *diagnostics) {
@@ -245,13 +721,19 @@ func (l *logger) logDiagnosticsEvents(d *diagnostics) {
 }
 
 func (l *logger) logDiagnosticsEvent(d *diagnosticsBase) {
-	if l.options.StatsigLoggerOptions.DisableInitDiagnostics && d.context == InitializeContext {
-		return
-	}
-	if l.options.StatsigLoggerOptions.DisableSyncDiagnostics && d.context == ConfigSyncContext {
+	l.mu.Lock()
+	options := l.statsigLoggerOptions
+	samplingRate := l.diagnosticsSamplingRate
+	l.mu.Unlock()
+	disabled := (options.DisableInitDiagnostics && d.context == InitializeContext) ||
+		(options.DisableSyncDiagnostics && d.context == ConfigSyncContext) ||
+		(options.DisableApiDiagnostics && d.context == ApiCallContext)
+	if disabled && l.diagnosticsCallback == nil {
 		return
 	}
-	if l.options.StatsigLoggerOptions.DisableApiDiagnostics && d.context == ApiCallContext {
+	sampledOut := samplingRate > 0 && samplingRate < 1 && !shouldKeepSample(samplingRate)
+	if sampledOut && l.diagnosticsCallback == nil {
+		d.clearMarkers()
 		return
 	}
 	serialized := d.serializeWithSampling()
@@ -263,11 +745,34 @@ func (l *logger) logDiagnosticsEvent(d *diagnosticsBase) {
 	if !ok || len(markersTyped) == 0 {
 		return
 	}
+	if l.diagnosticsCallback != nil {
+		l.exportDiagnostics(d.context, serialized)
+	}
+	d.clearMarkers()
+	if disabled || sampledOut {
+		return
+	}
+	if samplingRate > 0 && samplingRate < 1 {
+		serialized["samplingRate"] = samplingRate
+	}
 	event := diagnosticsEvent{
 		EventName: diagnosticsEventName,
-		Time:      getUnixMilli(),
+		Time:      l.clock.nowUnixMilli(),
 		Metadata:  serialized,
 	}
-	d.clearMarkers()
 	l.logInternal(event)
 }
+
+func (l *logger) exportDiagnostics(context DiagnosticsContext, serialized map[string]interface{}) {
+	defer func() {
+		if err := recover(); err != nil {
+			logRecoveredPanic("diagnostics callback", err)
+		}
+	}()
+	payload, err := l.transport.codec.Marshal(serialized)
+	if err != nil {
+		global.Logger().LogError(err)
+		return
+	}
+	l.diagnosticsCallback(context, payload)
+}
@@ -1,10 +1,17 @@
 package statsig
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -93,3 +100,350 @@ func TestLog(t *testing.T) {
 		t.Errorf("Config exposure event time not set correctly.")
 	}
 }
+
+func TestFlush(t *testing.T) {
+	var received int32
+	fail := int32(0)
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "log_event") {
+			if atomic.LoadInt32(&fail) == 1 {
+				res.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			atomic.AddInt32(&received, 1)
+		}
+		res.WriteHeader(http.StatusOK)
+		_, _ = res.Write([]byte("{}"))
+	}))
+	defer testServer.Close()
+	options := &Options{
+		API:                  testServer.URL,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	}
+	InitializeWithOptions("secret-key", options)
+	defer ShutdownAndDangerouslyClearInstance()
+
+	LogEvent(Event{EventName: "test_event", User: User{UserID: "123"}})
+	if err := Flush(); err != nil {
+		t.Errorf("Expected flush to succeed, got %v", err)
+	}
+	if atomic.LoadInt32(&received) != 1 {
+		t.Errorf("Expected events to be uploaded before Flush returns")
+	}
+
+	atomic.StoreInt32(&fail, 1)
+	LogEvent(Event{EventName: "test_event", User: User{UserID: "123"}})
+	if err := Flush(); err == nil {
+		t.Errorf("Expected flush to return the upload error")
+	}
+}
+
+func TestExposureDedupe(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {}))
+	defer testServer.Close()
+	opt := &Options{
+		API:                  testServer.URL,
+		ExposureDedupeWindow: time.Minute,
+	}
+	transport := newTransport("secret", opt)
+	logger := newLogger(transport, opt, nil)
+
+	user := User{UserID: "123"}
+	for i := 0; i < 100; i++ {
+		logger.logGateExposure(user, "test_gate", true, "rule_id", nil, nil, nil)
+		logger.logConfigExposure(user, "test_config", "rule_id", nil, nil, nil)
+	}
+	if len(logger.events) != 2 {
+		t.Errorf("Expected repeated exposures to be deduped, got %d events", len(logger.events))
+	}
+
+	logger.logGateExposure(User{UserID: "456"}, "test_gate", true, "rule_id", nil, nil, nil)
+	logger.logGateExposure(user, "test_gate", false, "other_rule", nil, nil, nil)
+	logger.logGateExposure(user, "test_gate", true, "rule_id", nil, nil, &logContext{isManualExposure: true})
+	if len(logger.events) != 5 {
+		t.Errorf("Expected distinct and manual exposures to be logged, got %d events", len(logger.events))
+	}
+
+	for key := range logger.dedupedExposures {
+		logger.dedupedExposures[key] = time.Now().Add(-time.Millisecond)
+	}
+	logger.logGateExposure(user, "test_gate", true, "rule_id", nil, nil, nil)
+	if len(logger.events) != 6 {
+		t.Errorf("Expected exposure to be logged again after the window")
+	}
+}
+
+func TestEventEnrichmentHook(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {}))
+	defer testServer.Close()
+	opt := &Options{
+		API: testServer.URL,
+		EventEnrichmentHook: func(event *Event) {
+			if event.EventName == "panicking_event" {
+				panic("hook failed")
+			}
+			event.Metadata["build"] = "abc123"
+			event.User.PrivateAttributes = map[string]interface{}{"tenant": "acme"}
+		},
+	}
+	transport := newTransport("secret", opt)
+	logger := newLogger(transport, opt, nil)
+
+	metadata := map[string]string{"page": "home"}
+	logger.logCustom(Event{EventName: "custom_event", User: User{UserID: "123"}, Metadata: metadata})
+	logger.logGateExposure(User{UserID: "123"}, "test_gate", true, "rule_id", nil, nil, nil)
+	logger.logCustom(Event{EventName: "panicking_event", User: User{UserID: "123"}})
+	if len(logger.events) != 3 {
+		t.Fatalf("Expected every event to be logged, got %d", len(logger.events))
+	}
+
+	custom := logger.events[0].(Event)
+	if custom.Metadata["build"] != "abc123" || custom.Metadata["page"] != "home" || custom.User.PrivateAttributes != nil {
+		t.Errorf("Expected the custom event to be enriched, got %+v", custom)
+	}
+	if _, exists := metadata["build"]; exists {
+		t.Errorf("Expected the caller's metadata not to be modified")
+	}
+	exposure := logger.events[1].(exposureEvent)
+	if exposure.Metadata["build"] != "abc123" || exposure.Metadata["gate"] != "test_gate" || exposure.User.PrivateAttributes != nil {
+		t.Errorf("Expected the exposure to be enriched, got %+v", exposure)
+	}
+}
+
+func TestExposureInterceptor(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {}))
+	defer testServer.Close()
+	intercepted := make([]string, 0)
+	opt := &Options{
+		API: testServer.URL,
+		ExposureInterceptor: func(exposure *Event) bool {
+			intercepted = append(intercepted, exposure.EventName)
+			switch {
+			case exposure.User.UserID == "panic":
+				panic("interceptor failed")
+			case strings.HasPrefix(exposure.User.UserID, "bot-"):
+				return false
+			}
+			exposure.Metadata["team"] = "growth"
+			return true
+		},
+	}
+	transport := newTransport("secret", opt)
+	logger := newLogger(transport, opt, nil)
+
+	logger.logGateExposure(User{UserID: "bot-1"}, "test_gate", true, "rule_id", nil, nil, nil)
+	logger.logConfigExposure(User{UserID: "123"}, "test_config", "rule_id", nil, nil, nil)
+	logger.logGateExposure(User{UserID: "panic"}, "test_gate", true, "rule_id", nil, nil, nil)
+	logger.logCustom(Event{EventName: "custom_event", User: User{UserID: "bot-1"}})
+	if len(logger.events) != 3 {
+		t.Fatalf("Expected the vetoed exposure to be dropped, got %d events", len(logger.events))
+	}
+	if exposure := logger.events[0].(exposureEvent); exposure.Metadata["team"] != "growth" || exposure.Metadata["config"] != "test_config" {
+		t.Errorf("Expected the exposure to be modified by the interceptor, got %+v", exposure)
+	}
+	if exposure := logger.events[1].(exposureEvent); exposure.User.UserID != "panic" {
+		t.Errorf("Expected the exposure to be kept when the interceptor panics")
+	}
+	if !reflect.DeepEqual(intercepted, []string{gateExposureEventName, configExposureEventName, gateExposureEventName}) {
+		t.Errorf("Expected only exposures to be intercepted, got %v", intercepted)
+	}
+}
+
+func TestExposureHookMetadataIsNotPooled(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {}))
+	defer testServer.Close()
+	owned := map[string]string{"team": "growth"}
+	opt := &Options{
+		API: testServer.URL,
+		EventEnrichmentHook: func(event *Event) {
+			event.Metadata = owned
+		},
+		ExposureInterceptor: func(exposure *Event) bool {
+			return exposure.User.UserID != "bot"
+		},
+	}
+	logger := newLogger(newTransport("secret", opt), opt, nil)
+
+	logger.logGateExposure(User{UserID: "bot"}, "test_gate", true, "rule_id", nil, nil, nil)
+	logger.logGateExposure(User{UserID: "123"}, "test_gate", true, "rule_id", nil, nil, nil)
+	if len(logger.events) != 1 || logger.events[0].(exposureEvent).Metadata["team"] != "growth" {
+		t.Fatalf("Expected the exposure to carry the hook's metadata, got %+v", logger.events)
+	}
+	releaseEvents(logger.events)
+	if !reflect.DeepEqual(owned, map[string]string{"team": "growth"}) {
+		t.Errorf("Expected the hook's map not to be cleared by the pool, got %v", owned)
+	}
+}
+
+func TestEventSampling(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {}))
+	defer testServer.Close()
+	opt := &Options{
+		API:                  testServer.URL,
+		LoggingMaxBufferSize: 10000,
+		EventSamplingRates:   map[string]float64{"dropped_event": 0, "sampled_event": 0.5, "kept_event": 1},
+		ExposureSamplingRate: 0.5,
+	}
+	transport := newTransport("secret", opt)
+	logger := newLogger(transport, opt, nil)
+
+	user := User{UserID: "123"}
+	metadata := map[string]string{"key": "value"}
+	for i := 0; i < 1000; i++ {
+		logger.logCustom(Event{EventName: "dropped_event", User: user})
+		logger.logCustom(Event{EventName: "sampled_event", User: user, Metadata: metadata})
+		logger.logCustom(Event{EventName: "kept_event", User: user})
+		logger.logGateExposure(user, "test_gate", true, "rule_id", nil, nil, nil)
+	}
+	logger.logGateExposure(user, "test_gate", true, "rule_id", nil, nil, &logContext{isManualExposure: true})
+
+	counts := make(map[string]int)
+	for _, evt := range logger.events {
+		switch e := evt.(type) {
+		case Event:
+			counts[e.EventName]++
+			if e.EventName == "sampled_event" && e.Metadata["samplingRate"] != "0.5" {
+				t.Errorf("Expected sampled events to be annotated with the sampling rate")
+			}
+			if e.EventName == "kept_event" && e.Metadata["samplingRate"] != "" {
+				t.Errorf("Expected unsampled events not to be annotated")
+			}
+		case exposureEvent:
+			if e.Metadata["isManualExposure"] == "true" {
+				counts["manual"]++
+				if e.Metadata["samplingRate"] != "" {
+					t.Errorf("Expected manual exposures not to be sampled")
+				}
+				continue
+			}
+			counts[e.EventName]++
+			if e.Metadata["samplingRate"] != "0.5" {
+				t.Errorf("Expected sampled exposures to be annotated with the sampling rate")
+			}
+		}
+	}
+	if counts["dropped_event"] != 0 || counts["kept_event"] != 1000 || counts["manual"] != 1 {
+		t.Errorf("Unexpected event counts %v", counts)
+	}
+	for _, name := range []string{"sampled_event", gateExposureEventName} {
+		if counts[name] < 400 || counts[name] > 600 {
+			t.Errorf("Expected about half of %s to be kept, got %d", name, counts[name])
+		}
+	}
+	if _, exists := metadata["samplingRate"]; exists {
+		t.Errorf("Expected the caller's metadata not to be modified")
+	}
+}
+
+func TestEventPooling(t *testing.T) {
+	var mu sync.Mutex
+	received := make(map[string]string)
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "log_event") {
+			var input struct {
+				Events []exposureEvent `json:"events"`
+			}
+			_ = json.NewDecoder(req.Body).Decode(&input)
+			mu.Lock()
+			for _, evt := range input.Events {
+				received[evt.Metadata["gate"]] = evt.Metadata["gateValue"] + "/" + evt.Metadata["ruleID"]
+			}
+			mu.Unlock()
+		}
+		res.WriteHeader(http.StatusOK)
+		_, _ = res.Write([]byte("{}"))
+	}))
+	defer testServer.Close()
+	opt := &Options{
+		API:                  testServer.URL,
+		LoggingMaxBufferSize: 10,
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	}
+	logger := newLogger(newTransport("secret", opt), opt, newDiagnostics())
+
+	// Metadata maps are recycled between batches, so each delivered event must still carry its own values
+	for i := 0; i < 100; i++ {
+		logger.logGateExposure(User{UserID: "123"}, fmt.Sprintf("gate_%d", i), i%2 == 0, fmt.Sprintf("rule_%d", i), nil, nil, nil)
+	}
+	if _, err := logger.flushWithContext(context.Background(), false); err != nil {
+		t.Fatalf("Expected flush to succeed, got %v", err)
+	}
+	waitForCondition(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 100
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	for i := 0; i < 100; i++ {
+		expected := fmt.Sprintf("%t/rule_%d", i%2 == 0, i)
+		if got := received[fmt.Sprintf("gate_%d", i)]; got != expected {
+			t.Errorf("Expected gate_%d to be delivered with %s, got %s", i, expected, got)
+		}
+	}
+
+	metadata := getExposureMetadata()
+	metadata["gate"] = "a_gate"
+	events := []interface{}{exposureEvent{Metadata: metadata}, Event{EventName: "custom"}}
+	releaseEvents(events)
+	if len(metadata) != 0 || events[0] != nil || events[1] != nil {
+		t.Errorf("Expected released events to be cleared")
+	}
+}
+
+func TestLogEventValue(t *testing.T) {
+	var mu sync.Mutex
+	var events []map[string]interface{}
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "log_event") {
+			var input struct {
+				Events []map[string]interface{} `json:"events"`
+			}
+			_ = json.NewDecoder(req.Body).Decode(&input)
+			mu.Lock()
+			events = append(events, input.Events...)
+			mu.Unlock()
+		}
+		res.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+	c := NewClientWithOptions("secret-key", &Options{
+		API:                  testServer.URL,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+	user := User{UserID: "123"}
+
+	if err := c.LogEventValue(user, "checkout", 42.5, map[string]string{"currency": "USD"}); err != nil {
+		t.Errorf("Expected a valid event, got %s", err)
+	}
+	for _, value := range []float64{math.NaN(), math.Inf(1)} {
+		if err := c.LogEventValue(user, "checkout", value, nil); err == nil {
+			t.Errorf("Expected %v to be rejected", value)
+		}
+	}
+	if err := c.LogEventValue(user, "", 1, nil); err == nil {
+		t.Errorf("Expected an empty event name to be rejected")
+	}
+	_ = c.Flush()
+
+	mu.Lock()
+	defer mu.Unlock()
+	custom := make([]map[string]interface{}, 0)
+	for _, event := range events {
+		if event["eventName"] == "checkout" {
+			custom = append(custom, event)
+		}
+	}
+	if len(custom) != 1 {
+		t.Fatalf("Expected a single event, got %v", custom)
+	}
+	if value, ok := custom[0]["value"].(float64); !ok || value != 42.5 {
+		t.Errorf("Expected the value to be sent as a number, got %#v", custom[0]["value"])
+	}
+	if custom[0]["metadata"].(map[string]interface{})["currency"] != "USD" {
+		t.Errorf("Expected the metadata, got %v", custom[0]["metadata"])
+	}
+}
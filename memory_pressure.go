@@ -0,0 +1,110 @@
+package statsig
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMemoryCheckInterval = 10 * time.Second
+	memoryPressureMinEvents    = 10
+	memoryPressureEventDivisor = 4
+)
+
+// Lets the SDK shed memory when the process is under memory pressure
+type MemoryPressureOptions struct {
+	// Heap size in bytes above which the SDK sheds memory. Zero disables heap monitoring,
+	// in which case pressure can still be reported with ReportMemoryPressure.
+	HeapThreshold uint64
+	// How often the heap size is checked. Defaults to 10 seconds.
+	CheckInterval time.Duration
+}
+
+type memoryMonitor struct {
+	options     MemoryPressureOptions
+	logger      *logger
+	diagnostics *diagnostics
+	// Hold the caches emptied under pressure
+	evaluator      *evaluator
+	stringInterner *stringInterner
+	underPressure  bool
+	shutdown       bool
+	stopped        chan struct{} // closed by stop, waking the heap size poller so it exits right away
+	mu             sync.Mutex
+}
+
+func newMemoryMonitor(
+	options MemoryPressureOptions,
+	logger *logger,
+	diagnostics *diagnostics,
+	evaluator *evaluator,
+	stringInterner *stringInterner,
+) *memoryMonitor {
+	if options.CheckInterval <= 0 {
+		options.CheckInterval = defaultMemoryCheckInterval
+	}
+	monitor := &memoryMonitor{
+		options:        options,
+		logger:         logger,
+		diagnostics:    diagnostics,
+		evaluator:      evaluator,
+		stringInterner: stringInterner,
+		stopped:        make(chan struct{}),
+	}
+	if options.HeapThreshold > 0 {
+		go monitor.pollHeapSize()
+	}
+	return monitor
+}
+
+func (m *memoryMonitor) pollHeapSize() {
+	var stats runtime.MemStats
+	ticker := time.NewTicker(m.options.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-m.stopped:
+			return
+		}
+		runtime.ReadMemStats(&stats)
+		m.setUnderPressure(stats.HeapAlloc >= m.options.HeapThreshold)
+	}
+}
+
+func (m *memoryMonitor) setUnderPressure(underPressure bool) {
+	m.mu.Lock()
+	changed := m.underPressure != underPressure
+	m.underPressure = underPressure
+	m.mu.Unlock()
+	if !changed {
+		return
+	}
+	if underPressure {
+		global.Logger().LogWarning("[Statsig] Memory pressure detected, shrinking the event queue and caches\n")
+		m.diagnostics.clearMarkers()
+		m.logger.tightenQueue(memoryPressureEventDivisor, memoryPressureMinEvents)
+		m.logger.clearDedupedExposures()
+		m.evaluator.cirCache.clear()
+		m.evaluator.cirHistory.clear()
+		m.stringInterner.clear()
+	} else {
+		m.logger.restoreQueue()
+	}
+}
+
+func (m *memoryMonitor) isUnderPressure() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.underPressure
+}
+
+func (m *memoryMonitor) stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.shutdown {
+		m.shutdown = true
+		close(m.stopped)
+	}
+}
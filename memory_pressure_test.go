@@ -0,0 +1,127 @@
+package statsig
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMemoryPressure(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+	options := &Options{
+		API:                  testServer.URL,
+		LoggingMaxBufferSize: 100,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	}
+	InitializeWithOptions("secret-key", options)
+	defer ShutdownAndDangerouslyClearInstance()
+
+	LogEvent(Event{EventName: "test_event", User: User{UserID: "123"}})
+	ReportMemoryPressure(true)
+
+	instance.logger.mu.Lock()
+	if instance.logger.maxEvents != 25 {
+		t.Errorf("Expected the event buffer to shrink to 25, got %d", instance.logger.maxEvents)
+	}
+	if len(instance.logger.events) != 0 {
+		t.Errorf("Expected queued events to be flushed")
+	}
+	instance.logger.mu.Unlock()
+	if !instance.memoryMonitor.isUnderPressure() {
+		t.Errorf("Expected the client to be under memory pressure")
+	}
+
+	ReportMemoryPressure(false)
+	instance.logger.mu.Lock()
+	if instance.logger.maxEvents != 100 {
+		t.Errorf("Expected the event buffer to be restored, got %d", instance.logger.maxEvents)
+	}
+	instance.logger.mu.Unlock()
+}
+
+func TestMemoryPressureHeapThreshold(t *testing.T) {
+	options := &Options{
+		LocalMode:             true,
+		OutputLoggerOptions:   getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions:  getStatsigLoggerOptionsForTest(t),
+		MemoryPressureOptions: MemoryPressureOptions{HeapThreshold: 1, CheckInterval: 10 * time.Millisecond},
+	}
+	InitializeWithOptions("secret-key", options)
+	defer ShutdownAndDangerouslyClearInstance()
+
+	waitForCondition(t, func() bool {
+		return instance.memoryMonitor.isUnderPressure()
+	})
+}
+
+func TestMemoryPressureClearsCaches(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	newTestClient := func(options *Options) *Client {
+		options.LocalMode = true
+		options.BootstrapValues = string(bytes)
+		options.OutputLoggerOptions = getOutputLoggerOptionsForTest(t)
+		options.StatsigLoggerOptions = getStatsigLoggerOptionsForTest(t)
+		return NewClientWithOptions("secret-key", options)
+	}
+	user := User{UserID: "123", Country: "US"}
+
+	t.Run("clears the ClientInitializeResponse cache", func(t *testing.T) {
+		c := newTestClient(&Options{ClientInitializeResponseCacheOptions: ClientInitializeResponseCacheOptions{MaxEntries: 10}})
+		defer c.Shutdown()
+		response := c.GetClientInitializeResponse(user, "")
+		c.GetClientInitializeResponseWithOptions(user, "", &ClientInitializeResponseOptions{PreviousHash: response.Hash})
+		c.ReportMemoryPressure(true)
+		c.evaluator.cirCache.mu.Lock()
+		if len(c.evaluator.cirCache.entries) != 0 || c.evaluator.cirCache.lru.Len() != 0 {
+			t.Errorf("Expected the ClientInitializeResponse cache to be emptied")
+		}
+		c.evaluator.cirCache.mu.Unlock()
+		c.evaluator.cirHistory.mu.Lock()
+		if len(c.evaluator.cirHistory.entries) != 0 {
+			t.Errorf("Expected the ClientInitializeResponse history to be emptied")
+		}
+		c.evaluator.cirHistory.mu.Unlock()
+	})
+
+	t.Run("clears the user string table", func(t *testing.T) {
+		c := newTestClient(&Options{UserInterningOptions: UserInterningOptions{MaxEntries: 10}})
+		defer c.Shutdown()
+		c.CheckGate(user, "always_on_gate")
+		c.stringInterner.mu.RLock()
+		interned := len(c.stringInterner.strings)
+		c.stringInterner.mu.RUnlock()
+		if interned == 0 {
+			t.Fatalf("Expected the user to be interned")
+		}
+		c.ReportMemoryPressure(true)
+		c.stringInterner.mu.RLock()
+		if len(c.stringInterner.strings) != 0 {
+			t.Errorf("Expected the user string table to be emptied")
+		}
+		c.stringInterner.mu.RUnlock()
+	})
+
+	t.Run("clears the exposure dedupe table", func(t *testing.T) {
+		c := newTestClient(&Options{ExposureDedupeWindow: time.Minute})
+		defer c.Shutdown()
+		c.CheckGate(user, "always_on_gate")
+		c.logger.mu.Lock()
+		deduped := len(c.logger.dedupedExposures)
+		c.logger.mu.Unlock()
+		if deduped == 0 {
+			t.Fatalf("Expected the exposure to be deduped")
+		}
+		c.ReportMemoryPressure(true)
+		c.logger.mu.Lock()
+		if len(c.logger.dedupedExposures) != 0 {
+			t.Errorf("Expected the exposure dedupe table to be emptied")
+		}
+		c.logger.mu.Unlock()
+	})
+}
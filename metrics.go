@@ -0,0 +1,318 @@
+package statsig
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	metricEvaluations          = "evaluations_total"
+	metricEvaluationTimeouts   = "evaluation_timeouts_total"
+	metricEvaluationsAbandoned = "evaluations_abandoned"
+	metricEventQueueDepth      = "event_queue_depth"
+	metricEventsInFlight       = "events_in_flight"
+	metricEventsDropped        = "events_dropped_total"
+	metricEventEnqueueFailures = "event_enqueue_failures_total"
+	metricRequestDuration      = "request_duration_seconds"
+	metricRequestFailures      = "request_failures_total"
+
+	metricKindCounter   = "counter"
+	metricKindGauge     = "gauge"
+	metricKindHistogram = "histogram"
+)
+
+var defaultLatencyBuckets = []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Enables the SDK's internal health metrics, served in the Prometheus text format by Client.MetricsHandler.
+// Register the handler on your metrics endpoint, or scrape it as a separate target. To add them to your own
+// prometheus.Registry instead, register the Collector of the github.com/statsig-io/go-sdk/statsigprom module.
+type MetricsOptions struct {
+	Enabled bool
+	// Prefix of every metric name. Defaults to "statsig".
+	Namespace string
+	// Upper bounds, in seconds, of the request latency histogram buckets
+	LatencyBuckets []float64
+}
+
+// A snapshot of one series of the SDK's internal metrics, as returned by Client.CollectMetrics
+type MetricSample struct {
+	// Prefixed with MetricsOptions.Namespace, e.g. statsig_evaluations_total
+	Name string
+	Help string
+	// "counter", "gauge" or "histogram"
+	Kind string
+	// Empty when the metric has no label
+	LabelName  string
+	LabelValue string
+	// The value of a counter or gauge
+	Value float64
+	// The upper bounds of the buckets of a histogram, and the cumulative number of observations in each
+	BucketBounds []float64
+	BucketCounts []uint64
+	Sum          float64
+	Count        uint64
+}
+
+type metricFamily struct {
+	name      string
+	help      string
+	kind      string
+	labelName string
+	// Label value to *metricSeries
+	series sync.Map
+}
+
+// Updated with atomics, so evaluations recording metrics do not contend on a lock
+type metricSeries struct {
+	// Bits of the float64 value, see atomicFloat64
+	value   uint64
+	buckets []uint64
+	sum     uint64
+	count   uint64
+}
+
+// Counters, gauges and histograms recorded by the transport, logger and evaluator, kept for the
+// Prometheus handler when MetricsOptions is enabled and forwarded to the ObservabilityClient when set.
+// A nil *metrics records nothing, so call sites do not need to check whether metrics are enabled.
+type metrics struct {
+	namespace string
+	buckets   []float64
+	// Only written by newMetrics
+	families      map[string]*metricFamily
+	observability *observabilityClient
+}
+
+func newMetrics(options MetricsOptions, observability ObservabilityClient) *metrics {
+	if !options.Enabled && observability == nil {
+		return nil
+	}
+	m := &metrics{
+		families:      make(map[string]*metricFamily),
+		observability: newObservabilityClient(observability),
+	}
+	if !options.Enabled {
+		return m
+	}
+	buckets := options.LatencyBuckets
+	if len(buckets) == 0 {
+		buckets = defaultLatencyBuckets
+	}
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+	m.namespace = defaultString(options.Namespace, "statsig")
+	m.buckets = buckets
+	m.register(metricEvaluations, metricKindCounter, "type", "Number of gate, config and layer evaluations")
+	m.register(metricEvaluationTimeouts, metricKindCounter, "", "Number of evaluations that exceeded the EvaluationLatencyBudget")
+	m.register(metricEvaluationsAbandoned, metricKindGauge, "", "Number of evaluations over the EvaluationLatencyBudget still running in the background")
+	m.register(metricEventQueueDepth, metricKindGauge, "", "Number of events waiting to be flushed")
+	m.register(metricEventsInFlight, metricKindGauge, "", "Number of flushed events still being sent")
+	m.register(metricEventsDropped, metricKindCounter, "", "Number of events that could not be delivered or spooled")
+	m.register(metricEventEnqueueFailures, metricKindCounter, "", "Number of events that could not be queued because the SDK was shut down")
+	m.register(metricRequestDuration, metricKindHistogram, "endpoint", "Latency of requests to Statsig, including retries")
+	m.register(metricRequestFailures, metricKindCounter, "endpoint", "Number of requests to Statsig that failed after all retries")
+	return m
+}
+
+func (m *metrics) register(name string, kind string, labelName string, help string) {
+	m.families[name] = &metricFamily{
+		name:      m.namespace + "_" + name,
+		help:      help,
+		kind:      kind,
+		labelName: labelName,
+	}
+}
+
+// Returns nil when the Prometheus handler is disabled
+func (m *metrics) getSeries(name string, labelValue string) *metricSeries {
+	family, exists := m.families[name]
+	if !exists {
+		return nil
+	}
+	if series, exists := family.series.Load(labelValue); exists {
+		return series.(*metricSeries)
+	}
+	series := &metricSeries{}
+	if family.kind == metricKindHistogram {
+		series.buckets = make([]uint64, len(m.buckets))
+	}
+	loaded, _ := family.series.LoadOrStore(labelValue, series)
+	return loaded.(*metricSeries)
+}
+
+func addFloat64(bits *uint64, delta float64) {
+	for {
+		old := atomic.LoadUint64(bits)
+		if atomic.CompareAndSwapUint64(bits, old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+func loadFloat64(bits *uint64) float64 {
+	return math.Float64frombits(atomic.LoadUint64(bits))
+}
+
+func (m *metrics) increment(name string, labelValue string, delta float64) {
+	if m == nil {
+		return
+	}
+	m.observability.increment(name, labelValue, delta)
+	if series := m.getSeries(name, labelValue); series != nil {
+		addFloat64(&series.value, delta)
+	}
+}
+
+func (m *metrics) setGauge(name string, labelValue string, value float64) {
+	if m == nil {
+		return
+	}
+	m.observability.gauge(name, labelValue, value)
+	if series := m.getSeries(name, labelValue); series != nil {
+		atomic.StoreUint64(&series.value, math.Float64bits(value))
+	}
+}
+
+func (m *metrics) observe(name string, labelValue string, value float64) {
+	if m == nil {
+		return
+	}
+	m.observability.distribution(name, labelValue, value)
+	series := m.getSeries(name, labelValue)
+	if series == nil {
+		return
+	}
+	for i, bound := range m.buckets {
+		if value <= bound {
+			atomic.AddUint64(&series.buckets[i], 1)
+		}
+	}
+	addFloat64(&series.sum, value)
+	atomic.AddUint64(&series.count, 1)
+}
+
+func (m *metrics) observeDuration(name string, labelValue string, start time.Time) {
+	m.observe(name, labelValue, time.Since(start).Seconds())
+}
+
+func (m *metrics) sortedFamilies() []*metricFamily {
+	names := make([]string, 0, len(m.families))
+	for name := range m.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	families := make([]*metricFamily, len(names))
+	for i, name := range names {
+		families[i] = m.families[name]
+	}
+	return families
+}
+
+// Returns the series of the family sorted by label value. The fields of a histogram are read one by one,
+// so a scrape racing an observation may see its count before its bucket.
+func (f *metricFamily) collect(buckets []float64) []MetricSample {
+	samples := make([]MetricSample, 0)
+	f.series.Range(func(key, value interface{}) bool {
+		series := value.(*metricSeries)
+		sample := MetricSample{
+			Name:       f.name,
+			Help:       f.help,
+			Kind:       f.kind,
+			LabelName:  f.labelName,
+			LabelValue: key.(string),
+		}
+		if f.kind != metricKindHistogram {
+			sample.Value = loadFloat64(&series.value)
+		} else {
+			sample.BucketBounds = append([]float64{}, buckets...)
+			sample.BucketCounts = make([]uint64, len(series.buckets))
+			for i := range series.buckets {
+				sample.BucketCounts[i] = atomic.LoadUint64(&series.buckets[i])
+			}
+			sample.Sum = loadFloat64(&series.sum)
+			sample.Count = atomic.LoadUint64(&series.count)
+		}
+		samples = append(samples, sample)
+		return true
+	})
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i].LabelValue < samples[j].LabelValue
+	})
+	return samples
+}
+
+// Returns every series recorded so far, sorted by metric name and label value
+func (m *metrics) collect() []MetricSample {
+	if m == nil {
+		return nil
+	}
+	samples := make([]MetricSample, 0)
+	for _, family := range m.sortedFamilies() {
+		samples = append(samples, family.collect(m.buckets)...)
+	}
+	return samples
+}
+
+// Writes every metric in the Prometheus text exposition format (version 0.0.4)
+func (m *metrics) write(w io.Writer) error {
+	if m == nil {
+		return nil
+	}
+	var b strings.Builder
+	for _, family := range m.sortedFamilies() {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", family.name, family.help, family.name, family.kind)
+		for _, sample := range family.collect(m.buckets) {
+			labels := ""
+			if sample.LabelName != "" {
+				labels = sample.LabelName + "=" + strconv.Quote(sample.LabelValue)
+			}
+			if sample.Kind != metricKindHistogram {
+				fmt.Fprintf(&b, "%s%s %s\n", sample.Name, formatLabels(labels), formatMetricValue(sample.Value))
+				continue
+			}
+			for i, bound := range sample.BucketBounds {
+				fmt.Fprintf(&b, "%s_bucket%s %d\n", sample.Name, formatLabels(labels, "le="+strconv.Quote(formatMetricValue(bound))), sample.BucketCounts[i])
+			}
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", sample.Name, formatLabels(labels, `le="+Inf"`), sample.Count)
+			fmt.Fprintf(&b, "%s_sum%s %s\n", sample.Name, formatLabels(labels), formatMetricValue(sample.Sum))
+			fmt.Fprintf(&b, "%s_count%s %d\n", sample.Name, formatLabels(labels), sample.Count)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func formatLabels(labels ...string) string {
+	nonEmpty := make([]string, 0, len(labels))
+	for _, label := range labels {
+		if label != "" {
+			nonEmpty = append(nonEmpty, label)
+		}
+	}
+	if len(nonEmpty) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(nonEmpty, ",") + "}"
+}
+
+func formatMetricValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+type metricsHandler struct {
+	metrics *metrics
+}
+
+func (h metricsHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	res.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := h.metrics.write(res); err != nil {
+		global.Logger().LogError(err)
+	}
+}
@@ -0,0 +1,233 @@
+package statsig
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	dcs, _ := os.ReadFile("download_config_specs.json")
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		switch {
+		case strings.Contains(req.URL.Path, "download_config_specs"):
+			res.WriteHeader(http.StatusOK)
+			_, _ = res.Write(dcs)
+		case strings.Contains(req.URL.Path, "log_event"):
+			res.WriteHeader(http.StatusBadRequest)
+		default:
+			res.WriteHeader(http.StatusOK)
+			_, _ = res.Write([]byte("{}"))
+		}
+	}))
+	defer testServer.Close()
+
+	InitializeWithOptions("secret-key", &Options{
+		API:                  testServer.URL,
+		MetricsOptions:       MetricsOptions{Enabled: true, LatencyBuckets: []float64{60, 0.5}},
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer ShutdownAndDangerouslyClearInstance()
+
+	user := User{UserID: "123"}
+	CheckGate(user, "always_on_gate")
+	CheckGate(user, "always_on_gate")
+	GetConfig(user, "test_config")
+	GetLayer(user, "a_layer")
+
+	scrape := func() string {
+		recorder := httptest.NewRecorder()
+		MetricsHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+		return recorder.Body.String()
+	}
+	body := scrape()
+	for _, line := range []string{
+		"# TYPE statsig_evaluations_total counter",
+		`statsig_evaluations_total{type="gate"} 2`,
+		`statsig_evaluations_total{type="config"} 1`,
+		`statsig_evaluations_total{type="layer"} 1`,
+		"statsig_event_queue_depth 3",
+		`statsig_request_duration_seconds_bucket{endpoint="/download_config_specs",le="0.5"} 1`,
+		`statsig_request_duration_seconds_bucket{endpoint="/download_config_specs",le="60"} 1`,
+		`statsig_request_duration_seconds_bucket{endpoint="/download_config_specs",le="+Inf"} 1`,
+		`statsig_request_duration_seconds_count{endpoint="/download_config_specs"} 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected metrics to contain %q, got\n%s", line, body)
+		}
+	}
+
+	var gates, requests *MetricSample
+	samples := CollectMetrics()
+	for i, sample := range samples {
+		switch {
+		case sample.Name == "statsig_evaluations_total" && sample.LabelValue == "gate":
+			gates = &samples[i]
+		case sample.Name == "statsig_request_duration_seconds" && sample.LabelValue == "/download_config_specs":
+			requests = &samples[i]
+		}
+	}
+	if gates == nil || gates.Kind != "counter" || gates.LabelName != "type" || gates.Value != 2 {
+		t.Errorf("Expected the gate evaluations to be collected, got %+v", gates)
+	}
+	if requests == nil || requests.Count != 1 || len(requests.BucketBounds) != 2 || requests.BucketCounts[1] != 1 {
+		t.Errorf("Expected the request latency histogram to be collected, got %+v", requests)
+	}
+
+	_ = FlushWithContext(context.Background())
+	body = scrape()
+	for _, line := range []string{
+		"statsig_event_queue_depth 0",
+		"statsig_events_dropped_total 3",
+		`statsig_request_failures_total{endpoint="/log_event"} 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected metrics to contain %q, got\n%s", line, body)
+		}
+	}
+}
+
+func TestMetricsConcurrentUpdates(t *testing.T) {
+	m := newMetrics(MetricsOptions{Enabled: true}, nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				m.increment(metricEvaluations, "gate", 1)
+				m.observe(metricRequestDuration, "/log_event", 0.02)
+			}
+		}()
+		_ = m.collect()
+	}
+	wg.Wait()
+	for _, sample := range m.collect() {
+		if sample.LabelValue == "gate" && sample.Value != 8000 {
+			t.Errorf("Expected 8000 gate evaluations, got %v", sample.Value)
+		}
+		if sample.LabelValue == "/log_event" && (sample.Count != 8000 || sample.BucketCounts[0] != 0 || sample.BucketCounts[1] != 8000) {
+			t.Errorf("Expected 8000 request latencies, got %+v", sample)
+		}
+	}
+}
+
+func TestMetricsDisabled(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	metricsHandler{metrics: newMetrics(MetricsOptions{}, nil)}.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if recorder.Body.Len() != 0 {
+		t.Errorf("Expected no metrics when disabled")
+	}
+}
+
+type testObservabilityClient struct {
+	increments    map[string]int
+	gauges        map[string]float64
+	distributions map[string][]map[string]string
+	shutdown      bool
+	mu            sync.Mutex
+}
+
+func (o *testObservabilityClient) Init() error {
+	o.increments = make(map[string]int)
+	o.gauges = make(map[string]float64)
+	o.distributions = make(map[string][]map[string]string)
+	return nil
+}
+
+func (o *testObservabilityClient) Increment(metricName string, value int, tags map[string]string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.increments[metricName+"|"+tags["type"]+tags["endpoint"]] += value
+}
+
+func (o *testObservabilityClient) Gauge(metricName string, value float64, tags map[string]string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.gauges[metricName] = value
+}
+
+func (o *testObservabilityClient) Distribution(metricName string, value float64, tags map[string]string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.distributions[metricName] = append(o.distributions[metricName], tags)
+}
+
+func (o *testObservabilityClient) Shutdown() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.shutdown = true
+}
+
+func TestObservabilityClient(t *testing.T) {
+	dcs, _ := os.ReadFile("download_config_specs.json")
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+		if strings.Contains(req.URL.Path, "download_config_specs") {
+			_, _ = res.Write(dcs)
+		} else {
+			_, _ = res.Write([]byte("{}"))
+		}
+	}))
+	defer testServer.Close()
+
+	observability := &testObservabilityClient{}
+	InitializeWithOptions("secret-key", &Options{
+		API:                  testServer.URL,
+		ObservabilityClient:  observability,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	CheckGate(User{UserID: "123"}, "always_on_gate")
+
+	observability.mu.Lock()
+	if observability.increments["statsig.sdk.evaluations|gate"] != 1 {
+		t.Errorf("Expected a gate evaluation increment, got %v", observability.increments)
+	}
+	if observability.gauges["statsig.sdk.event_queue_depth"] != 1 {
+		t.Errorf("Expected the event queue depth gauge, got %v", observability.gauges)
+	}
+	initialization := observability.distributions["statsig.sdk.initialization"]
+	if len(initialization) != 1 || initialization[0]["source"] != string(reasonNetwork) || initialization[0]["success"] != "true" {
+		t.Errorf("Expected an initialization distribution, got %v", initialization)
+	}
+	if len(observability.distributions["statsig.sdk.request_latency"]) == 0 {
+		t.Errorf("Expected request latency distributions")
+	}
+	observability.mu.Unlock()
+
+	// Nothing is served over the Prometheus handler unless MetricsOptions is enabled
+	recorder := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if recorder.Body.Len() != 0 {
+		t.Errorf("Expected no Prometheus metrics, got %s", recorder.Body.String())
+	}
+
+	ShutdownAndDangerouslyClearInstance()
+	observability.mu.Lock()
+	defer observability.mu.Unlock()
+	if !observability.shutdown {
+		t.Errorf("Expected the observability client to be shut down")
+	}
+}
+
+func TestObservabilityClientShutdownWithContext(t *testing.T) {
+	observability := &testObservabilityClient{}
+	c := NewClientWithOptions("secret-key", &Options{
+		LocalMode:            true,
+		ObservabilityClient:  observability,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	_, _ = c.ShutdownWithContext(context.Background())
+	observability.mu.Lock()
+	defer observability.mu.Unlock()
+	if !observability.shutdown {
+		t.Errorf("Expected the observability client to be shut down")
+	}
+}
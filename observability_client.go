@@ -0,0 +1,136 @@
+package statsig
+
+import (
+	"fmt"
+	"time"
+)
+
+// Receives the SDK's internal health metrics, matching the observability interface of the other
+// Statsig server SDKs, so they can be sent to StatsD, Datadog or any other metrics backend.
+// Metric names are prefixed with "statsig.sdk.". Calls may come from any goroutine.
+type ObservabilityClient interface {
+	// Called once while the SDK initializes. If it returns an error, no metrics are reported.
+	Init() error
+	Increment(metricName string, value int, tags map[string]string)
+	Gauge(metricName string, value float64, tags map[string]string)
+	// Records a sample of a distribution, such as a latency in milliseconds
+	Distribution(metricName string, value float64, tags map[string]string)
+	// Called once when the SDK shuts down
+	Shutdown()
+}
+
+const metricInitialization = "initialization"
+
+// Name, tag and unit conversion of each internal metric when reported to the ObservabilityClient
+var observabilityMetrics = map[string]struct {
+	name  string
+	tag   string
+	scale float64
+}{
+	metricEvaluations:          {name: "statsig.sdk.evaluations", tag: "type", scale: 1},
+	metricEvaluationTimeouts:   {name: "statsig.sdk.evaluation_timeouts", scale: 1},
+	metricEvaluationsAbandoned: {name: "statsig.sdk.evaluations_abandoned", scale: 1},
+	metricEventQueueDepth:      {name: "statsig.sdk.event_queue_depth", scale: 1},
+	metricEventsInFlight:       {name: "statsig.sdk.events_in_flight", scale: 1},
+	metricEventsDropped:        {name: "statsig.sdk.events_dropped", scale: 1},
+	metricEventEnqueueFailures: {name: "statsig.sdk.event_enqueue_failures", scale: 1},
+	metricRequestDuration:      {name: "statsig.sdk.request_latency", tag: "endpoint", scale: 1000},
+	metricRequestFailures:      {name: "statsig.sdk.request_failures", tag: "endpoint", scale: 1},
+	metricInitialization:       {name: "statsig.sdk.initialization", tag: "source", scale: 1000},
+}
+
+// Forwards metrics to the user's ObservabilityClient, recovering from any panic it raises.
+// A nil *observabilityClient forwards nothing.
+type observabilityClient struct {
+	client ObservabilityClient
+}
+
+func newObservabilityClient(client ObservabilityClient) *observabilityClient {
+	if client == nil {
+		return nil
+	}
+	o := &observabilityClient{client: client}
+	var err error
+	o.call("init", func() {
+		err = client.Init()
+	})
+	if err != nil {
+		global.Logger().LogError(fmt.Errorf("Failed to initialize the observability client, metrics will not be reported: %w", err))
+		return nil
+	}
+	return o
+}
+
+func (o *observabilityClient) call(method string, fn func()) {
+	defer func() {
+		if err := recover(); err != nil {
+			logRecoveredPanic("observability client "+method, err)
+		}
+	}()
+	fn()
+}
+
+func (o *observabilityClient) convert(name string, labelValue string, value float64) (string, float64, map[string]string) {
+	metric := observabilityMetrics[name]
+	tags := make(map[string]string)
+	if metric.tag != "" && labelValue != "" {
+		tags[metric.tag] = labelValue
+	}
+	return metric.name, value * metric.scale, tags
+}
+
+func (o *observabilityClient) increment(name string, labelValue string, delta float64) {
+	if o == nil {
+		return
+	}
+	metricName, value, tags := o.convert(name, labelValue, delta)
+	o.call("increment", func() {
+		o.client.Increment(metricName, int(value), tags)
+	})
+}
+
+func (o *observabilityClient) gauge(name string, labelValue string, value float64) {
+	if o == nil {
+		return
+	}
+	metricName, value, tags := o.convert(name, labelValue, value)
+	o.call("gauge", func() {
+		o.client.Gauge(metricName, value, tags)
+	})
+}
+
+func (o *observabilityClient) distribution(name string, labelValue string, value float64) {
+	if o == nil {
+		return
+	}
+	metricName, value, tags := o.convert(name, labelValue, value)
+	o.call("distribution", func() {
+		o.client.Distribution(metricName, value, tags)
+	})
+}
+
+func (o *observabilityClient) shutdown() {
+	if o == nil {
+		return
+	}
+	o.call("shutdown", o.client.Shutdown)
+}
+
+// Reports how long initialize took and where the rulesets came from
+func (m *metrics) observeInitialization(start time.Time, source evaluationReason, success bool) {
+	if m == nil || m.observability == nil {
+		return
+	}
+	metricName, value, tags := m.observability.convert(metricInitialization, string(source), time.Since(start).Seconds())
+	tags["success"] = fmt.Sprintf("%t", success)
+	m.observability.call("distribution", func() {
+		m.observability.client.Distribution(metricName, value, tags)
+	})
+}
+
+func (m *metrics) shutdown() {
+	if m == nil {
+		return
+	}
+	m.observability.shutdown()
+}
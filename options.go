@@ -0,0 +1,501 @@
+package statsig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	DefaultConfigSyncInterval   = 10 * time.Second
+	DefaultIDListSyncInterval   = time.Minute
+	DefaultLoggingInterval      = time.Minute
+	DefaultLoggingMaxBufferSize = 1000
+)
+
+// The subset of Options that can safely be changed on a running Client via UpdateOptions.
+// Zero values leave the current setting unchanged.
+type RuntimeOptions struct {
+	ConfigSyncInterval   time.Duration
+	IDListSyncInterval   time.Duration
+	LoggingInterval      time.Duration
+	LoggingMaxBufferSize int
+	// Change the output logger, which every Client in the process shares, keeping its other settings
+	EnableDebug *bool
+	LogLevel    *LogLevel
+	// Replace Options.ExposureSamplingRate, EventSamplingRates and DiagnosticsSamplingRate.
+	// A nil EventSamplingRates leaves them unchanged, and an empty one stops sampling by event name.
+	ExposureSamplingRate    *float64
+	EventSamplingRates      map[string]float64
+	DiagnosticsSamplingRate *float64
+	StatsigLoggerOptions    *StatsigLoggerOptions
+}
+
+// An Option configures the Statsig SDK when passed to NewClient, New or Initialize.
+// Options are applied in order on top of the defaults, so a later Option wins.
+type Option func(*Options)
+
+// Creates an Options struct with every default set explicitly, then applies the given Options on top.
+// The STATSIG_* environment variables take precedence over the defaults, and the given Options over both.
+func NewOptions(opts ...Option) *Options {
+	options := &Options{}
+	applyEnvironmentVariables(options)
+	if options.API == "" {
+		options.API = DefaultEndpoint
+	}
+	if options.ConfigSyncInterval == 0 {
+		options.ConfigSyncInterval = DefaultConfigSyncInterval
+	}
+	if options.IDListSyncInterval == 0 {
+		options.IDListSyncInterval = DefaultIDListSyncInterval
+	}
+	if options.LoggingInterval == 0 {
+		options.LoggingInterval = DefaultLoggingInterval
+	}
+	if options.LoggingMaxBufferSize == 0 {
+		options.LoggingMaxBufferSize = DefaultLoggingMaxBufferSize
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(options)
+		}
+	}
+	return options
+}
+
+// Sets the base URL used for requests to Statsig
+func WithAPI(api string) Option {
+	return func(o *Options) {
+		o.API = api
+	}
+}
+
+// Sets the base URLs of individual endpoints, overriding the one set by WithAPI
+func WithAPIOverrides(overrides APIOverrides) Option {
+	return func(o *Options) {
+		o.APIOverrides = overrides
+	}
+}
+
+// Sets the environment used for evaluation and attached to every event
+func WithEnvironment(environment Environment) Option {
+	return func(o *Options) {
+		o.Environment = environment
+	}
+}
+
+// Sets the tier of the environment, such as production or staging, keeping its other params
+func WithEnvironmentTier(tier string) Option {
+	return func(o *Options) {
+		o.Environment.Tier = tier
+	}
+}
+
+// Disables all network requests. Evaluations only use overrides, bootstrap values or the data adapter
+func WithLocalMode() Option {
+	return func(o *Options) {
+		o.LocalMode = true
+	}
+}
+
+// Sets custom fields merged into every User before evaluation and logging
+func WithGlobalCustomFields(fields map[string]interface{}) Option {
+	return func(o *Options) {
+		o.GlobalCustomFields = fields
+	}
+}
+
+// Sets a transform applied to every User before evaluation and logging
+func WithUserTransform(transform func(user User) User) Option {
+	return func(o *Options) {
+		o.UserTransform = transform
+	}
+}
+
+// Registers an operator for conditions the SDK does not evaluate itself. See CustomConditionOptions.
+func WithCustomOperator(name string, operator CustomOperator) Option {
+	return func(o *Options) {
+		if o.CustomConditionOptions.Operators == nil {
+			o.CustomConditionOptions.Operators = make(map[string]CustomOperator)
+		}
+		o.CustomConditionOptions.Operators[name] = operator
+	}
+}
+
+// Registers a resolver for the fields of a condition type the SDK does not evaluate itself. See CustomConditionOptions.
+func WithCustomFieldResolver(condType string, resolver CustomFieldResolver) Option {
+	return func(o *Options) {
+		if o.CustomConditionOptions.FieldResolvers == nil {
+			o.CustomConditionOptions.FieldResolvers = make(map[string]CustomFieldResolver)
+		}
+		o.CustomConditionOptions.FieldResolvers[condType] = resolver
+	}
+}
+
+// Sets the clock used for current_time conditions and event and diagnostics timestamps
+func WithClock(clock Clock) Option {
+	return func(o *Options) {
+		o.Clock = clock
+	}
+}
+
+// Calls the callback once the rulesets have not been synced for longer than the threshold
+func WithStaleConfigAlert(threshold time.Duration, callback func(sinceLastSync time.Duration)) Option {
+	return func(o *Options) {
+		o.StaleConfigThreshold = threshold
+		o.StaleConfigCallback = callback
+	}
+}
+
+// Stops collecting diagnostics, for services that want no diagnostics events nor their overhead
+func WithDiagnosticsDisabled() Option {
+	return func(o *Options) {
+		o.DisableDiagnostics = true
+	}
+}
+
+// Sets how many idle connections to Statsig are kept open, in total and to each host, and for how long.
+// Non-positive values keep the defaults.
+func WithConnectionPool(maxIdleConns int, maxIdleConnsPerHost int, idleConnTimeout time.Duration) Option {
+	return func(o *Options) {
+		if maxIdleConns > 0 {
+			o.HTTPOptions.MaxIdleConns = maxIdleConns
+		}
+		if maxIdleConnsPerHost > 0 {
+			o.HTTPOptions.MaxIdleConnsPerHost = maxIdleConnsPerHost
+		}
+		if idleConnTimeout > 0 {
+			o.HTTPOptions.IdleConnTimeout = idleConnTimeout
+		}
+	}
+}
+
+// Sends every request to Statsig through the given transport
+func WithHTTPTransport(transport *http.Transport) Option {
+	return func(o *Options) {
+		o.HTTPOptions.Transport = transport
+	}
+}
+
+// Sends every request over the Unix domain socket at the given path, e.g. to a local egress proxy
+func WithUnixSocket(path string) Option {
+	return func(o *Options) {
+		o.UnixSocketPath = path
+	}
+}
+
+// Adds the given headers to every request the SDK sends
+func WithAdditionalHeaders(headers map[string]string) Option {
+	return func(o *Options) {
+		o.AdditionalHeaders = headers
+	}
+}
+
+// Presents the given client certificate on every request, for egress proxies that require mutual TLS,
+// and trusts the certificate authorities of rootCAs instead of the system ones when it is not nil
+func WithClientCertificate(certificate tls.Certificate, rootCAs *x509.CertPool) Option {
+	return func(o *Options) {
+		o.HTTPOptions.ClientCertificates = []tls.Certificate{certificate}
+		o.HTTPOptions.RootCAs = rootCAs
+	}
+}
+
+// Rejects config specs from the network or the DataAdapter for which verify returns an error. Network responses
+// are verified with the value of signatureHeader, and adapter specs with an empty signature.
+func WithSpecsVerification(signatureHeader string, verify func(specs []byte, signature string) error) Option {
+	return func(o *Options) {
+		o.IntegrityOptions = IntegrityOptions{Verify: verify, SignatureHeader: signatureHeader}
+	}
+}
+
+// Asks the API for config spec deltas, for APIs implementing the protocol described at Options.EnableConfigSpecDeltas
+func WithConfigSpecDeltas() Option {
+	return func(o *Options) {
+		o.EnableConfigSpecDeltas = true
+	}
+}
+
+// Sets the data adapter used to bootstrap and cache config specs
+func WithDataAdapter(adapter IDataAdapter) Option {
+	return func(o *Options) {
+		o.DataAdapter = adapter
+	}
+}
+
+// Bootstraps from the first of the given data adapters that holds valid config specs, and caches fresh
+// specs in all of them. See NewDataAdapterChain.
+func WithDataAdapters(adapters ...IDataAdapter) Option {
+	return func(o *Options) {
+		o.DataAdapter = NewDataAdapterChain(adapters...)
+	}
+}
+
+// Sets a download_config_specs payload to initialize from instead of the network
+func WithBootstrapValues(values string) Option {
+	return func(o *Options) {
+		o.BootstrapValues = values
+	}
+}
+
+// Sets the path of a download_config_specs file to initialize from instead of the network
+func WithBootstrapFile(path string) Option {
+	return func(o *Options) {
+		o.BootstrapFile = path
+	}
+}
+
+// Stops every outbound request, for deployments that run only from a BootstrapFile or DataAdapter
+func WithNetworkDisabled() Option {
+	return func(o *Options) {
+		o.DisableNetwork = true
+	}
+}
+
+// Sets how often config specs and ID lists are synced. Non-positive intervals are ignored.
+func WithPolling(interval time.Duration) Option {
+	return func(o *Options) {
+		if interval > 0 {
+			o.ConfigSyncInterval = interval
+			o.IDListSyncInterval = interval
+		}
+	}
+}
+
+// Sets how often config specs are synced. Non-positive intervals are ignored.
+func WithConfigSyncInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		if interval > 0 {
+			o.ConfigSyncInterval = interval
+		}
+	}
+}
+
+// Sets how often ID lists are synced. Non-positive intervals are ignored.
+func WithIDListSyncInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		if interval > 0 {
+			o.IDListSyncInterval = interval
+		}
+	}
+}
+
+// Downloads only the entities targeting the given app
+func WithTargetApp(appId string) Option {
+	return func(o *Options) {
+		o.TargetApp = appId
+	}
+}
+
+// Stops ID lists from being downloaded, for services that do not use ID list segments
+func WithIDListsDisabled() Option {
+	return func(o *Options) {
+		o.DisableIDLists = true
+	}
+}
+
+// Sets how often events are flushed and how many events are buffered before a flush is forced.
+// Non-positive values are ignored.
+func WithLogging(interval time.Duration, maxBufferSize int) Option {
+	return func(o *Options) {
+		if interval > 0 {
+			o.LoggingInterval = interval
+		}
+		if maxBufferSize > 0 {
+			o.LoggingMaxBufferSize = maxBufferSize
+		}
+	}
+}
+
+// Sets the maximum time Initialize will block for. Non-positive timeouts are ignored.
+func WithInitTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		if timeout > 0 {
+			o.InitTimeout = timeout
+		}
+	}
+}
+
+// Sets a callback invoked with the raw specs whenever newer config specs are applied
+func WithRulesUpdatedCallback(callback func(rules string, time int64)) Option {
+	return func(o *Options) {
+		o.RulesUpdatedCallback = callback
+	}
+}
+
+// Parses and serializes the JSON exchanged with Statsig with the given codec instead of encoding/json
+func WithJSONCodec(codec JSONCodec) Option {
+	return func(o *Options) {
+		o.JSONCodec = codec
+	}
+}
+
+// Disables user agent parsing for memory sensitive deployments
+func WithUAParserDisabled() Option {
+	return func(o *Options) {
+		o.UAParserOptions.Disabled = true
+	}
+}
+
+// Resolves IP addresses to countries with the given lookup instead of the built-in table
+func WithCountryLookup(lookup CountryLookup) Option {
+	return func(o *Options) {
+		o.CountryLookupOptions.Lookup = lookup
+	}
+}
+
+// Sets a hook that is called with every custom event and exposure before it is queued
+func WithEventEnrichmentHook(hook func(event *Event)) Option {
+	return func(o *Options) {
+		o.EventEnrichmentHook = hook
+	}
+}
+
+// Sets a hook that can modify or drop every exposure before it is queued
+func WithExposureInterceptor(interceptor func(exposure *Event) bool) Option {
+	return func(o *Options) {
+		o.ExposureInterceptor = interceptor
+	}
+}
+
+// Scrubs users' emails, IP addresses and user agents from what the SDK sends to Statsig
+func WithPIIScrubbing(mode PIIScrubbingMode) Option {
+	return func(o *Options) {
+		o.PIIScrubbing = mode
+	}
+}
+
+// Replaces users' emails, IP addresses and user agents in what the SDK sends to Statsig by their HMAC keyed with key
+func WithPIIHashing(key []byte) Option {
+	return func(o *Options) {
+		o.PIIScrubbing = PIIScrubbingHash
+		o.PIIScrubbingKey = key
+	}
+}
+
+// Sets the options for the SDK's own output logging
+func WithOutputLoggerOptions(options OutputLoggerOptions) Option {
+	return func(o *Options) {
+		o.OutputLoggerOptions = options
+	}
+}
+
+// Sets the options for events the SDK logs to Statsig about itself
+func WithStatsigLoggerOptions(options StatsigLoggerOptions) Option {
+	return func(o *Options) {
+		o.StatsigLoggerOptions = options
+	}
+}
+
+// Sets a callback invoked with every error the SDK swallows, see Options.ErrorCallback
+func WithErrorCallback(callback func(err error, context string)) Option {
+	return func(o *Options) {
+		o.ErrorCallback = callback
+	}
+}
+
+// Stops the SDK from reporting its own errors to Statsig
+func WithErrorReportingDisabled() Option {
+	return func(o *Options) {
+		o.DisableErrorBoundaryReporting = true
+	}
+}
+
+// Sets the total time initialize may spend loading rulesets and ID lists. Non-positive budgets are ignored.
+func WithInitBudget(budget time.Duration) Option {
+	return func(o *Options) {
+		if budget > 0 {
+			o.InitBudget = budget
+		}
+	}
+}
+
+// Logs identical exposures only once within the window
+func WithExposureDedupeWindow(window time.Duration) Option {
+	return func(o *Options) {
+		o.ExposureDedupeWindow = window
+	}
+}
+
+// Keeps the given fraction of gate, config and layer exposures
+func WithExposureSamplingRate(rate float64) Option {
+	return func(o *Options) {
+		o.ExposureSamplingRate = rate
+	}
+}
+
+// Keeps the given fraction of the events with the given name. Can be passed once per event name.
+func WithEventSamplingRate(eventName string, rate float64) Option {
+	return func(o *Options) {
+		if o.EventSamplingRates == nil {
+			o.EventSamplingRates = make(map[string]float64)
+		}
+		o.EventSamplingRates[eventName] = rate
+	}
+}
+
+// Returns the default value of evaluations taking longer than the budget
+func WithEvaluationLatencyBudget(budget time.Duration) Option {
+	return func(o *Options) {
+		o.EvaluationLatencyBudget = budget
+	}
+}
+
+// Enables the SDK's internal health metrics, served by Client.MetricsHandler
+func WithMetricsOptions(options MetricsOptions) Option {
+	return func(o *Options) {
+		o.MetricsOptions = options
+	}
+}
+
+// Sets how the SDK traces initialize, config syncs, ID list syncs and event flushes
+func WithTracingOptions(options TracingOptions) Option {
+	return func(o *Options) {
+		o.TracingOptions = options
+	}
+}
+
+// Sets the client that receives the SDK's internal metrics
+func WithObservabilityClient(client ObservabilityClient) Option {
+	return func(o *Options) {
+		o.ObservabilityClient = client
+	}
+}
+
+// Sets a callback invoked with the event queue depth and the number of events lost, see Options.EventQueueMetricsCallback
+func WithEventQueueMetricsCallback(callback func(metrics EventQueueMetrics)) Option {
+	return func(o *Options) {
+		o.EventQueueMetricsCallback = callback
+	}
+}
+
+// Only outputs SDK logs at least as severe as the level
+func WithLogLevel(level LogLevel) Option {
+	return func(o *Options) {
+		o.OutputLoggerOptions.LogLevel = level
+	}
+}
+
+// Writes SDK logs to the writer instead of standard output
+func WithLogWriter(writer io.Writer) Option {
+	return func(o *Options) {
+		o.OutputLoggerOptions.Writer = writer
+	}
+}
+
+// Replaces the server session ID once it is older than the interval. Non-positive intervals are ignored.
+func WithSessionIDRotationInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		if interval > 0 {
+			o.SessionIDRotationOptions.Interval = interval
+		}
+	}
+}
+
+// Replaces the server session ID whenever a newer version of the rulesets is applied
+func WithSessionIDRotationOnConfigSync() Option {
+	return func(o *Options) {
+		o.SessionIDRotationOptions.OnConfigSync = true
+	}
+}
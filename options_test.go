@@ -0,0 +1,142 @@
+package statsig
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewOptionsDefaults(t *testing.T) {
+	options := NewOptions()
+	if options.API != DefaultEndpoint {
+		t.Errorf("Expected default API to be set")
+	}
+	if options.ConfigSyncInterval != DefaultConfigSyncInterval || options.IDListSyncInterval != DefaultIDListSyncInterval {
+		t.Errorf("Expected default sync intervals to be set")
+	}
+	if options.LoggingInterval != DefaultLoggingInterval || options.LoggingMaxBufferSize != DefaultLoggingMaxBufferSize {
+		t.Errorf("Expected default logging options to be set")
+	}
+
+	options = NewOptions(WithPolling(30*time.Second), WithPolling(0), WithLogging(0, 5), WithLocalMode())
+	if options.ConfigSyncInterval != 30*time.Second || options.IDListSyncInterval != 30*time.Second {
+		t.Errorf("Expected polling interval to be 30s and not be reset by a zero interval")
+	}
+	if options.LoggingInterval != DefaultLoggingInterval || options.LoggingMaxBufferSize != 5 {
+		t.Errorf("Expected only the buffer size to be overridden")
+	}
+	if !options.LocalMode {
+		t.Errorf("Expected local mode to be enabled")
+	}
+}
+
+func TestNewClientWithFunctionalOptions(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+		if strings.Contains(req.URL.Path, "download_config_specs") {
+			_, _ = res.Write(bytes)
+		}
+	}))
+	defer testServer.Close()
+
+	InitializeGlobalOutputLogger(getOutputLoggerOptionsForTest(t))
+	c := NewClient("secret-key",
+		WithAPI(testServer.URL),
+		WithPolling(time.Minute),
+		WithStatsigLoggerOptions(getStatsigLoggerOptionsForTest(t)),
+	)
+	defer c.Shutdown()
+
+	if !c.CheckGate(User{UserID: "123"}, "always_on_gate") {
+		t.Errorf("Expected always_on_gate to be on when configured with functional options")
+	}
+}
+
+func TestNew(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	c, err := New("client-key",
+		WithLocalMode(),
+		WithBootstrapValues(string(bytes)),
+		WithEnvironmentTier("staging"),
+		WithEventSamplingRate("purchase", 0.5),
+		WithEventSamplingRate("checkout", 0.1),
+		WithErrorReportingDisabled(),
+		WithStatsigLoggerOptions(getStatsigLoggerOptionsForTest(t)),
+	)
+	if err != nil {
+		t.Fatalf("Expected any SDK key to be accepted in LocalMode, got %s", err)
+	}
+	defer c.Shutdown()
+	if !c.CheckGate(User{UserID: "123"}, "always_on_gate") {
+		t.Errorf("Expected always_on_gate to pass from the bootstrap values")
+	}
+	if c.options.Environment.Tier != "staging" || c.options.API != DefaultEndpoint || !c.errorBoundary.disableReporting {
+		t.Errorf("Expected the tier to be set on top of the defaults")
+	}
+	if len(c.options.EventSamplingRates) != 2 || c.options.EventSamplingRates["checkout"] != 0.1 {
+		t.Errorf("Expected each sampling rate to be kept, got %v", c.options.EventSamplingRates)
+	}
+
+	c, err = New("client-key", WithAPI("http://127.0.0.1:1"))
+	if c != nil || err == nil || err.Error() != InvalidSDKKeyError {
+		t.Errorf("Expected an invalid SDK key to be returned as an error, got %v", err)
+	}
+}
+
+func TestUpdateOptions(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	options := &Options{
+		API:                  testServer.URL,
+		ConfigSyncInterval:   time.Hour,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	}
+	InitializeWithOptions("secret-key", options)
+	defer ShutdownAndDangerouslyClearInstance()
+
+	outputLogger := global.Logger()
+	enableDebug := true
+	logLevel := LogLevelWarn
+	exposureSamplingRate := 0.5
+	diagnosticsSamplingRate := 0.25
+	UpdateOptions(RuntimeOptions{
+		ConfigSyncInterval:      10 * time.Millisecond,
+		LoggingMaxBufferSize:    2,
+		EnableDebug:             &enableDebug,
+		LogLevel:                &logLevel,
+		ExposureSamplingRate:    &exposureSamplingRate,
+		EventSamplingRates:      map[string]float64{"purchase": 0.1},
+		DiagnosticsSamplingRate: &diagnosticsSamplingRate,
+	})
+	if instance.evaluator.store.getConfigSyncInterval() != 10*time.Millisecond {
+		t.Errorf("Expected config sync interval to be updated")
+	}
+	if instance.evaluator.store.getIDListSyncInterval() != DefaultIDListSyncInterval {
+		t.Errorf("Expected ID list sync interval to be unchanged")
+	}
+	if global.Logger() != outputLogger || !outputLogger.options.EnableDebug || outputLogger.options.LogLevel != LogLevelWarn {
+		t.Errorf("Expected the level of the output logger to be updated in place")
+	}
+	if outputLogger.options.LogCallback == nil {
+		t.Errorf("Expected the other output logger options to be kept")
+	}
+	if rate, sampled := instance.logger.getSamplingRate("purchase", false); !sampled || rate != 0.1 {
+		t.Errorf("Expected the event sampling rates to be updated, got %v", rate)
+	}
+	if rate, sampled := instance.logger.getSamplingRate(gateExposureEventName, true); !sampled || rate != 0.5 {
+		t.Errorf("Expected the exposure sampling rate to be updated, got %v", rate)
+	}
+	instance.logger.mu.Lock()
+	if instance.logger.maxEvents != 2 || instance.logger.diagnosticsSamplingRate != 0.25 {
+		t.Errorf("Expected max buffer size and diagnostics sampling rate to be updated")
+	}
+	instance.logger.mu.Unlock()
+}
@@ -2,6 +2,9 @@ package statsig
 
 import (
 	"fmt"
+	"io"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -10,31 +13,75 @@ type StatsigProcess string
 const (
 	StatsigProcessInitialize StatsigProcess = "Initialize"
 	StatsigProcessSync       StatsigProcess = "Sync"
+	StatsigProcessEvaluate   StatsigProcess = "Evaluate"
 )
 
+// The severity of SDK logs, ordered like the levels of log/slog
+type LogLevel int
+
+const (
+	LogLevelDebug LogLevel = -1
+	LogLevelInfo  LogLevel = 0
+	LogLevelWarn  LogLevel = 1
+	LogLevelError LogLevel = 2
+	// Outputs no logs at all
+	LogLevelNone LogLevel = 3
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelDebug:
+		return "debug"
+	case LogLevelInfo:
+		return "info"
+	case LogLevelWarn:
+		return "warn"
+	case LogLevelError:
+		return "error"
+	case LogLevelNone:
+		return "none"
+	}
+	return fmt.Sprintf("LogLevel(%d)", int(l))
+}
+
+// A leveled, structured logger. Fields are alternating keys and values, as in log/slog,
+// so most structured logging libraries can be adapted in a few lines. See NewSlogLogger.
+type Logger interface {
+	Debug(msg string, fields ...interface{})
+	Info(msg string, fields ...interface{})
+	Warn(msg string, fields ...interface{})
+	Error(msg string, fields ...interface{})
+}
+
 type OutputLogger struct {
 	options OutputLoggerOptions
+	// Serializes writes to options.Writer
+	mu sync.Mutex
+	// Guards options.LogLevel and options.EnableDebug, which UpdateOptions can change
+	levelMu sync.RWMutex
 }
 
 func (o *OutputLogger) Log(msg string, err error) {
-	if o.isInitialized() && o.options.LogCallback != nil {
-		o.options.LogCallback(msg, err)
-	} else {
-		formatted := msg
+	level := LogLevelInfo
+	if err != nil {
+		level = LogLevelError
+	}
+	if !o.isEnabled(level) {
+		return
+	}
+	if o.isInitialized() && o.options.Logger != nil {
 		if err != nil {
-			if formatted != "" {
-				formatted += "\n"
-			}
-			formatted += err.Error()
-		}
-		if formatted != "" {
-			fmt.Print(formatted)
+			o.options.Logger.Error(defaultString(trimLogMessage(msg), err.Error()), "error", err)
+		} else if msg := trimLogMessage(msg); msg != "" {
+			o.options.Logger.Info(msg)
 		}
+		return
 	}
+	o.print(msg, err)
 }
 
 func (o *OutputLogger) LogStep(process StatsigProcess, msg string) {
-	if !o.isInitialized() || !o.options.EnableDebug {
+	if !o.isInitialized() || !o.isEnabled(LogLevelDebug) {
 		return
 	}
 	if o.options.DisableInitDiagnostics && process == StatsigProcessInitialize {
@@ -43,21 +90,113 @@ func (o *OutputLogger) LogStep(process StatsigProcess, msg string) {
 	if o.options.DisableSyncDiagnostics && process == StatsigProcessSync {
 		return
 	}
+	if o.options.Logger != nil {
+		o.options.Logger.Debug(trimLogMessage(msg), "process", string(process))
+		return
+	}
 	timestamp := time.Now().Format(time.RFC3339)
-	o.Log(fmt.Sprintf("[%s][Statsig] %s: %s\n", timestamp, process, msg), nil)
+	o.print(fmt.Sprintf("[%s][Statsig] %s: %s\n", timestamp, process, msg), nil)
+}
+
+// Logs a problem the SDK recovered from on its own
+func (o *OutputLogger) LogWarning(msg string, fields ...interface{}) {
+	if !o.isEnabled(LogLevelWarn) {
+		return
+	}
+	if o.isInitialized() && o.options.Logger != nil {
+		o.options.Logger.Warn(trimLogMessage(msg), fields...)
+		return
+	}
+	o.print(msg, nil)
 }
 
 func (o *OutputLogger) LogError(err interface{}) {
+	if !o.isEnabled(LogLevelError) {
+		return
+	}
+	if o.isInitialized() && o.options.Logger != nil {
+		switch errTyped := err.(type) {
+		case error:
+			o.options.Logger.Error(trimLogMessage(errTyped.Error()), "error", errTyped)
+		default:
+			o.options.Logger.Error(trimLogMessage(fmt.Sprint(err)))
+		}
+		return
+	}
 	switch errTyped := err.(type) {
 	case string:
-		o.Log(errTyped, nil)
+		o.print(errTyped, nil)
 	case error:
-		o.Log("", errTyped)
+		o.print("", errTyped)
 	default:
-		fmt.Print(err)
+		o.print(fmt.Sprint(err), nil)
+	}
+}
+
+// Whether logs of the level are output. EnableDebug lowers the LogLevel to LogLevelDebug.
+func (o *OutputLogger) isEnabled(level LogLevel) bool {
+	if !o.isInitialized() {
+		return level >= LogLevelInfo
+	}
+	o.levelMu.RLock()
+	defer o.levelMu.RUnlock()
+	minLevel := o.options.LogLevel
+	if o.options.EnableDebug && minLevel > LogLevelDebug {
+		minLevel = LogLevelDebug
+	}
+	return level >= minLevel
+}
+
+func (o *OutputLogger) setLevel(level *LogLevel, enableDebug *bool) {
+	o.levelMu.Lock()
+	defer o.levelMu.Unlock()
+	if level != nil {
+		o.options.LogLevel = *level
+	}
+	if enableDebug != nil {
+		o.options.EnableDebug = *enableDebug
+	}
+}
+
+// Outputs an unstructured log to the LogCallback, the Writer or standard output
+func (o *OutputLogger) print(msg string, err error) {
+	if o.isInitialized() && o.options.LogCallback != nil {
+		o.options.LogCallback(msg, err)
+		return
+	}
+	formatted := msg
+	if err != nil {
+		if formatted != "" {
+			formatted += "\n"
+		}
+		formatted += err.Error()
+	}
+	if formatted == "" {
+		return
 	}
+	// Errors are formatted without a trailing newline
+	if !strings.HasSuffix(formatted, "\n") {
+		formatted += "\n"
+	}
+	if o.isInitialized() && o.options.Writer != nil {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		_, _ = io.WriteString(o.options.Writer, formatted)
+		return
+	}
+	fmt.Print(formatted)
 }
 
 func (o *OutputLogger) isInitialized() bool {
 	return o != nil
 }
+
+// Structured records carry no "[Statsig]" prefix or trailing newline
+func trimLogMessage(msg string) string {
+	return strings.TrimSpace(strings.TrimPrefix(msg, "[Statsig] "))
+}
+
+// Logs a panic recovered from a user-supplied callback or adapter, named by callee, e.g. "StaleConfigCallback"
+func logRecoveredPanic(callee string, recovered interface{}) {
+	global.Logger().LogError(fmt.Errorf("Error calling %s: %w", callee, toError(recovered)))
+}
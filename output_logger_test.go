@@ -0,0 +1,153 @@
+package statsig
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestOutputLoggerLevels(t *testing.T) {
+	tests := []struct {
+		options  OutputLoggerOptions
+		expected []string
+	}{
+		{OutputLoggerOptions{}, []string{"info", "warning", "error"}},
+		{OutputLoggerOptions{EnableDebug: true}, []string{"step", "info", "warning", "error"}},
+		{OutputLoggerOptions{LogLevel: LogLevelDebug}, []string{"step", "info", "warning", "error"}},
+		{OutputLoggerOptions{LogLevel: LogLevelWarn}, []string{"warning", "error"}},
+		{OutputLoggerOptions{LogLevel: LogLevelError, EnableDebug: true}, []string{"step", "info", "warning", "error"}},
+		{OutputLoggerOptions{LogLevel: LogLevelError}, []string{"error"}},
+		{OutputLoggerOptions{LogLevel: LogLevelNone}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.options.LogLevel.String(), func(t *testing.T) {
+			var buffer bytes.Buffer
+			test.options.Writer = &buffer
+			logger := &OutputLogger{options: test.options}
+			logger.LogStep(StatsigProcessInitialize, "step")
+			logger.Log("[Statsig] info", nil)
+			logger.LogWarning("[Statsig] warning\n")
+			logger.LogError(errors.New("error"))
+
+			var lines []string
+			if buffer.Len() > 0 {
+				lines = strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+			}
+			if len(lines) != len(test.expected) {
+				t.Fatalf("Expected %v, got %q", test.expected, buffer.String())
+			}
+			for i, line := range lines {
+				if !strings.HasSuffix(line, test.expected[i]) {
+					t.Errorf("Expected line %d to end with %q, got %q", i, test.expected[i], line)
+				}
+			}
+		})
+	}
+}
+
+func TestOutputLoggerLevelsApplyToEveryDestination(t *testing.T) {
+	var messages []string
+	logger := &OutputLogger{options: OutputLoggerOptions{
+		LogLevel: LogLevelWarn,
+		LogCallback: func(message string, err error) {
+			messages = append(messages, message)
+		},
+	}}
+	logger.Log("info", nil)
+	logger.LogWarning("warning")
+	if len(messages) != 1 || messages[0] != "warning" {
+		t.Errorf("Expected only the warning to reach the LogCallback, got %v", messages)
+	}
+
+	structured := &recordingLogger{}
+	logger = &OutputLogger{options: OutputLoggerOptions{LogLevel: LogLevelError, Logger: structured}}
+	logger.LogWarning("warning")
+	logger.LogError("error")
+	if len(structured.records) != 1 || structured.records[0] != "ERROR error" {
+		t.Errorf("Expected only the error to reach the Logger, got %v", structured.records)
+	}
+}
+
+func TestLogWriterOption(t *testing.T) {
+	var buffer bytes.Buffer
+	options := NewOptions(WithLogLevel(LogLevelWarn), WithLogWriter(&buffer))
+	InitializeGlobalOutputLogger(options.OutputLoggerOptions)
+	defer InitializeGlobalOutputLogger(getOutputLoggerOptionsForTest(t))
+	global.Logger().Log("[Statsig] Initialized\n", nil)
+	global.Logger().LogWarning("[Statsig] Memory pressure detected\n")
+	if buffer.String() != "[Statsig] Memory pressure detected\n" {
+		t.Errorf("Expected only the warning to be written, got %q", buffer.String())
+	}
+}
+
+func TestSyncFailuresAreLogged(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer testServer.Close()
+
+	var c *Client
+	logs := captureOutputLogs(t, func() {
+		c = NewClientWithOptions("secret-key", &Options{
+			API:                           testServer.URL,
+			DisableErrorBoundaryReporting: true,
+			StatsigLoggerOptions:          getStatsigLoggerOptionsForTest(t),
+		})
+	})
+	defer c.Shutdown()
+	if !strings.Contains(logs, "Failed to initialize from the network") {
+		t.Errorf("Expected the initialize failure to be logged, got %q", logs)
+	}
+
+	logs = captureOutputLogs(t, func() {
+		c.evaluator.store.syncFailureCount = int(syncOutdatedMax / c.evaluator.store.getConfigSyncInterval())
+		c.evaluator.store.handleSyncError(errors.New("unavailable"), false)
+	})
+	if !strings.Contains(logs, "Syncing the server SDK with Statsig network has failed") {
+		t.Errorf("Expected the sync failure to be logged, got %q", logs)
+	}
+}
+
+type recordingLogger struct {
+	records []string
+}
+
+func (l *recordingLogger) Debug(msg string, fields ...interface{}) {
+	l.records = append(l.records, "DEBUG "+msg)
+}
+
+func (l *recordingLogger) Info(msg string, fields ...interface{}) {
+	l.records = append(l.records, "INFO "+msg)
+}
+
+func (l *recordingLogger) Warn(msg string, fields ...interface{}) {
+	l.records = append(l.records, "WARN "+msg)
+}
+
+func (l *recordingLogger) Error(msg string, fields ...interface{}) {
+	l.records = append(l.records, "ERROR "+msg)
+}
+
+// Returns what task logged through the global output logger
+func captureOutputLogs(t *testing.T, task func()) string {
+	var mu sync.Mutex
+	var buffer bytes.Buffer
+	InitializeGlobalOutputLogger(OutputLoggerOptions{LogCallback: func(message string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		buffer.WriteString(message)
+		if err != nil {
+			buffer.WriteString(err.Error())
+		}
+		buffer.WriteString("\n")
+	}})
+	defer InitializeGlobalOutputLogger(getOutputLoggerOptionsForTest(t))
+	task()
+	mu.Lock()
+	defer mu.Unlock()
+	return buffer.String()
+}
@@ -1,6 +1,7 @@
 package statsig
 
 import (
+	"os"
 	"reflect"
 	"testing"
 )
@@ -75,3 +76,52 @@ func TestOverrides(t *testing.T) {
 		t.Errorf("Failed to get override value for a layer when in LocalMode")
 	}
 }
+
+func TestOverrideExperimentGroup(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	c := NewClientWithOptions(secret, &Options{
+		LocalMode:            true,
+		BootstrapValues:      string(bytes),
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+	getParam := func(user User) string {
+		experiment := c.GetExperiment(user, "sample_experiment")
+		return experiment.GetString("experiment_param", "")
+	}
+	user := User{UserID: "123"}
+	other := User{UserID: "456"}
+	assigned := getParam(user)
+	otherAssigned := getParam(other)
+
+	c.OverrideExperimentGroup(user, "sample_experiment", "Test")
+	experiment := c.GetExperiment(user, "sample_experiment")
+	if experiment.GetString("experiment_param", "") != "test" || experiment.RuleID != "2RamGujUou6h2bVNQWhtNZ" {
+		t.Errorf("Expected the Test group, got %v from rule %s", experiment.Value, experiment.RuleID)
+	}
+	layer := c.GetLayer(user, "a_layer")
+	if layer.GetString("experiment_param", "") != "test" {
+		t.Errorf("Expected the layer to serve the Test group through its experiment")
+	}
+	if getParam(other) != otherAssigned {
+		t.Errorf("Expected other users not to be overridden")
+	}
+
+	c.OverrideExperimentGroup(user, "sample_experiment", "control")
+	if getParam(user) != "control" {
+		t.Errorf("Expected group names to be matched case insensitively")
+	}
+
+	c.OverrideExperimentGroup(user, "sample_experiment", "no_such_group")
+	c.OverrideExperimentGroup(user, "no_such_experiment", "Test")
+	if getParam(user) != "control" {
+		t.Errorf("Expected unknown groups and experiments to be ignored")
+	}
+
+	c.OverrideExperimentGroup(user, "sample_experiment", "Test")
+	c.OverrideExperimentGroup(user, "sample_experiment", "")
+	if getParam(user) != assigned {
+		t.Errorf("Expected an empty group to remove the override")
+	}
+}
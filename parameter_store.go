@@ -0,0 +1,143 @@
+package statsig
+
+import (
+	"encoding/json"
+)
+
+const (
+	parameterRefTypeStatic        = "static"
+	parameterRefTypeGate          = "gate"
+	parameterRefTypeDynamicConfig = "dynamic_config"
+	parameterRefTypeExperiment    = "experiment"
+	parameterRefTypeLayer         = "layer"
+
+	parameterTypeString  = "string"
+	parameterTypeNumber  = "number"
+	parameterTypeBoolean = "boolean"
+	parameterTypeObject  = "object"
+	parameterTypeArray   = "array"
+)
+
+// A named set of parameters, from the param_stores of download_config_specs
+type paramStore struct {
+	Parameters   map[string]parameterSpec `json:"parameters"`
+	TargetAppIDs []string                 `json:"targetAppIDs,omitempty"`
+}
+
+// Where a parameter gets its value: a static value, the pass or fail value of a gate,
+// or a parameter of a dynamic config, experiment or layer
+type parameterSpec struct {
+	RefType        string          `json:"ref_type"`
+	ParamType      string          `json:"param_type"`
+	Value          json.RawMessage `json:"value,omitempty"`
+	GateName       string          `json:"gate_name,omitempty"`
+	PassValue      json.RawMessage `json:"pass_value,omitempty"`
+	FailValue      json.RawMessage `json:"fail_value,omitempty"`
+	ConfigName     string          `json:"config_name,omitempty"`
+	ExperimentName string          `json:"experiment_name,omitempty"`
+	LayerName      string          `json:"layer_name,omitempty"`
+	ParamName      string          `json:"param_name,omitempty"`
+}
+
+// Parameters configured in the Statsig Console, each backed by a static value or by a gate, dynamic config,
+// experiment or layer. Parameters are evaluated for the user when read, and reading one logs the exposure of
+// the gate, config, experiment or layer parameter it is backed by, as reading that entity directly would.
+// Getters return the fallback when the parameter does not exist or is of a different type.
+type ParameterStore struct {
+	Name        string
+	user        User
+	parameters  map[string]parameterSpec
+	client      *Client
+	logExposure bool
+}
+
+// Gets the string parameter with the given name
+func (p *ParameterStore) GetString(name string, fallback string) string {
+	if val, ok := p.getValue(name, parameterTypeString).(string); ok {
+		return val
+	}
+	return fallback
+}
+
+// Gets the number parameter with the given name
+func (p *ParameterStore) GetNumber(name string, fallback float64) float64 {
+	if val, ok := p.getValue(name, parameterTypeNumber).(float64); ok {
+		return val
+	}
+	return fallback
+}
+
+// Gets the boolean parameter with the given name
+func (p *ParameterStore) GetBool(name string, fallback bool) bool {
+	if val, ok := p.getValue(name, parameterTypeBoolean).(bool); ok {
+		return val
+	}
+	return fallback
+}
+
+// Gets the object parameter with the given name
+func (p *ParameterStore) GetMap(name string, fallback map[string]interface{}) map[string]interface{} {
+	if val, ok := p.getValue(name, parameterTypeObject).(map[string]interface{}); ok {
+		return val
+	}
+	return fallback
+}
+
+// Gets the array parameter with the given name
+func (p *ParameterStore) GetSlice(name string, fallback []interface{}) []interface{} {
+	if val, ok := p.getValue(name, parameterTypeArray).([]interface{}); ok {
+		return val
+	}
+	return fallback
+}
+
+// Resolves the parameter, or returns nil if it does not exist, is not of the given type or cannot be resolved
+func (p *ParameterStore) getValue(name string, paramType string) interface{} {
+	param, exists := p.parameters[name]
+	if !exists || param.ParamType != paramType || p.client == nil {
+		return nil
+	}
+	switch param.RefType {
+	case parameterRefTypeStatic:
+		return decodeParameterValue(param.Value)
+	case parameterRefTypeGate:
+		if p.client.checkGateImpl(p.user, param.GateName, checkGateOptions{logExposure: p.logExposure}) {
+			return decodeParameterValue(param.PassValue)
+		}
+		return decodeParameterValue(param.FailValue)
+	case parameterRefTypeDynamicConfig:
+		config := p.client.getConfigImpl(p.user, param.ConfigName, getConfigOptions{logExposure: p.logExposure})
+		return config.Value[param.ParamName]
+	case parameterRefTypeExperiment:
+		experiment := p.client.getConfigImpl(p.user, param.ExperimentName, getConfigOptions{logExposure: p.logExposure})
+		return experiment.Value[param.ParamName]
+	case parameterRefTypeLayer:
+		layer := p.client.getLayerImpl(p.user, param.LayerName, getLayerOptions{logExposure: p.logExposure})
+		val, exists := layer.Value[param.ParamName]
+		if !exists {
+			return nil
+		}
+		// Only the layer parameter that is read is exposed, as with Layer.Get*
+		logExposure(&layer.configBase, param.ParamName)
+		return val
+	}
+	return nil
+}
+
+func decodeParameterValue(raw json.RawMessage) interface{} {
+	var value interface{}
+	if len(raw) == 0 || json.Unmarshal(raw, &value) != nil {
+		return nil
+	}
+	return value
+}
+
+func (c *Client) getParameterStoreImpl(user User, name string, logExposure bool) ParameterStore {
+	store := ParameterStore{Name: name, user: user, client: c, logExposure: logExposure}
+	c.errorBoundary.captureVoid(func() {
+		if spec, exists := c.evaluator.store.getParamStore(name); exists {
+			store.parameters = spec.Parameters
+		}
+	})
+	return store
+}
@@ -0,0 +1,123 @@
+package statsig
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func TestParameterStore(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	var specs map[string]interface{}
+	_ = json.Unmarshal(bytes, &specs)
+	specs["param_stores"] = map[string]interface{}{
+		"checkout": map[string]interface{}{
+			"parameters": map[string]interface{}{
+				"title":        map[string]interface{}{"ref_type": "static", "param_type": "string", "value": "Checkout"},
+				"discount":     map[string]interface{}{"ref_type": "gate", "param_type": "number", "gate_name": "always_on_gate", "pass_value": 0.5, "fail_value": 0},
+				"email_promo":  map[string]interface{}{"ref_type": "gate", "param_type": "boolean", "gate_name": "on_for_statsig_email", "pass_value": true, "fail_value": false},
+				"limit":        map[string]interface{}{"ref_type": "dynamic_config", "param_type": "number", "config_name": "test_config", "param_name": "number"},
+				"variant":      map[string]interface{}{"ref_type": "experiment", "param_type": "string", "experiment_name": "sample_experiment", "param_name": "experiment_param"},
+				"layer_param":  map[string]interface{}{"ref_type": "layer", "param_type": "string", "layer_name": "a_layer", "param_name": "experiment_param"},
+				"missing_ref":  map[string]interface{}{"ref_type": "dynamic_config", "param_type": "string", "config_name": "test_config", "param_name": "no_such_param"},
+				"static_items": map[string]interface{}{"ref_type": "static", "param_type": "array", "value": []string{"a", "b"}},
+			},
+		},
+	}
+	bootstrap, _ := json.Marshal(specs)
+	c := NewClientWithOptions("secret-key", &Options{
+		LocalMode:            true,
+		BootstrapValues:      string(bootstrap),
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+	user := User{UserID: "123"}
+	resetEvents := func() {
+		c.logger.mu.Lock()
+		c.logger.events = c.logger.events[:0]
+		c.logger.mu.Unlock()
+	}
+	loggedEvents := func() []exposureEvent {
+		c.logger.mu.Lock()
+		defer c.logger.mu.Unlock()
+		events := make([]exposureEvent, 0, len(c.logger.events))
+		for _, event := range c.logger.events {
+			events = append(events, event.(exposureEvent))
+		}
+		return events
+	}
+
+	t.Run("resolves parameters from their references", func(t *testing.T) {
+		store := c.GetParameterStoreWithExposureLoggingDisabled(user, "checkout")
+		if store.GetString("title", "") != "Checkout" {
+			t.Errorf("Expected the static value")
+		}
+		if store.GetNumber("discount", 0) != 0.5 || store.GetBool("email_promo", true) {
+			t.Errorf("Expected the pass and fail values of the gates")
+		}
+		config := c.GetConfigWithExposureLoggingDisabled(user, "test_config")
+		if store.GetNumber("limit", -1) != config.GetNumber("number", -2) {
+			t.Errorf("Expected the dynamic config parameter")
+		}
+		experiment := c.GetExperimentWithExposureLoggingDisabled(user, "sample_experiment")
+		if store.GetString("variant", "") != experiment.GetString("experiment_param", "fallback") {
+			t.Errorf("Expected the experiment parameter")
+		}
+		layer := c.GetLayerWithExposureLoggingDisabled(user, "a_layer")
+		if store.GetString("layer_param", "") != layer.GetString("experiment_param", "fallback") {
+			t.Errorf("Expected the layer parameter")
+		}
+		if items := store.GetSlice("static_items", nil); len(items) != 2 || items[0] != "a" {
+			t.Errorf("Expected the static array, got %v", items)
+		}
+	})
+
+	t.Run("returns the fallback for unknown, mistyped and unresolved parameters", func(t *testing.T) {
+		store := c.GetParameterStoreWithExposureLoggingDisabled(user, "checkout")
+		if store.GetNumber("title", 1) != 1 || store.GetString("unknown", "fallback") != "fallback" {
+			t.Errorf("Expected the fallback for mistyped and unknown parameters")
+		}
+		if store.GetString("missing_ref", "fallback") != "fallback" {
+			t.Errorf("Expected the fallback when the referenced config lacks the parameter")
+		}
+		unknown := c.GetParameterStore(user, "unknown_store")
+		if unknown.GetString("title", "fallback") != "fallback" {
+			t.Errorf("Expected the fallback for an unknown store")
+		}
+		var zero ParameterStore
+		if zero.GetBool("email_promo", true) != true {
+			t.Errorf("Expected the zero value to return fallbacks")
+		}
+	})
+
+	t.Run("logs the exposures of the referenced entities", func(t *testing.T) {
+		resetEvents()
+		store := c.GetParameterStore(user, "checkout")
+		store.GetString("title", "")
+		if len(loggedEvents()) != 0 {
+			t.Errorf("Expected static parameters not to log exposures")
+		}
+		store.GetNumber("discount", 0)
+		store.GetString("layer_param", "")
+		events := loggedEvents()
+		if len(events) != 2 {
+			t.Fatalf("Expected a gate and a layer exposure, got %d events", len(events))
+		}
+		if events[0].EventName != gateExposureEventName || events[0].Metadata["gate"] != "always_on_gate" {
+			t.Errorf("Expected a gate exposure, got %+v", events[0])
+		}
+		if events[1].EventName != layerExposureEventName || events[1].Metadata["parameterName"] != "experiment_param" {
+			t.Errorf("Expected a layer exposure for the parameter, got %+v", events[1])
+		}
+
+		resetEvents()
+		disabled := c.GetParameterStoreWithExposureLoggingDisabled(user, "checkout")
+		disabled.GetNumber("discount", 0)
+		disabled.GetString("variant", "")
+		disabled.GetString("layer_param", "")
+		if len(loggedEvents()) != 0 {
+			t.Errorf("Expected no exposures with exposure logging disabled")
+		}
+	})
+}
@@ -0,0 +1,167 @@
+package statsig
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"regexp"
+)
+
+// How the email, IP address and user agent of users are scrubbed from what the SDK sends to Statsig.
+// Users are still evaluated with them.
+type PIIScrubbingMode string
+
+const (
+	// Users are logged as given, minus their PrivateAttributes
+	PIIScrubbingDisabled PIIScrubbingMode = ""
+	// The email, IP address and user agent are removed
+	PIIScrubbingStrip PIIScrubbingMode = "strip"
+	// The email, IP address and user agent are replaced by their HMAC-SHA256 keyed with Options.PIIScrubbingKey,
+	// so events of the same user can still be joined on them without the values being recoverable by hashing
+	// guesses. Falls back to PIIScrubbingStrip when no key is set.
+	PIIScrubbingHash PIIScrubbingMode = "hash"
+)
+
+const redactedPII = "[redacted]"
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	ipv4Pattern  = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+)
+
+// Scrubs PII according to Options.PIIScrubbing. The zero value scrubs nothing.
+type piiScrubber struct {
+	mode PIIScrubbingMode
+	key  []byte
+}
+
+func newPIIScrubber(options *Options) piiScrubber {
+	mode := options.PIIScrubbing
+	if mode == PIIScrubbingHash && len(options.PIIScrubbingKey) == 0 {
+		mode = PIIScrubbingStrip
+	}
+	return piiScrubber{mode: mode, key: options.PIIScrubbingKey}
+}
+
+// Returns a copy of the user without the PII the mode scrubs, including emails in the values of User.Custom
+func (p piiScrubber) scrubUser(user User) User {
+	switch p.mode {
+	case PIIScrubbingStrip:
+		user.Email = ""
+		user.IpAddress = ""
+		user.UserAgent = ""
+	case PIIScrubbingHash:
+		user.Email = p.hash(user.Email)
+		user.IpAddress = p.hash(user.IpAddress)
+		user.UserAgent = p.hash(user.UserAgent)
+	default:
+		return user
+	}
+	if custom, changed := p.scrubEmailsInValue(user.Custom); changed {
+		user.Custom = custom.(map[string]interface{})
+	}
+	return user
+}
+
+// Scrubs emails from the metadata values. The metadata is only copied when an email is found, unless
+// inPlace is set because the SDK owns it.
+func (p piiScrubber) scrubMetadata(metadata map[string]string, inPlace bool) map[string]string {
+	if p.mode == PIIScrubbingDisabled {
+		return metadata
+	}
+	scrubbed := metadata
+	for key, value := range metadata {
+		if !emailPattern.MatchString(value) {
+			continue
+		}
+		if !inPlace {
+			scrubbed = copyMetadata(metadata)
+			inPlace = true
+		}
+		scrubbed[key] = emailPattern.ReplaceAllStringFunc(value, p.replacement)
+	}
+	return scrubbed
+}
+
+// Scrubs email and IPv4 addresses from free text, such as exception messages and stack traces
+func (p piiScrubber) scrubText(text string) string {
+	if p.mode == PIIScrubbingDisabled {
+		return text
+	}
+	text = emailPattern.ReplaceAllStringFunc(text, p.replacement)
+	return ipv4Pattern.ReplaceAllStringFunc(text, p.replacement)
+}
+
+// Returns value with the emails in its strings scrubbed, copying the maps and slices holding them
+func (p piiScrubber) scrubEmailsInValue(value interface{}) (interface{}, bool) {
+	switch typed := value.(type) {
+	case string:
+		if !emailPattern.MatchString(typed) {
+			return value, false
+		}
+		return emailPattern.ReplaceAllStringFunc(typed, p.replacement), true
+	case []string:
+		var scrubbed []string
+		for i, element := range typed {
+			if !emailPattern.MatchString(element) {
+				continue
+			}
+			if scrubbed == nil {
+				scrubbed = append([]string{}, typed...)
+			}
+			scrubbed[i] = emailPattern.ReplaceAllStringFunc(element, p.replacement)
+		}
+		if scrubbed == nil {
+			return value, false
+		}
+		return scrubbed, true
+	case []interface{}:
+		var scrubbed []interface{}
+		for i, element := range typed {
+			if element, changed := p.scrubEmailsInValue(element); changed {
+				if scrubbed == nil {
+					scrubbed = append([]interface{}{}, typed...)
+				}
+				scrubbed[i] = element
+			}
+		}
+		if scrubbed == nil {
+			return value, false
+		}
+		return scrubbed, true
+	case map[string]interface{}:
+		var scrubbed map[string]interface{}
+		for key, element := range typed {
+			if element, changed := p.scrubEmailsInValue(element); changed {
+				if scrubbed == nil {
+					scrubbed = make(map[string]interface{}, len(typed))
+					for key, element := range typed {
+						scrubbed[key] = element
+					}
+				}
+				scrubbed[key] = element
+			}
+		}
+		if scrubbed == nil {
+			return value, false
+		}
+		return scrubbed, true
+	}
+	return value, false
+}
+
+func (p piiScrubber) replacement(match string) string {
+	if p.mode == PIIScrubbingHash {
+		return p.hash(match)
+	}
+	return redactedPII
+}
+
+func (p piiScrubber) hash(value string) string {
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(value))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
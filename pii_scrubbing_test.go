@@ -0,0 +1,103 @@
+package statsig
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPIIScrubbing(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {}))
+	defer testServer.Close()
+	user := User{UserID: "123", Email: "jane@statsig.com", IpAddress: "1.2.3.4", UserAgent: "Mozilla/5.0", Country: "US"}
+
+	t.Run("strips PII from logged events", func(t *testing.T) {
+		opt := &Options{API: testServer.URL, PIIScrubbing: PIIScrubbingStrip}
+		logger := newLogger(newTransport("secret", opt), opt, nil)
+		logger.logCustom(Event{EventName: "custom_event", User: user})
+		logger.logGateExposure(user, "test_gate", true, "rule_id", nil, nil, nil)
+		custom := logger.events[0].(Event).User
+		exposure := logger.events[1].(exposureEvent).User
+		for _, logged := range []User{custom, exposure} {
+			if logged.Email != "" || logged.IpAddress != "" || logged.UserAgent != "" || logged.UserID != "123" || logged.Country != "US" {
+				t.Errorf("Expected only the email, IP and user agent to be stripped, got %+v", logged)
+			}
+		}
+	})
+
+	t.Run("hashes PII in logged events with the key", func(t *testing.T) {
+		opt := &Options{API: testServer.URL, PIIScrubbing: PIIScrubbingHash, PIIScrubbingKey: []byte("key")}
+		logger := newLogger(newTransport("secret", opt), opt, nil)
+		logger.logCustom(Event{EventName: "custom_event", User: user})
+		logged := logger.events[0].(Event).User
+		mac := hmac.New(sha256.New, []byte("key"))
+		mac.Write([]byte(user.Email))
+		if logged.Email != base64.StdEncoding.EncodeToString(mac.Sum(nil)) || logged.IpAddress == user.IpAddress || logged.UserAgent == user.UserAgent {
+			t.Errorf("Expected the email, IP and user agent to be hashed, got %+v", logged)
+		}
+	})
+
+	t.Run("strips PII when hashing without a key", func(t *testing.T) {
+		opt := &Options{API: testServer.URL, PIIScrubbing: PIIScrubbingHash}
+		logger := newLogger(newTransport("secret", opt), opt, nil)
+		logger.logCustom(Event{EventName: "custom_event", User: user})
+		if logged := logger.events[0].(Event).User; logged.Email != "" || logged.IpAddress != "" {
+			t.Errorf("Expected the email and IP to be stripped, got %+v", logged)
+		}
+	})
+
+	t.Run("scrubs emails from custom fields and metadata", func(t *testing.T) {
+		opt := &Options{API: testServer.URL, PIIScrubbing: PIIScrubbingStrip}
+		logger := newLogger(newTransport("secret", opt), opt, nil)
+		custom := map[string]interface{}{"contact": "jane@statsig.com", "teams": []interface{}{"a", "bob@statsig.com"}, "plan": "pro"}
+		metadata := map[string]string{"invitee": "bob@statsig.com", "source": "web"}
+		logger.logCustom(Event{EventName: "invite", User: User{UserID: "123", Custom: custom}, Metadata: metadata})
+		logged := logger.events[0].(Event)
+		if logged.User.Custom["contact"] != redactedPII || logged.User.Custom["teams"].([]interface{})[1] != redactedPII ||
+			logged.User.Custom["plan"] != "pro" {
+			t.Errorf("Expected the emails in custom fields to be scrubbed, got %v", logged.User.Custom)
+		}
+		if logged.Metadata["invitee"] != redactedPII || logged.Metadata["source"] != "web" {
+			t.Errorf("Expected the emails in metadata to be scrubbed, got %v", logged.Metadata)
+		}
+		if custom["contact"] != "jane@statsig.com" || metadata["invitee"] != "bob@statsig.com" {
+			t.Errorf("Expected the caller's maps to be left unchanged")
+		}
+	})
+
+	t.Run("still evaluates with PII", func(t *testing.T) {
+		bytes, _ := os.ReadFile("download_config_specs.json")
+		c := NewClientWithOptions("secret-key", &Options{
+			LocalMode:            true,
+			BootstrapValues:      string(bytes),
+			PIIScrubbing:         PIIScrubbingStrip,
+			OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+			StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+		})
+		defer c.Shutdown()
+		if !c.CheckGate(User{UserID: "123", Email: "jane@statsig.com"}, "on_for_statsig_email") {
+			t.Errorf("Expected the email to be used for evaluation")
+		}
+	})
+
+	t.Run("scrubs exceptions reported to Statsig", func(t *testing.T) {
+		var reported logExceptionRequestBody
+		exceptionServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			_ = json.NewDecoder(req.Body).Decode(&reported)
+		}))
+		defer exceptionServer.Close()
+		opt := &Options{API: exceptionServer.URL, PIIScrubbing: PIIScrubbingStrip}
+		errorBoundary := newErrorBoundary("client-key", opt, newDiagnostics())
+		errorBoundary.logException(errors.New("failed for jane@statsig.com from 10.0.0.1"))
+		if reported.Exception != "failed for [redacted] from [redacted]" || strings.Contains(reported.Info, "jane@statsig.com") {
+			t.Errorf("Expected the exception to be scrubbed, got %q", reported.Exception)
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package statsig
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Controls when the server session ID, sent with every request to Statsig to correlate the SDK's traffic
+// in Statsig's debugging tools, is replaced. By default a Client keeps the same session ID until it shuts down.
+type SessionIDRotationOptions struct {
+	// Replaces the session ID once it is this old. Zero never replaces it on a schedule.
+	Interval time.Duration
+	// Replaces the session ID whenever a newer version of the rulesets is applied, so each session covers
+	// one config sync epoch
+	OnConfigSync bool
+}
+
+type serverSession struct {
+	id        string
+	startedAt time.Time
+	interval  time.Duration
+	mu        sync.Mutex
+}
+
+func newServerSession(options SessionIDRotationOptions) *serverSession {
+	return &serverSession{
+		id:        uuid.NewString(),
+		startedAt: time.Now(),
+		interval:  options.Interval,
+	}
+}
+
+// Returns the current session ID, first replacing it if it is older than the rotation interval
+func (s *serverSession) getID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.interval > 0 && time.Since(s.startedAt) >= s.interval {
+		s.rotateLocked()
+	}
+	return s.id
+}
+
+func (s *serverSession) rotate() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rotateLocked()
+}
+
+func (s *serverSession) rotateLocked() string {
+	s.id = uuid.NewString()
+	s.startedAt = time.Now()
+	global.Logger().LogStep(StatsigProcessSync, "Rotated the server session ID")
+	return s.id
+}
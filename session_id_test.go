@@ -0,0 +1,100 @@
+package statsig
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSessionID(t *testing.T) {
+	dcs, _ := os.ReadFile("download_config_specs.json")
+	var mu sync.Mutex
+	var sessionIDs []string
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		sessionIDs = append(sessionIDs, req.Header.Get("STATSIG-SERVER-SESSION-ID"))
+		mu.Unlock()
+		res.WriteHeader(http.StatusOK)
+		if strings.Contains(req.URL.Path, "download_config_specs") {
+			_, _ = res.Write(dcs)
+		} else {
+			_, _ = res.Write([]byte("{}"))
+		}
+	}))
+	defer testServer.Close()
+
+	c := NewClientWithOptions("secret-key", &Options{
+		API:                  testServer.URL,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+	sessionID := c.GetSessionID()
+	if sessionID == "" || c.GetSessionID() != sessionID {
+		t.Errorf("Expected a session ID that lasts as long as the Client, got %q", sessionID)
+	}
+	mu.Lock()
+	if len(sessionIDs) == 0 || sessionIDs[0] != sessionID {
+		t.Errorf("Expected requests to be sent with the session ID %s, got %v", sessionID, sessionIDs)
+	}
+	mu.Unlock()
+
+	rotated := c.RotateSessionID()
+	if rotated == sessionID || c.GetSessionID() != rotated {
+		t.Errorf("Expected RotateSessionID to replace the session ID")
+	}
+}
+
+func TestSessionIDRotationOnConfigSync(t *testing.T) {
+	dcs, _ := os.ReadFile("download_config_specs.json")
+	var specs downloadConfigSpecResponse
+	_ = json.Unmarshal(dcs, &specs)
+	var mu sync.Mutex
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+		if strings.Contains(req.URL.Path, "download_config_specs") {
+			mu.Lock()
+			defer mu.Unlock()
+			specs.Time += 1
+			_ = json.NewEncoder(res).Encode(specs)
+		} else {
+			_, _ = res.Write([]byte("{}"))
+		}
+	}))
+	defer testServer.Close()
+
+	c := NewClient("secret-key",
+		WithAPI(testServer.URL),
+		WithConfigSyncInterval(10*time.Millisecond),
+		WithSessionIDRotationOnConfigSync(),
+		WithOutputLoggerOptions(getOutputLoggerOptionsForTest(t)),
+		WithStatsigLoggerOptions(getStatsigLoggerOptionsForTest(t)),
+	)
+	defer c.Shutdown()
+	sessionID := c.GetSessionID()
+	waitForCondition(t, func() bool { return c.GetSessionID() != sessionID })
+}
+
+func TestSessionIDRotationInterval(t *testing.T) {
+	session := newServerSession(SessionIDRotationOptions{Interval: 20 * time.Millisecond})
+	sessionID := session.getID()
+	if session.getID() != sessionID {
+		t.Errorf("Expected the session ID to last for the interval")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if session.getID() == sessionID {
+		t.Errorf("Expected the session ID to be replaced once older than the interval")
+	}
+
+	session = newServerSession(SessionIDRotationOptions{})
+	sessionID = session.getID()
+	session.startedAt = time.Now().Add(-24 * time.Hour)
+	if session.getID() != sessionID {
+		t.Errorf("Expected the session ID never to be replaced without an interval")
+	}
+}
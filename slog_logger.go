@@ -0,0 +1,37 @@
+//go:build go1.21
+
+package statsig
+
+import (
+	"context"
+	"log/slog"
+)
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// Adapts a *slog.Logger to the Logger interface, for OutputLoggerOptions.Logger.
+// Records are logged with a "component" attribute of "statsig". A nil logger uses slog.Default().
+func NewSlogLogger(logger *slog.Logger) Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &slogLogger{logger: logger.With("component", "statsig")}
+}
+
+func (l *slogLogger) Debug(msg string, fields ...interface{}) {
+	l.logger.Log(context.Background(), slog.LevelDebug, msg, fields...)
+}
+
+func (l *slogLogger) Info(msg string, fields ...interface{}) {
+	l.logger.Log(context.Background(), slog.LevelInfo, msg, fields...)
+}
+
+func (l *slogLogger) Warn(msg string, fields ...interface{}) {
+	l.logger.Log(context.Background(), slog.LevelWarn, msg, fields...)
+}
+
+func (l *slogLogger) Error(msg string, fields ...interface{}) {
+	l.logger.Log(context.Background(), slog.LevelError, msg, fields...)
+}
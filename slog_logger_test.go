@@ -0,0 +1,62 @@
+//go:build go1.21
+
+package statsig
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buffer bytes.Buffer
+	handler := slog.NewJSONHandler(&buffer, &slog.HandlerOptions{Level: slog.LevelDebug})
+	InitializeGlobalOutputLogger(OutputLoggerOptions{
+		Logger:      NewSlogLogger(slog.New(handler)),
+		EnableDebug: true,
+	})
+	defer InitializeGlobalOutputLogger(getOutputLoggerOptionsForTest(t))
+
+	global.Logger().Log("[Statsig] Initialized\n", nil)
+	global.Logger().LogStep(StatsigProcessSync, "Downloading specs")
+	global.Logger().LogWarning("Statsig is already initialized.")
+	global.Logger().LogError(errors.New("request failed"))
+	global.Logger().LogError("plain failure")
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("Expected 5 records, got %d: %s", len(lines), buffer.String())
+	}
+	records := make([]map[string]interface{}, 0, len(lines))
+	for _, line := range lines {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Failed to parse record %s", line)
+		}
+		if record["component"] != "statsig" {
+			t.Errorf("Expected the statsig component attribute, got %v", record)
+		}
+		records = append(records, record)
+	}
+	expected := []struct{ level, msg string }{
+		{"INFO", "Initialized"},
+		{"DEBUG", "Downloading specs"},
+		{"WARN", "Statsig is already initialized."},
+		{"ERROR", "request failed"},
+		{"ERROR", "plain failure"},
+	}
+	for i, e := range expected {
+		if records[i]["level"] != e.level || records[i]["msg"] != e.msg {
+			t.Errorf("Expected %s %q, got %v", e.level, e.msg, records[i])
+		}
+	}
+	if records[1]["process"] != string(StatsigProcessSync) {
+		t.Errorf("Expected the process field on debug steps, got %v", records[1])
+	}
+	if records[3]["error"] != "request failed" {
+		t.Errorf("Expected the error field, got %v", records[3])
+	}
+}
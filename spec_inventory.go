@@ -0,0 +1,94 @@
+package statsig
+
+import "sort"
+
+const (
+	SpecTypeFeatureGate   = "feature_gate"
+	SpecTypeDynamicConfig = "dynamic_config"
+	SpecTypeLayer         = "layer"
+)
+
+// The Entity of dynamic configs that are experiments
+const EntityExperiment = "experiment"
+
+// A read-only summary of a single gate, dynamic config, experiment or layer definition
+type SpecEntity struct {
+	Name string
+	// One of SpecTypeFeatureGate, SpecTypeDynamicConfig or SpecTypeLayer
+	Type string
+	// The console entity, e.g. "experiment" or "autotune" for dynamic configs
+	Entity    string
+	Enabled   bool
+	RuleCount int
+	IDType    string
+}
+
+// A point-in-time copy of the definitions the SDK is evaluating with.
+// It shares no state with the Client, so it can be read from any goroutine for as long as needed.
+type SpecInventory struct {
+	// The lcut (last config update time) of the rulesets, in unix millis
+	LastConfigSyncTime int64
+	// Sorted by Type, then Name
+	Entities []SpecEntity
+}
+
+func (s *store) getSpecInventory() SpecInventory {
+	return getSpecInventory(s.getRulesets())
+}
+
+func getSpecInventory(rulesets *rulesetSnapshot) SpecInventory {
+	inventory := SpecInventory{
+		LastConfigSyncTime: rulesets.time,
+		Entities:           make([]SpecEntity, 0, len(rulesets.featureGates)+len(rulesets.dynamicConfigs)+len(rulesets.layerConfigs)),
+	}
+	add := func(specType string, specs map[string]configSpec) {
+		for _, spec := range specs {
+			inventory.Entities = append(inventory.Entities, SpecEntity{
+				Name:      spec.Name,
+				Type:      specType,
+				Entity:    spec.Entity,
+				Enabled:   spec.Enabled,
+				RuleCount: len(spec.Rules),
+				IDType:    spec.IDType,
+			})
+		}
+	}
+	add(SpecTypeFeatureGate, rulesets.featureGates)
+	add(SpecTypeDynamicConfig, rulesets.dynamicConfigs)
+	add(SpecTypeLayer, rulesets.layerConfigs)
+	sort.Slice(inventory.Entities, func(i, j int) bool {
+		a, b := inventory.Entities[i], inventory.Entities[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Name < b.Name
+	})
+	return inventory
+}
+
+// Returns the entities for which keep returns true, in inventory order
+func (i SpecInventory) filter(keep func(SpecEntity) bool) []SpecEntity {
+	entities := make([]SpecEntity, 0)
+	for _, entity := range i.Entities {
+		if keep(entity) {
+			entities = append(entities, entity)
+		}
+	}
+	return entities
+}
+
+func isFeatureGate(entity SpecEntity) bool {
+	return entity.Type == SpecTypeFeatureGate
+}
+
+func isDynamicConfig(entity SpecEntity) bool {
+	return entity.Type == SpecTypeDynamicConfig && entity.Entity != EntityExperiment
+}
+
+func isExperiment(entity SpecEntity) bool {
+	return entity.Type == SpecTypeDynamicConfig && entity.Entity == EntityExperiment
+}
+
+func isLayer(entity SpecEntity) bool {
+	return entity.Type == SpecTypeLayer
+}
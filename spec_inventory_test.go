@@ -0,0 +1,95 @@
+package statsig
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestSpecInventory(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	InitializeWithOptions("secret-key", &Options{
+		BootstrapValues:      string(bytes),
+		LocalMode:            true,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer ShutdownAndDangerouslyClearInstance()
+
+	inventory := GetSpecInventory()
+	if inventory.LastConfigSyncTime != configSyncTime {
+		t.Errorf("Expected lcut %d, got %d", configSyncTime, inventory.LastConfigSyncTime)
+	}
+	if len(inventory.Entities) != 9 {
+		t.Fatalf("Expected 9 entities, got %d", len(inventory.Entities))
+	}
+	first := inventory.Entities[0]
+	if first.Name != "sample_experiment" || first.Type != SpecTypeDynamicConfig {
+		t.Errorf("Expected entities sorted by type then name, got %+v", first)
+	}
+
+	found := false
+	for _, entity := range inventory.Entities {
+		if entity.Name == "sample_experiment" {
+			found = true
+			if entity.RuleCount != 3 || !entity.Enabled {
+				t.Errorf("Unexpected summary for sample_experiment %+v", entity)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected sample_experiment in the inventory")
+	}
+
+	inventory.Entities[0].Name = "mutated"
+	if GetSpecInventory().Entities[0].Name != "sample_experiment" {
+		t.Errorf("Expected the inventory to be a copy")
+	}
+}
+
+func TestSpecLists(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	var specs map[string]interface{}
+	_ = json.Unmarshal(bytes, &specs)
+	for _, config := range specs["dynamic_configs"].([]interface{}) {
+		config := config.(map[string]interface{})
+		if config["name"] == "sample_experiment" {
+			config["entity"] = EntityExperiment
+		}
+	}
+	bootstrap, _ := json.Marshal(specs)
+	InitializeWithOptions("secret-key", &Options{
+		BootstrapValues:      string(bootstrap),
+		LocalMode:            true,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer ShutdownAndDangerouslyClearInstance()
+
+	names := func(entities []SpecEntity) []string {
+		result := make([]string, 0, len(entities))
+		for _, entity := range entities {
+			result = append(result, entity.Name)
+		}
+		return result
+	}
+	expected := map[string][]string{
+		"gates":       {"always_on_gate", "fractional_gate", "on_for_id_list", "on_for_statsig_email"},
+		"configs":     {"test_config"},
+		"experiments": {"sample_experiment"},
+		"layers":      {"a_layer", "b_layer_no_alloc", "c_layer_with_holdout"},
+	}
+	actual := map[string][]string{
+		"gates":       names(GetFeatureGateList()),
+		"configs":     names(GetDynamicConfigList()),
+		"experiments": names(GetExperimentList()),
+		"layers":      names(GetLayerList()),
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected lists %v, got %v", expected, actual)
+	}
+	if experiment := GetExperimentList()[0]; experiment.Entity != EntityExperiment || !experiment.Enabled {
+		t.Errorf("Unexpected summary for sample_experiment %+v", experiment)
+	}
+}
@@ -0,0 +1,51 @@
+package statsig
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Verifies config specs before they are applied, so corrupt or truncated payloads are rejected
+// instead of replacing a working ruleset
+type IntegrityOptions struct {
+	// Called with the raw download_config_specs JSON from the network or the DataAdapter, and for network
+	// responses with the value of the SignatureHeader. Returning an error rejects the specs and keeps the
+	// current ones. Called from the goroutine that syncs the rulesets.
+	Verify func(specs []byte, signature string) error
+	// Response header holding the checksum or signature of the specs, e.g. one added by your proxy
+	SignatureHeader string
+}
+
+// An error wrapping the reason the Verify function of IntegrityOptions rejected config specs
+type IntegrityError struct {
+	Source string
+	Err    error
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("config specs from %s failed the integrity check: %s", e.Source, e.Err)
+}
+
+func (e *IntegrityError) Unwrap() error {
+	return e.Err
+}
+
+func (s *store) verifySpecs(specs []byte, header http.Header, source string) (err error) {
+	options := s.transport.options.IntegrityOptions
+	if options.Verify == nil {
+		return nil
+	}
+	defer func() {
+		if panicked := recover(); panicked != nil {
+			err = &IntegrityError{Source: source, Err: toError(panicked)}
+		}
+	}()
+	signature := ""
+	if header != nil && options.SignatureHeader != "" {
+		signature = header.Get(options.SignatureHeader)
+	}
+	if verifyErr := options.Verify(specs, signature); verifyErr != nil {
+		return &IntegrityError{Source: source, Err: verifyErr}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package statsig
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestIntegrityOptions(t *testing.T) {
+	specs, _ := os.ReadFile("download_config_specs.json")
+	tampered := []byte(strings.NewReplacer(`"always_on_gate"`, `"tampered_gate"`, "1631638014811", "1631638014812").Replace(string(specs)))
+	var serveTampered int32
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "download_config_specs") {
+			checksum := sha256.Sum256(specs)
+			res.Header().Set("X-Specs-SHA256", hex.EncodeToString(checksum[:]))
+			if atomic.LoadInt32(&serveTampered) == 1 {
+				_, _ = res.Write(tampered)
+			} else {
+				_, _ = res.Write(specs)
+			}
+			return
+		}
+		res.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	var mu sync.Mutex
+	var integrityErrors []*IntegrityError
+	c := NewClientWithOptions("secret-key", &Options{
+		API: testServer.URL,
+		IntegrityOptions: IntegrityOptions{
+			SignatureHeader: "X-Specs-SHA256",
+			Verify: func(specs []byte, signature string) error {
+				checksum := sha256.Sum256(specs)
+				if hex.EncodeToString(checksum[:]) != signature {
+					return errors.New("checksum mismatch")
+				}
+				return nil
+			},
+		},
+		ErrorCallback: func(err error, context string) {
+			var integrityErr *IntegrityError
+			if errors.As(err, &integrityErr) {
+				mu.Lock()
+				integrityErrors = append(integrityErrors, integrityErr)
+				mu.Unlock()
+			}
+		},
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+	user := User{UserID: "123"}
+	if !c.CheckGate(user, "always_on_gate") {
+		t.Fatalf("Expected verified specs to be applied")
+	}
+
+	t.Run("rejects tampered network responses", func(t *testing.T) {
+		atomic.StoreInt32(&serveTampered, 1)
+		defer atomic.StoreInt32(&serveTampered, 0)
+		c.evaluator.store.fetchConfigSpecsFromServer(false)
+		if !c.CheckGate(user, "always_on_gate") || c.CheckGate(user, "tampered_gate") {
+			t.Errorf("Expected the tampered specs to be rejected")
+		}
+	})
+
+	t.Run("rejects unverified adapter specs", func(t *testing.T) {
+		c.evaluator.store.applyConfigSpecsFromAdapter(string(tampered), false)
+		if !c.CheckGate(user, "always_on_gate") {
+			t.Errorf("Expected the adapter specs to be rejected")
+		}
+	})
+
+	t.Run("does not verify the adapter specs last applied again", func(t *testing.T) {
+		store := c.evaluator.store
+		store.mu.Lock()
+		store.adapterSpecsHash = getHashBase64StringEncoding(string(tampered))
+		store.mu.Unlock()
+		defer func() {
+			store.mu.Lock()
+			store.adapterSpecsHash = ""
+			store.mu.Unlock()
+		}()
+		// Verifying it would add a third integrity error below
+		store.applyConfigSpecsFromAdapter(string(tampered), false)
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(integrityErrors) != 2 || integrityErrors[0].Source != "network" || integrityErrors[1].Source != "DataAdapter" {
+		t.Errorf("Expected an integrity error for each rejected payload, got %v", integrityErrors)
+	}
+}
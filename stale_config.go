@@ -0,0 +1,59 @@
+package statsig
+
+import (
+	"fmt"
+	"time"
+)
+
+// Records that the rulesets were just synced, from the network, a data adapter, bootstrap values or a snapshot,
+// whether or not they changed
+func (s *store) markSynced() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.syncedAt = time.Now()
+	s.staleNotified = false
+}
+
+// How long ago the rulesets were last synced, or the store created if they never were, and whether that is
+// longer than Options.StaleConfigThreshold. Rulesets are never stale without a threshold, or when nothing
+// syncs them: in LocalMode, or with DisableNetwork unless a DataAdapter is polled for updates.
+func (s *store) getStaleness() (time.Duration, bool) {
+	s.mu.RLock()
+	syncedAt := s.syncedAt
+	if syncedAt.IsZero() {
+		syncedAt = s.createdAt
+	}
+	s.mu.RUnlock()
+	sinceSync := time.Since(syncedAt)
+	options := s.transport.options
+	polled := !options.DisableNetwork || (s.dataAdapter != nil && s.dataAdapter.ShouldBeUsedForQueryingUpdates(CONFIG_SPECS_KEY))
+	stale := !options.LocalMode && polled && options.StaleConfigThreshold > 0 && sinceSync > options.StaleConfigThreshold
+	return sinceSync, stale
+}
+
+// Warns and calls the StaleConfigCallback when the rulesets became stale since the last check.
+// Only the first check of each stale period notifies.
+func (s *store) checkStaleness() {
+	sinceSync, stale := s.getStaleness()
+	if !stale {
+		return
+	}
+	s.mu.Lock()
+	notified := s.staleNotified
+	s.staleNotified = true
+	s.mu.Unlock()
+	if notified {
+		return
+	}
+	global.Logger().LogWarning(fmt.Sprintf("[Statsig] The rulesets have not been synced for %s\n", sinceSync.Round(time.Second)), "sinceLastSync", sinceSync)
+	callback := s.transport.options.StaleConfigCallback
+	if callback == nil {
+		return
+	}
+	defer func() {
+		if err := recover(); err != nil {
+			logRecoveredPanic("StaleConfigCallback", err)
+		}
+	}()
+	callback(sinceSync)
+}
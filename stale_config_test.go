@@ -0,0 +1,96 @@
+package statsig
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStaleConfig(t *testing.T) {
+	var dcsOnline int32 = 1
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "download_config_specs") {
+			if atomic.LoadInt32(&dcsOnline) == 0 {
+				res.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			bytes, _ := os.ReadFile("download_config_specs.json")
+			_, _ = res.Write(bytes)
+			return
+		}
+		res.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	var alerts int32
+	c := NewClientWithOptions("secret-key", &Options{
+		API:                  testServer.URL,
+		ConfigSyncInterval:   10 * time.Millisecond,
+		StaleConfigThreshold: 100 * time.Millisecond,
+		StaleConfigCallback: func(sinceLastSync time.Duration) {
+			if sinceLastSync <= 100*time.Millisecond {
+				t.Errorf("Expected the time since the last sync to exceed the threshold, got %s", sinceLastSync)
+			}
+			atomic.AddInt32(&alerts, 1)
+		},
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+
+	time.Sleep(200 * time.Millisecond)
+	if c.IsConfigStale() || atomic.LoadInt32(&alerts) != 0 {
+		t.Errorf("Expected fresh rulesets while syncs succeed")
+	}
+
+	atomic.StoreInt32(&dcsOnline, 0)
+	waitForCondition(t, func() bool { return atomic.LoadInt32(&alerts) == 1 })
+	if !c.IsConfigStale() {
+		t.Errorf("Expected stale rulesets once syncs fail for longer than the threshold")
+	}
+	if !c.CheckGate(User{UserID: "123"}, "always_on_gate") {
+		t.Errorf("Expected stale rulesets to still be served")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if atomic.LoadInt32(&alerts) != 1 {
+		t.Errorf("Expected a single alert per stale period, got %d", atomic.LoadInt32(&alerts))
+	}
+
+	atomic.StoreInt32(&dcsOnline, 1)
+	waitForCondition(t, func() bool { return !c.IsConfigStale() })
+	atomic.StoreInt32(&dcsOnline, 0)
+	waitForCondition(t, func() bool { return atomic.LoadInt32(&alerts) == 2 })
+}
+
+func TestStaleConfigDisabled(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	c := NewClientWithOptions("secret-key", &Options{
+		LocalMode:            true,
+		BootstrapValues:      string(bytes),
+		StaleConfigThreshold: time.Millisecond,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+	time.Sleep(10 * time.Millisecond)
+	if c.IsConfigStale() {
+		t.Errorf("Expected rulesets never to be stale in LocalMode")
+	}
+
+	offline := NewClientWithOptions("secret-key", &Options{
+		DisableNetwork:       true,
+		BootstrapValues:      string(bytes),
+		StaleConfigThreshold: time.Millisecond,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer offline.Shutdown()
+	time.Sleep(10 * time.Millisecond)
+	if offline.IsConfigStale() {
+		t.Errorf("Expected rulesets never to be stale with DisableNetwork and no polled DataAdapter")
+	}
+}
@@ -2,48 +2,235 @@
 package statsig
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"net/http"
+	"sync"
 	"time"
+
+	"github.com/statsig-io/ip3country-go/pkg/countrylookup"
 )
 
 const DefaultEndpoint = "https://statsigapi.net/v1"
 
-var instance *Client
+var (
+	instance *Client
+	// Guards instance, which Initialize replaces after Shutdown while other goroutines may still be calling the SDK
+	instanceMu sync.RWMutex
+	// Held while the global instance initializes, so concurrent calls to Initialize build a single instance
+	initializeMu sync.Mutex
+)
 
-// Initializes the global Statsig instance with the given sdkKey
-func Initialize(sdkKey string) {
-	InitializeGlobalOutputLogger(OutputLoggerOptions{})
-	if IsInitialized() {
-		global.Logger().Log("Statsig is already initialized.", nil)
-		return
-	}
+func getInstance() *Client {
+	instanceMu.RLock()
+	defer instanceMu.RUnlock()
+	return instance
+}
+
+func setInstance(client *Client) {
+	instanceMu.Lock()
+	defer instanceMu.Unlock()
+	instance = client
+}
 
-	instance = NewClient(sdkKey)
+// Initializes the global Statsig instance with the given sdkKey and functional options
+func Initialize(sdkKey string, opts ...Option) {
+	InitializeWithOptions(sdkKey, NewOptions(opts...))
 }
 
-// Advanced options for configuring the Statsig SDK
+// Base URLs, like Options.API, for individual endpoints, so traffic can be split between a CDN, a proxy
+// and Statsig. Endpoints left empty use Options.API.
+type APIOverrides struct {
+	DownloadConfigSpecs string `json:"downloadConfigSpecs"`
+	LogEvent            string `json:"logEvent"`
+	IDLists             string `json:"idLists"`
+}
+
+// Advanced options for configuring the Statsig SDK. Settings left unset are read from the STATSIG_*
+// environment variables, see EnvAPI.
 type Options struct {
-	API                  string      `json:"api"`
-	Environment          Environment `json:"environment"`
-	LocalMode            bool        `json:"localMode"`
-	ConfigSyncInterval   time.Duration
-	IDListSyncInterval   time.Duration
+	// Base URL of the Statsig API, such as https://statsigapi.net/v1. APIOverrides take precedence for their endpoints.
+	API          string       `json:"api"`
+	APIOverrides APIOverrides `json:"apiOverrides"`
+	Environment  Environment  `json:"environment"`
+	LocalMode    bool         `json:"localMode"`
+	// How often config specs are synced. Intervals below one second are raised to one second, and each
+	// instance adds a random jitter of up to 10% so that servers deployed together poll at different times.
+	ConfigSyncInterval time.Duration
+	IDListSyncInterval time.Duration
+	// Downloads only the entities targeting this app, to save memory and parse time in projects with many
+	// unrelated entities. Entities that do not list the app in their target apps are dropped, so gates they
+	// depend on must target the app too. Every entity is downloaded when it is empty.
+	TargetApp string
+	// Asks the API for the changes since the last sync instead of every config spec. Only for an API, such as a
+	// relay proxy, implementing this protocol: a request with "acceptsDeltas": true may be answered with
+	// "is_delta": true, the entities changed since its sinceTime, the names of the deleted ones in deleted_gates,
+	// deleted_configs and deleted_layers, and a "checksum" of the merged specs. The checksum is the DJB2 hash
+	// of the JSON of feature_gates, dynamic_configs and layer_configs, each sorted by name. A delta that does
+	// not match its checksum is discarded and all the specs are downloaded again.
+	EnableConfigSpecDeltas bool
+	// Never downloads ID lists, for services that do not target segments backed by them.
+	// Users are then never in an ID list segment.
+	DisableIDLists       bool
 	LoggingInterval      time.Duration
 	LoggingMaxBufferSize int
-	BootstrapValues      string
+	// A download_config_specs response to initialize from without a network request. Evaluations
+	// report the reason Bootstrap. Ignored when a DataAdapter is set.
+	BootstrapValues string
+	// Called with the raw ruleset JSON and its lcut each time a new ruleset version is applied,
+	// whether it came from the network, BootstrapValues or the DataAdapter
 	RulesUpdatedCallback func(rules string, time int64)
-	InitTimeout          time.Duration
+	// Maximum time initialize waits for the config specs and ID lists. When it runs out, initialize returns and
+	// the downloads continue in the background. Until the specs arrive, evaluations return defaults with the
+	// reason Uninitialized.
+	InitTimeout time.Duration
+	// Recovered panics and internal errors are only logged locally, never sent to Statsig's /sdk_exception endpoint
+	DisableErrorBoundaryReporting bool
+	// Called whenever the SDK swallows an error, such as a failed config sync or event flush, or a panic in
+	// the DataAdapter, so applications can alert on SDK degradation. The context is one of the ErrorContext
+	// constants. Called from SDK goroutines, so it must not block.
+	ErrorCallback func(err error, context string)
+	// Called with every custom event and exposure before it is queued, so standard metadata such as a build SHA,
+	// region or tenant can be added in one place. Sampled out and deduplicated exposures are not passed to it.
+	// Exposure metadata maps are reused once the event is sent, so the hook must not keep a reference to them.
+	EventEnrichmentHook func(event *Event)
+	// Called with every gate, config and layer exposure right before it is queued, after the EventEnrichmentHook.
+	// It may modify the exposure, and returning false drops it, e.g. to skip exposures of synthetic traffic
+	// without disabling exposure logging. The same rules as for the EventEnrichmentHook apply to its metadata.
+	ExposureInterceptor func(exposure *Event) bool
+	// Scrubs the email, IP address and user agent of users from logged events, emails from the values of
+	// User.Custom and event metadata, and emails and IP addresses from the exceptions reported to Statsig.
+	// Evaluations still use them.
+	PIIScrubbing PIIScrubbingMode
+	// Secret key of the HMAC that PIIScrubbingHash replaces PII with. Keep it stable to join events across
+	// restarts, and out of Statsig. Required by PIIScrubbingHash.
+	PIIScrubbingKey []byte
+	// Replaces encoding/json for the JSON exchanged with Statsig and the data adapter. See JSONCodec.
+	JSONCodec JSONCodec
+	// Total time initialize may spend on the adapter read, config download and ID list download, in that order.
+	// A step still running when the budget is spent continues in the background, and an adapter read that
+	// has not returned by then is ignored. The lower of InitBudget and InitTimeout applies.
+	InitBudget           time.Duration
 	DataAdapter          IDataAdapter
 	OutputLoggerOptions  OutputLoggerOptions
 	StatsigLoggerOptions StatsigLoggerOptions
+	UAParserOptions      UAParserOptions
+	CountryLookupOptions CountryLookupOptions
+	GlobalCustomFields   map[string]interface{} // Merged into every User.Custom. Values set on the User take precedence.
+	// Applied to every User before it is evaluated or logged, and before the Environment and GlobalCustomFields
+	// are added, so internal identities can be mapped to Statsig users in one place, e.g. to lowercase emails or
+	// derive CustomIDs. Users without a UserID or CustomIDs are only rejected if they still have none afterwards.
+	// Called on every evaluation, so it must be fast and safe for concurrent use.
+	UserTransform func(user User) User
+	// When set, gate, config and layer evaluations taking longer than this return the default value with reason Timeout.
+	// They keep running in the background, and while 1000 of them are, evaluations return the default right away.
+	EvaluationLatencyBudget time.Duration
+	CustomConditionOptions  CustomConditionOptions
+	MemoryPressureOptions   MemoryPressureOptions
+	EventSpoolOptions       EventSpoolOptions
+	// Caches GetClientInitializeResponse results for identical users. Disabled by default.
+	ClientInitializeResponseCacheOptions ClientInitializeResponseCacheOptions
+	// When set, identical exposures for the same user, entity and rule are only logged once within this window
+	ExposureDedupeWindow time.Duration
+	// Fraction (0 to 1) of events to keep, by event name. Kept events get a samplingRate metadata
+	// entry so analysis can reweight them. Applies to exposure event names such as statsig::gate_exposure too.
+	EventSamplingRates map[string]float64
+	// Fraction (0 to 1) of gate, config and layer exposures to keep. Zero keeps every exposure.
+	// Manual exposures are never sampled.
+	ExposureSamplingRate float64
+	// Fraction (0 to 1) of diagnostics events (initialize, config sync and API call timings) to send to Statsig.
+	// Zero sends every diagnostics event. To send none, use the StatsigLoggerOptions Disable*Diagnostics options.
+	DiagnosticsSamplingRate float64
+	// Receives each diagnostics payload as JSON ({"context": ..., "markers": [...]}), the same metadata sent to
+	// Statsig in statsig::diagnostics events, so init and sync timings can be exported to your own telemetry.
+	// Payloads are delivered even when DiagnosticsSamplingRate or the Disable*Diagnostics options keep them from
+	// Statsig. Called from the goroutine that flushes events.
+	DiagnosticsCallback  func(context DiagnosticsContext, payload []byte)
+	UserInterningOptions UserInterningOptions
+	MetricsOptions       MetricsOptions
+	TracingOptions       TracingOptions
+	ObservabilityClient  ObservabilityClient
+	// Replaces the system clock for current_time conditions and the timestamps of events and diagnostics
+	Clock Clock
+	// How long the rulesets can go without a successful sync, from the network or a data adapter, before they
+	// are stale. Stale rulesets are still served. Zero disables stale config detection, and so do LocalMode and
+	// DisableNetwork without a DataAdapter polled for updates, as nothing syncs the rulesets then.
+	StaleConfigThreshold time.Duration
+	// Called once the rulesets become stale, with how long ago they were last synced. Called again only after
+	// a later sync succeeds and they become stale again. Called from the goroutine that syncs the rulesets.
+	StaleConfigCallback func(sinceLastSync time.Duration)
+	// Stops collecting diagnostics altogether: no statsig::diagnostics events are logged, the DiagnosticsCallback
+	// is never called and evaluations allocate no diagnostics markers. Initialization steps are still logged.
+	DisableDiagnostics bool
+	// Connection pooling and mutual TLS of the requests to Statsig
+	HTTPOptions HTTPOptions
+	// Sends every request, ID list downloads included, over this Unix domain socket, e.g. to a sidecar or local
+	// egress proxy. The proxy gets the requests unchanged, so point API at the http:// URL it expects.
+	UnixSocketPath string
+	// Added to every request, ID list downloads and exception reports included, e.g. for the auth or routing
+	// headers of a corporate gateway. Headers the SDK sets itself, such as STATSIG-API-KEY, are not replaced.
+	AdditionalHeaders map[string]string
+	// Checks the integrity of config specs from the network and the DataAdapter before they are applied
+	IntegrityOptions IntegrityOptions
+	// Keeps ID lists in memory-mapped files instead of the heap
+	IDListStorageOptions IDListStorageOptions
+	// Path of a download_config_specs response to initialize from, read once on initialize. BootstrapValues
+	// take precedence. Evaluations report the reason Bootstrap.
+	BootstrapFile string
+	// Makes no outbound requests at all: rulesets and ID lists are never downloaded, events and diagnostics
+	// are dropped instead of sent, and errors are never reported to Statsig. Rulesets come only from
+	// BootstrapValues, the BootstrapFile or the DataAdapter, e.g. for air-gapped deployments.
+	DisableNetwork bool
+	// Called with the depth of the event queue and the number of events lost so far once queued events are
+	// flushed, and whenever events could not be queued, so operators can alert when events are lost. Called
+	// from SDK goroutines, so it must not block, and it must not log events itself.
+	EventQueueMetricsCallback func(metrics EventQueueMetrics)
+	// Rotates the server session ID periodically or on every config sync. See Client.GetSessionID.
+	SessionIDRotationOptions SessionIDRotationOptions
 }
 
 type OutputLoggerOptions struct {
+	// Receives SDK logs as leveled, structured records. Takes precedence over LogCallback.
+	Logger                 Logger
 	LogCallback            func(message string, err error)
 	EnableDebug            bool
 	DisableInitDiagnostics bool
 	DisableSyncDiagnostics bool
+	// The least severe logs to output, whichever destination they go to. The zero value, LogLevelInfo,
+	// outputs everything but debug logs, which EnableDebug also turns on. LogLevelNone outputs nothing.
+	LogLevel LogLevel
+	// Receives the logs as text lines instead of standard output, e.g. to route them to a file.
+	// The Logger and LogCallback take precedence. Writes are serialized.
+	Writer io.Writer
+}
+
+// Controls parsing of User.UserAgent for browser and OS conditions
+type UAParserOptions struct {
+	// Skips loading the user agent parser. Saves memory, but browser_name, browser_version,
+	// os_name and os_version conditions only match values set explicitly on the User.
+	Disabled bool
+}
+
+// Resolves an IP address to a two letter ISO 3166-1 country code for country conditions, e.g. with a
+// MaxMind GeoIP2 database or an internal geo service. Called from the goroutine evaluating the condition,
+// so implementations must be safe for concurrent use. A panic is treated as an unknown country.
+type CountryLookup interface {
+	LookupIp(ip string) (string, bool)
+}
+
+// Returns the built-in IP to country table, e.g. for a custom CountryLookup to fall back to for addresses
+// it cannot resolve. Loading the table takes tens of milliseconds and a few megabytes.
+func NewBuiltinCountryLookup() CountryLookup {
+	return countrylookup.New()
+}
+
+// Controls how User.IpAddress is resolved to a country when User.Country is not set
+type CountryLookupOptions struct {
+	// Skips loading the built-in IP to country table. Country conditions only match User.Country.
+	Disabled bool
+	// Replaces the built-in IP to country table
+	Lookup CountryLookup
 }
 
 type StatsigLoggerOptions struct {
@@ -60,34 +247,103 @@ type Environment struct {
 
 // IsInitialized returns whether the global Statsig instance has already been initialized or not
 func IsInitialized() bool {
-	return instance != nil
+	return getInstance() != nil
+}
+
+// Whether the global Statsig instance is initialized and has not been shut down. Initializing again after
+// Shutdown replaces the instance.
+func isRunning() bool {
+	client := getInstance()
+	return client != nil && !client.isShutdown()
 }
 
-// Initializes the global Statsig instance with the given sdkKey and options
+// Initializes the global Statsig instance with the given sdkKey and options. After Shutdown, a new
+// instance is built with the new options, which is safe while other goroutines keep calling the SDK.
 func InitializeWithOptions(sdkKey string, options *Options) {
 	InitializeGlobalOutputLogger(options.OutputLoggerOptions)
-	if IsInitialized() {
-		global.Logger().Log("Statsig is already initialized.", nil)
+	initializeMu.Lock()
+	defer initializeMu.Unlock()
+	if isRunning() {
+		global.Logger().LogWarning("Statsig is already initialized.")
 		return
 	}
 
-	if options.InitTimeout > 0 {
-		channel := make(chan *Client, 1)
-		go func() {
-			client := NewClientWithOptions(sdkKey, options)
-			channel <- client
-		}()
+	start := time.Now()
+	setInstance(NewClientWithOptions(sdkKey, options))
+	if options.InitTimeout > 0 && time.Since(start) >= options.InitTimeout {
+		global.Logger().LogStep(StatsigProcessInitialize, "Timed out, continuing in the background")
+	}
+}
 
-		select {
-		case res := <-channel:
-			instance = res
-		case <-time.After(options.InitTimeout):
-			global.Logger().LogStep(StatsigProcessInitialize, "Timed out")
-			return
-		}
-	} else {
-		instance = NewClientWithOptions(sdkKey, options)
+// Initializes the global Statsig instance with the given sdkKey and options, aborting the initial config
+// and ID list requests if ctx is done first. In that case the instance is shut down and left uninitialized,
+// and the context's error is returned.
+func InitializeWithContextAndOptions(ctx context.Context, sdkKey string, options *Options) error {
+	InitializeGlobalOutputLogger(options.OutputLoggerOptions)
+	initializeMu.Lock()
+	defer initializeMu.Unlock()
+	if isRunning() {
+		global.Logger().LogWarning("Statsig is already initialized.")
+		return nil
+	}
+	client := newClient(ctx, sdkKey, options, nil)
+	if err := ctx.Err(); err != nil {
+		global.Logger().LogStep(StatsigProcessInitialize, "Cancelled")
+		// ctx is already done, so it cannot bound the flush
+		client.Shutdown()
+		return err
+	}
+	setInstance(client)
+	return nil
+}
+
+// Result of initializing the global Statsig instance in the background
+type InitResult struct {
+	// Whether rulesets were loaded, from the network, BootstrapValues or the DataAdapter
+	Success bool
+	// Where the rulesets came from: Network, Bootstrap, DataAdapter, or Uninitialized if none loaded
+	Source string
+	// Time from the start of initialization until the rulesets and ID lists loaded
+	Duration time.Duration
+	// Why the rulesets could not be downloaded, when that calls for a fix on your side: an
+	// *SDKKeyRejectedError when Statsig rejected the SDK key. Nil otherwise.
+	Error error
+	// Whether the ID lists were loaded. Always false when they are disabled, and false when they are still
+	// loading in the background after Options.InitTimeout.
+	IDListsLoaded bool
+}
+
+// Initializes the global Statsig instance like InitializeWithOptions, and returns how that went, e.g. to log
+// it or fall back when no rulesets could be loaded. When the instance is already initialized, returns the
+// details of its rulesets as of now.
+func InitializeWithDetails(sdkKey string, options *Options) InitResult {
+	start := time.Now()
+	InitializeWithOptions(sdkKey, options)
+	client := getInstance()
+	if client == nil {
+		// Shut down and cleared in the meantime
+		return InitResult{Source: string(reasonUninitialized), Duration: time.Since(start)}
 	}
+	return client.initResult(start)
+}
+
+// Initializes the global Statsig instance without waiting for the network. The instance can be used right
+// away, and evaluations return defaults with the reason Uninitialized until the rulesets load. The returned
+// channel receives a single InitResult once they have.
+func InitializeAsync(sdkKey string, options *Options) <-chan InitResult {
+	InitializeGlobalOutputLogger(options.OutputLoggerOptions)
+	initializeMu.Lock()
+	defer initializeMu.Unlock()
+	result := make(chan InitResult, 1)
+	if isRunning() {
+		global.Logger().LogWarning("Statsig is already initialized.")
+		result <- getInstance().initResult(time.Now())
+		return result
+	}
+	setInstance(newClient(context.Background(), sdkKey, options, func(res InitResult) {
+		result <- res
+	}))
+	return result
 }
 
 // Checks the value of a Feature Gate for the given user
@@ -95,7 +351,47 @@ func CheckGate(user User, gate string) bool {
 	if !IsInitialized() {
 		panic(fmt.Errorf("must Initialize() statsig before calling CheckGate"))
 	}
-	return instance.CheckGate(user, gate)
+	return getInstance().CheckGate(user, gate)
+}
+
+// Checks the value of a Feature Gate for the given user. The evaluation span, if traced, is a child of the span in ctx.
+func CheckGateWithContext(ctx context.Context, user User, gate string) bool {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling CheckGateWithContext"))
+	}
+	return getInstance().CheckGateWithContext(ctx, user, gate)
+}
+
+// Checks the values of several Feature Gates for the given user, by gate name, queueing the exposures together
+func CheckGates(user User, gates []string) map[string]bool {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling CheckGates"))
+	}
+	return getInstance().CheckGates(user, gates)
+}
+
+// Checks the values of several Feature Gates for the given user, and returns the details of each evaluation
+func CheckGatesWithDetails(user User, gates []string) (map[string]bool, map[string]EvaluationDetails) {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling CheckGatesWithDetails"))
+	}
+	return getInstance().CheckGatesWithDetails(user, gates)
+}
+
+// Checks the value of a Feature Gate for each user, in the order of the users, e.g. to segment users offline
+func CheckGateForUsers(users []User, gate string, options *BatchEvaluationOptions) []bool {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling CheckGateForUsers"))
+	}
+	return getInstance().CheckGateForUsers(users, gate, options)
+}
+
+// Gets the DynamicConfig value of an Experiment for each user, in the order of the users
+func GetExperimentForUsers(users []User, experiment string, options *BatchEvaluationOptions) []DynamicConfig {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling GetExperimentForUsers"))
+	}
+	return getInstance().GetExperimentForUsers(users, experiment, options)
 }
 
 // Checks the value of a Feature Gate for the given user without logging an exposure event
@@ -103,7 +399,23 @@ func CheckGateWithExposureLoggingDisabled(user User, gate string) bool {
 	if !IsInitialized() {
 		panic(fmt.Errorf("must Initialize() statsig before calling CheckGateWithExposureLoggingDisabled"))
 	}
-	return instance.CheckGateWithExposureLoggingDisabled(user, gate)
+	return getInstance().CheckGateWithExposureLoggingDisabled(user, gate)
+}
+
+// Checks the value of a Feature Gate for the given user, and returns the details of the evaluation
+func CheckGateWithDetails(user User, gate string) (bool, EvaluationDetails) {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling CheckGateWithDetails"))
+	}
+	return getInstance().CheckGateWithDetails(user, gate)
+}
+
+// Explains the value of a Feature Gate for the given user, without logging an exposure event
+func ExplainGate(user User, gate string) GateExplanation {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling ExplainGate"))
+	}
+	return getInstance().ExplainGate(user, gate)
 }
 
 // Logs an exposure event for the gate
@@ -111,7 +423,23 @@ func ManuallyLogGateExposure(user User, config string) {
 	if !IsInitialized() {
 		panic(fmt.Errorf("must Initialize() statsig before calling ManuallyLogGateExposure"))
 	}
-	instance.ManuallyLogGateExposure(user, config)
+	getInstance().ManuallyLogGateExposure(user, config)
+}
+
+// Gets the Feature Gate for the given user, including the rule and gate dependencies that decided its value
+func GetGate(user User, gate string) FeatureGate {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling GetGate"))
+	}
+	return getInstance().GetGate(user, gate)
+}
+
+// Gets the Feature Gate for the given user without logging an exposure event
+func GetGateWithExposureLoggingDisabled(user User, gate string) FeatureGate {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling GetGateWithExposureLoggingDisabled"))
+	}
+	return getInstance().GetGateWithExposureLoggingDisabled(user, gate)
 }
 
 // Gets the DynamicConfig value for the given user
@@ -119,7 +447,15 @@ func GetConfig(user User, config string) DynamicConfig {
 	if !IsInitialized() {
 		panic(fmt.Errorf("must Initialize() statsig before calling GetConfig"))
 	}
-	return instance.GetConfig(user, config)
+	return getInstance().GetConfig(user, config)
+}
+
+// Gets the DynamicConfig value for the given user. The evaluation span, if traced, is a child of the span in ctx.
+func GetConfigWithContext(ctx context.Context, user User, config string) DynamicConfig {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling GetConfigWithContext"))
+	}
+	return getInstance().GetConfigWithContext(ctx, user, config)
 }
 
 // Gets the DynamicConfig value for the given user without logging an exposure event
@@ -127,7 +463,15 @@ func GetConfigWithExposureLoggingDisabled(user User, config string) DynamicConfi
 	if !IsInitialized() {
 		panic(fmt.Errorf("must Initialize() statsig before calling GetConfigWithExposureLoggingDisabled"))
 	}
-	return instance.GetConfigWithExposureLoggingDisabled(user, config)
+	return getInstance().GetConfigWithExposureLoggingDisabled(user, config)
+}
+
+// Gets the DynamicConfig value for the given user, and the details of the evaluation
+func GetConfigWithDetails(user User, config string) (DynamicConfig, EvaluationDetails) {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling GetConfigWithDetails"))
+	}
+	return getInstance().GetConfigWithDetails(user, config)
 }
 
 // Logs an exposure event for the dynamic config
@@ -135,7 +479,7 @@ func ManuallyLogConfigExposure(user User, config string) {
 	if !IsInitialized() {
 		panic(fmt.Errorf("must Initialize() statsig before calling ManuallyLogConfigExposure"))
 	}
-	instance.ManuallyLogConfigExposure(user, config)
+	getInstance().ManuallyLogConfigExposure(user, config)
 }
 
 // Override the value of a Feature Gate for the given user
@@ -143,7 +487,7 @@ func OverrideGate(gate string, val bool) {
 	if !IsInitialized() {
 		panic(fmt.Errorf("must Initialize() statsig before calling OverrideGate"))
 	}
-	instance.OverrideGate(gate, val)
+	getInstance().OverrideGate(gate, val)
 }
 
 // Override the DynamicConfig value for the given user
@@ -151,7 +495,7 @@ func OverrideConfig(config string, val map[string]interface{}) {
 	if !IsInitialized() {
 		panic(fmt.Errorf("must Initialize() statsig before calling OverrideConfig"))
 	}
-	instance.OverrideConfig(config, val)
+	getInstance().OverrideConfig(config, val)
 }
 
 // Override the Layer value for the given user
@@ -159,7 +503,15 @@ func OverrideLayer(layer string, val map[string]interface{}) {
 	if !IsInitialized() {
 		panic(fmt.Errorf("must Initialize() statsig before calling OverrideLayer"))
 	}
-	instance.OverrideLayer(layer, val)
+	getInstance().OverrideLayer(layer, val)
+}
+
+// In LocalMode, puts the user in the named group of the experiment
+func OverrideExperimentGroup(user User, experiment string, group string) {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling OverrideExperimentGroup"))
+	}
+	getInstance().OverrideExperimentGroup(user, experiment, group)
 }
 
 // Gets the DynamicConfig value of an Experiment for the given user
@@ -167,7 +519,16 @@ func GetExperiment(user User, experiment string) DynamicConfig {
 	if !IsInitialized() {
 		panic(fmt.Errorf("must Initialize() statsig before calling GetExperiment"))
 	}
-	return instance.GetExperiment(user, experiment)
+	return getInstance().GetExperiment(user, experiment)
+}
+
+// Gets the DynamicConfig value of an Experiment for the given user. The evaluation span, if traced, is a child
+// of the span in ctx.
+func GetExperimentWithContext(ctx context.Context, user User, experiment string) DynamicConfig {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling GetExperimentWithContext"))
+	}
+	return getInstance().GetExperimentWithContext(ctx, user, experiment)
 }
 
 // Gets the DynamicConfig value of an Experiment for the given user without logging an exposure event
@@ -175,7 +536,15 @@ func GetExperimentWithExposureLoggingDisabled(user User, experiment string) Dyna
 	if !IsInitialized() {
 		panic(fmt.Errorf("must Initialize() statsig before calling GetExperimentWithExposureLoggingDisabled"))
 	}
-	return instance.GetExperimentWithExposureLoggingDisabled(user, experiment)
+	return getInstance().GetExperimentWithExposureLoggingDisabled(user, experiment)
+}
+
+// Gets the DynamicConfig value of an Experiment for the given user, and the details of the evaluation
+func GetExperimentWithDetails(user User, experiment string) (DynamicConfig, EvaluationDetails) {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling GetExperimentWithDetails"))
+	}
+	return getInstance().GetExperimentWithDetails(user, experiment)
 }
 
 // Logs an exposure event for the experiment
@@ -183,7 +552,95 @@ func ManuallyLogExperimentExposure(user User, experiment string) {
 	if !IsInitialized() {
 		panic(fmt.Errorf("must Initialize() statsig before calling ManuallyLogExperimentExposure"))
 	}
-	instance.ManuallyLogExperimentExposure(user, experiment)
+	getInstance().ManuallyLogExperimentExposure(user, experiment)
+}
+
+// Whether the user is in one of the groups of the experiment. No exposure event is logged.
+func IsUserInExperiment(user User, experiment string) bool {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling IsUserInExperiment"))
+	}
+	return getInstance().IsUserInExperiment(user, experiment)
+}
+
+// Whether the experiment is running, i.e. started and neither stopped nor launched
+func IsExperimentActive(experiment string) bool {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling IsExperimentActive"))
+	}
+	return getInstance().IsExperimentActive(experiment)
+}
+
+// Whether the ID of the user of the given type, "userID" or the name of one of the CustomIDs, is in the named ID list
+func IsUserInIDList(user User, listName string, idType string) bool {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling IsUserInIDList"))
+	}
+	return getInstance().IsUserInIDList(user, listName, idType)
+}
+
+// The names of the ID lists synced so far, sorted
+func GetIDListNames() []string {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling GetIDListNames"))
+	}
+	return getInstance().GetIDListNames()
+}
+
+// The server session ID sent with every request to Statsig
+func GetSessionID() string {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling GetSessionID"))
+	}
+	return getInstance().GetSessionID()
+}
+
+// Replaces the server session ID right away and returns the new one
+func RotateSessionID() string {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling RotateSessionID"))
+	}
+	return getInstance().RotateSessionID()
+}
+
+// Gets the parameters a contextual multi-armed bandit (CMAB) chose for the given user
+func GetCMAB(user User, cmab string) DynamicConfig {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling GetCMAB"))
+	}
+	return getInstance().GetCMAB(user, cmab)
+}
+
+// Gets the parameters a CMAB chose for the given user without logging an exposure event
+func GetCMABWithExposureLoggingDisabled(user User, cmab string) DynamicConfig {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling GetCMABWithExposureLoggingDisabled"))
+	}
+	return getInstance().GetCMABWithExposureLoggingDisabled(user, cmab)
+}
+
+// Logs an exposure event for the CMAB
+func ManuallyLogCMABExposure(user User, cmab string) {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling ManuallyLogCMABExposure"))
+	}
+	getInstance().ManuallyLogCMABExposure(user, cmab)
+}
+
+// Gets the Parameter Store for the given user. Parameters are evaluated when read.
+func GetParameterStore(user User, parameterStore string) ParameterStore {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling GetParameterStore"))
+	}
+	return getInstance().GetParameterStore(user, parameterStore)
+}
+
+// Gets the Parameter Store for the given user. Reading its parameters does not log exposure events.
+func GetParameterStoreWithExposureLoggingDisabled(user User, parameterStore string) ParameterStore {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling GetParameterStoreWithExposureLoggingDisabled"))
+	}
+	return getInstance().GetParameterStoreWithExposureLoggingDisabled(user, parameterStore)
 }
 
 // Gets the Layer object for the given user
@@ -191,7 +648,15 @@ func GetLayer(user User, layer string) Layer {
 	if !IsInitialized() {
 		panic(fmt.Errorf("must Initialize() statsig before calling GetLayer"))
 	}
-	return instance.GetLayer(user, layer)
+	return getInstance().GetLayer(user, layer)
+}
+
+// Gets the Layer object for the given user. The evaluation span, if traced, is a child of the span in ctx.
+func GetLayerWithContext(ctx context.Context, user User, layer string) Layer {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling GetLayerWithContext"))
+	}
+	return getInstance().GetLayerWithContext(ctx, user, layer)
 }
 
 // Gets the Layer object for the given user without logging an exposure event
@@ -199,7 +664,15 @@ func GetLayerWithExposureLoggingDisabled(user User, layer string) Layer {
 	if !IsInitialized() {
 		panic(fmt.Errorf("must Initialize() statsig before calling GetLayerWithExposureLoggingDisabled"))
 	}
-	return instance.GetLayerWithExposureLoggingDisabled(user, layer)
+	return getInstance().GetLayerWithExposureLoggingDisabled(user, layer)
+}
+
+// Gets the Layer object for the given user, and the details of the evaluation
+func GetLayerWithDetails(user User, layer string) (Layer, EvaluationDetails) {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling GetLayerWithDetails"))
+	}
+	return getInstance().GetLayerWithDetails(user, layer)
 }
 
 // Logs an exposure event for the parameter in the given layer
@@ -207,7 +680,7 @@ func ManuallyLogLayerParameterExposure(user User, layer string, parameter string
 	if !IsInitialized() {
 		panic(fmt.Errorf("must Initialize() statsig before calling ManuallyLogLayerParameterExposure"))
 	}
-	instance.ManuallyLogLayerParameterExposure(user, layer, parameter)
+	getInstance().ManuallyLogLayerParameterExposure(user, layer, parameter)
 }
 
 // Logs an event to the Statsig console
@@ -215,29 +688,70 @@ func LogEvent(event Event) {
 	if !IsInitialized() {
 		panic(fmt.Errorf("must Initialize() statsig before calling LogEvent"))
 	}
-	instance.LogEvent(event)
+	getInstance().LogEvent(event)
 }
 
-// Logs a slice of events to Statsig server immediately
+// Logs an event that happened at eventTime, clamped to between 7 days in the past and an hour in the future
+func LogEventWithTime(event Event, eventTime time.Time) {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling LogEventWithTime"))
+	}
+	getInstance().LogEventWithTime(event, eventTime)
+}
+
+// Logs a custom event with a numeric value, e.g. a latency or an order amount for a Pulse metric
+func LogEventValue(user User, eventName string, value float64, metadata map[string]string) error {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling LogEventValue"))
+	}
+	return getInstance().LogEventValue(user, eventName, value, metadata)
+}
+
+// Logs a slice of events to Statsig server immediately, in batches of up to 500 events
 func LogImmediate(events []Event) (*http.Response, error) {
 	if !IsInitialized() {
 		panic(fmt.Errorf("must Initialize() statsig before calling LogImmediate"))
 	}
-	return instance.LogImmediate(events)
+	return getInstance().LogImmediate(events)
 }
 
 func GetClientInitializeResponse(user User) ClientInitializeResponse {
 	if !IsInitialized() {
 		panic(fmt.Errorf("must Initialize() statsig before calling GetClientInitializeResponse"))
 	}
-	return instance.GetClientInitializeResponse(user, "")
+	return getInstance().GetClientInitializeResponse(user, "")
+}
+
+// Gets the ClientInitializeResponse for the given user, shaped by the options, e.g. to only get what changed
+// since the response a client was last bootstrapped with
+func GetClientInitializeResponseWithOptions(user User, clientKey string, options *ClientInitializeResponseOptions) ClientInitializeResponse {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling GetClientInitializeResponseWithOptions"))
+	}
+	return getInstance().GetClientInitializeResponseWithOptions(user, clientKey, options)
 }
 
 func GetClientInitializeResponseForTargetApp(user User, clientKey string) ClientInitializeResponse {
 	if !IsInitialized() {
 		panic(fmt.Errorf("must Initialize() statsig before calling GetClientInitializeResponseForTargetApp"))
 	}
-	return instance.GetClientInitializeResponse(user, clientKey)
+	return getInstance().GetClientInitializeResponse(user, clientKey)
+}
+
+// Applies the given settings to the global Statsig instance without reinitializing it
+func UpdateOptions(options RuntimeOptions) {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling UpdateOptions"))
+	}
+	getInstance().UpdateOptions(options)
+}
+
+// Tells the global Statsig instance whether the process is under memory pressure
+func ReportMemoryPressure(underPressure bool) {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling ReportMemoryPressure"))
+	}
+	getInstance().ReportMemoryPressure(underPressure)
 }
 
 // Cleans up Statsig, persisting any Event Logs and cleanup processes
@@ -246,11 +760,156 @@ func Shutdown() {
 	if !IsInitialized() {
 		return
 	}
-	instance.Shutdown()
+	getInstance().Shutdown()
+}
+
+// Reports whether the rulesets have gone longer than Options.StaleConfigThreshold without a successful sync
+func IsConfigStale() bool {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling IsConfigStale"))
+	}
+	return getInstance().IsConfigStale()
+}
+
+// Reports whether the SDK is initialized, where its rulesets came from, when they and the ID lists were last
+// synced and how many events wait to be flushed
+func GetStatus() Status {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling GetStatus"))
+	}
+	return getInstance().GetStatus()
+}
+
+// Exports the rulesets and ID lists the SDK currently evaluates with
+func ExportSnapshot() ([]byte, error) {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling ExportSnapshot"))
+	}
+	return getInstance().ExportSnapshot()
+}
+
+// Replaces the rulesets and ID lists with those of a snapshot from ExportSnapshot
+func RestoreSnapshot(snapshot []byte) error {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling RestoreSnapshot"))
+	}
+	return getInstance().RestoreSnapshot(snapshot)
+}
+
+// Synchronously sends all queued events to Statsig, returning an error if the upload fails
+func Flush() error {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling Flush"))
+	}
+	return getInstance().Flush()
+}
+
+// Synchronously sends all queued events to Statsig, returning an error if the upload fails or ctx is done first
+func FlushWithContext(ctx context.Context) error {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling FlushWithContext"))
+	}
+	return getInstance().FlushWithContext(ctx)
+}
+
+// Cleans up Statsig, returning once ctx is done even if events are still being uploaded.
+// Returns the number of events that were dropped.
+// Using any method is undefined after ShutdownWithContext() has been called
+func ShutdownWithContext(ctx context.Context) (int, error) {
+	if !IsInitialized() {
+		return 0, nil
+	}
+	return getInstance().ShutdownWithContext(ctx)
+}
+
+// Calls listener whenever the definition of the named gate, config or layer is added, changed or removed
+// by a ruleset update. Returns a function that unsubscribes the listener.
+func Subscribe(entityName string, listener func(change ChangeEvent)) func() {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling Subscribe"))
+	}
+	return getInstance().Subscribe(entityName, listener)
+}
+
+// Gets a read-only copy of the gate, config and layer definitions currently in use, for inventory reports
+func GetSpecInventory() SpecInventory {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling GetSpecInventory"))
+	}
+	return getInstance().GetSpecInventory()
+}
+
+// Gets a summary of every Feature Gate currently in use, sorted by name
+func GetFeatureGateList() []SpecEntity {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling GetFeatureGateList"))
+	}
+	return getInstance().GetFeatureGateList()
+}
+
+// Gets a summary of every Dynamic Config currently in use, excluding experiments, sorted by name
+func GetDynamicConfigList() []SpecEntity {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling GetDynamicConfigList"))
+	}
+	return getInstance().GetDynamicConfigList()
+}
+
+// Gets a summary of every Experiment currently in use, sorted by name
+func GetExperimentList() []SpecEntity {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling GetExperimentList"))
+	}
+	return getInstance().GetExperimentList()
+}
+
+// Gets a summary of every Layer currently in use, sorted by name
+func GetLayerList() []SpecEntity {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling GetLayerList"))
+	}
+	return getInstance().GetLayerList()
+}
+
+// Evaluates every gate, config, experiment and layer for the given user without logging exposures
+func EvaluateAll(user User) UserEvaluation {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling EvaluateAll"))
+	}
+	return getInstance().EvaluateAll(user)
+}
+
+// Serves the SDK's internal metrics in the Prometheus text format. Requires MetricsOptions.Enabled.
+func MetricsHandler() http.Handler {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling MetricsHandler"))
+	}
+	return getInstance().MetricsHandler()
+}
+
+// Returns a snapshot of the SDK's internal metrics, for exporting them to a metrics registry. Requires
+// MetricsOptions.Enabled.
+func CollectMetrics() []MetricSample {
+	if !IsInitialized() {
+		panic(fmt.Errorf("must Initialize() statsig before calling CollectMetrics"))
+	}
+	return getInstance().CollectMetrics()
+}
+
+// Serves a readiness probe, e.g. for Kubernetes: 200 when the SDK has loaded rulesets that are not stale
+// per Options.StaleConfigThreshold, and 503 otherwise. Unlike other functions it can be called before
+// Initialize, and serves 503 until then.
+func ReadyzHandler() http.Handler {
+	return readyzHandler{getClient: func() *Client {
+		if !IsInitialized() {
+			return nil
+		}
+		return getInstance()
+	}}
 }
 
-// For test only so we can clear the shared instance. Not thread safe.
+// For test only so we can clear the shared instance. Calls made concurrently may panic as uninitialized.
 func ShutdownAndDangerouslyClearInstance() {
 	Shutdown()
-	instance = nil
+	setInstance(nil)
 }
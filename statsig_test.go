@@ -3,12 +3,20 @@ package statsig
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestBootstrap(t *testing.T) {
@@ -39,6 +47,266 @@ func TestBootstrap(t *testing.T) {
 		t.Errorf("always_on_gate should return true bootstrap value is provided")
 	}
 	ShutdownAndDangerouslyClearInstance()
+
+	// Invalid bootstrap values fall back to the network
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+		if strings.Contains(req.URL.Path, "download_config_specs") {
+			_, _ = res.Write(bytes)
+		}
+	}))
+	defer testServer.Close()
+	var bootstrapErr *InvalidBootstrapValuesError
+	defer InitializeGlobalOutputLogger(getOutputLoggerOptionsForTest(t))
+	InitializeWithOptions("secret-key", &Options{
+		API:             testServer.URL,
+		BootstrapValues: "not json",
+		OutputLoggerOptions: OutputLoggerOptions{LogCallback: func(message string, err error) {
+			if bootstrapErr == nil {
+				errors.As(err, &bootstrapErr)
+			}
+		}},
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	if bootstrapErr == nil || bootstrapErr.Err == nil {
+		t.Errorf("Expected invalid bootstrap values to be reported")
+	}
+	details := instance.evaluator.checkGate(User{UserID: "123"}, "always_on_gate").EvaluationDetails
+	if details.reason != reasonNetwork {
+		t.Errorf("Expected reason Network after falling back, got %s", details.reason)
+	}
+	ShutdownAndDangerouslyClearInstance()
+}
+
+func TestDisableNetwork(t *testing.T) {
+	var requests int32
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		res.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	bootstrapFile := filepath.Join(t.TempDir(), "download_config_specs.json")
+	_ = os.WriteFile(bootstrapFile, bytes, 0644)
+
+	c := NewClientWithOptions("client-key", NewOptions(
+		WithAPI(testServer.URL),
+		WithBootstrapFile(bootstrapFile),
+		WithNetworkDisabled(),
+		WithPolling(10*time.Millisecond),
+	))
+	user := User{UserID: "123"}
+	gate := c.evaluator.checkGate(user, "always_on_gate")
+	if !gate.Pass || gate.EvaluationDetails.reason != reasonBootstrap {
+		t.Errorf("Expected always_on_gate to pass from the BootstrapFile, got %v with reason %s", gate.Pass, gate.EvaluationDetails.reason)
+	}
+	c.LogEvent(Event{EventName: "offline_event", User: user})
+	if _, err := c.LogImmediate([]Event{{EventName: "offline_event", User: user}}); !errors.Is(err, errNetworkDisabled) {
+		t.Errorf("Expected LogImmediate to fail with the network disabled, got %v", err)
+	}
+	c.errorBoundary.logException(errors.New("offline error"))
+	time.Sleep(50 * time.Millisecond)
+	c.Shutdown()
+	if count := atomic.LoadInt32(&requests); count != 0 {
+		t.Errorf("Expected no requests with the network disabled, got %d", count)
+	}
+
+	var logged []string
+	var mu sync.Mutex
+	InitializeGlobalOutputLogger(OutputLoggerOptions{
+		LogCallback: func(message string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				message += err.Error()
+			}
+			logged = append(logged, message)
+		},
+	})
+	defer InitializeGlobalOutputLogger(getOutputLoggerOptionsForTest(t))
+	c = NewClientWithOptions("client-key", &Options{
+		API:                  testServer.URL,
+		BootstrapFile:        filepath.Join(t.TempDir(), "missing.json"),
+		DisableNetwork:       true,
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+	if c.CheckGate(user, "always_on_gate") {
+		t.Errorf("Expected always_on_gate to fail without rulesets")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(logged) == 0 || !strings.Contains(strings.Join(logged, "\n"), "Failed to read the BootstrapFile") {
+		t.Errorf("Expected the missing BootstrapFile to be logged, got %v", logged)
+	}
+	if count := atomic.LoadInt32(&requests); count != 0 {
+		t.Errorf("Expected no requests with the network disabled, got %d", count)
+	}
+}
+
+func TestInitializeAfterShutdown(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+		if strings.Contains(req.URL.Path, "download_config_specs") {
+			_, _ = res.Write(bytes)
+		}
+	}))
+	defer testServer.Close()
+	options := func() *Options {
+		return &Options{
+			API:                  testServer.URL,
+			ConfigSyncInterval:   10 * time.Millisecond,
+			IDListSyncInterval:   10 * time.Millisecond,
+			LoggingInterval:      10 * time.Millisecond,
+			OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+			StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+		}
+	}
+	baseline := runtime.NumGoroutine()
+
+	for i := 0; i < 3; i++ {
+		InitializeWithOptions("secret-key", options())
+		first := instance
+		if !CheckGate(User{UserID: "123"}, "always_on_gate") {
+			t.Errorf("Expected always_on_gate to pass after initialization %d", i+1)
+		}
+		InitializeWithOptions("secret-key", options())
+		if instance != first {
+			t.Errorf("Expected initializing a running instance to keep it")
+		}
+		Shutdown()
+		Shutdown()
+		if !IsInitialized() {
+			t.Errorf("Expected the instance to remain set after Shutdown")
+		}
+
+		InitializeWithOptions("secret-key", options())
+		if instance == first {
+			t.Errorf("Expected initializing after Shutdown to replace the instance")
+		}
+		if !CheckGate(User{UserID: "123"}, "always_on_gate") {
+			t.Errorf("Expected always_on_gate to pass after reinitialization %d", i+1)
+		}
+		Shutdown()
+	}
+
+	waitForCondition(t, func() bool { return runtime.NumGoroutine() <= baseline })
+	if goroutines := runtime.NumGoroutine(); goroutines > baseline {
+		t.Errorf("Expected no goroutines to outlive Shutdown, %d leaked", goroutines-baseline)
+	}
+	ShutdownAndDangerouslyClearInstance()
+}
+
+func TestInitializeWithDetails(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	var dcsStatus int32 = http.StatusOK
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "download_config_specs") {
+			res.WriteHeader(int(atomic.LoadInt32(&dcsStatus)))
+			_, _ = res.Write(bytes)
+			return
+		}
+		res.WriteHeader(http.StatusOK)
+		if strings.Contains(req.URL.Path, "get_id_lists") {
+			_, _ = res.Write([]byte("{}"))
+		}
+	}))
+	defer testServer.Close()
+	options := func() *Options {
+		return &Options{
+			API:                  testServer.URL,
+			OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+			StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+		}
+	}
+
+	details := InitializeWithDetails("secret-key", options())
+	if !details.Success || details.Source != "Network" || details.Error != nil || !details.IDListsLoaded {
+		t.Errorf("Expected a successful network initialization with ID lists, got %+v", details)
+	}
+	if details.Duration <= 0 {
+		t.Errorf("Expected the duration of the initialization, got %s", details.Duration)
+	}
+	ShutdownAndDangerouslyClearInstance()
+
+	atomic.StoreInt32(&dcsStatus, http.StatusUnauthorized)
+	details = InitializeWithDetails("secret-key", options())
+	if details.Success || details.Source != "Uninitialized" {
+		t.Errorf("Expected a failed initialization, got %+v", details)
+	}
+	var keyErr *SDKKeyRejectedError
+	if !errors.As(details.Error, &keyErr) || keyErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected the rejected SDK key error, got %v", details.Error)
+	}
+	ShutdownAndDangerouslyClearInstance()
+
+	atomic.StoreInt32(&dcsStatus, http.StatusInternalServerError)
+	opt := options()
+	opt.BootstrapValues = string(bytes)
+	details = InitializeWithDetails("secret-key", opt)
+	if !details.Success || details.Source != "Bootstrap" || details.Error != nil {
+		t.Errorf("Expected a successful bootstrap initialization, got %+v", details)
+	}
+	if again := InitializeWithDetails("secret-key", options()); again.Source != "Bootstrap" {
+		t.Errorf("Expected the details of the running instance, got %+v", again)
+	}
+	ShutdownAndDangerouslyClearInstance()
+
+	opt = options()
+	opt.LocalMode = true
+	details = InitializeWithDetails("secret-key", opt)
+	if details.Success || details.Error != nil || details.IDListsLoaded {
+		t.Errorf("Expected nothing to load in LocalMode, got %+v", details)
+	}
+	ShutdownAndDangerouslyClearInstance()
+}
+
+func TestInitializeAfterShutdownWhileServing(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+		if strings.Contains(req.URL.Path, "download_config_specs") {
+			_, _ = res.Write(bytes)
+		}
+	}))
+	defer testServer.Close()
+	options := func() *Options {
+		return &Options{
+			API:                  testServer.URL,
+			OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+			StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+		}
+	}
+	InitializeWithOptions("secret-key", options())
+	defer ShutdownAndDangerouslyClearInstance()
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					CheckGate(User{UserID: "123"}, "always_on_gate")
+					LogEvent(Event{EventName: "serving", User: User{UserID: "123"}})
+				}
+			}
+		}()
+	}
+	for i := 0; i < 3; i++ {
+		Shutdown()
+		InitializeWithOptions("secret-key", options())
+	}
+	close(stop)
+	wg.Wait()
+	if !CheckGate(User{UserID: "123"}, "always_on_gate") {
+		t.Errorf("Expected always_on_gate to pass after reinitializing while serving")
+	}
 }
 
 func TestRulesUpdatedCallback(t *testing.T) {
@@ -96,6 +364,7 @@ func TestRulesUpdatedCallback(t *testing.T) {
 
 func TestLogImmediate(t *testing.T) {
 	env := ""
+	var privateAttributes map[string]interface{}
 	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
 		if req.Method != "POST" {
 			t.Errorf("Expected ‘POST’ request, got '%s'", req.Method)
@@ -112,6 +381,7 @@ func TestLogImmediate(t *testing.T) {
 
 			_ = json.Unmarshal(buf.Bytes(), &input)
 			env = input.Events[0].User.StatsigEnvironment["tier"]
+			privateAttributes = input.Events[0].User.PrivateAttributes
 		}
 
 		res.WriteHeader(http.StatusOK)
@@ -124,7 +394,7 @@ func TestLogImmediate(t *testing.T) {
 		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
 	}
 	InitializeWithOptions("secret-key", opt)
-	event := Event{EventName: "test_event", User: User{UserID: "123"}}
+	event := Event{EventName: "test_event", User: User{UserID: "123", PrivateAttributes: map[string]interface{}{"secret": "shh"}}}
 	response, err := LogImmediate([]Event{event})
 	if response.StatusCode != http.StatusOK {
 		t.Errorf("Status should be OK")
@@ -135,10 +405,77 @@ func TestLogImmediate(t *testing.T) {
 	if env != "test" {
 		t.Errorf("Environment not set on user")
 	}
+	if privateAttributes != nil {
+		t.Errorf("Private attributes should not be logged")
+	}
 
 	ShutdownAndDangerouslyClearInstance()
 }
 
+func TestLogImmediateBatches(t *testing.T) {
+	backoff := eventBatchRetryBackoff
+	eventBatchRetryBackoff = time.Millisecond
+	defer func() { eventBatchRetryBackoff = backoff }()
+
+	var mu sync.Mutex
+	batchSizes := make([]int, 0)
+	attempts := make(map[string]int)
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if !strings.Contains(req.URL.Path, "log_event") {
+			res.WriteHeader(http.StatusOK)
+			return
+		}
+		var input struct {
+			Events []Event `json:"events"`
+		}
+		_ = json.NewDecoder(req.Body).Decode(&input)
+		batch := input.Events[0].EventName
+		mu.Lock()
+		batchSizes = append(batchSizes, len(input.Events))
+		attempts[batch]++
+		attempt := attempts[batch]
+		mu.Unlock()
+		switch {
+		case batch == "batch_1" && attempt == 1:
+			res.WriteHeader(http.StatusServiceUnavailable)
+		case batch == "batch_2":
+			res.WriteHeader(http.StatusBadRequest)
+		default:
+			res.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer testServer.Close()
+	c := NewClientWithOptions("secret-key", &Options{
+		API:                  testServer.URL,
+		DisableIDLists:       true,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+
+	events := make([]Event, 0, 1200)
+	for i := 0; i < 1200; i++ {
+		events = append(events, Event{EventName: fmt.Sprintf("batch_%d", i/500), User: User{UserID: "123"}})
+	}
+	response, err := c.LogImmediate(events)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(batchSizes, []int{500, 500, 500, 200}) {
+		t.Errorf("Expected batches of at most 500 events, with the failed batch retried, got %v", batchSizes)
+	}
+	if attempts["batch_2"] != 1 {
+		t.Errorf("Expected a batch rejected with a 400 not to be retried")
+	}
+	var batchErr *EventBatchError
+	if !errors.As(err, &batchErr) || batchErr.Batches != 1 || len(batchErr.FailedEvents) != 200 {
+		t.Fatalf("Expected the events of the rejected batch to be returned, got %v", err)
+	}
+	if batchErr.FailedEvents[0].EventName != "batch_2" || response == nil || response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected the response of the rejected batch")
+	}
+}
+
 func TestVersion(t *testing.T) {
 	metadata := getStatsigMetadata()
 	versionsString, _ := exec.Command("go", "list", "-m", "-versions").Output()
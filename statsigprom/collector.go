@@ -0,0 +1,65 @@
+// Package statsigprom registers the Statsig SDK's internal metrics with a prometheus.Registry. It is a
+// module of its own, so the SDK itself does not depend on the Prometheus client. Enable the metrics with
+// MetricsOptions, then register a Collector for the Client:
+//
+//	client := statsig.NewClientWithOptions("secret-key", &statsig.Options{
+//		MetricsOptions: statsig.MetricsOptions{Enabled: true},
+//	})
+//	prometheus.MustRegister(statsigprom.NewCollector(client.CollectMetrics))
+//
+// To export the metrics of the global instance, pass statsig.CollectMetrics once statsig.Initialize returns.
+package statsigprom
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	statsig "github.com/statsig-io/go-sdk"
+)
+
+// A prometheus.Collector reporting the MetricSamples returned by collect as const metrics. It is unchecked:
+// the series only appear once the SDK records them, so Describe sends no descriptors.
+type Collector struct {
+	collect func() []statsig.MetricSample
+}
+
+func NewCollector(collect func() []statsig.MetricSample) *Collector {
+	return &Collector{collect: collect}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, sample := range c.collect() {
+		ch <- newConstMetric(sample)
+	}
+}
+
+func newConstMetric(sample statsig.MetricSample) prometheus.Metric {
+	var labelNames, labelValues []string
+	if sample.LabelName != "" {
+		labelNames = []string{sample.LabelName}
+		labelValues = []string{sample.LabelValue}
+	}
+	desc := prometheus.NewDesc(sample.Name, sample.Help, labelNames, nil)
+	var metric prometheus.Metric
+	var err error
+	switch sample.Kind {
+	case "counter":
+		metric, err = prometheus.NewConstMetric(desc, prometheus.CounterValue, sample.Value, labelValues...)
+	case "gauge":
+		metric, err = prometheus.NewConstMetric(desc, prometheus.GaugeValue, sample.Value, labelValues...)
+	case "histogram":
+		buckets := make(map[float64]uint64, len(sample.BucketBounds))
+		for i, bound := range sample.BucketBounds {
+			buckets[bound] = sample.BucketCounts[i]
+		}
+		metric, err = prometheus.NewConstHistogram(desc, sample.Count, sample.Sum, buckets, labelValues...)
+	default:
+		err = fmt.Errorf("unknown kind %q of metric %s", sample.Kind, sample.Name)
+	}
+	if err != nil {
+		return prometheus.NewInvalidMetric(desc, err)
+	}
+	return metric
+}
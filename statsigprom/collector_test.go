@@ -0,0 +1,56 @@
+package statsigprom
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	statsig "github.com/statsig-io/go-sdk"
+	"github.com/statsig-io/go-sdk/statsigtest"
+)
+
+func TestCollector(t *testing.T) {
+	server := statsigtest.NewServer(t, statsigtest.NewSpecs(
+		statsigtest.Gate("new_checkout", statsigtest.Everyone()),
+	))
+	client := statsig.NewClientWithOptions("secret-key", server.Options(
+		statsig.WithMetricsOptions(statsig.MetricsOptions{Enabled: true}),
+	))
+	defer client.Shutdown()
+	client.CheckGate(statsig.User{UserID: "user-1"}, "new_checkout")
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewCollector(client.CollectMetrics))
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Expected the metrics to be gathered, got %s", err)
+	}
+	byName := make(map[string]*dto.MetricFamily)
+	for _, family := range families {
+		byName[family.GetName()] = family
+	}
+
+	evaluations := byName["statsig_evaluations_total"]
+	if evaluations == nil || evaluations.GetType() != dto.MetricType_COUNTER {
+		t.Fatalf("Expected the evaluations counter, got %v", evaluations)
+	}
+	gates := evaluations.GetMetric()[0]
+	if gates.GetLabel()[0].GetValue() != "gate" || gates.GetCounter().GetValue() != 1 {
+		t.Errorf("Expected one gate evaluation, got %v", gates)
+	}
+	requests := byName["statsig_request_duration_seconds"]
+	if requests == nil || requests.GetType() != dto.MetricType_HISTOGRAM {
+		t.Fatalf("Expected the request latency histogram, got %v", requests)
+	}
+	if histogram := requests.GetMetric()[0].GetHistogram(); histogram.GetSampleCount() == 0 || len(histogram.GetBucket()) == 0 {
+		t.Errorf("Expected the request latencies to be observed, got %v", histogram)
+	}
+
+	disabled := statsig.NewClientWithOptions("secret-key", server.Options())
+	defer disabled.Shutdown()
+	registry = prometheus.NewRegistry()
+	registry.MustRegister(NewCollector(disabled.CollectMetrics))
+	if families, err := registry.Gather(); err != nil || len(families) != 0 {
+		t.Errorf("Expected no metrics when they are disabled, got %d families", len(families))
+	}
+}
@@ -0,0 +1,104 @@
+package statsigtest
+
+import (
+	"strconv"
+	"testing"
+)
+
+const (
+	gateExposureEventName   = "statsig::gate_exposure"
+	configExposureEventName = "statsig::config_exposure"
+	layerExposureEventName  = "statsig::layer_exposure"
+)
+
+// Asserts that an event with the given name was logged, and returns the first one
+func (s *Server) AssertEventLogged(t testing.TB, eventName string) LoggedEvent {
+	t.Helper()
+	events := s.EventsNamed(eventName)
+	if len(events) == 0 {
+		t.Errorf("Expected a %s event, got %d other events", eventName, len(s.Events()))
+		return LoggedEvent{}
+	}
+	return events[0]
+}
+
+// Asserts that no event with the given name was logged
+func (s *Server) AssertNoEventLogged(t testing.TB, eventName string) {
+	t.Helper()
+	if events := s.EventsNamed(eventName); len(events) != 0 {
+		t.Errorf("Expected no %s event, got %d", eventName, len(events))
+	}
+}
+
+// Asserts that the exposure of the gate to the user was logged with the given value
+func (s *Server) AssertGateExposure(t testing.TB, userID string, gate string, value bool) {
+	t.Helper()
+	s.assertExposure(t, gateExposureEventName, userID, map[string]string{
+		"gate":      gate,
+		"gateValue": strconv.FormatBool(value),
+	})
+}
+
+// Asserts that the exposure of the dynamic config or experiment to the user was logged with the given rule ID
+func (s *Server) AssertConfigExposure(t testing.TB, userID string, config string, ruleID string) {
+	t.Helper()
+	s.assertExposure(t, configExposureEventName, userID, map[string]string{
+		"config": config,
+		"ruleID": ruleID,
+	})
+}
+
+// Asserts that the exposure of the layer parameter to the user was logged
+func (s *Server) AssertLayerExposure(t testing.TB, userID string, layer string, parameterName string) {
+	t.Helper()
+	s.assertExposure(t, layerExposureEventName, userID, map[string]string{
+		"config":        layer,
+		"parameterName": parameterName,
+	})
+}
+
+// Asserts that no exposure of the gate, dynamic config, experiment or layer was logged, for any user
+func (s *Server) AssertNoExposure(t testing.TB, name string) {
+	t.Helper()
+	for _, event := range s.Events() {
+		if isExposure(event) && (event.Metadata["gate"] == name || event.Metadata["config"] == name) {
+			t.Errorf("Expected no exposure of %s, got a %s event for user %q", name, event.EventName, event.User.UserID)
+			return
+		}
+	}
+}
+
+func (s *Server) assertExposure(t testing.TB, eventName string, userID string, metadata map[string]string) {
+	t.Helper()
+	var found []LoggedEvent
+	for _, event := range s.EventsNamed(eventName) {
+		if event.User.UserID == userID && hasMetadata(event, metadata) {
+			return
+		}
+		if event.User.UserID == userID {
+			found = append(found, event)
+		}
+	}
+	if len(found) == 0 {
+		t.Errorf("Expected a %s event for user %q with %v, got none", eventName, userID, metadata)
+		return
+	}
+	t.Errorf("Expected a %s event for user %q with %v, got %v", eventName, userID, metadata, found[0].Metadata)
+}
+
+func hasMetadata(event LoggedEvent, metadata map[string]string) bool {
+	for key, value := range metadata {
+		if event.Metadata[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func isExposure(event LoggedEvent) bool {
+	switch event.EventName {
+	case gateExposureEventName, configExposureEventName, layerExposureEventName:
+		return true
+	}
+	return false
+}
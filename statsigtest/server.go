@@ -0,0 +1,167 @@
+// Package statsigtest helps test code that uses the Statsig SDK. A Server stands in for the Statsig API:
+// it serves rulesets built in Go with NewSpecs, and captures the events and exceptions the SDK logs, so
+// tests can assert on exposures without running their own httptest server. Assertions check the events
+// logged so far, and the SDK logs events in batches, so flush the client first.
+//
+//	server := statsigtest.NewServer(t, statsigtest.NewSpecs(
+//		statsigtest.Gate("new_checkout", statsigtest.UnitIDs("user-1")),
+//	))
+//	client := statsig.NewClientWithOptions("secret-key", server.Options())
+//	defer client.Shutdown()
+//	client.CheckGate(statsig.User{UserID: "user-1"}, "new_checkout")
+//	client.Flush()
+//	server.AssertGateExposure(t, "user-1", "new_checkout", true)
+package statsigtest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	statsig "github.com/statsig-io/go-sdk"
+)
+
+// An event the SDK logged to the Server
+type LoggedEvent struct {
+	EventName string            `json:"eventName"`
+	User      statsig.User      `json:"user"`
+	Value     string            `json:"value"`
+	Metadata  map[string]string `json:"metadata"`
+	Time      int64             `json:"time"`
+	// Set on exposures: the gates evaluated while evaluating the exposed gate, config or layer
+	SecondaryExposures []map[string]string `json:"secondaryExposures"`
+}
+
+// An in-memory Statsig API. It is safe for concurrent use.
+type Server struct {
+	// The URL to use as Options.API
+	URL string
+
+	server     *httptest.Server
+	mu         sync.Mutex
+	specs      []byte
+	events     []LoggedEvent
+	exceptions []string
+	requests   map[string]int
+}
+
+type logEventInput struct {
+	Events []json.RawMessage `json:"events"`
+}
+
+type exceptionInput struct {
+	Exception string `json:"exception"`
+}
+
+// Starts a Server serving the given specs. It is closed when the test and its subtests complete.
+func NewServer(t testing.TB, specs *Specs) *Server {
+	s := &Server{requests: make(map[string]int)}
+	s.SetSpecs(specs)
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	s.URL = s.server.URL
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Returns Options that point the SDK at the Server, with the given Options applied on top
+func (s *Server) Options(opts ...statsig.Option) *statsig.Options {
+	return statsig.NewOptions(append([]statsig.Option{statsig.WithAPI(s.URL)}, opts...)...)
+}
+
+// Replaces the specs the Server serves. The SDK picks them up on its next config sync.
+func (s *Server) SetSpecs(specs *Specs) {
+	if specs == nil {
+		specs = NewSpecs()
+	}
+	bytes := specs.JSON()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.specs = bytes
+}
+
+// Gets the events logged so far, in the order they were logged, without the SDK's diagnostics events
+func (s *Server) Events() []LoggedEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]LoggedEvent{}, s.events...)
+}
+
+// Gets the events with the given name logged so far
+func (s *Server) EventsNamed(eventName string) []LoggedEvent {
+	var events []LoggedEvent
+	for _, event := range s.Events() {
+		if event.EventName == eventName {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// Gets the exceptions the SDK reported so far
+func (s *Server) Exceptions() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.exceptions...)
+}
+
+// Gets how many requests the SDK made to the endpoint, e.g. "/download_config_specs"
+func (s *Server) Requests(endpoint string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[endpoint]
+}
+
+// Forgets the events and exceptions logged so far
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = nil
+	s.exceptions = nil
+}
+
+// Stops the Server
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+func (s *Server) handle(res http.ResponseWriter, req *http.Request) {
+	endpoint := req.URL.Path[strings.LastIndex(req.URL.Path, "/"):]
+	body, _ := io.ReadAll(req.Body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[endpoint]++
+
+	res.Header().Set("Content-Type", "application/json")
+	switch endpoint {
+	case "/download_config_specs":
+		_, _ = res.Write(s.specs)
+	case "/get_id_lists":
+		_, _ = res.Write([]byte("{}"))
+	case "/log_event":
+		var input logEventInput
+		if err := json.Unmarshal(body, &input); err != nil {
+			res.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, raw := range input.Events {
+			var event LoggedEvent
+			// Diagnostics events have metadata of other types, and are not the SDK user's events
+			if json.Unmarshal(raw, &event) == nil && event.EventName != "statsig::diagnostics" {
+				s.events = append(s.events, event)
+			}
+		}
+		_, _ = res.Write([]byte("{}"))
+	case "/sdk_exception":
+		var input exceptionInput
+		if json.Unmarshal(body, &input) == nil {
+			s.exceptions = append(s.exceptions, input.Exception)
+		}
+		_, _ = res.Write([]byte("{}"))
+	default:
+		res.WriteHeader(http.StatusNotFound)
+	}
+}
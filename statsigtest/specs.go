@@ -0,0 +1,379 @@
+package statsigtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	statsig "github.com/statsig-io/go-sdk"
+)
+
+// The rulesets served by a Server, or given to the SDK as Options.BootstrapValues through JSON.
+// Build them from the gates, dynamic configs, experiments and layers a test needs.
+type Specs struct {
+	specs []*Spec
+}
+
+// Creates the rulesets from the given gates, dynamic configs, experiments and layers
+func NewSpecs(specs ...*Spec) *Specs {
+	return (&Specs{}).Add(specs...)
+}
+
+// Adds gates, dynamic configs, experiments or layers, replacing any of the same kind and name
+func (s *Specs) Add(specs ...*Spec) *Specs {
+	for _, spec := range specs {
+		replaced := false
+		for i, existing := range s.specs {
+			if existing.specType == spec.specType && existing.name == spec.name {
+				s.specs[i] = spec
+				replaced = true
+			}
+		}
+		if !replaced {
+			s.specs = append(s.specs, spec)
+		}
+	}
+	return s
+}
+
+// A gate, dynamic config, experiment or layer. Rules are evaluated in order and the first passing rule wins.
+type Spec struct {
+	name         string
+	specType     string
+	entity       string
+	idType       string
+	disabled     bool
+	defaultValue interface{}
+	rules        []Rule
+}
+
+// A gate that fails for everyone unless one of the rules passes
+func Gate(name string, rules ...Rule) *Spec {
+	return &Spec{
+		name:         name,
+		specType:     statsig.SpecTypeFeatureGate,
+		entity:       "feature_gate",
+		defaultValue: false,
+		rules:        rules,
+	}
+}
+
+// A dynamic config that serves the default value unless one of the rules passes
+func Config(name string, defaultValue map[string]interface{}, rules ...Rule) *Spec {
+	return &Spec{
+		name:         name,
+		specType:     statsig.SpecTypeDynamicConfig,
+		entity:       "dynamic_config",
+		defaultValue: defaultValueOrEmpty(defaultValue),
+		rules:        rules,
+	}
+}
+
+// An experiment that serves the default value to users in none of the groups. Each rule is a group,
+// e.g. Buckets(0, 500).Group("Control").Returning(...), and the users it passes are in that group.
+func Experiment(name string, defaultValue map[string]interface{}, groups ...Rule) *Spec {
+	for i := range groups {
+		groups[i].experimentGroup = true
+	}
+	return &Spec{
+		name:         name,
+		specType:     statsig.SpecTypeDynamicConfig,
+		entity:       statsig.EntityExperiment,
+		defaultValue: defaultValueOrEmpty(defaultValue),
+		rules:        groups,
+	}
+}
+
+// A layer that serves the default value unless one of the rules passes, e.g. Everyone().DelegateTo(experiment)
+func Layer(name string, defaultValue map[string]interface{}, rules ...Rule) *Spec {
+	return &Spec{
+		name:         name,
+		specType:     statsig.SpecTypeLayer,
+		entity:       "layer",
+		defaultValue: defaultValueOrEmpty(defaultValue),
+		rules:        rules,
+	}
+}
+
+// Evaluates the spec for a custom ID, e.g. "companyID", instead of the user ID
+func (s *Spec) WithIDType(idType string) *Spec {
+	s.idType = idType
+	return s
+}
+
+// Disables the spec, so it serves its default value to everyone
+func (s *Spec) Disabled() *Spec {
+	s.disabled = true
+	return s
+}
+
+// A rule of a gate, dynamic config, experiment or layer. Its conditions must all pass for the rule to pass.
+type Rule struct {
+	id              string
+	groupName       string
+	passPercentage  float64
+	conditions      []condition
+	returnValue     map[string]interface{}
+	delegate        string
+	experimentGroup bool
+}
+
+type condition struct {
+	Type             string                 `json:"type"`
+	Operator         string                 `json:"operator,omitempty"`
+	Field            string                 `json:"field,omitempty"`
+	TargetValue      interface{}            `json:"targetValue"`
+	AdditionalValues map[string]interface{} `json:"additionalValues,omitempty"`
+	IDType           string                 `json:"idType,omitempty"`
+}
+
+func newRule(conditions ...condition) Rule {
+	return Rule{passPercentage: 100, conditions: conditions}
+}
+
+// Passes for everyone
+func Everyone() Rule {
+	return newRule(condition{Type: "public"})
+}
+
+// Passes for the users with one of the given IDs, of the spec's ID type
+func UnitIDs(ids ...string) Rule {
+	return newRule(condition{Type: "unit_id", Operator: "any", TargetValue: ids})
+}
+
+// Passes when the user field, e.g. "email" or "country", passes the operator, e.g. "any" or "str_ends_with_any"
+func UserField(field string, operator string, targetValue interface{}) Rule {
+	return newRule(condition{Type: "user_field", Operator: operator, Field: field, TargetValue: targetValue})
+}
+
+// Passes for the users the gate passes for
+func PassingGate(gate string) Rule {
+	return newRule(condition{Type: "pass_gate", TargetValue: gate})
+}
+
+// Passes for the users whose bucket, from 0 to 999, is at least from and below to.
+// Buckets are how experiments allocate users, so groups over adjacent ranges split them.
+func Buckets(from int, to int) Rule {
+	return newRule(
+		condition{Type: "user_bucket", Operator: "gte", TargetValue: from},
+		condition{Type: "user_bucket", Operator: "lt", TargetValue: to},
+	)
+}
+
+// Also requires the conditions of the other rule to pass
+func (r Rule) And(other Rule) Rule {
+	r.conditions = append(append([]condition{}, r.conditions...), other.conditions...)
+	return r
+}
+
+// Passes only the given percentage of the users that pass the conditions
+func (r Rule) WithPassPercentage(percentage float64) Rule {
+	r.passPercentage = percentage
+	return r
+}
+
+// Sets the rule ID logged with exposures. Defaults to the group name, or to "<spec name>:<rule index>".
+func (r Rule) WithID(id string) Rule {
+	r.id = id
+	return r
+}
+
+// Names the experiment group of the rule
+func (r Rule) Group(name string) Rule {
+	r.groupName = name
+	return r
+}
+
+// Sets the value the dynamic config, experiment or layer serves when the rule passes
+func (r Rule) Returning(value map[string]interface{}) Rule {
+	r.returnValue = value
+	return r
+}
+
+// Serves the parameters of the experiment to the users of the layer that pass the rule
+func (r Rule) DelegateTo(experiment string) Rule {
+	r.delegate = experiment
+	return r
+}
+
+type specJSON struct {
+	Name               string      `json:"name"`
+	Type               string      `json:"type"`
+	Entity             string      `json:"entity"`
+	Salt               string      `json:"salt"`
+	Enabled            bool        `json:"enabled"`
+	DefaultValue       interface{} `json:"defaultValue"`
+	IDType             string      `json:"idType"`
+	Rules              []ruleJSON  `json:"rules"`
+	ExplicitParameters []string    `json:"explicitParameters,omitempty"`
+	IsActive           *bool       `json:"isActive,omitempty"`
+}
+
+type ruleJSON struct {
+	Name              string      `json:"name"`
+	ID                string      `json:"id"`
+	Salt              string      `json:"salt"`
+	GroupName         string      `json:"groupName,omitempty"`
+	PassPercentage    float64     `json:"passPercentage"`
+	Conditions        []condition `json:"conditions"`
+	ReturnValue       interface{} `json:"returnValue"`
+	IDType            string      `json:"idType"`
+	ConfigDelegate    string      `json:"configDelegate,omitempty"`
+	IsExperimentGroup *bool       `json:"isExperimentGroup,omitempty"`
+}
+
+type specsJSON struct {
+	HasUpdates     bool                `json:"has_updates"`
+	Time           int64               `json:"time"`
+	FeatureGates   []specJSON          `json:"feature_gates"`
+	DynamicConfigs []specJSON          `json:"dynamic_configs"`
+	LayerConfigs   []specJSON          `json:"layer_configs"`
+	Layers         map[string][]string `json:"layers"`
+	IDLists        map[string]bool     `json:"id_lists"`
+}
+
+var lastSpecsTime int64
+
+// Each serialization gets a later time than the previous one, so the SDK always takes updated specs
+func nextSpecsTime() int64 {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	for {
+		last := atomic.LoadInt64(&lastSpecsTime)
+		next := now
+		if next <= last {
+			next = last + 1
+		}
+		if atomic.CompareAndSwapInt64(&lastSpecsTime, last, next) {
+			return next
+		}
+	}
+}
+
+// Serializes the rulesets the way download_config_specs returns them
+func (s *Specs) JSON() []byte {
+	out := specsJSON{
+		HasUpdates:     true,
+		Time:           nextSpecsTime(),
+		FeatureGates:   []specJSON{},
+		DynamicConfigs: []specJSON{},
+		LayerConfigs:   []specJSON{},
+		Layers:         map[string][]string{},
+		IDLists:        map[string]bool{},
+	}
+	for _, spec := range s.specs {
+		serialized := spec.toJSON()
+		switch spec.specType {
+		case statsig.SpecTypeFeatureGate:
+			out.FeatureGates = append(out.FeatureGates, serialized)
+		case statsig.SpecTypeLayer:
+			out.LayerConfigs = append(out.LayerConfigs, serialized)
+			for _, rule := range spec.rules {
+				if rule.delegate != "" {
+					out.Layers[spec.name] = append(out.Layers[spec.name], rule.delegate)
+				}
+			}
+		default:
+			out.DynamicConfigs = append(out.DynamicConfigs, serialized)
+		}
+	}
+	bytes, err := json.Marshal(out)
+	if err != nil {
+		panic(fmt.Errorf("statsigtest: failed to serialize specs: %w", err))
+	}
+	return bytes
+}
+
+func (s *Spec) toJSON() specJSON {
+	// Layers are served with the type of dynamic configs, in layer_configs
+	specType := statsig.SpecTypeDynamicConfig
+	if s.specType == statsig.SpecTypeFeatureGate {
+		specType = statsig.SpecTypeFeatureGate
+	}
+	out := specJSON{
+		Name:         s.name,
+		Type:         specType,
+		Entity:       s.entity,
+		Salt:         s.name,
+		Enabled:      !s.disabled,
+		DefaultValue: s.defaultValue,
+		IDType:       s.idType,
+		Rules:        make([]ruleJSON, 0, len(s.rules)),
+	}
+	if s.entity == statsig.EntityExperiment {
+		active := !s.disabled
+		out.IsActive = &active
+		out.ExplicitParameters = s.parameterNames()
+	}
+	for i, rule := range s.rules {
+		out.Rules = append(out.Rules, s.ruleToJSON(i, rule))
+	}
+	return out
+}
+
+func (s *Spec) ruleToJSON(index int, rule Rule) ruleJSON {
+	id := rule.id
+	if id == "" {
+		id = rule.groupName
+	}
+	if id == "" {
+		id = fmt.Sprintf("%s:%d", s.name, index)
+	}
+	conditions := make([]condition, len(rule.conditions))
+	for i, cond := range rule.conditions {
+		cond.IDType = s.idType
+		if cond.Type == "user_bucket" {
+			cond.AdditionalValues = map[string]interface{}{"salt": s.name}
+		}
+		conditions[i] = cond
+	}
+	var returnValue interface{} = true
+	if s.specType != statsig.SpecTypeFeatureGate {
+		returnValue = defaultValueOrEmpty(rule.returnValue)
+	}
+	out := ruleJSON{
+		Name:           id,
+		ID:             id,
+		Salt:           id,
+		GroupName:      rule.groupName,
+		PassPercentage: rule.passPercentage,
+		Conditions:     conditions,
+		ReturnValue:    returnValue,
+		IDType:         s.idType,
+		ConfigDelegate: rule.delegate,
+	}
+	if rule.experimentGroup {
+		isExperimentGroup := true
+		out.IsExperimentGroup = &isExperimentGroup
+	}
+	return out
+}
+
+// The parameters of an experiment are the keys of its default value and group values
+func (s *Spec) parameterNames() []string {
+	names := make(map[string]bool)
+	if value, ok := s.defaultValue.(map[string]interface{}); ok {
+		for name := range value {
+			names[name] = true
+		}
+	}
+	for _, rule := range s.rules {
+		for name := range rule.returnValue {
+			names[name] = true
+		}
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+func defaultValueOrEmpty(value map[string]interface{}) map[string]interface{} {
+	if value == nil {
+		return map[string]interface{}{}
+	}
+	return value
+}
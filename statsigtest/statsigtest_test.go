@@ -0,0 +1,91 @@
+package statsigtest_test
+
+import (
+	"testing"
+
+	statsig "github.com/statsig-io/go-sdk"
+	"github.com/statsig-io/go-sdk/statsigtest"
+)
+
+func TestServer(t *testing.T) {
+	server := statsigtest.NewServer(t, statsigtest.NewSpecs(
+		statsigtest.Gate("new_checkout", statsigtest.UnitIDs("user-1")),
+		statsigtest.Gate("statsig_emails", statsigtest.UserField("email", "str_ends_with_any", []string{"@statsig.com"})),
+		statsigtest.Gate("disabled_gate", statsigtest.Everyone()).Disabled(),
+		statsigtest.Config("limits", map[string]interface{}{"max": 10},
+			statsigtest.PassingGate("new_checkout").Returning(map[string]interface{}{"max": 20}),
+		),
+		statsigtest.Experiment("button_color", map[string]interface{}{"color": "grey"},
+			statsigtest.UnitIDs("user-2").Group("Blue").Returning(map[string]interface{}{"color": "blue"}),
+			statsigtest.Buckets(0, 1000).Group("Control").Returning(map[string]interface{}{"color": "red"}),
+		),
+		statsigtest.Layer("checkout_layer", map[string]interface{}{"color": "grey"},
+			statsigtest.Everyone().DelegateTo("button_color"),
+		),
+	))
+	c := statsig.NewClientWithOptions("secret-key", server.Options())
+	defer c.Shutdown()
+	user := statsig.User{UserID: "user-1", Email: "jane@statsig.com"}
+	other := statsig.User{UserID: "user-2"}
+
+	t.Run("serves the specs", func(t *testing.T) {
+		if !c.CheckGate(user, "new_checkout") || c.CheckGate(other, "new_checkout") {
+			t.Errorf("Expected the gate to pass for user-1 only")
+		}
+		if !c.CheckGate(user, "statsig_emails") || c.CheckGate(other, "statsig_emails") {
+			t.Errorf("Expected the gate to pass for Statsig emails only")
+		}
+		if c.CheckGate(user, "disabled_gate") {
+			t.Errorf("Expected a disabled gate to fail")
+		}
+		limits := c.GetConfig(user, "limits")
+		otherLimits := c.GetConfig(other, "limits")
+		if limits.GetNumber("max", 0) != 20 || otherLimits.GetNumber("max", 0) != 10 {
+			t.Errorf("Expected the rule value for user-1 and the default for user-2")
+		}
+		experiment := c.GetExperiment(other, "button_color")
+		if experiment.GetString("color", "") != "blue" || experiment.RuleID != "Blue" {
+			t.Errorf("Expected user-2 in the Blue group, got %v from rule %s", experiment.Value, experiment.RuleID)
+		}
+		layer := c.GetLayer(user, "checkout_layer")
+		if layer.GetString("color", "") != "red" {
+			t.Errorf("Expected the layer to serve the experiment group of user-1")
+		}
+	})
+
+	t.Run("captures exposures and custom events", func(t *testing.T) {
+		_ = c.Flush()
+		server.Reset()
+		c.CheckGate(user, "new_checkout")
+		c.GetExperiment(other, "button_color")
+		layer := c.GetLayer(user, "checkout_layer")
+		layer.GetString("color", "")
+		c.LogEvent(statsig.Event{EventName: "purchase", User: user, Value: "42"})
+		if err := c.Flush(); err != nil {
+			t.Fatalf("Failed to flush: %s", err)
+		}
+		server.AssertGateExposure(t, "user-1", "new_checkout", true)
+		server.AssertConfigExposure(t, "user-2", "button_color", "Blue")
+		server.AssertLayerExposure(t, "user-1", "checkout_layer", "color")
+		server.AssertNoExposure(t, "limits")
+		server.AssertNoEventLogged(t, "refund")
+		if purchase := server.AssertEventLogged(t, "purchase"); purchase.Value != "42" {
+			t.Errorf("Expected the event value, got %q", purchase.Value)
+		}
+		if server.Requests("/download_config_specs") == 0 {
+			t.Errorf("Expected the SDK to download the specs")
+		}
+	})
+
+	t.Run("bootstraps from the specs", func(t *testing.T) {
+		specs := statsigtest.NewSpecs(statsigtest.Gate("new_checkout", statsigtest.Everyone()))
+		local := statsig.NewClientWithOptions("secret-key", statsig.NewOptions(
+			statsig.WithLocalMode(),
+			statsig.WithBootstrapValues(string(specs.JSON())),
+		))
+		defer local.Shutdown()
+		if !local.CheckGate(other, "new_checkout") {
+			t.Errorf("Expected the bootstrapped gate to pass")
+		}
+	})
+}
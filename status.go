@@ -0,0 +1,75 @@
+package statsig
+
+import (
+	"io"
+	"net/http"
+	"time"
+)
+
+// A point-in-time view of the SDK's health, as returned by GetStatus
+type Status struct {
+	// Whether rulesets were loaded, from the network, BootstrapValues, the DataAdapter or a snapshot
+	Initialized bool
+	// Where the current rulesets came from: Network, Bootstrap, DataAdapter, or Uninitialized if none loaded
+	Source string
+	// The time of the current rulesets on Statsig's servers, in milliseconds, or 0 if none loaded
+	ConfigSyncTime int64
+	// When the rulesets and ID lists were last synced, whether or not they changed. Zero if they never were.
+	LastConfigSync time.Time
+	LastIDListSync time.Time
+	// Whether the rulesets have gone longer than Options.StaleConfigThreshold without a successful sync
+	Stale bool
+	// The number of events waiting for the next flush
+	PendingEvents int
+}
+
+func (s *store) markIDListsSynced() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.idListsSyncedAt = time.Now()
+}
+
+func (s *store) getStatus() Status {
+	_, stale := s.getStaleness()
+	rulesets := s.getRulesets()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return Status{
+		Initialized:    rulesets.time != 0,
+		Source:         string(rulesets.initReason),
+		ConfigSyncTime: rulesets.time,
+		LastConfigSync: s.syncedAt,
+		LastIDListSync: s.idListsSyncedAt,
+		Stale:          stale,
+	}
+}
+
+func (l *logger) pendingEvents() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.events)
+}
+
+// Serves 200 when the rulesets are loaded and not stale, and 503 otherwise. getClient returns nil
+// until there is a Client to check, so the global handler can be registered before Initialize.
+type readyzHandler struct {
+	getClient func() *Client
+}
+
+func (h readyzHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	res.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	res.Header().Set("Cache-Control", "no-store")
+	message := "ok"
+	code := http.StatusOK
+	if c := h.getClient(); c == nil {
+		message, code = "statsig is not initialized", http.StatusServiceUnavailable
+	} else if status := c.GetStatus(); !status.Initialized {
+		message, code = "statsig has not loaded rulesets", http.StatusServiceUnavailable
+	} else if status.Stale {
+		message, code = "statsig rulesets are stale", http.StatusServiceUnavailable
+	}
+	res.WriteHeader(code)
+	if req.Method != http.MethodHead {
+		_, _ = io.WriteString(res, message+"\n")
+	}
+}
@@ -0,0 +1,120 @@
+package statsig
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetStatus(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "download_config_specs") {
+			bytes, _ := os.ReadFile("download_config_specs.json")
+			_, _ = res.Write(bytes)
+			return
+		}
+		if strings.Contains(req.URL.Path, "get_id_lists") {
+			_, _ = res.Write([]byte("{}"))
+			return
+		}
+		res.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	start := time.Now()
+	c := NewClientWithOptions("secret-key", &Options{
+		API:                  testServer.URL,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+
+	status := c.GetStatus()
+	if !status.Initialized || status.Source != "Network" || status.ConfigSyncTime != 1631638014811 || status.Stale {
+		t.Errorf("Expected rulesets from the network, got %+v", status)
+	}
+	if status.LastConfigSync.Before(start) || status.LastIDListSync.Before(start) {
+		t.Errorf("Expected the rulesets and ID lists to have synced, got %+v", status)
+	}
+
+	c.LogEvent(Event{User: User{UserID: "123"}, EventName: "test_event"})
+	if pending := c.GetStatus().PendingEvents; pending != 1 {
+		t.Errorf("Expected 1 pending event, got %d", pending)
+	}
+	_ = c.Flush()
+	if pending := c.GetStatus().PendingEvents; pending != 0 {
+		t.Errorf("Expected no pending events after a flush, got %d", pending)
+	}
+}
+
+func TestGetStatusUninitialized(t *testing.T) {
+	c := NewClientWithOptions("secret-key", &Options{
+		LocalMode:            true,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+
+	status := c.GetStatus()
+	if status.Initialized || status.Source != "Uninitialized" || !status.LastConfigSync.IsZero() {
+		t.Errorf("Expected no rulesets, got %+v", status)
+	}
+}
+
+func TestReadyzHandler(t *testing.T) {
+	var dcsOnline int32 = 1
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "download_config_specs") && atomic.LoadInt32(&dcsOnline) == 1 {
+			bytes, _ := os.ReadFile("download_config_specs.json")
+			_, _ = res.Write(bytes)
+			return
+		}
+		res.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer testServer.Close()
+
+	probe := func(handler http.Handler) int {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/readyz", nil))
+		return recorder.Code
+	}
+
+	t.Run("not ready before Initialize", func(t *testing.T) {
+		if code := probe(ReadyzHandler()); code != http.StatusServiceUnavailable {
+			t.Errorf("Expected 503 before Initialize, got %d", code)
+		}
+	})
+
+	t.Run("ready with fresh rulesets only", func(t *testing.T) {
+		c := NewClientWithOptions("secret-key", &Options{
+			API:                  testServer.URL,
+			ConfigSyncInterval:   10 * time.Millisecond,
+			StaleConfigThreshold: 100 * time.Millisecond,
+			OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+			StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+		})
+		defer c.Shutdown()
+		handler := c.ReadyzHandler()
+		if code := probe(handler); code != http.StatusOK {
+			t.Errorf("Expected 200 once initialized, got %d", code)
+		}
+		atomic.StoreInt32(&dcsOnline, 0)
+		waitForCondition(t, func() bool { return probe(handler) == http.StatusServiceUnavailable })
+	})
+
+	t.Run("not ready without rulesets", func(t *testing.T) {
+		c := NewClientWithOptions("secret-key", &Options{
+			API:                  testServer.URL,
+			OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+			StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+		})
+		defer c.Shutdown()
+		if code := probe(c.ReadyzHandler()); code != http.StatusServiceUnavailable {
+			t.Errorf("Expected 503 without rulesets, got %d", code)
+		}
+	})
+}
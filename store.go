@@ -1,12 +1,16 @@
 package statsig
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math/rand"
+	"net/http"
 	"os"
+	"sort"
 	"strconv"
-	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -39,6 +43,41 @@ func (c configSpec) hasTargetAppID(appId string) bool {
 	return false
 }
 
+// Drops the entities that do not target the app, in case the server did not filter them already
+func filterConfigSpecsForTargetApp(specs downloadConfigSpecResponse, appId string) downloadConfigSpecResponse {
+	filter := func(all []configSpec) []configSpec {
+		filtered := make([]configSpec, 0, len(all))
+		for _, spec := range all {
+			if spec.hasTargetAppID(appId) {
+				filtered = append(filtered, spec)
+			}
+		}
+		return filtered
+	}
+	specs.FeatureGates = filter(specs.FeatureGates)
+	specs.DynamicConfigs = filter(specs.DynamicConfigs)
+	specs.LayerConfigs = filter(specs.LayerConfigs)
+	if specs.CMABConfigs != nil {
+		cmabConfigs := make(map[string]cmabConfig, len(specs.CMABConfigs))
+		for name, cmab := range specs.CMABConfigs {
+			if (configSpec{TargetAppIDs: cmab.TargetAppIDs}).hasTargetAppID(appId) {
+				cmabConfigs[name] = cmab
+			}
+		}
+		specs.CMABConfigs = cmabConfigs
+	}
+	if specs.ParamStores != nil {
+		paramStores := make(map[string]paramStore, len(specs.ParamStores))
+		for name, store := range specs.ParamStores {
+			if (configSpec{TargetAppIDs: store.TargetAppIDs}).hasTargetAppID(appId) {
+				paramStores[name] = store
+			}
+		}
+		specs.ParamStores = paramStores
+	}
+	return specs
+}
+
 type configRule struct {
 	Name              string            `json:"name"`
 	ID                string            `json:"id"`
@@ -49,6 +88,9 @@ type configRule struct {
 	IDType            string            `json:"idType"`
 	ConfigDelegate    string            `json:"configDelegate"`
 	IsExperimentGroup *bool             `json:"isExperimentGroup,omitempty"`
+	GroupName         string            `json:"groupName,omitempty"`
+	// The raw JSON of Conditions until the rule is interned, see internConfigSpecs
+	conditionsJSON string
 }
 
 type configCondition struct {
@@ -61,20 +103,34 @@ type configCondition struct {
 }
 
 type downloadConfigSpecResponse struct {
-	HasUpdates             bool                `json:"has_updates"`
-	Time                   int64               `json:"time"`
-	FeatureGates           []configSpec        `json:"feature_gates"`
-	DynamicConfigs         []configSpec        `json:"dynamic_configs"`
-	LayerConfigs           []configSpec        `json:"layer_configs"`
-	Layers                 map[string][]string `json:"layers"`
-	IDLists                map[string]bool     `json:"id_lists"`
-	DiagnosticsSampleRates map[string]int      `json:"diagnostics"`
-	SDKKeysToAppID         map[string]string   `json:"sdk_keys_to_app_ids,omitempty"`
+	HasUpdates             bool                  `json:"has_updates"`
+	Time                   int64                 `json:"time"`
+	FeatureGates           []configSpec          `json:"feature_gates"`
+	DynamicConfigs         []configSpec          `json:"dynamic_configs"`
+	LayerConfigs           []configSpec          `json:"layer_configs"`
+	Layers                 map[string][]string   `json:"layers"`
+	CMABConfigs            map[string]cmabConfig `json:"cmab_configs,omitempty"`
+	ParamStores            map[string]paramStore `json:"param_stores,omitempty"`
+	IDLists                map[string]bool       `json:"id_lists"`
+	DiagnosticsSampleRates map[string]int        `json:"diagnostics"`
+	SDKKeysToAppID         map[string]string     `json:"sdk_keys_to_app_ids,omitempty"`
+	HashedSDKKeyUsed       string                `json:"hashed_sdk_key_used,omitempty"`
+	// Set when the response only carries the changes since the sinceTime of the request
+	IsDelta        bool     `json:"is_delta,omitempty"`
+	DeletedGates   []string `json:"deleted_gates,omitempty"`
+	DeletedConfigs []string `json:"deleted_configs,omitempty"`
+	DeletedLayers  []string `json:"deleted_layers,omitempty"`
+	// Checksum of the specs once a delta is merged, see getConfigSpecsChecksum
+	Checksum string `json:"checksum,omitempty"`
 }
 
 type downloadConfigsInput struct {
 	SinceTime       int64           `json:"sinceTime"`
 	StatsigMetadata statsigMetadata `json:"statsigMetadata"`
+	// Lets the server answer with a delta instead of every spec
+	AcceptsDeltas bool `json:"acceptsDeltas,omitempty"`
+	// Limits the response to the entities of this target app
+	TargetAppID string `json:"targetAppID,omitempty"`
 }
 
 type idList struct {
@@ -83,176 +139,397 @@ type idList struct {
 	CreationTime int64  `json:"creationTime"`
 	URL          string `json:"url"`
 	FileID       string `json:"fileID"`
-	ids          *sync.Map
+	ids          idListStorage
+}
+
+func (l *idList) contains(id string) bool {
+	return l.ids != nil && l.ids.contains(id)
 }
 
 type getIDListsInput struct {
 	StatsigMetadata statsigMetadata `json:"statsigMetadata"`
 }
 
+// The rulesets of a single sync, and the evaluation details that go with them. A snapshot is never modified
+// once stored, so evaluations read it without locking.
+type rulesetSnapshot struct {
+	featureGates      map[string]configSpec
+	dynamicConfigs    map[string]configSpec
+	layerConfigs      map[string]configSpec
+	experimentToLayer map[string]string
+	sdkKeysToAppID    map[string]string
+	cmabConfigs       map[string]cmabConfig
+	paramStores       map[string]paramStore
+	// Time of the rulesets, zero until any are loaded
+	time                int64
+	initialSyncTime     int64
+	initReason          evaluationReason
+	loadingInitialSpecs bool
+}
+
 type store struct {
-	featureGates         map[string]configSpec
-	dynamicConfigs       map[string]configSpec
-	layerConfigs         map[string]configSpec
-	experimentToLayer    map[string]string
-	sdkKeysToAppID       map[string]string
-	idLists              map[string]*idList
-	lastSyncTime         int64
-	initialSyncTime      int64
-	initReason           evaluationReason
+	// Holds a *rulesetSnapshot, swapped whole by setConfigSpecs and updateRulesets
+	rulesets      atomic.Value
+	idLists       map[string]*idList
+	forceFullSync bool
+	// Closed once initialize has loaded the first rulesets and ID lists, or given up on them
+	initialized          chan struct{}
 	initializedIDLists   bool
 	transport            *transport
 	configSyncInterval   time.Duration
+	configSyncJitter     float64
 	idListSyncInterval   time.Duration
+	disableIDLists       bool
 	shutdown             bool
+	stopped              chan struct{} // closed on shutdown, waking the pollers so they exit right away
 	rulesUpdatedCallback func(rules string, time int64)
+	bootstrapValues      string
 	errorBoundary        *errorBoundary
 	dataAdapter          IDataAdapter
 	syncFailureCount     int
 	diagnostics          *diagnostics
-	mu                   sync.RWMutex
+	hashedSDKKeyUsed     string
+	warnedSDKKeyHash     string
+	sdkKeyRejectedError  *SDKKeyRejectedError
+	rejectedSpecsCount   int
+	changeListeners      *changeListeners
+	adapterSpecsHash     string
+	adapterIDListsHash   string
+	// When the rulesets and ID lists were last synced from any source, zero until they are, see status.go
+	createdAt       time.Time
+	syncedAt        time.Time
+	idListsSyncedAt time.Time
+	staleNotified   bool
+	mu              sync.RWMutex
 }
 
 var syncOutdatedMax = 2 * time.Minute
 
+// Shorter config sync intervals are raised to this, so a misconfigured interval cannot hammer download_config_specs
+var minConfigSyncInterval = time.Second
+
+// Each store stretches its config sync interval by a random fraction of up to this much, so servers
+// deployed together do not all poll download_config_specs in the same second
+var maxConfigSyncJitter = 0.1
+
+// How long polling backs off to at most while the synced specs keep being rejected as invalid
+var maxRejectedSpecsBackoff = 5 * time.Minute
+
+func guardConfigSyncInterval(interval time.Duration) time.Duration {
+	if interval < minConfigSyncInterval {
+		global.Logger().LogWarning(fmt.Sprintf("ConfigSyncInterval %s is below the minimum, using %s", interval, minConfigSyncInterval))
+		return minConfigSyncInterval
+	}
+	return interval
+}
+
 func newStore(
 	transport *transport,
 	errorBoundary *errorBoundary,
 	options *Options,
 	diagnostics *diagnostics,
+	initOpts initOptions,
 ) *store {
-	configSyncInterval := 10 * time.Second
-	idListSyncInterval := time.Minute
+	configSyncInterval := DefaultConfigSyncInterval
+	idListSyncInterval := DefaultIDListSyncInterval
 	if options.ConfigSyncInterval > 0 {
-		configSyncInterval = options.ConfigSyncInterval
+		configSyncInterval = guardConfigSyncInterval(options.ConfigSyncInterval)
 	}
 	if options.IDListSyncInterval > 0 {
 		idListSyncInterval = options.IDListSyncInterval
 	}
+	initOpts.budget = options.InitBudget
+	if options.InitTimeout > 0 && (initOpts.budget <= 0 || options.InitTimeout < initOpts.budget) {
+		initOpts.budget = options.InitTimeout
+	}
+	bootstrapValues := options.BootstrapValues
+	if bootstrapValues == "" && options.BootstrapFile != "" {
+		bootstrapValues = readBootstrapFile(options.BootstrapFile)
+	}
 	return newStoreInternal(
 		transport,
 		configSyncInterval,
 		idListSyncInterval,
-		options.BootstrapValues,
+		options.DisableIDLists,
+		bootstrapValues,
 		options.RulesUpdatedCallback,
 		errorBoundary,
 		options.DataAdapter,
 		diagnostics,
+		initOpts,
 	)
 }
 
+func readBootstrapFile(path string) string {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		global.Logger().LogError(fmt.Errorf("Failed to read the BootstrapFile: %w", err))
+		return ""
+	}
+	return string(bytes)
+}
+
 func newStoreInternal(
 	transport *transport,
 	configSyncInterval time.Duration,
 	idListSyncInterval time.Duration,
+	disableIDLists bool,
 	bootstrapValues string,
 	rulesUpdatedCallback func(rules string, time int64),
 	errorBoundary *errorBoundary,
 	dataAdapter IDataAdapter,
 	diagnostics *diagnostics,
+	initOpts initOptions,
 ) *store {
 	store := &store{
-		featureGates:         make(map[string]configSpec),
-		dynamicConfigs:       make(map[string]configSpec),
 		idLists:              make(map[string]*idList),
 		transport:            transport,
 		configSyncInterval:   configSyncInterval,
+		configSyncJitter:     rand.Float64() * maxConfigSyncJitter,
 		idListSyncInterval:   idListSyncInterval,
+		disableIDLists:       disableIDLists,
 		rulesUpdatedCallback: rulesUpdatedCallback,
+		bootstrapValues:      bootstrapValues,
 		errorBoundary:        errorBoundary,
-		initReason:           reasonUninitialized,
+		initialized:          make(chan struct{}),
+		stopped:              make(chan struct{}),
 		initializedIDLists:   false,
 		dataAdapter:          dataAdapter,
 		syncFailureCount:     0,
 		diagnostics:          diagnostics,
+		changeListeners:      newChangeListeners(),
+		createdAt:            time.Now(),
+	}
+	store.rulesets.Store(&rulesetSnapshot{
+		featureGates:        make(map[string]configSpec),
+		dynamicConfigs:      make(map[string]configSpec),
+		layerConfigs:        make(map[string]configSpec),
+		experimentToLayer:   make(map[string]string),
+		initReason:          reasonUninitialized,
+		loadingInitialSpecs: true,
+	})
+	ctx := initOpts.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if initOpts.background {
+		go store.initialize(ctx, nil)
+	} else {
+		store.initialize(ctx, newInitBudget(initOpts.budget))
 	}
+	return store
+}
+
+// Loads the first rulesets and ID lists, then starts polling for changes.
+// Once ctx is done, in-flight requests are aborted and the remaining steps skipped.
+func (s *store) initialize(ctx context.Context, budget *initBudget) {
 	firstAttempt := true
-	if dataAdapter != nil {
+	revalidate := false
+	if s.dataAdapter != nil {
 		firstAttempt = false
-		dataAdapter.Initialize()
-		store.fetchConfigSpecsFromAdapter()
-	} else if bootstrapValues != "" {
+		var specString string
+		var verified bool
+		if !budget.wait(ctx, s.dataAdapter.Initialize) {
+			s.diagnostics.initDiagnostics.logProcess("Initialize budget exhausted or cancelled, skipping adapter specs")
+		} else if budget.wait(ctx, func() { specString, verified = s.readConfigSpecsFromAdapter(false) }) {
+			s.applyConfigSpecsFromAdapter(specString, verified)
+		} else {
+			s.diagnostics.initDiagnostics.logProcess("Initialize budget exhausted or cancelled, skipping adapter specs")
+		}
+		// Serve the cached adapter specs right away, but refresh them from the network in the background
+		revalidate = s.getLastSyncTime() != 0 && !s.dataAdapter.ShouldBeUsedForQueryingUpdates(CONFIG_SPECS_KEY)
+	} else if s.bootstrapValues != "" {
 		firstAttempt = false
-		if store.processConfigSpecs(bootstrapValues, store.addDiagnostics().bootstrap()) {
-			store.mu.Lock()
-			store.initReason = reasonBootstrap
-			store.mu.Unlock()
+		if applied, err := s.processConfigSpecs(s.bootstrapValues, "BootstrapValues", s.addDiagnostics().bootstrap()); applied {
+			s.markSynced()
+			s.setInitReason(reasonBootstrap)
+			s.checkSDKKeyMatches("BootstrapValues")
+		} else {
+			global.Logger().LogError(&InvalidBootstrapValuesError{Err: err})
 		}
 	}
-	if store.lastSyncTime == 0 {
+	if s.getLastSyncTime() == 0 {
 		if !firstAttempt {
-			store.diagnostics.initDiagnostics.logProcess("Retrying with network...")
-		}
-		store.fetchConfigSpecsFromServer(true)
-	}
-	store.mu.Lock()
-	store.initialSyncTime = store.lastSyncTime
-	store.mu.Unlock()
-	store.syncIDLists()
-	store.mu.Lock()
-	store.initializedIDLists = true
-	store.mu.Unlock()
-	go store.pollForRulesetChanges()
-	go store.pollForIDListChanges()
-	return store
+			s.diagnostics.initDiagnostics.logProcess("Retrying with network...")
+		}
+		if !budget.wait(ctx, func() {
+			s.fetchConfigSpecsFromServerWithDiagnostics(ctx, true, s.addDiagnostics)
+			s.updateRulesets(func(rulesets *rulesetSnapshot) {
+				rulesets.loadingInitialSpecs = false
+			})
+		}) {
+			s.diagnostics.initDiagnostics.logProcess("Initialize budget exhausted, downloading specs in the background")
+		}
+	}
+	s.updateRulesets(func(rulesets *rulesetSnapshot) {
+		rulesets.initialSyncTime = rulesets.time
+		if rulesets.time != 0 {
+			rulesets.loadingInitialSpecs = false
+		}
+	})
+	if s.disableIDLists {
+		s.diagnostics.initDiagnostics.logProcess("ID lists are disabled")
+	} else if ctx.Err() != nil {
+		s.diagnostics.initDiagnostics.logProcess("Initialize cancelled, skipping ID lists")
+	} else if !budget.wait(ctx, func() { s.syncIDListsWithContext(ctx) }) {
+		s.diagnostics.initDiagnostics.logProcess("Initialize budget exhausted, downloading ID lists in the background")
+	}
+	s.mu.Lock()
+	s.initializedIDLists = true
+	s.mu.Unlock()
+	close(s.initialized)
+	if revalidate {
+		s.diagnostics.initDiagnostics.logProcess("Revalidating adapter specs with network...")
+		go func() {
+			s.fetchConfigSpecsFromServerWithDiagnostics(context.Background(), false, s.diagnostics.initialize)
+			s.pollForRulesetChanges()
+		}()
+	} else {
+		go s.pollForRulesetChanges()
+	}
+	if !s.disableIDLists {
+		go s.pollForIDListChanges()
+	}
+}
+
+func (s *store) getRulesets() *rulesetSnapshot {
+	return s.rulesets.Load().(*rulesetSnapshot)
+}
+
+// Replaces the rulesets with a copy changed by update. Writers hold s.mu so no update is lost.
+func (s *store) updateRulesets(update func(rulesets *rulesetSnapshot)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rulesets := *s.getRulesets()
+	update(&rulesets)
+	s.rulesets.Store(&rulesets)
+}
+
+func (s *store) setInitReason(reason evaluationReason) {
+	s.updateRulesets(func(rulesets *rulesetSnapshot) {
+		rulesets.initReason = reason
+	})
+}
+
+// Time of the current rulesets, zero until any are loaded
+func (s *store) getLastSyncTime() int64 {
+	return s.getRulesets().time
 }
 
 func (s *store) getGate(name string) (configSpec, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	gate, ok := s.featureGates[name]
+	gate, ok := s.getRulesets().featureGates[name]
 	return gate, ok
 }
 
 func (s *store) getDynamicConfig(name string) (configSpec, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	config, ok := s.dynamicConfigs[name]
+	config, ok := s.getRulesets().dynamicConfigs[name]
 	return config, ok
 }
 
 func (s *store) getLayerConfig(name string) (configSpec, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	layer, ok := s.layerConfigs[name]
+	layer, ok := s.getRulesets().layerConfigs[name]
 	return layer, ok
 }
 
 func (s *store) getExperimentLayer(experimentName string) (string, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	layer, ok := s.experimentToLayer[experimentName]
+	layer, ok := s.getRulesets().experimentToLayer[experimentName]
 	return layer, ok
 }
 
+func (s *store) getCMABConfig(name string) (cmabConfig, bool) {
+	cmab, ok := s.getRulesets().cmabConfigs[name]
+	return cmab, ok
+}
+
+func (s *store) getParamStore(name string) (paramStore, bool) {
+	store, ok := s.getRulesets().paramStores[name]
+	return store, ok
+}
+
 func (s *store) getAppIDForSDKKey(clientKey string) (string, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	appId, ok := s.sdkKeysToAppID[clientKey]
+	appId, ok := s.getRulesets().sdkKeysToAppID[clientKey]
 	return appId, ok
 }
 
 func (s *store) fetchConfigSpecsFromAdapter() {
+	s.applyConfigSpecsFromAdapter(s.readConfigSpecsFromAdapter(true))
+}
+
+// Reads the specs while polling or on initialize. A chain of adapters verifies them as it reads, so specs
+// rejected by IntegrityOptions in one adapter fall through to the next, and reports them as verified.
+func (s *store) readConfigSpecsFromAdapter(polling bool) (specString string, verified bool) {
 	s.addDiagnostics().dataStoreConfigSpecs().fetch().start().mark()
 	defer func() {
 		if err := recover(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error calling data adapter get: %s\n", toError(err).Error())
+			logRecoveredPanic("data adapter get", err)
+			s.errorBoundary.reportError(toError(err), ErrorContextDataAdapter)
 		}
 	}()
-	specString := s.dataAdapter.Get(CONFIG_SPECS_KEY)
+	if chain, ok := s.dataAdapter.(*dataAdapterChain); ok {
+		specString = chain.get(CONFIG_SPECS_KEY, polling, func(value string) bool {
+			if s.isAdapterSpecsUnchanged(getHashBase64StringEncoding(value)) {
+				return true
+			}
+			if err := s.verifySpecs([]byte(value), nil, "DataAdapter"); err != nil {
+				global.Logger().LogError(err)
+				s.errorBoundary.reportError(err, ErrorContextDataAdapter)
+				return false
+			}
+			return true
+		})
+		verified = true
+	} else {
+		specString = s.dataAdapter.Get(CONFIG_SPECS_KEY)
+	}
 	s.addDiagnostics().dataStoreConfigSpecs().fetch().end().success(true).mark()
-	if s.processConfigSpecs(specString, s.addDiagnostics().dataStoreConfigSpecs()) {
-		s.mu.Lock()
-		s.initReason = reasonDataAdapter
-		s.mu.Unlock()
+	return specString, verified
+}
+
+func (s *store) applyConfigSpecsFromAdapter(specString string, verified bool) {
+	if specString == "" {
+		return
+	}
+	// Readers polling the adapter usually see the same payload many times between writes. It was verified
+	// when first applied, so it is not verified again.
+	hash := getHashBase64StringEncoding(specString)
+	if s.isAdapterSpecsUnchanged(hash) {
+		s.markSynced()
+		return
 	}
+	if !verified {
+		if err := s.verifySpecs([]byte(specString), nil, "DataAdapter"); err != nil {
+			global.Logger().LogError(err)
+			s.errorBoundary.reportError(err, ErrorContextDataAdapter)
+			return
+		}
+	}
+	applied, err := s.processConfigSpecs(specString, "DataAdapter", s.addDiagnostics().dataStoreConfigSpecs())
+	if err != nil {
+		return
+	}
+	s.markSynced()
+	s.mu.Lock()
+	s.adapterSpecsHash = hash
+	s.mu.Unlock()
+	if applied {
+		s.setInitReason(reasonDataAdapter)
+		s.checkSDKKeyMatches("DataAdapter")
+	}
+}
+
+// Whether the specs with the hash are the last ones applied from the DataAdapter
+func (s *store) isAdapterSpecsUnchanged(hash string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.getLastSyncTime() != 0 && hash == s.adapterSpecsHash
 }
 
 func (s *store) saveConfigSpecsToAdapter(specs downloadConfigSpecResponse) {
-	specString, err := json.Marshal(specs)
+	specString, err := s.transport.codec.Marshal(specs)
 	defer func() {
 		if err := recover(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error calling data adapter set: %s\n", toError(err).Error())
+			logRecoveredPanic("data adapter set", err)
+			s.errorBoundary.reportError(toError(err), ErrorContextDataAdapter)
 		}
 	}()
 	if err == nil {
@@ -261,73 +538,198 @@ func (s *store) saveConfigSpecsToAdapter(specs downloadConfigSpecResponse) {
 }
 
 func (s *store) handleSyncError(err error, isColdStart bool) {
+	s.errorBoundary.reportError(err, ErrorContextConfigSync)
 	s.syncFailureCount += 1
-	failDuration := time.Duration(s.syncFailureCount) * s.configSyncInterval
+	failDuration := time.Duration(s.syncFailureCount) * s.getConfigSyncInterval()
 	if isColdStart {
-		fmt.Fprintf(os.Stderr, "Failed to initialize from the network. "+
-			"See https://docs.statsig.com/messages/serverSDKConnection for more information\n")
+		global.Logger().LogError("Failed to initialize from the network. " +
+			"See https://docs.statsig.com/messages/serverSDKConnection for more information")
 		s.errorBoundary.logException(err)
 	} else if failDuration > syncOutdatedMax {
-		fmt.Fprintf(os.Stderr, "Syncing the server SDK with Statsig network has failed for %dms. "+
+		global.Logger().LogWarning(fmt.Sprintf("Syncing the server SDK with Statsig network has failed for %dms. "+
 			"Your sdk will continue to serve gate/config/experiment definitions as of the last successful sync. "+
-			"See https://docs.statsig.com/messages/serverSDKConnection for more information\n", int64(failDuration/time.Millisecond))
+			"See https://docs.statsig.com/messages/serverSDKConnection for more information", int64(failDuration/time.Millisecond)),
+			"error", err)
 		s.errorBoundary.logException(err)
 		s.syncFailureCount = 0
 	}
 }
 
 func (s *store) fetchConfigSpecsFromServer(isColdStart bool) {
-	s.addDiagnostics().downloadConfigSpecs().networkRequest().start().mark()
+	s.fetchConfigSpecsFromServerWithDiagnostics(context.Background(), isColdStart, s.addDiagnostics)
+}
+
+func (s *store) fetchConfigSpecsFromServerWithDiagnostics(ctx context.Context, isColdStart bool, addDiagnostics func() *marker) {
+	if s.transport.options.DisableNetwork {
+		return
+	}
+	_, span := s.transport.tracing.start(ctx, "statsig.config_sync")
+	defer span.End()
+	addDiagnostics().downloadConfigSpecs().networkRequest().start().mark()
 	s.mu.RLock()
 	input := &downloadConfigsInput{
-		SinceTime:       s.lastSyncTime,
+		SinceTime:       s.getLastSyncTime(),
 		StatsigMetadata: s.transport.metadata,
+		AcceptsDeltas:   s.transport.options.EnableConfigSpecDeltas && !s.forceFullSync,
+		TargetAppID:     s.transport.options.TargetApp,
+	}
+	if s.forceFullSync {
+		input.SinceTime = 0
 	}
 	s.mu.RUnlock()
+	span.SetAttribute("statsig.since_time", input.SinceTime)
 	var specs downloadConfigSpecResponse
-	res, err := s.transport.postRequest("/download_config_specs", input, &specs)
+	var res *http.Response
+	var err error
+	if s.transport.options.IntegrityOptions.Verify != nil {
+		var raw []byte
+		res, err = s.transport.postRequestWithContext(ctx, "/download_config_specs", input, &raw)
+		if err == nil && res != nil {
+			if err = s.verifySpecs(raw, res.Header, "network"); err == nil {
+				err = s.transport.codec.Unmarshal(raw, &specs)
+			}
+		}
+	} else {
+		res, err = s.transport.postRequestWithContext(ctx, "/download_config_specs", input, &specs)
+	}
+	if err == nil && res != nil {
+		internConfigSpecs(&specs)
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
 	if res == nil || err != nil {
-		marker := s.addDiagnostics().downloadConfigSpecs().networkRequest().end().success(false)
+		marker := addDiagnostics().downloadConfigSpecs().networkRequest().end().success(false)
 		if res != nil {
 			marker.statusCode(res.StatusCode).sdkRegion(safeGetFirst(res.Header["X-Statsig-Region"]))
 		}
 		marker.mark()
+		// Cancelled by the caller rather than a failure to report
+		if ctx.Err() != nil {
+			return
+		}
+		if res != nil && isColdStart && (res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden) {
+			keyErr := &SDKKeyRejectedError{StatusCode: res.StatusCode}
+			s.mu.Lock()
+			s.sdkKeyRejectedError = keyErr
+			s.mu.Unlock()
+			global.Logger().LogError(keyErr)
+			err = keyErr
+		}
 		s.handleSyncError(err, isColdStart)
 		return
 	}
-	s.addDiagnostics().downloadConfigSpecs().networkRequest().end().
+	addDiagnostics().downloadConfigSpecs().networkRequest().end().
 		success(true).statusCode(res.StatusCode).sdkRegion(safeGetFirst(res.Header["X-Statsig-Region"])).mark()
-	if s.processConfigSpecs(specs, s.addDiagnostics().downloadConfigSpecs()) {
+	// Stamp the specs with the key that downloaded them, so copies handed to the RulesUpdatedCallback
+	// or DataAdapter can be checked against the key of the SDK that loads them later
+	specs.HashedSDKKeyUsed = getDJB2Hash(s.transport.sdkKey)
+	span.SetAttribute("statsig.lcut", specs.Time)
+	if specs.HasUpdates && specs.IsDelta && s.transport.options.EnableConfigSpecDeltas {
+		merged, ok := s.mergeConfigSpecsDelta(specs)
+		if !ok {
+			addDiagnostics().downloadConfigSpecs().process().start().mark()
+			addDiagnostics().downloadConfigSpecs().process().end().success(false).mark()
+			s.mu.Lock()
+			s.forceFullSync = true
+			s.mu.Unlock()
+			// The specs drifted from the server's, so replace them all, unless this was already a full sync.
+			// Nothing was applied, so the store is not marked as synced.
+			if input.AcceptsDeltas {
+				s.fetchConfigSpecsFromServerWithDiagnostics(ctx, isColdStart, addDiagnostics)
+			}
+			return
+		}
+		specs = merged
+	}
+	s.mu.Lock()
+	s.forceFullSync = false
+	s.mu.Unlock()
+	applied, err := s.processConfigSpecs(specs, "network", addDiagnostics().downloadConfigSpecs())
+	if err != nil {
+		// The specs may be a delta merged into specs that drifted from the server's, so retry with all of them
 		s.mu.Lock()
-		s.initReason = reasonNetwork
+		s.forceFullSync = true
 		s.mu.Unlock()
-		if s.rulesUpdatedCallback != nil {
-			v, _ := json.Marshal(specs)
-			s.rulesUpdatedCallback(string(v[:]), specs.Time)
-		}
+		return
+	}
+	s.markSynced()
+	if applied {
+		s.setInitReason(reasonNetwork)
 		if s.dataAdapter != nil {
 			s.saveConfigSpecsToAdapter(specs)
 		}
 	}
 }
 
-func (s *store) processConfigSpecs(configSpecs interface{}, diagnosticsMarker *marker) bool {
+// Applies the specs unless they are unchanged. Specs that cannot be parsed or fail validation are rejected
+// whole, keeping the current rulesets: the returned *InvalidConfigSpecsError is already reported.
+func (s *store) processConfigSpecs(configSpecs interface{}, source string, diagnosticsMarker *marker) (bool, error) {
 	diagnosticsMarker.process().start().mark()
 	specs := downloadConfigSpecResponse{}
 	success := false
+	previousSyncTime := s.getLastSyncTime()
+	var err error
 	switch specsTyped := configSpecs.(type) {
 	case string:
-		err := json.Unmarshal([]byte(specsTyped), &specs)
-		if err == nil {
-			success = s.setConfigSpecs(specs)
+		if err = s.transport.codec.Unmarshal([]byte(specsTyped), &specs); err == nil {
+			internConfigSpecs(&specs)
 		}
 	case downloadConfigSpecResponse:
-		success = s.setConfigSpecs(specsTyped)
+		specs = specsTyped
 	default:
-		success = false
+		err = fmt.Errorf("unsupported type %T", configSpecs)
+	}
+	if err == nil && specs.HasUpdates {
+		err = validateConfigSpecs(specs)
 	}
+	if err != nil {
+		diagnosticsMarker.process().end().success(false).mark()
+		return false, s.rejectConfigSpecs(source, err)
+	}
+	s.mu.Lock()
+	s.rejectedSpecsCount = 0
+	s.mu.Unlock()
+	success = s.setConfigSpecs(specs)
 	diagnosticsMarker.process().end().success(success).mark()
-	return success
+	if success && specs.Time != previousSyncTime {
+		if previousSyncTime != 0 && s.transport.options.SessionIDRotationOptions.OnConfigSync {
+			s.transport.session.rotate()
+		}
+		s.notifyRulesUpdated(configSpecs, specs.Time)
+	}
+	return success, nil
+}
+
+// Reports rejected specs, and backs off polling while they keep being rejected, see getConfigSyncDelay
+func (s *store) rejectConfigSpecs(source string, err error) error {
+	invalid := &InvalidConfigSpecsError{Source: source, Err: err}
+	s.mu.Lock()
+	s.rejectedSpecsCount += 1
+	s.mu.Unlock()
+	global.Logger().LogError(invalid)
+	s.errorBoundary.reportError(invalid, ErrorContextConfigSync)
+	s.errorBoundary.logException(invalid)
+	return invalid
+}
+
+// Hands a newly applied ruleset to the RulesUpdatedCallback, whether it came from
+// the network, bootstrap values or the data adapter
+func (s *store) notifyRulesUpdated(configSpecs interface{}, time int64) {
+	if s.rulesUpdatedCallback == nil {
+		return
+	}
+	defer func() {
+		if err := recover(); err != nil {
+			logRecoveredPanic("RulesUpdatedCallback", err)
+		}
+	}()
+	rules, isString := configSpecs.(string)
+	if !isString {
+		v, _ := s.transport.codec.Marshal(configSpecs)
+		rules = string(v[:])
+	}
+	s.rulesUpdatedCallback(rules, time)
 }
 
 func (s *store) setConfigSpecs(specs downloadConfigSpecResponse) bool {
@@ -336,6 +738,9 @@ func (s *store) setConfigSpecs(specs downloadConfigSpecResponse) bool {
 
 	if specs.HasUpdates {
 		// TODO: when adding eval details, differentiate REASON between bootstrap and network here
+		if targetApp := s.transport.options.TargetApp; targetApp != "" {
+			specs = filterConfigSpecsForTargetApp(specs, targetApp)
+		}
 		newGates := make(map[string]configSpec)
 		for _, gate := range specs.FeatureGates {
 			newGates[gate.Name] = gate
@@ -358,19 +763,61 @@ func (s *store) setConfigSpecs(specs downloadConfigSpecResponse) bool {
 			}
 		}
 
+		subscribed := s.changeListeners.subscribedNames()
 		s.mu.Lock()
-		s.featureGates = newGates
-		s.dynamicConfigs = newConfigs
-		s.layerConfigs = newLayers
-		s.experimentToLayer = newExperimentToLayer
-		s.sdkKeysToAppID = specs.SDKKeysToAppID
-		s.lastSyncTime = specs.Time
+		previous := s.getRulesets()
+		before := takeSpecSnapshot(subscribed, map[string]map[string]configSpec{
+			SpecTypeFeatureGate:   previous.featureGates,
+			SpecTypeDynamicConfig: previous.dynamicConfigs,
+			SpecTypeLayer:         previous.layerConfigs,
+		})
+		s.rulesets.Store(&rulesetSnapshot{
+			featureGates:        newGates,
+			dynamicConfigs:      newConfigs,
+			layerConfigs:        newLayers,
+			experimentToLayer:   newExperimentToLayer,
+			sdkKeysToAppID:      specs.SDKKeysToAppID,
+			cmabConfigs:         specs.CMABConfigs,
+			paramStores:         specs.ParamStores,
+			time:                specs.Time,
+			initialSyncTime:     previous.initialSyncTime,
+			initReason:          previous.initReason,
+			loadingInitialSpecs: previous.loadingInitialSpecs,
+		})
+		s.hashedSDKKeyUsed = specs.HashedSDKKeyUsed
 		s.mu.Unlock()
+		after := takeSpecSnapshot(subscribed, map[string]map[string]configSpec{
+			SpecTypeFeatureGate:   newGates,
+			SpecTypeDynamicConfig: newConfigs,
+			SpecTypeLayer:         newLayers,
+		})
+		for _, change := range diffSpecSnapshots(subscribed, before, after, specs.Time) {
+			s.changeListeners.notify(change)
+		}
 		return true
 	}
 	return false
 }
 
+// Logs an SDKKeyMismatchError if the current specs were downloaded with a different SDK key.
+// Each mismatching key is only reported once.
+func (s *store) checkSDKKeyMatches(source string) {
+	s.mu.Lock()
+	usedHash := s.hashedSDKKeyUsed
+	expectedHash := getDJB2Hash(s.transport.sdkKey)
+	if usedHash == "" || usedHash == expectedHash || usedHash == s.warnedSDKKeyHash {
+		s.mu.Unlock()
+		return
+	}
+	s.warnedSDKKeyHash = usedHash
+	s.mu.Unlock()
+	global.Logger().LogError(&SDKKeyMismatchError{
+		Source:           source,
+		HashedSDKKey:     expectedHash,
+		HashedSDKKeyUsed: usedHash,
+	})
+}
+
 func (s *store) getIDList(name string) *idList {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -393,22 +840,162 @@ func (s *store) setIDList(name string, list *idList) {
 	s.idLists[name] = list
 }
 
+// The shape ID lists are saved in under ID_LISTS_KEY of a DataAdapter
+type adapterIDList struct {
+	Size         int64    `json:"size"`
+	CreationTime int64    `json:"creationTime"`
+	FileID       string   `json:"fileID"`
+	IDs          []string `json:"ids"`
+}
+
 func (s *store) syncIDLists() {
+	s.syncIDListsWithContext(context.Background())
+}
+
+func (s *store) syncIDListsWithContext(ctx context.Context) {
+	if s.dataAdapter != nil && s.dataAdapter.ShouldBeUsedForQueryingUpdates(ID_LISTS_KEY) {
+		s.syncIDListsFromAdapter()
+		return
+	}
+	s.syncIDListsFromServer(ctx)
+	if s.dataAdapter != nil {
+		s.saveIDListsToAdapter()
+	}
+}
+
+func (s *store) syncIDListsFromAdapter() {
+	defer func() {
+		if err := recover(); err != nil {
+			logRecoveredPanic("data adapter get", err)
+			s.errorBoundary.reportError(toError(err), ErrorContextDataAdapter)
+		}
+	}()
+	var listsString string
+	if chain, ok := s.dataAdapter.(*dataAdapterChain); ok {
+		listsString = chain.get(ID_LISTS_KEY, true, nil)
+	} else {
+		listsString = s.dataAdapter.Get(ID_LISTS_KEY)
+	}
+	if listsString == "" {
+		return
+	}
+	var adapterLists map[string]adapterIDList
+	if err := s.transport.codec.Unmarshal([]byte(listsString), &adapterLists); err != nil {
+		s.errorBoundary.logException(err)
+		return
+	}
+	s.setAdapterIDLists(adapterLists)
+}
+
+// Replaces the ID lists with the given ones, keeping those whose file and size did not change
+func (s *store) setAdapterIDLists(adapterLists map[string]adapterIDList) {
+	for name, adapterList := range adapterLists {
+		localList := s.getIDList(name)
+		if localList != nil && localList.FileID == adapterList.FileID && atomic.LoadInt64(&localList.Size) == adapterList.Size {
+			continue
+		}
+		ids := s.newIDListStorage()
+		update := ids.update()
+		for _, id := range adapterList.IDs {
+			update.add([]byte(id))
+		}
+		if err := update.commit(); err != nil {
+			global.Logger().LogError(err)
+			continue
+		}
+		s.setIDList(name, &idList{
+			Name:         name,
+			Size:         adapterList.Size,
+			CreationTime: adapterList.CreationTime,
+			FileID:       adapterList.FileID,
+			ids:          ids,
+		})
+	}
+	s.mu.Lock()
+	for name := range s.idLists {
+		if _, ok := adapterLists[name]; !ok {
+			delete(s.idLists, name)
+		}
+	}
+	s.mu.Unlock()
+	s.markIDListsSynced()
+}
+
+// Saves the ID lists for processes that read them from the adapter. Skipped when nothing changed.
+func (s *store) saveIDListsToAdapter() {
+	defer func() {
+		if err := recover(); err != nil {
+			logRecoveredPanic("data adapter set", err)
+			s.errorBoundary.reportError(toError(err), ErrorContextDataAdapter)
+		}
+	}()
+	listsString, err := s.transport.codec.Marshal(s.getAdapterIDLists())
+	if err != nil {
+		return
+	}
+	hash := getHashBase64StringEncoding(string(listsString))
+	s.mu.Lock()
+	unchanged := hash == s.adapterIDListsHash
+	s.adapterIDListsHash = hash
+	s.mu.Unlock()
+	if !unchanged {
+		s.dataAdapter.Set(ID_LISTS_KEY, string(listsString))
+	}
+}
+
+func (s *store) getAdapterIDLists() map[string]adapterIDList {
+	s.mu.RLock()
+	lists := make([]*idList, 0, len(s.idLists))
+	for _, list := range s.idLists {
+		lists = append(lists, list)
+	}
+	s.mu.RUnlock()
+	adapterLists := make(map[string]adapterIDList, len(lists))
+	for _, list := range lists {
+		ids := make([]string, 0)
+		if list.ids != nil {
+			ids = list.ids.ids()
+		}
+		sort.Strings(ids)
+		adapterLists[list.Name] = adapterIDList{
+			Size:         atomic.LoadInt64(&list.Size),
+			CreationTime: list.CreationTime,
+			FileID:       list.FileID,
+			IDs:          ids,
+		}
+	}
+	return adapterLists
+}
+
+func (s *store) syncIDListsFromServer(ctx context.Context) {
+	if s.transport.options.DisableNetwork {
+		return
+	}
+	_, span := s.transport.tracing.start(ctx, "statsig.id_list_sync")
+	defer span.End()
 	var serverLists map[string]idList
 	s.addDiagnostics().getIdListSources().networkRequest().start().mark()
-	res, err := s.transport.postRequest("/get_id_lists", getIDListsInput{StatsigMetadata: s.transport.metadata}, &serverLists)
+	res, err := s.transport.postRequestWithContext(ctx, "/get_id_lists", getIDListsInput{StatsigMetadata: s.transport.metadata}, &serverLists)
+	if err != nil {
+		span.RecordError(err)
+	}
 	if res == nil || err != nil {
 		marker := s.addDiagnostics().getIdListSources().networkRequest().end().success(false)
 		if res != nil {
 			marker.statusCode(res.StatusCode).sdkRegion(safeGetFirst(res.Header["X-Statsig-Region"]))
 		}
 		marker.mark()
+		if ctx.Err() != nil {
+			return
+		}
 		s.errorBoundary.logException(err)
+		s.errorBoundary.reportError(err, ErrorContextIDListSync)
 		return
 	}
 	s.addDiagnostics().getIdListSources().networkRequest().end().
 		success(true).statusCode(res.StatusCode).sdkRegion(safeGetFirst(res.Header["X-Statsig-Region"])).mark()
 	s.addDiagnostics().getIdListSources().process().start().idListCount(len(serverLists)).mark()
+	span.SetAttribute("statsig.id_list_count", len(serverLists))
 	wg := sync.WaitGroup{}
 	for name, serverList := range serverLists {
 		localList := s.getIDList(name)
@@ -430,7 +1017,7 @@ func (s *store) syncIDLists() {
 				CreationTime: serverList.CreationTime,
 				URL:          serverList.URL,
 				FileID:       serverList.FileID,
-				ids:          &sync.Map{},
+				ids:          s.newIDListStorage(),
 			}
 			s.setIDList(name, localList)
 		}
@@ -444,7 +1031,7 @@ func (s *store) syncIDLists() {
 		go func(name string, l *idList) {
 			defer wg.Done()
 			s.addDiagnostics().getIdList().networkRequest().start().url(l.URL).mark()
-			res, err := s.transport.get(l.URL, map[string]string{"Range": fmt.Sprintf("bytes=%d-", l.Size)})
+			res, err := s.transport.getWithContext(ctx, l.URL, map[string]string{"Range": fmt.Sprintf("bytes=%d-", l.Size)})
 			if err != nil || res == nil {
 				marker := s.addDiagnostics().getIdList().networkRequest().end().url(l.URL).success(false)
 				if res != nil {
@@ -466,32 +1053,23 @@ func (s *store) syncIDLists() {
 				return
 			}
 
-			bodyBytes, err := io.ReadAll(res.Body)
-			if err != nil {
+			body := bufio.NewReader(res.Body)
+			prefix, err := body.Peek(2)
+			if err != nil && err != io.EOF {
 				s.addDiagnostics().getIdList().process().end().url(l.URL).success(false).mark()
 				s.errorBoundary.logException(err)
 				return
 			}
-			content := string(bodyBytes)
-			if len(content) <= 1 || (string(content[0]) != "-" && string(content[0]) != "+") {
+			if len(prefix) <= 1 || (prefix[0] != '-' && prefix[0] != '+') {
 				s.addDiagnostics().getIdList().process().end().url(l.URL).success(false).mark()
 				s.deleteIDList(name)
 				return
 			}
 
-			lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
-			for _, line := range lines {
-				line = strings.TrimSpace(line)
-				if len(line) <= 1 {
-					continue
-				}
-				id := line[1:]
-				op := string(line[0])
-				if op == "+" {
-					l.ids.Store(id, true)
-				} else if op == "-" {
-					l.ids.Delete(id)
-				}
+			if err := applyIDListLines(body, l.ids.update()); err != nil {
+				s.addDiagnostics().getIdList().process().end().url(l.URL).success(false).mark()
+				s.errorBoundary.logException(err)
+				return
 			}
 			atomic.AddInt64((&l.Size), int64(length))
 			s.addDiagnostics().getIdList().process().end().url(l.URL).success(true).mark()
@@ -503,47 +1081,82 @@ func (s *store) syncIDLists() {
 			s.deleteIDList(name)
 		}
 	}
+	s.markIDListsSynced()
 	s.addDiagnostics().getIdListSources().process().end().success(true).idListCount(len(serverLists)).mark()
 }
 
 func (s *store) pollForIDListChanges() {
-	for {
-		time.Sleep(s.idListSyncInterval)
-		stop := func() bool {
-			s.mu.RLock()
-			defer s.mu.RUnlock()
-			return s.shutdown
-		}()
-		if stop {
-			break
-		}
+	for s.waitForNextPoll(s.getIDListSyncInterval()) {
 		s.syncIDLists()
 	}
 }
 
 func (s *store) pollForRulesetChanges() {
-	for {
-		time.Sleep(s.configSyncInterval)
-		stop := func() bool {
-			s.mu.RLock()
-			defer s.mu.RUnlock()
-			return s.shutdown
-		}()
-		if stop {
-			break
-		}
+	for s.waitForNextPoll(s.getConfigSyncDelay()) {
 		if s.dataAdapter != nil && s.dataAdapter.ShouldBeUsedForQueryingUpdates(CONFIG_SPECS_KEY) {
 			s.fetchConfigSpecsFromAdapter()
 		} else {
 			s.fetchConfigSpecsFromServer(false)
 		}
+		s.checkStaleness()
+	}
+}
+
+func (s *store) getConfigSyncInterval() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.configSyncInterval
+}
+
+// The config sync interval stretched by this store's jitter, and doubled for each of the last consecutive
+// syncs whose specs were rejected, up to maxRejectedSpecsBackoff
+func (s *store) getConfigSyncDelay() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	delay := s.configSyncInterval + time.Duration(float64(s.configSyncInterval)*s.configSyncJitter)
+	for i := 0; i < s.rejectedSpecsCount && delay*2 <= maxRejectedSpecsBackoff; i++ {
+		delay *= 2
+	}
+	return delay
+}
+
+func (s *store) getIDListSyncInterval() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.idListSyncInterval
+}
+
+// Takes effect after the current polling interval elapses
+func (s *store) setSyncIntervals(configSyncInterval time.Duration, idListSyncInterval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if configSyncInterval > 0 {
+		s.configSyncInterval = guardConfigSyncInterval(configSyncInterval)
+	}
+	if idListSyncInterval > 0 {
+		s.idListSyncInterval = idListSyncInterval
+	}
+}
+
+// Waits for the interval to elapse. Returns false, as soon as it happens, when the store shuts down.
+func (s *store) waitForNextPoll(interval time.Duration) bool {
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-s.stopped:
+		return false
 	}
 }
 
 func (s *store) stopPolling() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.shutdown = true
+	if !s.shutdown {
+		s.shutdown = true
+		close(s.stopped)
+	}
 }
 
 func (s *store) addDiagnostics() *marker {
@@ -0,0 +1,75 @@
+package statsig
+
+import (
+	"fmt"
+	"sort"
+)
+
+const storeSnapshotVersion = 1
+
+// The rulesets and ID lists of a store, as exported by Client.ExportSnapshot
+type storeSnapshot struct {
+	Version int                        `json:"version"`
+	Specs   downloadConfigSpecResponse `json:"specs"`
+	IDLists map[string]adapterIDList   `json:"id_lists"`
+}
+
+// Rebuilds a download_config_specs response holding the current rulesets, sorted by name
+func (s *store) getConfigSpecsResponse() downloadConfigSpecResponse {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	rulesets := s.getRulesets()
+	layers := make(map[string][]string)
+	for experiment, layer := range rulesets.experimentToLayer {
+		layers[layer] = append(layers[layer], experiment)
+	}
+	for _, experiments := range layers {
+		sort.Strings(experiments)
+	}
+	return downloadConfigSpecResponse{
+		HasUpdates:       true,
+		Time:             rulesets.time,
+		FeatureGates:     mergeSpecs(rulesets.featureGates, nil, nil),
+		DynamicConfigs:   mergeSpecs(rulesets.dynamicConfigs, nil, nil),
+		LayerConfigs:     mergeSpecs(rulesets.layerConfigs, nil, nil),
+		Layers:           layers,
+		SDKKeysToAppID:   rulesets.sdkKeysToAppID,
+		CMABConfigs:      rulesets.cmabConfigs,
+		ParamStores:      rulesets.paramStores,
+		HashedSDKKeyUsed: s.hashedSDKKeyUsed,
+	}
+}
+
+func (s *store) exportSnapshot() ([]byte, error) {
+	if s.getLastSyncTime() == 0 {
+		return nil, fmt.Errorf("no rulesets have been loaded yet")
+	}
+	return s.transport.codec.Marshal(storeSnapshot{
+		Version: storeSnapshotVersion,
+		Specs:   s.getConfigSpecsResponse(),
+		IDLists: s.getAdapterIDLists(),
+	})
+}
+
+// Replaces the rulesets and ID lists with those of the snapshot, even if they are older than the current ones
+func (s *store) restoreSnapshot(data []byte) error {
+	var snapshot storeSnapshot
+	if err := s.transport.codec.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("invalid snapshot: %w", err)
+	}
+	internConfigSpecs(&snapshot.Specs)
+	if snapshot.Version != storeSnapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", snapshot.Version)
+	}
+	applied, err := s.processConfigSpecs(snapshot.Specs, "snapshot", s.addDiagnostics().bootstrap())
+	if err != nil {
+		return err
+	}
+	if !applied {
+		return fmt.Errorf("the snapshot has no rulesets")
+	}
+	s.markSynced()
+	s.setInitReason(reasonBootstrap)
+	s.setAdapterIDLists(snapshot.IDLists)
+	return nil
+}
@@ -0,0 +1,85 @@
+package statsig
+
+import (
+	"os"
+	"testing"
+)
+
+func TestStoreSnapshot(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	newLocalClient := func(bootstrap string) *Client {
+		return NewClientWithOptions("secret-key", &Options{
+			LocalMode:            true,
+			BootstrapValues:      bootstrap,
+			OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+			StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+		})
+	}
+	user := User{UserID: "123", Email: "jane@statsig.com"}
+
+	source := newLocalClient(string(bytes))
+	defer source.Shutdown()
+	ids := &memoryIDListStorage{}
+	update := ids.update()
+	update.add([]byte("abc"))
+	_ = update.commit()
+	source.evaluator.store.setIDList("list_1", &idList{Name: "list_1", Size: 5, FileID: "file_1", ids: ids})
+	snapshot, err := source.ExportSnapshot()
+	if err != nil {
+		t.Fatalf("Failed to export the snapshot: %s", err)
+	}
+
+	t.Run("restores the rulesets and ID lists", func(t *testing.T) {
+		target := newLocalClient("")
+		defer target.Shutdown()
+		if target.CheckGate(user, "always_on_gate") {
+			t.Errorf("Expected no rulesets before restoring")
+		}
+		if err := target.RestoreSnapshot(snapshot); err != nil {
+			t.Fatalf("Failed to restore the snapshot: %s", err)
+		}
+		for _, gate := range []string{"always_on_gate", "on_for_statsig_email", "fractional_gate"} {
+			if target.CheckGate(user, gate) != source.CheckGate(user, gate) {
+				t.Errorf("Expected %s to evaluate as in the source client", gate)
+			}
+		}
+		config := target.GetConfig(user, "test_config")
+		if config.GetNumber("number", 0) != 7 {
+			t.Errorf("Expected the restored config, got %v", config.Value)
+		}
+		layer := target.GetLayer(user, "a_layer")
+		if layer.GetString("experiment_param", "") == "" {
+			t.Errorf("Expected the restored layer to delegate to its experiment")
+		}
+		list := target.evaluator.store.getIDList("list_1")
+		if list == nil || list.FileID != "file_1" {
+			t.Fatalf("Expected the restored ID list, got %+v", list)
+		}
+		if !list.contains("abc") {
+			t.Errorf("Expected the IDs of the restored list")
+		}
+	})
+
+	t.Run("exports what it restores", func(t *testing.T) {
+		target := newLocalClient("")
+		defer target.Shutdown()
+		_ = target.RestoreSnapshot(snapshot)
+		exported, _ := target.ExportSnapshot()
+		if string(exported) != string(snapshot) {
+			t.Errorf("Expected a restored snapshot to export unchanged")
+		}
+	})
+
+	t.Run("rejects invalid snapshots", func(t *testing.T) {
+		target := newLocalClient("")
+		defer target.Shutdown()
+		if _, err := target.ExportSnapshot(); err == nil {
+			t.Errorf("Expected an error exporting before any rulesets are loaded")
+		}
+		for _, invalid := range []string{"not json", `{"version":2}`, `{"version":1,"specs":{}}`} {
+			if err := target.RestoreSnapshot([]byte(invalid)); err == nil {
+				t.Errorf("Expected an error restoring %s", invalid)
+			}
+		}
+	})
+}
@@ -2,8 +2,10 @@ package statsig
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"reflect"
 	"strings"
 	"sync"
@@ -115,7 +117,7 @@ func TestStoreSync(t *testing.T) {
 	n := newTransport("secret-123", opt)
 	d := newDiagnostics()
 	e := newErrorBoundary("client-key", opt, d)
-	s := newStoreInternal(n, time.Second, time.Second, "", nil, e, nil, d)
+	s := newStoreInternal(n, time.Second, time.Second, false, "", nil, e, nil, d, initOptions{})
 
 	if s.getGatesCount() != 1 {
 		t.Errorf("Wrong number of feature gates after initialize")
@@ -128,11 +130,11 @@ func TestStoreSync(t *testing.T) {
 		t.Errorf("Wrong number of id lists after initialize")
 	}
 	if !compareIDLists(s.getIDList("list_1"),
-		&idList{Name: "list_1", Size: 3, URL: testServer.URL + "/list_1", CreationTime: 1, FileID: "file_id_1", ids: idListMapToSyncMap(map[string]bool{"1": true})}) {
+		&idList{Name: "list_1", Size: 3, URL: testServer.URL + "/list_1", CreationTime: 1, FileID: "file_id_1", ids: idListMapToStorage(map[string]bool{"1": true})}) {
 		t.Errorf("list_1 is incorrect after initialize")
 	}
 	if !compareIDLists(s.getIDList("list_2"),
-		&idList{Name: "list_2", Size: 3, URL: testServer.URL + "/list_2", CreationTime: 1, FileID: "file_id_2", ids: idListMapToSyncMap(map[string]bool{"a": true})}) {
+		&idList{Name: "list_2", Size: 3, URL: testServer.URL + "/list_2", CreationTime: 1, FileID: "file_id_2", ids: idListMapToStorage(map[string]bool{"a": true})}) {
 		t.Errorf("list_2 is incorrect after initialize")
 	}
 	if s.getIDList("list_3") != nil {
@@ -151,7 +153,7 @@ func TestStoreSync(t *testing.T) {
 
 	time.Sleep(time.Millisecond * 1100)
 	if !compareIDLists(s.getIDList("list_1"),
-		&idList{Name: "list_1", Size: 9, URL: testServer.URL + "/list_1", CreationTime: 1, FileID: "file_id_1", ids: idListMapToSyncMap(map[string]bool{"2": true})}) {
+		&idList{Name: "list_1", Size: 9, URL: testServer.URL + "/list_1", CreationTime: 1, FileID: "file_id_1", ids: idListMapToStorage(map[string]bool{"2": true})}) {
 		t.Errorf("list_1 is incorrect after 1 second")
 	}
 	if s.getIDList("list_2") != nil {
@@ -173,7 +175,7 @@ func TestStoreSync(t *testing.T) {
 
 	time.Sleep(time.Millisecond * 1100)
 	if !compareIDLists(s.getIDList("list_1"),
-		&idList{Name: "list_1", Size: 3, URL: testServer.URL + "/list_1", CreationTime: 3, FileID: "file_id_1_a", ids: idListMapToSyncMap(map[string]bool{"3": true})}) {
+		&idList{Name: "list_1", Size: 3, URL: testServer.URL + "/list_1", CreationTime: 3, FileID: "file_id_1_a", ids: idListMapToStorage(map[string]bool{"3": true})}) {
 		t.Errorf("list_1 is incorrect after 2 seconds")
 	}
 	if s.getIDList("list_2") != nil {
@@ -195,7 +197,7 @@ func TestStoreSync(t *testing.T) {
 
 	time.Sleep(time.Millisecond * 1100)
 	if !compareIDLists(s.getIDList("list_1"),
-		&idList{Name: "list_1", Size: 3, URL: testServer.URL + "/list_1", CreationTime: 3, FileID: "file_id_1_a", ids: idListMapToSyncMap(map[string]bool{"3": true})}) {
+		&idList{Name: "list_1", Size: 3, URL: testServer.URL + "/list_1", CreationTime: 3, FileID: "file_id_1_a", ids: idListMapToStorage(map[string]bool{"3": true})}) {
 		t.Errorf("list_1 should NOT have changed after 3 seconds because response was pointing to the older url")
 	}
 	if s.getIDList("list_2") != nil {
@@ -223,7 +225,7 @@ func TestStoreSync(t *testing.T) {
 		t.Errorf("list_2 should be nil after 4 seconds")
 	}
 	if !compareIDLists(s.getIDList("list_3"),
-		&idList{Name: "list_3", Size: 3, URL: testServer.URL + "/list_3", CreationTime: 5, FileID: "file_id_3", ids: idListMapToSyncMap(map[string]bool{"0": true})}) {
+		&idList{Name: "list_3", Size: 3, URL: testServer.URL + "/list_3", CreationTime: 5, FileID: "file_id_3", ids: idListMapToStorage(map[string]bool{"0": true})}) {
 		t.Errorf("list_3 should not be nil anymore after 4 seconds")
 	}
 
@@ -239,14 +241,14 @@ func TestStoreSync(t *testing.T) {
 
 	time.Sleep(time.Millisecond * 1100)
 	if !compareIDLists(s.getIDList("list_1"),
-		&idList{Name: "list_1", Size: 18, URL: testServer.URL + "/list_1", CreationTime: 3, FileID: "file_id_1_a", ids: idListMapToSyncMap(map[string]bool{"3": true, "5": true, "6": true})}) {
+		&idList{Name: "list_1", Size: 18, URL: testServer.URL + "/list_1", CreationTime: 3, FileID: "file_id_1_a", ids: idListMapToStorage(map[string]bool{"3": true, "5": true, "6": true})}) {
 		t.Errorf("list_1 is incorrect after 5 seconds")
 	}
 	if s.getIDList("list_2") != nil {
 		t.Errorf("list_2 should be nil after 5 seconds")
 	}
 	if !compareIDLists(s.getIDList("list_3"),
-		&idList{Name: "list_3", Size: 3, URL: testServer.URL + "/list_3", CreationTime: 5, FileID: "file_id_3", ids: idListMapToSyncMap(map[string]bool{"0": true})}) {
+		&idList{Name: "list_3", Size: 3, URL: testServer.URL + "/list_3", CreationTime: 5, FileID: "file_id_3", ids: idListMapToStorage(map[string]bool{"0": true})}) {
 		t.Errorf("list_3 is incorrect after 5 seconds")
 	}
 
@@ -271,21 +273,24 @@ func compareIDLists(l1 *idList, l2 *idList) bool {
 	return reflect.DeepEqual(ids1, ids2)
 }
 
-func unsyncIDList(m *sync.Map) map[string]bool {
+func unsyncIDList(m idListStorage) map[string]bool {
 	mm := make(map[string]bool)
-	m.Range(func(k, v interface{}) bool {
-		mm[toString(k)] = true
-		return true
-	})
+	for _, id := range m.ids() {
+		mm[id] = true
+	}
 	return mm
 }
 
-func idListMapToSyncMap(m map[string]bool) *sync.Map {
-	mm := sync.Map{}
-	for k := range m {
-		mm.Store(k, true)
+func idListMapToStorage(m map[string]bool) idListStorage {
+	storage := &memoryIDListStorage{}
+	update := storage.update()
+	for id, added := range m {
+		if added {
+			update.add([]byte(id))
+		}
 	}
-	return &mm
+	_ = update.commit()
+	return storage
 }
 
 func getCounter(val *int32) int32 {
@@ -297,13 +302,233 @@ func incrementCounter(val *int32) {
 }
 
 func (s *store) getGatesCount() int {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	return len(s.featureGates)
+	return len(s.getRulesets().featureGates)
 }
 
 func (s *store) getConfigsCount() int {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	return len(s.dynamicConfigs)
+	return len(s.getRulesets().dynamicConfigs)
+}
+
+func TestSDKKeyMismatch(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	var specs map[string]interface{}
+	_ = json.Unmarshal(bytes, &specs)
+
+	initWithSpecsFromKey := func(key string) []error {
+		var mu sync.Mutex
+		errs := make([]error, 0)
+		specs["hashed_sdk_key_used"] = getDJB2Hash(key)
+		bootstrap, _ := json.Marshal(specs)
+		InitializeWithOptions("secret-key", &Options{
+			BootstrapValues: string(bootstrap),
+			LocalMode:       true,
+			OutputLoggerOptions: OutputLoggerOptions{
+				LogCallback: func(message string, err error) {
+					mu.Lock()
+					defer mu.Unlock()
+					if err != nil {
+						errs = append(errs, err)
+					}
+				},
+			},
+			StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+		})
+		ShutdownAndDangerouslyClearInstance()
+		mu.Lock()
+		defer mu.Unlock()
+		return errs
+	}
+
+	if errs := initWithSpecsFromKey("secret-key"); len(errs) != 0 {
+		t.Errorf("Expected no errors for specs downloaded with the same key, got %v", errs)
+	}
+
+	errs := initWithSpecsFromKey("secret-other-project")
+	var mismatch *SDKKeyMismatchError
+	if len(errs) != 1 || !errors.As(errs[0], &mismatch) {
+		t.Fatalf("Expected an SDKKeyMismatchError, got %v", errs)
+	}
+	if mismatch.Source != "BootstrapValues" || mismatch.HashedSDKKeyUsed != getDJB2Hash("secret-other-project") {
+		t.Errorf("Unexpected mismatch details %+v", mismatch)
+	}
+}
+
+func TestConfigSyncIntervalGuardAndJitter(t *testing.T) {
+	minConfigSyncInterval, maxConfigSyncJitter = time.Second, 0.1
+	defer func() {
+		minConfigSyncInterval, maxConfigSyncJitter = 0, 0
+	}()
+	InitializeGlobalOutputLogger(getOutputLoggerOptionsForTest(t))
+	c := NewClientWithOptions("secret-key", &Options{
+		LocalMode:          true,
+		ConfigSyncInterval: 10 * time.Millisecond,
+	})
+	defer c.Shutdown()
+	s := c.evaluator.store
+	if s.getConfigSyncInterval() != time.Second {
+		t.Errorf("Expected the interval to be raised to the minimum, got %s", s.getConfigSyncInterval())
+	}
+	c.UpdateOptions(RuntimeOptions{ConfigSyncInterval: 20 * time.Second})
+	if s.getConfigSyncInterval() != 20*time.Second {
+		t.Errorf("Expected the interval to be updated, got %s", s.getConfigSyncInterval())
+	}
+	c.UpdateOptions(RuntimeOptions{ConfigSyncInterval: time.Millisecond})
+	if s.getConfigSyncInterval() != time.Second {
+		t.Errorf("Expected updates to be raised to the minimum too, got %s", s.getConfigSyncInterval())
+	}
+	delay := s.getConfigSyncDelay()
+	if delay < time.Second || delay > 1100*time.Millisecond {
+		t.Errorf("Expected at most 10%% jitter, got %s", delay)
+	}
+	if delay != s.getConfigSyncDelay() {
+		t.Errorf("Expected the jitter to be fixed per instance")
+	}
+}
+
+func TestDisableIDLists(t *testing.T) {
+	var idListsRequested int32
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "get_id_lists") {
+			atomic.AddInt32(&idListsRequested, 1)
+		}
+		res.WriteHeader(http.StatusOK)
+		_, _ = res.Write([]byte("{}"))
+	}))
+	defer testServer.Close()
+
+	options := &Options{
+		API:                  testServer.URL,
+		IDListSyncInterval:   10 * time.Millisecond,
+		DisableIDLists:       true,
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	}
+	InitializeWithOptions("secret-key", options)
+	time.Sleep(100 * time.Millisecond)
+	ShutdownAndDangerouslyClearInstance()
+	if requests := atomic.LoadInt32(&idListsRequested); requests != 0 {
+		t.Errorf("Expected no get_id_lists requests, got %d", requests)
+	}
+}
+
+func TestRulesetSnapshotSwap(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	var specs downloadConfigSpecResponse
+	_ = json.Unmarshal(bytes, &specs)
+	InitializeGlobalOutputLogger(getOutputLoggerOptionsForTest(t))
+	c := NewClientWithOptions("secret-key", &Options{
+		LocalMode:            true,
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+	s := c.evaluator.store
+	s.setConfigSpecs(specs)
+	held := s.getRulesets()
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if _, exists := s.getGate("always_on_gate"); !exists {
+					t.Errorf("Expected readers to always see a complete ruleset")
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		specs.Time++
+		s.setConfigSpecs(specs)
+	}
+	close(stop)
+	wg.Wait()
+
+	if held.time == s.getRulesets().time {
+		t.Errorf("Expected syncs to swap in a new snapshot")
+	}
+	if _, exists := held.featureGates["always_on_gate"]; !exists || len(held.featureGates) != len(specs.FeatureGates) {
+		t.Errorf("Expected a held snapshot not to be modified by later syncs")
+	}
+}
+
+func TestTargetApp(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	var specs downloadConfigSpecResponse
+	_ = json.Unmarshal(bytes, &specs)
+	for i, gate := range specs.FeatureGates {
+		if gate.Name == "always_on_gate" {
+			specs.FeatureGates[i].TargetAppIDs = []string{"app_1"}
+		}
+	}
+	specs.SDKKeysToAppID = map[string]string{"secret-key": "app_2"}
+	response, _ := json.Marshal(specs)
+
+	var mu sync.Mutex
+	var targetAppIDs []string
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "download_config_specs") {
+			var input downloadConfigsInput
+			_ = json.NewDecoder(req.Body).Decode(&input)
+			mu.Lock()
+			targetAppIDs = append(targetAppIDs, input.TargetAppID)
+			mu.Unlock()
+			res.WriteHeader(http.StatusOK)
+			_, _ = res.Write(response)
+			return
+		}
+		res.WriteHeader(http.StatusOK)
+		_, _ = res.Write([]byte("{}"))
+	}))
+	defer testServer.Close()
+	newTestClient := func(targetApp string) *Client {
+		return NewClientWithOptions("secret-key", &Options{
+			API:                  testServer.URL,
+			TargetApp:            targetApp,
+			DisableIDLists:       true,
+			OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+			StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+		})
+	}
+
+	t.Run("filters entities for the target app", func(t *testing.T) {
+		c := newTestClient("app_1")
+		defer c.Shutdown()
+		if _, exists := c.evaluator.store.getGate("always_on_gate"); !exists {
+			t.Errorf("Expected gates targeting the app to be kept")
+		}
+		if _, exists := c.evaluator.store.getGate("on_for_statsig_email"); exists {
+			t.Errorf("Expected gates not targeting the app to be dropped")
+		}
+		c.evaluator.store.fetchConfigSpecsFromServer(false)
+		mu.Lock()
+		defer mu.Unlock()
+		if targetAppIDs[0] != "app_1" || targetAppIDs[1] != "app_1" {
+			t.Errorf("Expected the target app to be sent, got %v", targetAppIDs)
+		}
+	})
+
+	t.Run("sends no target app without a TargetApp", func(t *testing.T) {
+		mu.Lock()
+		targetAppIDs = nil
+		mu.Unlock()
+		c := newTestClient("")
+		defer c.Shutdown()
+		if _, exists := c.evaluator.store.getGate("on_for_statsig_email"); !exists {
+			t.Errorf("Expected entities to be kept without a TargetApp")
+		}
+		c.evaluator.store.fetchConfigSpecsFromServer(false)
+		mu.Lock()
+		defer mu.Unlock()
+		if targetAppIDs[0] != "" || targetAppIDs[1] != "" {
+			t.Errorf("Expected no target app to be sent, got %v", targetAppIDs)
+		}
+	})
 }
@@ -0,0 +1,97 @@
+package statsig
+
+import "sync"
+
+// Shares the memory of user attribute values that repeat across many users, such as country codes,
+// app versions and user agents. High cardinality fields like UserID, Email and IpAddress are never interned.
+type UserInterningOptions struct {
+	// Maximum number of distinct strings kept in the table. Once full, new values are no longer interned.
+	// Zero disables interning.
+	MaxEntries int
+	// Keys of User.Custom whose string values are interned as well
+	CustomFields []string
+}
+
+type stringInterner struct {
+	strings      map[string]string
+	maxEntries   int
+	customFields []string
+	mu           sync.RWMutex
+}
+
+func newStringInterner(options UserInterningOptions) *stringInterner {
+	if options.MaxEntries <= 0 {
+		return nil
+	}
+	return &stringInterner{
+		strings:      make(map[string]string),
+		maxEntries:   options.MaxEntries,
+		customFields: options.CustomFields,
+	}
+}
+
+func (i *stringInterner) intern(s string) string {
+	if s == "" {
+		return s
+	}
+	i.mu.RLock()
+	interned, exists := i.strings[s]
+	i.mu.RUnlock()
+	if exists {
+		return interned
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if interned, exists := i.strings[s]; exists {
+		return interned
+	}
+	if len(i.strings) < i.maxEntries {
+		i.strings[s] = s
+	}
+	return s
+}
+
+// Empties the table, for memory pressure. Values already interned keep sharing their memory.
+func (i *stringInterner) clear() {
+	if i == nil {
+		return
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.strings = make(map[string]string)
+}
+
+// Expects a normalized user, whose StatsigEnvironment map is owned by the SDK
+func (i *stringInterner) internUser(user User) User {
+	if i == nil {
+		return user
+	}
+	user.Country = i.intern(user.Country)
+	user.Locale = i.intern(user.Locale)
+	user.AppVersion = i.intern(user.AppVersion)
+	user.UserAgent = i.intern(user.UserAgent)
+	for k, v := range user.StatsigEnvironment {
+		user.StatsigEnvironment[k] = i.intern(v)
+	}
+	if len(i.customFields) > 0 && len(user.Custom) > 0 {
+		var custom map[string]interface{}
+		for _, field := range i.customFields {
+			value, isString := user.Custom[field].(string)
+			if !isString {
+				continue
+			}
+			if custom == nil {
+				// Copy so the caller's map is not modified
+				custom = make(map[string]interface{}, len(user.Custom))
+				for k, v := range user.Custom {
+					custom[k] = v
+				}
+			}
+			custom[field] = i.intern(value)
+		}
+		if custom != nil {
+			user.Custom = custom
+		}
+	}
+	return user
+}
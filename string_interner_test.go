@@ -0,0 +1,58 @@
+package statsig
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func stringData(s string) uintptr {
+	return (*reflect.StringHeader)(unsafe.Pointer(&s)).Data
+}
+
+func TestStringInterner(t *testing.T) {
+	if newStringInterner(UserInterningOptions{}) != nil {
+		t.Errorf("Expected interning to be disabled by default")
+	}
+	interner := newStringInterner(UserInterningOptions{MaxEntries: 3, CustomFields: []string{"plan"}})
+
+	// Build equal strings with distinct backing arrays
+	newUser := func() User {
+		custom := map[string]interface{}{"plan": strings.Repeat("pro", 1), "count": 3}
+		return User{
+			UserID:             strings.Repeat("1", 3),
+			Country:            strings.Repeat("U", 1) + "S",
+			AppVersion:         strings.Repeat("1.0", 1) + ".0",
+			Custom:             custom,
+			StatsigEnvironment: map[string]string{"tier": strings.Repeat("prod", 1)},
+		}
+	}
+	first := newUser()
+	firstCustom := first.Custom
+	first = interner.internUser(first)
+	second := interner.internUser(newUser())
+
+	if stringData(first.Country) != stringData(second.Country) {
+		t.Errorf("Expected Country to be interned")
+	}
+	if stringData(first.AppVersion) != stringData(second.AppVersion) {
+		t.Errorf("Expected AppVersion to be interned")
+	}
+	if stringData(first.Custom["plan"].(string)) != stringData(second.Custom["plan"].(string)) {
+		t.Errorf("Expected listed custom fields to be interned")
+	}
+	if stringData(first.UserID) == stringData(second.UserID) {
+		t.Errorf("Expected UserID not to be interned")
+	}
+	if reflect.ValueOf(first.Custom).Pointer() == reflect.ValueOf(firstCustom).Pointer() {
+		t.Errorf("Expected the caller's custom map to be copied")
+	}
+	// The table is full, so further values are passed through
+	if len(interner.strings) != 3 {
+		t.Errorf("Expected the table to stop growing at MaxEntries, got %d", len(interner.strings))
+	}
+	if second.StatsigEnvironment["tier"] != "prod" || second.Custom["count"] != 3 {
+		t.Errorf("Expected values to be preserved")
+	}
+}
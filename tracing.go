@@ -0,0 +1,83 @@
+package statsig
+
+import (
+	"context"
+	"math/rand"
+)
+
+const tracerName = "github.com/statsig-io/go-sdk"
+
+// A span in a distributed trace. The method set mirrors OpenTelemetry's trace.Span, so adapting an
+// OpenTelemetry span only needs the attribute value converted with attribute.KeyValue.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// Starts spans as children of the span in ctx, as OpenTelemetry's trace.Tracer does
+type Tracer interface {
+	Start(ctx context.Context, spanName string) (context.Context, Span)
+}
+
+// Supplies the Tracer the SDK uses. Wrap an OpenTelemetry TracerProvider to report SDK latency
+// without the SDK depending on OpenTelemetry. Spans are children of the span in the context passed to
+// InitializeWithContextAndOptions, FlushWithContext, ShutdownWithContext and the evaluation methods ending in
+// WithContext, such as CheckGateWithContext. Other evaluations and background syncs start root spans.
+type TracerProvider interface {
+	Tracer(instrumentationName string) Tracer
+}
+
+// Emits spans for initialize, config syncs, ID list syncs, event flushes and, optionally, evaluations
+type TracingOptions struct {
+	TracerProvider TracerProvider
+	// Fraction (0 to 1) of gate, config and layer evaluations to trace. Zero traces none.
+	EvaluationSamplingRate float64
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+func (noopSpan) RecordError(err error)                      {}
+func (noopSpan) End()                                       {}
+
+// A nil *tracing starts no-op spans, so call sites do not need to check whether tracing is enabled
+type tracing struct {
+	tracer                 Tracer
+	evaluationSamplingRate float64
+}
+
+func newTracing(options TracingOptions) *tracing {
+	if options.TracerProvider == nil {
+		return nil
+	}
+	tracer := options.TracerProvider.Tracer(tracerName)
+	if tracer == nil {
+		return nil
+	}
+	return &tracing{tracer: tracer, evaluationSamplingRate: options.EvaluationSamplingRate}
+}
+
+func (t *tracing) start(ctx context.Context, spanName string) (context.Context, Span) {
+	if t == nil {
+		return ctx, noopSpan{}
+	}
+	ctx, span := t.tracer.Start(ctx, spanName)
+	if span == nil {
+		return ctx, noopSpan{}
+	}
+	return ctx, span
+}
+
+// Starts a span for a sampled fraction of evaluations, as a child of the span in ctx when set
+func (t *tracing) startEvaluation(ctx context.Context, spanName string, name string) Span {
+	if t == nil || t.evaluationSamplingRate <= 0 || rand.Float64() >= t.evaluationSamplingRate {
+		return noopSpan{}
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	_, span := t.start(ctx, spanName)
+	span.SetAttribute("statsig.name", name)
+	return span
+}
@@ -0,0 +1,149 @@
+package statsig
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type testSpan struct {
+	name       string
+	attributes map[string]interface{}
+	errors     []error
+	ended      bool
+	parent     *testSpan
+	tracer     *testTracer
+}
+
+type testSpanKey struct{}
+
+func (s *testSpan) SetAttribute(key string, value interface{}) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.attributes[key] = value
+}
+
+func (s *testSpan) RecordError(err error) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.errors = append(s.errors, err)
+}
+
+func (s *testSpan) End() {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.ended = true
+}
+
+type testTracer struct {
+	spans []*testSpan
+	mu    sync.Mutex
+}
+
+func (t *testTracer) Tracer(instrumentationName string) Tracer {
+	return t
+}
+
+func (t *testTracer) Start(ctx context.Context, spanName string) (context.Context, Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span := &testSpan{name: spanName, attributes: make(map[string]interface{}), tracer: t}
+	span.parent, _ = ctx.Value(testSpanKey{}).(*testSpan)
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, testSpanKey{}, span), span
+}
+
+func (t *testTracer) ended(name string) []testSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	spans := make([]testSpan, 0)
+	for _, span := range t.spans {
+		if span.name == name && span.ended {
+			spans = append(spans, *span)
+		}
+	}
+	return spans
+}
+
+func TestTracing(t *testing.T) {
+	dcs, _ := os.ReadFile("download_config_specs.json")
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		switch {
+		case strings.Contains(req.URL.Path, "download_config_specs"):
+			res.WriteHeader(http.StatusOK)
+			_, _ = res.Write(dcs)
+		case strings.Contains(req.URL.Path, "log_event"):
+			res.WriteHeader(http.StatusBadRequest)
+		default:
+			res.WriteHeader(http.StatusOK)
+			_, _ = res.Write([]byte("{}"))
+		}
+	}))
+	defer testServer.Close()
+
+	tracer := &testTracer{}
+	InitializeWithOptions("secret-key", &Options{
+		API:                  testServer.URL,
+		TracingOptions:       TracingOptions{TracerProvider: tracer, EvaluationSamplingRate: 1},
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer ShutdownAndDangerouslyClearInstance()
+
+	if spans := tracer.ended("statsig.initialize"); len(spans) != 1 || spans[0].attributes["statsig.lcut"] != int64(configSyncTime) {
+		t.Errorf("Expected an initialize span with the lcut, got %+v", spans)
+	}
+	if spans := tracer.ended("statsig.config_sync"); len(spans) != 1 || spans[0].attributes["statsig.lcut"] != int64(configSyncTime) {
+		t.Errorf("Expected a config sync span, got %+v", spans)
+	}
+	if spans := tracer.ended("statsig.id_list_sync"); len(spans) != 1 {
+		t.Errorf("Expected an ID list sync span, got %+v", spans)
+	}
+
+	user := User{UserID: "123"}
+	CheckGate(user, "always_on_gate")
+	GetConfig(user, "test_config")
+	GetLayer(user, "a_layer")
+	gateSpans := tracer.ended("statsig.check_gate")
+	if len(gateSpans) != 1 || gateSpans[0].attributes["statsig.name"] != "always_on_gate" || gateSpans[0].attributes["statsig.value"] != true {
+		t.Errorf("Expected a check gate span, got %+v", gateSpans)
+	}
+	if len(tracer.ended("statsig.get_config")) != 1 || len(tracer.ended("statsig.get_layer")) != 1 {
+		t.Errorf("Expected config and layer evaluation spans")
+	}
+	if gateSpans[0].parent != nil {
+		t.Errorf("Expected evaluations without a context to start root spans")
+	}
+
+	_ = FlushWithContext(context.Background())
+	flushSpans := tracer.ended("statsig.log_event")
+	if len(flushSpans) != 1 || flushSpans[0].attributes["statsig.event_count"] != 2 || len(flushSpans[0].errors) != 1 {
+		t.Errorf("Expected a failed log_event span, got %+v", flushSpans)
+	}
+
+	ctx, request := tracer.Start(context.Background(), "request")
+	CheckGateWithContext(ctx, user, "always_on_gate")
+	GetExperimentWithContext(ctx, user, "sample_experiment")
+	GetLayerWithContext(ctx, user, "a_layer")
+	for _, name := range []string{"statsig.check_gate", "statsig.get_config", "statsig.get_layer"} {
+		if spans := tracer.ended(name); len(spans) != 2 || spans[1].parent != request {
+			t.Errorf("Expected the %s span to be a child of the span in the context, got %+v", name, spans)
+		}
+	}
+}
+
+func TestTracingEvaluationSampling(t *testing.T) {
+	tracer := &testTracer{}
+	sampled := newTracing(TracingOptions{TracerProvider: tracer})
+	sampled.startEvaluation(context.Background(), "statsig.check_gate", "a_gate").End()
+	if len(tracer.spans) != 0 {
+		t.Errorf("Expected evaluations not to be traced by default")
+	}
+	var disabled *tracing
+	_, span := disabled.start(context.Background(), "statsig.initialize")
+	span.End()
+}
@@ -2,32 +2,42 @@ package statsig
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
-
-	"github.com/google/uuid"
 )
 
 const (
 	maxRetries        = 5
 	backoffMultiplier = 10
+	// The most events log_event accepts in one request
+	maxEventBatchSize = 500
+	// Each batch of LogImmediate is retried on its own, so a failed batch does not resend the others
+	maxEventBatchRetries = 2
 )
 
-type transport struct {
-	api       string
-	sdkKey    string
-	metadata  statsigMetadata // Safe to read from but not thread safe to write into. If value needs to change, please ensure thread safety.
-	client    *http.Client
-	options   *Options
-	sessionID string
-}
+var eventBatchRetryBackoff = time.Second
 
-func getSessionID() string {
-	return uuid.NewString()
+var errNetworkDisabled = errors.New("network requests are disabled with Options.DisableNetwork")
+
+type transport struct {
+	api string
+	// Base URL of each endpoint that does not use api
+	apiOverrides map[string]string
+	sdkKey       string
+	metadata     statsigMetadata // Safe to read from but not thread safe to write into. If value needs to change, please ensure thread safety.
+	client       *http.Client
+	options      *Options
+	session      *serverSession
+	metrics      *metrics
+	tracing      *tracing
+	codec        JSONCodec
 }
 
 func newTransport(secret string, options *Options) *transport {
@@ -38,15 +48,28 @@ func newTransport(secret string, options *Options) *transport {
 			global.Logger().LogError(err)
 		}
 	}()
-	sid := getSessionID()
+	apiOverrides := make(map[string]string)
+	for endpoint, override := range map[string]string{
+		"/download_config_specs": options.APIOverrides.DownloadConfigSpecs,
+		"/log_event":             options.APIOverrides.LogEvent,
+		"/get_id_lists":          options.APIOverrides.IDLists,
+	} {
+		if override != "" {
+			apiOverrides[endpoint] = strings.TrimSuffix(override, "/")
+		}
+	}
 
 	return &transport{
-		api:       api,
-		metadata:  getStatsigMetadata(),
-		sdkKey:    secret,
-		client:    &http.Client{Timeout: time.Second * 3},
-		options:   options,
-		sessionID: sid,
+		api:          api,
+		apiOverrides: apiOverrides,
+		metadata:     getStatsigMetadata(),
+		sdkKey:       secret,
+		client:       newHTTPClient(options),
+		options:      options,
+		session:      newServerSession(options.SessionIDRotationOptions),
+		metrics:      newMetrics(options.MetricsOptions, options.ObservabilityClient),
+		tracing:      newTracing(options.TracingOptions),
+		codec:        getJSONCodec(options),
 	}
 }
 
@@ -55,7 +78,17 @@ func (transport *transport) postRequest(
 	in interface{},
 	out interface{},
 ) (*http.Response, error) {
-	return transport.postRequestInternal(endpoint, in, out, 0, 0)
+	return transport.postRequestInternal(context.Background(), endpoint, in, out, 0, 0)
+}
+
+// Same as postRequest, but aborts the request once ctx is done
+func (transport *transport) postRequestWithContext(
+	ctx context.Context,
+	endpoint string,
+	in interface{},
+	out interface{},
+) (*http.Response, error) {
+	return transport.postRequestInternal(ctx, endpoint, in, out, 0, 0)
 }
 
 func (transport *transport) retryablePostRequest(
@@ -64,41 +97,88 @@ func (transport *transport) retryablePostRequest(
 	out interface{},
 	retries int,
 ) (*http.Response, error) {
-	return transport.postRequestInternal(endpoint, in, out, retries, time.Second)
+	return transport.postRequestInternal(context.Background(), endpoint, in, out, retries, time.Second)
+}
+
+// Same as retryablePostRequest, but gives up on the request and any remaining retries once ctx is done
+func (transport *transport) retryablePostRequestWithContext(
+	ctx context.Context,
+	endpoint string,
+	in interface{},
+	out interface{},
+	retries int,
+) (*http.Response, error) {
+	return transport.postRequestInternal(ctx, endpoint, in, out, retries, time.Second)
 }
 
 func (transport *transport) postRequestInternal(
+	ctx context.Context,
 	endpoint string,
 	in interface{},
 	out interface{},
 	retries int,
 	backoff time.Duration,
 ) (*http.Response, error) {
-	if transport.options.LocalMode {
+	if transport.options.LocalMode || transport.options.DisableNetwork {
 		return nil, nil
 	}
-	body, err := json.Marshal(in)
+	body, err := transport.codec.Marshal(in)
 	if err != nil {
 		return nil, err
 	}
 
-	return retry(retries, time.Duration(backoff), func() (*http.Response, bool, error) {
-		response, err := transport.doRequest(endpoint, body)
+	start := time.Now()
+	defer func() {
+		transport.metrics.observeDuration(metricRequestDuration, endpoint, start)
+		if err != nil {
+			transport.metrics.increment(metricRequestFailures, endpoint, 1)
+		}
+	}()
+	var res *http.Response
+	res, err = retry(ctx, retries, time.Duration(backoff), func() (*http.Response, bool, error) {
+		response, err := transport.doRequestWithContext(ctx, endpoint, body)
 		if err != nil {
 			return response, response != nil, err
 		}
 		defer response.Body.Close()
 
 		if response.StatusCode >= 200 && response.StatusCode < 300 {
-			return response, false, json.NewDecoder(response.Body).Decode(&out)
+			data, err := io.ReadAll(response.Body)
+			if err != nil {
+				return response, false, err
+			}
+			// Callers that check the raw body before decoding it pass a *[]byte
+			if raw, ok := out.(*[]byte); ok {
+				*raw = data
+				return response, false, nil
+			}
+			return response, false, transport.codec.Unmarshal(data, decodeTarget(out))
 		}
 
 		return response, shouldRetry(response.StatusCode), fmt.Errorf("http response error code: %d", response.StatusCode)
 	})
+	return res, err
+}
+
+// Codecs other than encoding/json do not all decode into a pointer held by an interface,
+// so out is passed as is when it is already a pointer
+func decodeTarget(out interface{}) interface{} {
+	if out != nil && reflect.ValueOf(out).Kind() == reflect.Ptr {
+		return out
+	}
+	return &out
 }
 
 func (transport *transport) doRequest(endpoint string, body []byte) (*http.Response, error) {
-	req, err := http.NewRequest("POST", transport.api+endpoint, bytes.NewBuffer(body))
+	return transport.doRequestWithContext(context.Background(), endpoint, body)
+}
+
+func (transport *transport) doRequestWithContext(ctx context.Context, endpoint string, body []byte) (*http.Response, error) {
+	api, overridden := transport.apiOverrides[endpoint]
+	if !overridden {
+		api = transport.api
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", api+endpoint, bytes.NewBuffer(body))
 	if err != nil {
 		return nil, err
 	}
@@ -106,7 +186,7 @@ func (transport *transport) doRequest(endpoint string, body []byte) (*http.Respo
 	req.Header.Add("STATSIG-API-KEY", transport.sdkKey)
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Add("STATSIG-CLIENT-TIME", strconv.FormatInt(getUnixMilli(), 10))
-	req.Header.Add("STATSIG-SERVER-SESSION-ID", transport.sessionID)
+	req.Header.Add("STATSIG-SERVER-SESSION-ID", transport.session.getID())
 	req.Header.Add("STATSIG-SDK-TYPE", transport.metadata.SDKType)
 	req.Header.Add("STATSIG-SDK-VERSION", transport.metadata.SDKVersion)
 
@@ -114,7 +194,14 @@ func (transport *transport) doRequest(endpoint string, body []byte) (*http.Respo
 }
 
 func (transport *transport) get(url string, headers map[string]string) (*http.Response, error) {
-	req, err := http.NewRequest("GET", url, nil)
+	return transport.getWithContext(context.Background(), url, headers)
+}
+
+func (transport *transport) getWithContext(ctx context.Context, url string, headers map[string]string) (*http.Response, error) {
+	if transport.options.DisableNetwork {
+		return nil, errNetworkDisabled
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -126,7 +213,7 @@ func (transport *transport) get(url string, headers map[string]string) (*http.Re
 	return transport.client.Do(req)
 }
 
-func retry(retries int, backoff time.Duration, fn func() (*http.Response, bool, error)) (*http.Response, error) {
+func retry(ctx context.Context, retries int, backoff time.Duration, fn func() (*http.Response, bool, error)) (*http.Response, error) {
 	for {
 		if response, retry, err := fn(); retry {
 			if retries <= 0 {
@@ -134,7 +221,13 @@ func retry(retries int, backoff time.Duration, fn func() (*http.Response, bool,
 			}
 
 			retries--
-			time.Sleep(backoff)
+			timer := time.NewTimer(backoff)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return response, ctx.Err()
+			}
 			backoff = backoff * backoffMultiplier
 		} else {
 			return response, err
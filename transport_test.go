@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"sync"
 	"testing"
 )
 
@@ -85,3 +87,46 @@ func TestRetries(t *testing.T) {
 		t.Errorf("Expected successful request but got error")
 	}
 }
+
+func TestAPIOverrides(t *testing.T) {
+	newServer := func(hits *[]string) *httptest.Server {
+		var mu sync.Mutex
+		return httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			mu.Lock()
+			*hits = append(*hits, req.URL.Path)
+			mu.Unlock()
+			res.WriteHeader(http.StatusOK)
+			_, _ = res.Write([]byte("{}"))
+		}))
+	}
+	var apiHits, dcsHits, logHits []string
+	api := newServer(&apiHits)
+	defer api.Close()
+	dcs := newServer(&dcsHits)
+	defer dcs.Close()
+	logEvent := newServer(&logHits)
+	defer logEvent.Close()
+
+	n := newTransport("secret-123", &Options{
+		API: api.URL,
+		APIOverrides: APIOverrides{
+			DownloadConfigSpecs: dcs.URL + "/v1/",
+			LogEvent:            logEvent.URL,
+		},
+	})
+	var out map[string]interface{}
+	for _, endpoint := range []string{"/download_config_specs", "/log_event", "/get_id_lists"} {
+		if _, err := n.postRequest(endpoint, Empty{}, &out); err != nil {
+			t.Errorf("Expected %s to succeed, got %v", endpoint, err)
+		}
+	}
+	if !reflect.DeepEqual(dcsHits, []string{"/v1/download_config_specs"}) {
+		t.Errorf("Expected download_config_specs to use its override, got %v", dcsHits)
+	}
+	if !reflect.DeepEqual(logHits, []string{"/log_event"}) {
+		t.Errorf("Expected log_event to use its override, got %v", logHits)
+	}
+	if !reflect.DeepEqual(apiHits, []string{"/get_id_lists"}) {
+		t.Errorf("Expected endpoints without an override to use the API, got %v", apiHits)
+	}
+}
@@ -0,0 +1,69 @@
+package statsig
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Implemented by DynamicConfig and Layer, so the generic getters below accept either
+type TypedConfig interface {
+	getConfigBase() configBase
+}
+
+func (d configBase) getConfigBase() configBase {
+	return d
+}
+
+// Gets the value at the given key converted to T, decoding from the config JSON when the stored
+// value is not already a T (numbers into int types, objects into structs, and so on).
+// Returns the fallback if the key is not found, is null, or cannot be decoded into T.
+func GetTyped[T any](config TypedConfig, key string, fallback T) T {
+	base := config.getConfigBase()
+	v, ok := base.Value[key]
+	if !ok || v == nil {
+		return fallback
+	}
+	if val, ok := v.(T); ok {
+		logExposure(&base, key)
+		return val
+	}
+	var val T
+	if !base.decodeKey(key, &val) {
+		return fallback
+	}
+	logExposure(&base, key)
+	return val
+}
+
+// Gets the slice at the given key with every element converted to T
+// Returns the fallback if the key is not found or any element cannot be decoded into T
+func GetSlice[T any](config TypedConfig, key string, fallback []T) []T {
+	return GetTyped(config, key, fallback)
+}
+
+// Gets the object at the given key with every value converted to V
+// Returns the fallback if the key is not found or any value cannot be decoded into V
+func GetMap[V any](config TypedConfig, key string, fallback map[string]V) map[string]V {
+	return GetTyped(config, key, fallback)
+}
+
+func (d *configBase) decodeKey(key string, v interface{}) bool {
+	var data []byte
+	if d.rawValue != nil {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(d.rawValue, &fields); err == nil {
+			data = fields[key]
+		}
+	}
+	if data == nil {
+		encoded, err := json.Marshal(d.Value[key])
+		if err != nil {
+			return false
+		}
+		data = encoded
+	}
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return false
+	}
+	return json.Unmarshal(data, v) == nil
+}
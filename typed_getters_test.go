@@ -0,0 +1,63 @@
+package statsig
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestTypedGetters(t *testing.T) {
+	raw := []byte(`{"id": 9007199254740993, "count": 3, "ratio": 1.5, "name": "str", "tags": ["a", "b"],
+		"limits": {"free": 1, "pro": 10}, "nested": {"enabled": true, "name": "n"}, "nothing": null}`)
+	jsonMap := make(map[string]interface{})
+	_ = json.Unmarshal(raw, &jsonMap)
+	config := NewConfig("test", jsonMap, "rule_id")
+	config.rawValue = raw
+
+	if GetTyped(config, "name", "") != "str" {
+		t.Errorf("Failed to get string")
+	}
+	if GetTyped(config, "count", 0) != 3 {
+		t.Errorf("Failed to get int")
+	}
+	if GetTyped[int64](config, "id", 0) != 9007199254740993 {
+		t.Errorf("Failed to get exact int64")
+	}
+	if GetTyped(config, "ratio", 0) != 0 {
+		t.Errorf("Expected the fallback for a non-integer number")
+	}
+	if GetTyped(config, "name", 7) != 7 {
+		t.Errorf("Expected the fallback for a mismatched type")
+	}
+	if GetTyped(config, "nothing", "fallback") != "fallback" || GetTyped(config, "missing", "fallback") != "fallback" {
+		t.Errorf("Expected the fallback for null and missing keys")
+	}
+	type nested struct {
+		Enabled bool   `json:"enabled"`
+		Name    string `json:"name"`
+	}
+	if GetTyped(config, "nested", nested{}) != (nested{Enabled: true, Name: "n"}) {
+		t.Errorf("Failed to decode struct")
+	}
+	if !reflect.DeepEqual(GetSlice(config, "tags", []string{}), []string{"a", "b"}) {
+		t.Errorf("Failed to get slice")
+	}
+	if GetSlice(config, "tags", []int{9})[0] != 9 {
+		t.Errorf("Expected the fallback for mismatched slice elements")
+	}
+	if !reflect.DeepEqual(GetMap(config, "limits", map[string]int{}), map[string]int{"free": 1, "pro": 10}) {
+		t.Errorf("Failed to get map")
+	}
+
+	exposures := make([]string, 0)
+	logFunc := func(config configBase, parameterName string) {
+		exposures = append(exposures, parameterName)
+	}
+	layer := NewLayer("layer", jsonMap, "rule_id", &logFunc)
+	if GetTyped(layer, "count", 0) != 3 || GetTyped(layer, "missing", 0) != 0 {
+		t.Errorf("Failed to get layer parameter")
+	}
+	if !reflect.DeepEqual(exposures, []string{"count"}) {
+		t.Errorf("Expected an exposure for the layer parameter only, got %v", exposures)
+	}
+}
@@ -1,5 +1,13 @@
 package statsig
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
 // User specific attributes for evaluating Feature Gates, Experiments, and DynamicConfigs
 //
 // NOTE: UserID is **required** - see https://docs.statsig.com/messages/serverRequiredUserID\
@@ -28,11 +36,36 @@ type Event struct {
 	Time      int64             `json:"time"`
 }
 
+// A gate that was evaluated as a dependency (holdouts, targeting gates) while evaluating another gate, config or layer
+type SecondaryExposure struct {
+	Gate      string `json:"gate"`
+	GateValue string `json:"gateValue"`
+	RuleID    string `json:"ruleID"`
+}
+
+// The value of a Feature Gate for a user, along with the rule and dependencies that decided it
+type FeatureGate struct {
+	Name               string              `json:"name"`
+	Value              bool                `json:"value"`
+	RuleID             string              `json:"rule_id"`
+	SecondaryExposures []SecondaryExposure `json:"secondary_exposures"`
+}
+
 type configBase struct {
 	Name        string                 `json:"name"`
 	Value       map[string]interface{} `json:"value"`
 	RuleID      string                 `json:"rule_id"`
 	LogExposure *func(configBase, string)
+	// Gates evaluated while evaluating this config or layer, in evaluation order
+	SecondaryExposures []SecondaryExposure `json:"secondary_exposures"`
+	// The name of the group of the rule that assigned the user, e.g. "Control" for an experiment. Empty when the
+	// user got the default value.
+	GroupName string `json:"group_name,omitempty"`
+	// The experiment the layer allocated the user to, empty when the layer allocated the user to none.
+	// Only set on layers.
+	AllocatedExperimentName string `json:"allocated_experiment_name,omitempty"`
+	// the config value as it came from Statsig, kept so numbers can be read without float64 rounding
+	rawValue json.RawMessage
 }
 
 // A json blob configured in the Statsig Console
@@ -98,6 +131,85 @@ func (d *configBase) GetNumber(key string, fallback float64) float64 {
 	return fallback
 }
 
+// Gets the int64 value at the given key in the DynamicConfig
+// Returns the fallback int64 if the item at the given key is not found or not an integer.
+// Unlike GetNumber, integers above 2^53 (IDs, epoch millis) are returned exactly.
+func (d *configBase) GetInt64(key string, fallback int64) int64 {
+	if n, ok := d.getRawNumber(key); ok {
+		if val, err := n.Int64(); err == nil {
+			logExposure(d, key)
+			return val
+		}
+		if f, err := n.Float64(); err == nil && isInt64(f) {
+			logExposure(d, key)
+			return int64(f)
+		}
+		return fallback
+	}
+	if v, ok := d.Value[key]; ok {
+		switch val := v.(type) {
+		case int:
+			logExposure(d, key)
+			return int64(val)
+		case int64:
+			logExposure(d, key)
+			return val
+		case json.Number:
+			if i, err := val.Int64(); err == nil {
+				logExposure(d, key)
+				return i
+			}
+		case float64:
+			if isInt64(val) {
+				logExposure(d, key)
+				return int64(val)
+			}
+		}
+	}
+	return fallback
+}
+
+// Decodes the DynamicConfig value into the given pointer, as json.Unmarshal would.
+// Numbers are decoded from the original JSON, so integer fields keep their exact value.
+// Layer parameter exposures are not logged by this method.
+func (d *configBase) UnmarshalInto(v interface{}) error {
+	if d.rawValue != nil {
+		return json.Unmarshal(d.rawValue, v)
+	}
+	data, err := json.Marshal(d.Value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Decodes the DynamicConfig value into the given struct pointer, as json.Unmarshal would.
+// The returned error names the config and wraps the underlying *json.UnmarshalTypeError or similar.
+func (d *DynamicConfig) UnmarshalValue(v interface{}) error {
+	if err := d.UnmarshalInto(v); err != nil {
+		return fmt.Errorf("failed to unmarshal the value of %s: %w", d.Name, err)
+	}
+	return nil
+}
+
+// Decodes the Layer parameters into the given struct pointer, as json.Unmarshal would.
+// On success an exposure is logged for every parameter in the layer, since the struct may read any of them.
+// The returned error names the layer and wraps the underlying *json.UnmarshalTypeError or similar.
+func (l *Layer) UnmarshalParams(v interface{}) error {
+	if err := l.UnmarshalInto(v); err != nil {
+		return fmt.Errorf("failed to unmarshal the parameters of %s: %w", l.Name, err)
+	}
+	params := make([]string, 0, len(l.Value))
+	for param := range l.Value {
+		params = append(params, param)
+	}
+	sort.Strings(params)
+	for _, param := range params {
+		logExposure(&l.configBase, param)
+	}
+	return nil
+}
+
 // Gets the boolean value at the given key in the DynamicConfig
 // Returns the fallback boolean if the item at the given key is not found or not of type boolean
 func (d *configBase) GetBool(key string, fallback bool) bool {
@@ -135,6 +247,24 @@ func (d *configBase) GetMap(key string, fallback map[string]interface{}) map[str
 	return fallback
 }
 
+func (d *configBase) getRawNumber(key string) (json.Number, bool) {
+	if d.rawValue == nil {
+		return "", false
+	}
+	var values map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(d.rawValue))
+	decoder.UseNumber()
+	if err := decoder.Decode(&values); err != nil {
+		return "", false
+	}
+	n, ok := values[key].(json.Number)
+	return n, ok
+}
+
+func isInt64(f float64) bool {
+	return f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64
+}
+
 func logExposure(c *configBase, parameterName string) {
 	if c == nil || c.LogExposure == nil {
 		return
@@ -143,3 +273,15 @@ func logExposure(c *configBase, parameterName string) {
 	l := *c.LogExposure
 	l(*c, parameterName)
 }
+
+func toSecondaryExposures(exposures []map[string]string) []SecondaryExposure {
+	result := make([]SecondaryExposure, 0, len(exposures))
+	for _, exposure := range exposures {
+		result = append(result, SecondaryExposure{
+			Gate:      exposure["gate"],
+			GateValue: exposure["gateValue"],
+			RuleID:    exposure["ruleID"],
+		})
+	}
+	return result
+}
@@ -2,7 +2,11 @@ package statsig
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -84,3 +88,134 @@ func TestBasic(t *testing.T) {
 		t.Errorf("Failed to get number array")
 	}
 }
+
+func TestLargeIntegers(t *testing.T) {
+	raw := []byte(`{"id": 9007199254740993, "small": 42, "ratio": 1.5, "name": "str"}`)
+	jsonMap := make(map[string]interface{})
+	_ = json.Unmarshal(raw, &jsonMap)
+
+	c := NewConfig("test", jsonMap, "rule_id")
+	c.rawValue = raw
+
+	if c.GetInt64("id", 0) != 9007199254740993 {
+		t.Errorf("Failed to get exact int64, got %d", c.GetInt64("id", 0))
+	}
+	if c.GetInt64("small", 0) != 42 {
+		t.Errorf("Failed to get int64")
+	}
+	if c.GetInt64("ratio", 7) != 7 {
+		t.Errorf("Failed to use fallback for non-integer number")
+	}
+	if c.GetInt64("name", 7) != 7 {
+		t.Errorf("Failed to use fallback for string")
+	}
+
+	var out struct {
+		ID    int64   `json:"id"`
+		Ratio float64 `json:"ratio"`
+		Name  string  `json:"name"`
+	}
+	if err := c.UnmarshalInto(&out); err != nil {
+		t.Errorf("Failed to unmarshal: %s", err.Error())
+	}
+	if out.ID != 9007199254740993 || out.Ratio != 1.5 || out.Name != "str" {
+		t.Errorf("Unexpected unmarshalled value %+v", out)
+	}
+
+	override := NewConfig("test", map[string]interface{}{"id": int64(9007199254740993)}, "override")
+	if override.GetInt64("id", 0) != 9007199254740993 {
+		t.Errorf("Failed to get int64 from override value")
+	}
+	out.ID = 0
+	if err := override.UnmarshalInto(&out); err != nil || out.ID != 9007199254740993 {
+		t.Errorf("Failed to unmarshal override value")
+	}
+}
+
+func TestUnmarshalValueAndParams(t *testing.T) {
+	type settings struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+	value := map[string]interface{}{"name": "str", "count": float64(3)}
+
+	config := NewConfig("a_config", value, "rule_id")
+	var out settings
+	if err := config.UnmarshalValue(&out); err != nil || out != (settings{Name: "str", Count: 3}) {
+		t.Errorf("Failed to unmarshal config value %+v %v", out, err)
+	}
+	var wrong struct {
+		Name int `json:"name"`
+	}
+	err := config.UnmarshalValue(&wrong)
+	var typeErr *json.UnmarshalTypeError
+	if err == nil || !errors.As(err, &typeErr) || !strings.Contains(err.Error(), "a_config") {
+		t.Errorf("Expected a type error naming the config, got %v", err)
+	}
+
+	exposures := make([]string, 0)
+	logFunc := func(config configBase, parameterName string) {
+		exposures = append(exposures, parameterName)
+	}
+	layer := NewLayer("a_layer", value, "rule_id", &logFunc)
+	if err := layer.UnmarshalParams(&wrong); err == nil || !strings.Contains(err.Error(), "a_layer") {
+		t.Errorf("Expected an error naming the layer, got %v", err)
+	}
+	if len(exposures) != 0 {
+		t.Errorf("Expected no exposures when unmarshalling fails")
+	}
+	out = settings{}
+	if err := layer.UnmarshalParams(&out); err != nil || out != (settings{Name: "str", Count: 3}) {
+		t.Errorf("Failed to unmarshal layer params %+v %v", out, err)
+	}
+	if !reflect.DeepEqual(exposures, []string{"count", "name"}) {
+		t.Errorf("Expected an exposure for every parameter, got %v", exposures)
+	}
+}
+
+func TestGroupNameAndAllocatedExperiment(t *testing.T) {
+	bytes, _ := os.ReadFile("download_config_specs.json")
+	c := NewClientWithOptions("secret-key", &Options{
+		LocalMode:            true,
+		BootstrapValues:      string(bytes),
+		OutputLoggerOptions:  getOutputLoggerOptionsForTest(t),
+		StatsigLoggerOptions: getStatsigLoggerOptionsForTest(t),
+	})
+	defer c.Shutdown()
+	groups := map[string]string{
+		"2RamGsERWbWMIMnSfOlQuX": "Control",
+		"2RamGujUou6h2bVNQWhtNZ": "Test",
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		user := User{UserID: fmt.Sprint(i)}
+		experiment := c.GetExperimentWithExposureLoggingDisabled(user, "sample_experiment")
+		if experiment.GroupName != groups[experiment.RuleID] || experiment.AllocatedExperimentName != "" {
+			t.Fatalf("Expected the group of rule %s, got %+v", experiment.RuleID, experiment)
+		}
+		seen[experiment.GroupName] = true
+		layer := c.GetLayerWithExposureLoggingDisabled(user, "a_layer")
+		if layer.AllocatedExperimentName != "sample_experiment" || layer.RuleID != experiment.RuleID || layer.GroupName != experiment.GroupName {
+			t.Fatalf("Expected the layer to report the experiment group %+v, got %+v", experiment, layer)
+		}
+	}
+	if !seen["Control"] || !seen["Test"] {
+		t.Errorf("Expected users in both groups, got %v", seen)
+	}
+
+	layer := c.GetLayerWithExposureLoggingDisabled(User{UserID: "123"}, "b_layer_no_alloc")
+	if layer.GroupName != "" || layer.AllocatedExperimentName != "" {
+		t.Errorf("Expected no allocation, got %+v", layer)
+	}
+	config := c.GetConfigWithExposureLoggingDisabled(User{UserID: "123", Email: "jane@example.com"}, "test_config")
+	if config.GroupName != "" || config.RuleID != "default" {
+		t.Errorf("Expected no group for the default value, got %+v", config)
+	}
+
+	user := User{UserID: "123"}
+	c.OverrideExperimentGroup(user, "sample_experiment", "Test")
+	if experiment := c.GetExperimentWithExposureLoggingDisabled(user, "sample_experiment"); experiment.GroupName != "Test" {
+		t.Errorf("Expected the overridden group, got %+v", experiment)
+	}
+}
@@ -0,0 +1,130 @@
+package statsig
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Builds a User, checking for the common mistakes Build reports. Start one with NewUser.
+//
+//	user, err := statsig.NewUser("123").WithEmail("a@statsig.com").WithCustomID("orgID", "org_1").Build()
+type UserBuilder struct {
+	user User
+	errs []error
+}
+
+// Starts a User with the given UserID. Pass an empty ID for users identified only by CustomIDs.
+func NewUser(userID string) *UserBuilder {
+	return &UserBuilder{user: User{UserID: userID}}
+}
+
+func (b *UserBuilder) WithEmail(email string) *UserBuilder {
+	b.user.Email = email
+	return b
+}
+
+func (b *UserBuilder) WithIpAddress(ip string) *UserBuilder {
+	b.user.IpAddress = ip
+	return b
+}
+
+func (b *UserBuilder) WithUserAgent(userAgent string) *UserBuilder {
+	b.user.UserAgent = userAgent
+	return b
+}
+
+// Sets the two letter ISO 3166-1 country code
+func (b *UserBuilder) WithCountry(country string) *UserBuilder {
+	b.user.Country = country
+	return b
+}
+
+func (b *UserBuilder) WithLocale(locale string) *UserBuilder {
+	b.user.Locale = locale
+	return b
+}
+
+func (b *UserBuilder) WithAppVersion(appVersion string) *UserBuilder {
+	b.user.AppVersion = appVersion
+	return b
+}
+
+// Sets a custom attribute, which is logged with the user's events and exposures
+func (b *UserBuilder) WithCustom(key string, value interface{}) *UserBuilder {
+	if b.user.Custom == nil {
+		b.user.Custom = make(map[string]interface{})
+	}
+	b.user.Custom[key] = value
+	return b
+}
+
+// Sets an attribute used for targeting only, which is never logged
+func (b *UserBuilder) WithPrivateAttribute(key string, value interface{}) *UserBuilder {
+	if b.user.PrivateAttributes == nil {
+		b.user.PrivateAttributes = make(map[string]interface{})
+	}
+	b.user.PrivateAttributes[key] = value
+	return b
+}
+
+// Sets the ID of the user for a custom unit type, such as stableID or a company's orgID
+func (b *UserBuilder) WithCustomID(idType string, id string) *UserBuilder {
+	switch {
+	case idType == "":
+		b.errs = append(b.errs, fmt.Errorf("custom ID %q has no ID type", id))
+	case strings.EqualFold(idType, "userID"):
+		b.errs = append(b.errs, errors.New("the userID is passed to NewUser, not set as a custom ID"))
+	case id == "":
+		b.errs = append(b.errs, fmt.Errorf("custom ID %q is empty", idType))
+	default:
+		if b.user.CustomIDs == nil {
+			b.user.CustomIDs = make(map[string]string)
+		}
+		b.user.CustomIDs[idType] = id
+	}
+	return b
+}
+
+// Returns the User, and an error if it has no UserID or CustomIDs, a custom ID was invalid, or an attribute is
+// both custom and private, which would log the value the private attribute was meant to keep out of logs.
+// The User does not share maps with the builder, so the builder can be reused to build similar users.
+func (b *UserBuilder) Build() (User, error) {
+	errs := append([]error{}, b.errs...)
+	if isEmptyUser(b.user) {
+		errs = append(errs, errors.New(EmptyUserError))
+	}
+	for key := range b.user.PrivateAttributes {
+		if _, exists := b.user.Custom[key]; exists {
+			errs = append(errs, fmt.Errorf("%q is both a custom and a private attribute, so its value would be logged", key))
+		}
+	}
+	user := b.user
+	user.Custom = copyUserAttributes(b.user.Custom)
+	user.PrivateAttributes = copyUserAttributes(b.user.PrivateAttributes)
+	if b.user.CustomIDs != nil {
+		user.CustomIDs = make(map[string]string, len(b.user.CustomIDs))
+		for idType, id := range b.user.CustomIDs {
+			user.CustomIDs[idType] = id
+		}
+	}
+	if len(errs) > 0 {
+		messages := make([]string, len(errs))
+		for i, err := range errs {
+			messages[i] = err.Error()
+		}
+		return user, fmt.Errorf("invalid user: %s", strings.Join(messages, "; "))
+	}
+	return user, nil
+}
+
+func copyUserAttributes(attributes map[string]interface{}) map[string]interface{} {
+	if attributes == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(attributes))
+	for k, v := range attributes {
+		copied[k] = v
+	}
+	return copied
+}
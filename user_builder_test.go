@@ -0,0 +1,56 @@
+package statsig
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUserBuilder(t *testing.T) {
+	builder := NewUser("123").
+		WithEmail("a@statsig.com").
+		WithCountry("US").
+		WithCustom("plan", "pro").
+		WithPrivateAttribute("ssn", "000-00-0000").
+		WithCustomID("orgID", "org_1")
+	user, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := User{
+		UserID:            "123",
+		Email:             "a@statsig.com",
+		Country:           "US",
+		Custom:            map[string]interface{}{"plan": "pro"},
+		PrivateAttributes: map[string]interface{}{"ssn": "000-00-0000"},
+		CustomIDs:         map[string]string{"orgID": "org_1"},
+	}
+	if !reflect.DeepEqual(user, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, user)
+	}
+
+	other, _ := builder.WithCustom("plan", "free").Build()
+	if user.Custom["plan"] != "pro" || other.Custom["plan"] != "free" {
+		t.Errorf("Expected built users not to share maps with the builder")
+	}
+
+	if user, err := NewUser("").WithCustomID("stableID", "abc").Build(); err != nil || user.CustomIDs["stableID"] != "abc" {
+		t.Errorf("Expected a user with only custom IDs to be valid, got %v", err)
+	}
+
+	invalid := []struct {
+		builder  *UserBuilder
+		contains string
+	}{
+		{NewUser(""), "UserID"},
+		{NewUser("123").WithCustomID("userID", "123"), "passed to NewUser"},
+		{NewUser("123").WithCustomID("", "abc"), "no ID type"},
+		{NewUser("123").WithCustomID("orgID", ""), "is empty"},
+		{NewUser("123").WithCustom("email", "a").WithPrivateAttribute("email", "a"), "would be logged"},
+	}
+	for _, tc := range invalid {
+		if _, err := tc.builder.Build(); err == nil || !strings.Contains(err.Error(), tc.contains) {
+			t.Errorf("Expected an error containing %q, got %v", tc.contains, err)
+		}
+	}
+}
@@ -30,6 +30,15 @@ func getHashUint64Encoding(key string) uint64 {
 	return binary.BigEndian.Uint64(hash)
 }
 
+// The 32 bit djb2 hash, formatted as a decimal string
+func getDJB2Hash(key string) string {
+	var hash int32
+	for _, c := range key {
+		hash = (hash << 5) - hash + int32(c)
+	}
+	return strconv.FormatInt(int64(hash), 10)
+}
+
 func getHashBase64StringEncoding(configName string) string {
 	hash := getHash(configName)
 	return base64.StdEncoding.EncodeToString(hash)